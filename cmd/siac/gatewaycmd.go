@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"text/tabwriter"
+	"time"
 
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/spf13/cobra"
@@ -17,6 +19,37 @@ var (
 		Run:   wrap(gatewayaddresscmd),
 	}
 
+	gatewayBandwidthCmd = &cobra.Command{
+		Use:   "bandwidth",
+		Short: "View the traffic of the gateway",
+		Long:  "View the number of bytes that the gateway sent and received, in total and per RPC.",
+		Run:   wrap(gatewaybandwidthcmd),
+	}
+
+	gatewayBlacklistCmd = &cobra.Command{
+		Use:   "blacklist",
+		Short: "View the blacklisted hosts",
+		Long: `View the hosts that the gateway refuses to connect to. Hosts are blacklisted
+by the user, or automatically for a while when their peers misbehave.`,
+		Run: wrap(gatewayblacklistcmd),
+	}
+
+	gatewayBlacklistAddCmd = &cobra.Command{
+		Use:   "add [ip] [ip]...",
+		Short: "Blacklist hosts",
+		Long: `Blacklist one or more hosts by IP address. The gateway disconnects from their
+peers and refuses new connections. Without --duration, the hosts stay
+blacklisted until they are removed.`,
+		Run: gatewayblacklistaddcmd,
+	}
+
+	gatewayBlacklistRemoveCmd = &cobra.Command{
+		Use:   "remove [ip] [ip]...",
+		Short: "Remove hosts from the blacklist",
+		Long:  "Remove one or more hosts from the blacklist.",
+		Run:   gatewayblacklistremovecmd,
+	}
+
 	gatewayCmd = &cobra.Command{
 		Use:   "gateway",
 		Short: "Perform gateway actions",
@@ -44,6 +77,15 @@ var (
 		Long:  "View the current peer list.",
 		Run:   wrap(gatewaylistcmd),
 	}
+
+	gatewayRatelimitCmd = &cobra.Command{
+		Use:   "ratelimit [maxdownloadspeed] [maxuploadspeed]",
+		Short: "Set the global bandwidth limits of the gateway",
+		Long: `Set the maximum download and upload speed of all gateway traffic, e.g.
+'siac gateway ratelimit 1MB 500KB'. Speeds are specified as a filesize per
+second. A speed of 0 removes the limit.`,
+		Run: wrap(gatewayratelimitcmd),
+	}
 )
 
 // gatewayconnectcmd is the handler for the command `siac gateway add [address]`.
@@ -84,7 +126,10 @@ func gatewaycmd() {
 		die("Could not get gateway address:", err)
 	}
 	fmt.Println("Address:", info.NetAddress)
+	fmt.Println("Node ID:", info.NodeID)
 	fmt.Println("Active peers:", len(info.Peers))
+	fmt.Printf("Traffic: %v up, %v down\n", filesizeUnits(int64(info.Bandwidth.Upload)), filesizeUnits(int64(info.Bandwidth.Download)))
+	fmt.Printf("Rate limits: %v up, %v down\n", speedUnits(info.MaxUploadSpeed), speedUnits(info.MaxDownloadSpeed))
 }
 
 // gatewaylistcmd is the handler for the command `siac gateway list`.
@@ -100,9 +145,118 @@ func gatewaylistcmd() {
 	}
 	fmt.Println(len(info.Peers), "active peers:")
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "Version\tOutbound\tAddress")
+	fmt.Fprintln(w, "Version\tOutbound\tEncrypted\tAddress\tUp\tDown")
 	for _, peer := range info.Peers {
-		fmt.Fprintf(w, "%v\t%v\t%v\n", peer.Version, yesNo(!peer.Inbound), peer.NetAddress)
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", peer.Version, yesNo(!peer.Inbound), yesNo(peer.Encrypted), peer.NetAddress,
+			filesizeUnits(int64(peer.Upload)), filesizeUnits(int64(peer.Download)))
+	}
+	w.Flush()
+}
+
+// gatewayblacklistcmd is the handler for the command `siac gateway blacklist`.
+// Prints the blacklisted hosts.
+func gatewayblacklistcmd() {
+	gbg, err := httpClient.GatewayBlacklistGet()
+	if err != nil {
+		die("Could not get blacklist:", err)
+	}
+	if len(gbg.Blacklist) == 0 {
+		fmt.Println("No blacklisted hosts.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Host\tUntil\tReason")
+	for _, entry := range gbg.Blacklist {
+		until := "permanent"
+		if entry.Expiry != 0 {
+			until = time.Unix(int64(entry.Expiry), 0).Format(time.RFC822)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\n", entry.Host, until, entry.Reason)
+	}
+	w.Flush()
+}
+
+// gatewayblacklistaddcmd is the handler for the command `siac gateway
+// blacklist add [ip]...`. Blacklists the given hosts.
+func gatewayblacklistaddcmd(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	var duration time.Duration
+	if gatewayBlacklistDuration != "" {
+		var err error
+		duration, err = time.ParseDuration(gatewayBlacklistDuration)
+		if err != nil {
+			die("Could not parse duration:", err)
+		}
+	}
+	if err := httpClient.GatewayBlacklistAddPost(args, duration); err != nil {
+		die("Could not blacklist hosts:", err)
+	}
+	fmt.Printf("Blacklisted %v hosts.\n", len(args))
+}
+
+// gatewayblacklistremovecmd is the handler for the command `siac gateway
+// blacklist remove [ip]...`. Removes the given hosts from the blacklist.
+func gatewayblacklistremovecmd(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	if err := httpClient.GatewayBlacklistRemovePost(args); err != nil {
+		die("Could not remove hosts from the blacklist:", err)
+	}
+	fmt.Printf("Removed %v hosts from the blacklist.\n", len(args))
+}
+
+// gatewaybandwidthcmd is the handler for the command `siac gateway
+// bandwidth`. Prints the traffic of the gateway per RPC.
+func gatewaybandwidthcmd() {
+	info, err := httpClient.GatewayGet()
+	if err != nil {
+		die("Could not get gateway bandwidth:", err)
+	}
+	fmt.Printf("Total: %v up, %v down\n", filesizeUnits(int64(info.Bandwidth.Upload)), filesizeUnits(int64(info.Bandwidth.Download)))
+	if len(info.Bandwidth.RPCs) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RPC\tUp\tDown")
+	for _, rpc := range info.Bandwidth.RPCs {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", rpc.Name, filesizeUnits(int64(rpc.Upload)), filesizeUnits(int64(rpc.Download)))
 	}
 	w.Flush()
 }
+
+// gatewayratelimitcmd is the handler for the command `siac gateway ratelimit
+// [maxdownloadspeed] [maxuploadspeed]`. Sets the global bandwidth limits of
+// the gateway.
+func gatewayratelimitcmd(downloadSpeedStr, uploadSpeedStr string) {
+	downloadSpeed, err := parseSpeed(downloadSpeedStr)
+	if err != nil {
+		die("Could not parse maxdownloadspeed:", err)
+	}
+	uploadSpeed, err := parseSpeed(uploadSpeedStr)
+	if err != nil {
+		die("Could not parse maxuploadspeed:", err)
+	}
+	if err := httpClient.GatewayRateLimitPost(downloadSpeed, uploadSpeed); err != nil {
+		die("Could not set gateway rate limits:", err)
+	}
+	fmt.Printf("Set gateway rate limits to %v down, %v up\n", speedUnits(downloadSpeed), speedUnits(uploadSpeed))
+}
+
+// parseSpeed parses a speed given as a filesize per second, where 0 means
+// unlimited.
+func parseSpeed(speed string) (int64, error) {
+	if speed == "0" {
+		return 0, nil
+	}
+	bytes, err := parseFilesize(speed)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(bytes, 10, 64)
+}
@@ -59,12 +59,29 @@ Available settings:
      minstorageprice:           currency / TB / Month
      minuploadbandwidthprice:   currency / TB
 
+     maxconnections:      number
+     maxconnectionsperip: number
+     maxdownloadspeed:    bytes / second
+     maxuploadspeed:      bytes / second
+
+     maxrentercollateral: currency
+     minrenewfilesize:    bytes
+     maxrenewextension:   blocks
+
 Currency units can be specified, e.g. 10SC; run 'siac help wallet' for details.
 
-Durations (maxduration and windowsize) must be specified in either blocks (b),
+Durations (maxduration, windowsize and maxrenewextension) must be specified in either blocks (b),
 hours (h), days (d), or weeks (w). A block is approximately 10 minutes, so one
 hour is six blocks, a day is 144 blocks, and a week is 1008 blocks.
 
+Speeds (maxdownloadspeed and maxuploadspeed) are specified as a filesize per
+second, e.g. 10MB for 10 megabytes per second. Setting a speed to 0B or a
+number of connections to 0 removes the limit.
+
+The renewal policy (maxrentercollateral, minrenewfilesize and maxrenewextension)
+declines renewals of contracts that the host does not want to carry forward.
+Setting a value to 0 disables the check.
+
 For a description of each parameter, see doc/API.md.
 
 To configure the host to accept new contracts, set acceptingcontracts to true:
@@ -116,6 +133,24 @@ other storage folders.`,
 		Run: wrap(hostfolderresizecmd),
 	}
 
+	hostMaintenanceCmd = &cobra.Command{
+		Use:   "maintenance [on|off]",
+		Short: "View or change the maintenance mode of the host",
+		Long: `View or change the maintenance mode of the host. While in maintenance mode the
+host refuses new contracts, renewals and uploads, but keeps serving downloads
+and submitting storage proofs for its existing contracts.
+
+Without arguments, the maintenance state is shown together with the next window
+in which the host can go offline without missing a storage proof. Use the
+--duration flag to find a window of at least the given length, e.g.:
+	siac host maintenance --duration 1d
+
+When enabling maintenance mode, the --announce flag makes the host announce
+itself again once maintenance mode is disabled, e.g.:
+	siac host maintenance on --announce`,
+		Run: hostmaintenancecmd,
+	}
+
 	hostSectorCmd = &cobra.Command{
 		Use:   "sector",
 		Short: "Add or delete a sector (add not supported)",
@@ -208,6 +243,15 @@ Host Internal Settings:
 	minstorageprice:           %v / TB / Month
 	minuploadbandwidthprice:   %v / TB
 
+	maxconnections:      %v
+	maxconnectionsperip: %v
+	maxdownloadspeed:    %v
+	maxuploadspeed:      %v
+
+	maxrentercollateral: %v
+	minrenewfilesize:    %v
+	maxrenewextension:   %v Hours
+
 Host Financials:
 	Contract Count:               %v
 	Transaction Fee Compensation: %v
@@ -234,6 +278,12 @@ RPC Stats:
 	Revise Calls:       %v
 	Settings Calls:     %v
 	FormContract Calls: %v
+
+Renewal Policy:
+	Renewals Accepted:       %v
+	Declined for Collateral: %v
+	Declined for Extension:  %v
+	Declined for File Size:  %v
 `,
 			connectabilityString,
 
@@ -251,6 +301,12 @@ RPC Stats:
 			currencyUnits(is.MinStoragePrice.Mul(modules.BlockBytesPerMonthTerabyte)),
 			currencyUnits(is.MinUploadBandwidthPrice.Mul(modules.BytesPerTerabyte)),
 
+			limitUnits(is.MaxConnections), limitUnits(is.MaxConnectionsPerIP),
+			speedUnits(is.MaxDownloadSpeed), speedUnits(is.MaxUploadSpeed),
+
+			currencyUnits(is.MaxRenterCollateral),
+			filesizeUnits(int64(is.MinRenewFileSize)), is.MaxRenewExtension/6,
+
 			fm.ContractCount, currencyUnits(fm.ContractCompensation),
 			currencyUnits(fm.PotentialContractCompensation),
 			currencyUnits(fm.TransactionFeeExpenses),
@@ -269,7 +325,10 @@ RPC Stats:
 
 			nm.ErrorCalls, nm.UnrecognizedCalls, nm.DownloadCalls,
 			nm.RenewCalls, nm.ReviseCalls, nm.SettingsCalls,
-			nm.FormContractCalls)
+			nm.FormContractCalls,
+
+			nm.RenewalsAccepted, nm.RenewalsDeclinedCollateral,
+			nm.RenewalsDeclinedExtension, nm.RenewalsDeclinedFileSize)
 	} else {
 		fmt.Printf(`Host info:
 	Connectability Status: %v
@@ -329,7 +388,7 @@ func hostconfigcmd(param, value string) {
 	var err error
 	switch param {
 	// currency (convert to hastings)
-	case "collateralbudget", "maxcollateral", "mincontractprice":
+	case "collateralbudget", "maxcollateral", "mincontractprice", "maxrentercollateral":
 		value, err = parseCurrency(value)
 		if err != nil {
 			die("Could not parse "+param+":", err)
@@ -365,14 +424,22 @@ func hostconfigcmd(param, value string) {
 		}
 
 	// duration (convert to blocks)
-	case "maxduration", "windowsize":
+	case "maxduration", "windowsize", "maxrenewextension":
 		value, err = parsePeriod(value)
 		if err != nil {
 			die("Could not parse "+param+":", err)
 		}
 
+	// size (convert to bytes), speed (convert to bytes/second)
+	case "minrenewfilesize", "maxdownloadspeed", "maxuploadspeed":
+		value, err = parseFilesize(value)
+		if err != nil {
+			die("Could not parse "+param+":", err)
+		}
+
 	// other valid settings
-	case "maxdownloadbatchsize", "maxrevisebatchsize", "netaddress":
+	case "maxdownloadbatchsize", "maxrevisebatchsize", "netaddress",
+		"maxconnections", "maxconnectionsperip":
 
 	// invalid settings
 	default:
@@ -513,3 +580,66 @@ func hostsectordeletecmd(root string) {
 	}
 	fmt.Println("Deleted sector", root)
 }
+
+// hostmaintenancecmd is the handler for the command `siac host maintenance
+// [on|off]`. Displays or changes the maintenance mode of the host.
+func hostmaintenancecmd(cmd *cobra.Command, args []string) {
+	switch len(args) {
+	case 0:
+	case 1:
+		var enabled bool
+		switch strings.ToLower(args[0]) {
+		case "on":
+			enabled = true
+		case "off":
+			enabled = false
+		default:
+			cmd.UsageFunc()(cmd)
+			os.Exit(exitCodeUsage)
+		}
+		err := httpClient.HostMaintenancePost(enabled, hostMaintenanceAnnounce)
+		if err != nil {
+			die("Could not change maintenance mode:", err)
+		}
+		if enabled {
+			fmt.Println("The host is now in maintenance mode.")
+		} else {
+			fmt.Println("The host has left maintenance mode.")
+		}
+	default:
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+
+	minDuration := "1"
+	if hostMaintenanceDuration != "" {
+		var err error
+		minDuration, err = parsePeriod(hostMaintenanceDuration)
+		if err != nil {
+			die("Could not parse duration:", err)
+		}
+	}
+	var duration types.BlockHeight
+	fmt.Sscan(minDuration, &duration)
+	hmg, err := httpClient.HostMaintenanceGet(duration)
+	if err != nil {
+		die("Could not get maintenance status:", err)
+	}
+
+	status := "Disabled"
+	if hmg.Enabled {
+		status = fmt.Sprintf("Enabled since block %v", hmg.EnabledHeight)
+		if hmg.AnnounceOnExit {
+			status += " (the host will re-announce when maintenance ends)"
+		}
+	}
+	window := fmt.Sprintf("from block %v onwards", hmg.NextDowntime.Start)
+	if hmg.NextDowntime.End != 0 {
+		window = fmt.Sprintf("from block %v until block %v (%v blocks)", hmg.NextDowntime.Start,
+			hmg.NextDowntime.End, hmg.NextDowntime.End-hmg.NextDowntime.Start)
+	}
+	fmt.Printf(`Maintenance Mode:   %v
+Active Obligations: %v
+Next Safe Downtime: %v
+`, status, hmg.ActiveObligations, window)
+}
@@ -8,19 +8,36 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/node/api/client"
 )
 
 var (
 	// Flags.
-	hostContractOutputType string // output type for host contracts
-	hostVerbose            bool   // display additional host info
-	initForce              bool   // destroy and re-encrypt the wallet on init if it already exists
-	initPassword           bool   // supply a custom password when creating a wallet
-	renterAllContracts     bool   // Show all active and expired contracts
-	renterDownloadAsync    bool   // Downloads files asynchronously
-	renterListVerbose      bool   // Show additional info about uploaded files.
-	renterShowHistory      bool   // Show download history in addition to download queue.
+	gatewayBlacklistDuration   string // duration of a ban, e.g. 24h
+	hostContractOutputType     string // output type for host contracts
+	hostMaintenanceAnnounce    bool   // re-announce the host when maintenance mode ends
+	hostMaintenanceDuration    string // minimum length of the downtime window to find
+	hostVerbose                bool   // display additional host info
+	initForce                  bool   // destroy and re-encrypt the wallet on init if it already exists
+	initPassword               bool   // supply a custom password when creating a wallet
+	renterAllContracts         bool   // Show all active and expired contracts
+	renterDownloadAsync        bool   // Downloads files asynchronously
+	renterListVerbose          bool   // Show additional info about uploaded files.
+	renterShowHistory          bool   // Show download history in addition to download queue.
+	walletAddressesBalance     bool   // list the balance of each address
+	walletBumpFeeFee           string // fee of a transaction whose fee is bumped
+	walletBumpFeeMethod        string // method used to bump the fee of a transaction
+	walletMultisigUnused       bool   // skip the rescan when creating a multisig address
+	walletSendOutputs          string // comma-separated IDs of the outputs that fund a transaction
+	walletSendTarget           uint64 // number of blocks within which a transaction should be confirmed
+	walletSignKeys             uint64 // number of seed keys to search when signing a transaction
+	walletTransactionsCSV      bool   // print the transaction history as comma separated values
+	walletWatchRemove          bool   // remove the watch-only addresses instead of adding them
+	walletWatchUnused          bool   // skip the rescan when adding watch-only addresses
+	walletWebhookConfirmations uint64 // number of confirmations a webhook is notified of
+	walletWebhookEvents        string // comma-separated event types a webhook is notified of
+	walletWebhookSecret        string // secret used to sign the requests to a webhook
 )
 
 var (
@@ -90,11 +107,13 @@ func main() {
 	updateCmd.AddCommand(updateCheckCmd)
 
 	root.AddCommand(hostCmd)
-	hostCmd.AddCommand(hostConfigCmd, hostAnnounceCmd, hostFolderCmd, hostContractCmd, hostSectorCmd)
+	hostCmd.AddCommand(hostConfigCmd, hostAnnounceCmd, hostFolderCmd, hostContractCmd, hostMaintenanceCmd, hostSectorCmd)
 	hostFolderCmd.AddCommand(hostFolderAddCmd, hostFolderRemoveCmd, hostFolderResizeCmd)
 	hostSectorCmd.AddCommand(hostSectorDeleteCmd)
 	hostCmd.Flags().BoolVarP(&hostVerbose, "verbose", "v", false, "Display detailed host info")
 	hostContractCmd.Flags().StringVarP(&hostContractOutputType, "type", "t", "value", "Select output type")
+	hostMaintenanceCmd.Flags().BoolVarP(&hostMaintenanceAnnounce, "announce", "", false, "Re-announce the host when maintenance mode is disabled")
+	hostMaintenanceCmd.Flags().StringVarP(&hostMaintenanceDuration, "duration", "d", "", "Minimum length of the downtime window, e.g. 6h or 1d")
 
 	root.AddCommand(hostdbCmd)
 	hostdbCmd.AddCommand(hostdbViewCmd)
@@ -105,15 +124,32 @@ func main() {
 	minerCmd.AddCommand(minerStartCmd, minerStopCmd)
 
 	root.AddCommand(walletCmd)
-	walletCmd.AddCommand(walletAddressCmd, walletAddressesCmd, walletChangepasswordCmd, walletInitCmd, walletInitSeedCmd,
-		walletLoadCmd, walletLockCmd, walletSeedsCmd, walletSendCmd, walletSweepCmd,
-		walletBalanceCmd, walletTransactionsCmd, walletUnlockCmd)
+	walletCmd.AddCommand(walletAddressCmd, walletAddressesCmd, walletAutoLockCmd, walletBroadcastCmd, walletBumpFeeCmd, walletChangepasswordCmd, walletCoinSelectionCmd, walletInitCmd, walletInitSeedCmd,
+		walletLabelCmd, walletLoadCmd, walletLockCmd, walletMultisigCmd, walletOutputsCmd, walletSeedsCmd, walletSendCmd, walletSignCmd, walletSpendingPasswordCmd, walletSweepCmd, walletTimelockCmd,
+		walletBalanceCmd, walletTransactionsCmd, walletUnlockCmd, walletWatchCmd, walletWebhooksCmd)
+	walletAddressesCmd.Flags().BoolVarP(&walletAddressesBalance, "balance", "", false, "List the balance of each address")
+	walletBumpFeeCmd.Flags().StringVarP(&walletBumpFeeFee, "fee", "", "", "Fee to pay, e.g. 1SC")
+	walletBumpFeeCmd.Flags().StringVarP(&walletBumpFeeMethod, "method", "", string(modules.BumpFeeReplace), "Method used to raise the fee: rbf or cpfp")
 	walletInitCmd.Flags().BoolVarP(&initPassword, "password", "p", false, "Prompt for a custom password")
 	walletInitCmd.Flags().BoolVarP(&initForce, "force", "", false, "destroy the existing wallet and re-encrypt")
 	walletInitSeedCmd.Flags().BoolVarP(&initForce, "force", "", false, "destroy the existing wallet")
 	walletLoadCmd.AddCommand(walletLoad033xCmd, walletLoadSeedCmd, walletLoadSiagCmd)
+	walletMultisigCmd.AddCommand(walletMultisigCreateCmd, walletMultisigPublicKeyCmd, walletMultisigSignCmd, walletMultisigSpendCmd)
+	walletTimelockCmd.AddCommand(walletTimelockCreateCmd)
+	walletMultisigCreateCmd.Flags().BoolVarP(&walletMultisigUnused, "unused", "", false, "Skip the rescan for an address that has never been used")
+	walletOutputsCmd.AddCommand(walletOutputsLockCmd, walletOutputsUnlockCmd)
 	walletSendCmd.AddCommand(walletSendSiacoinsCmd, walletSendSiafundsCmd)
+	walletSendSiacoinsCmd.Flags().StringVarP(&walletSendOutputs, "outputs", "", "", "Comma-separated IDs of the outputs that fund the transaction")
+	walletSendSiacoinsCmd.Flags().Uint64VarP(&walletSendTarget, "target", "", 0, "Pay the fee for being confirmed within this many blocks")
+	walletTransactionsCmd.Flags().BoolVarP(&walletTransactionsCSV, "csv", "", false, "Print the confirmed transactions as comma separated values")
 	walletUnlockCmd.Flags().BoolVarP(&initPassword, "password", "p", false, "Display interactive password prompt even if SIA_WALLET_PASSWORD is set")
+	walletSignCmd.Flags().Uint64VarP(&walletSignKeys, "keys", "", 1e6, "Maximum number of keys of the seed to search")
+	walletWatchCmd.Flags().BoolVarP(&walletWatchRemove, "remove", "", false, "Remove the addresses instead of adding them")
+	walletWatchCmd.Flags().BoolVarP(&walletWatchUnused, "unused", "", false, "Skip the rescan for addresses that have never been used")
+	walletWebhooksCmd.AddCommand(walletWebhooksAddCmd, walletWebhooksRemoveCmd)
+	walletWebhooksAddCmd.Flags().StringVarP(&walletWebhookSecret, "secret", "", "", "Secret used to sign the requests to the webhook")
+	walletWebhooksAddCmd.Flags().StringVarP(&walletWebhookEvents, "events", "", "", "Comma-separated event types to post to the webhook")
+	walletWebhooksAddCmd.Flags().Uint64VarP(&walletWebhookConfirmations, "confirmations", "", 0, "Only post confirmations events for this number of confirmations")
 
 	root.AddCommand(renterCmd)
 	renterCmd.AddCommand(renterFilesDeleteCmd, renterFilesDownloadCmd,
@@ -133,7 +169,9 @@ func main() {
 	renterExportCmd.AddCommand(renterExportContractTxnsCmd)
 
 	root.AddCommand(gatewayCmd)
-	gatewayCmd.AddCommand(gatewayConnectCmd, gatewayDisconnectCmd, gatewayAddressCmd, gatewayListCmd)
+	gatewayCmd.AddCommand(gatewayConnectCmd, gatewayDisconnectCmd, gatewayAddressCmd, gatewayListCmd, gatewayBandwidthCmd, gatewayBlacklistCmd, gatewayRatelimitCmd)
+	gatewayBlacklistCmd.AddCommand(gatewayBlacklistAddCmd, gatewayBlacklistRemoveCmd)
+	gatewayBlacklistAddCmd.Flags().StringVarP(&gatewayBlacklistDuration, "duration", "d", "", "Remove the hosts from the blacklist after this duration, e.g. 24h")
 
 	root.AddCommand(consensusCmd)
 
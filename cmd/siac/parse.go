@@ -22,6 +22,24 @@ func filesizeUnits(size int64) string {
 	return fmt.Sprintf("%.*f %s", i, float64(size)/math.Pow10(3*i), sizes[i])
 }
 
+// speedUnits returns a string that displays a speed limit in human-readable
+// units, where a speed of 0 means that there is no limit.
+func speedUnits(bps int64) string {
+	if bps == 0 {
+		return "unlimited"
+	}
+	return filesizeUnits(bps) + "/s"
+}
+
+// limitUnits returns a string that displays a limit, where a limit of 0 means
+// that there is no limit.
+func limitUnits(limit uint64) string {
+	if limit == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprint(limit)
+}
+
 // parseFilesize converts strings of form 10GB to a size in bytes. Fractional
 // sizes are truncated at the byte size.
 func parseFilesize(strSize string) (string, error) {
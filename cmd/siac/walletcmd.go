@@ -1,17 +1,27 @@
 package main
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"math/big"
 	"os"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
+	"github.com/NebulousLabs/entropy-mnemonics"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/ssh/terminal"
 
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/wallet"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -26,8 +36,9 @@ var (
 	walletAddressesCmd = &cobra.Command{
 		Use:   "addresses",
 		Short: "List all addresses",
-		Long:  "List all addresses that have been generated by the wallet.",
-		Run:   wrap(walletaddressescmd),
+		Long: `List all addresses that have been generated by the wallet. With --balance,
+the confirmed and unconfirmed siacoins of each address are listed as well.`,
+		Run: wrap(walletaddressescmd),
 	}
 
 	walletBalanceCmd = &cobra.Command{
@@ -37,6 +48,46 @@ var (
 		Run:   wrap(walletbalancecmd),
 	}
 
+	walletBroadcastCmd = &cobra.Command{
+		Use:   "broadcast [txn]",
+		Short: "Broadcast a signed transaction",
+		Long: `Submit a signed transaction to the transaction pool, which broadcasts it to
+the network. 'txn' is the base64 encoded transaction printed by 'siac wallet sign'.`,
+		Run: wrap(walletbroadcastcmd),
+	}
+
+	walletBumpFeeCmd = &cobra.Command{
+		Use:   "bumpfee [txid]",
+		Short: "Raise the fee of an unconfirmed transaction",
+		Long: `Raise the miner fee of an unconfirmed transaction of the wallet that is stuck
+in the transaction pool. The available methods are:
+	rbf   replace the transaction with one that spends the same inputs and
+	      pays a higher fee (default)
+	cpfp  spend the change output of the transaction in a child transaction
+	      that pays a high fee
+With rbf, --fee is the new total fee of the transaction; with cpfp, it is the
+fee of the child. If --fee is not given, the wallet chooses the fee.`,
+		Run: wrap(walletbumpfeecmd),
+	}
+
+	walletLabelCmd = &cobra.Command{
+		Use:   "label [id] [label] [note]",
+		Short: "Label a transaction or an address",
+		Long: `Attach a label and an optional note to a transaction or an address. 'id' is
+either a transaction ID or an address; addresses of counterparties can be
+labeled as well. An empty label removes the label. Without arguments, all
+labels are listed.`,
+		Run: walletlabelcmd,
+	}
+
+	walletAutoLockCmd = &cobra.Command{
+		Use:   "autolock [seconds]",
+		Short: "View or change the auto-lock timeout",
+		Long: `View or change the number of seconds after which an unlocked wallet locks
+itself again. A timeout of 0 disables the auto-lock.`,
+		Run: walletautolockcmd,
+	}
+
 	walletChangepasswordCmd = &cobra.Command{
 		Use:   "change-password",
 		Short: "Change the wallet password",
@@ -44,6 +95,16 @@ var (
 		Run:   wrap(walletchangepasswordcmd),
 	}
 
+	walletSpendingPasswordCmd = &cobra.Command{
+		Use:   "spending-password",
+		Short: "Set or remove the spending password",
+		Long: `Set a second password that is required to send coins or sign transactions
+with the wallet, in addition to the API password. An empty spending password
+removes it. If the SIA_SPENDING_PASSWORD environment variable is set, siac
+uses it instead of prompting for the spending password.`,
+		Run: wrap(walletspendingpasswordcmd),
+	}
+
 	walletCmd = &cobra.Command{
 		Use:   "wallet",
 		Short: "Perform wallet actions",
@@ -121,6 +182,28 @@ By default the wallet encryption / unlock password is the same as the generated
 		Run:   wrap(walletseedscmd),
 	}
 
+	walletOutputsCmd = &cobra.Command{
+		Use:   "outputs",
+		Short: "List the spendable outputs of the wallet",
+		Long:  "List the confirmed siacoin outputs that the wallet can spend, and whether they are locked.",
+		Run:   wrap(walletoutputscmd),
+	}
+
+	walletOutputsLockCmd = &cobra.Command{
+		Use:   "lock [id...]",
+		Short: "Lock outputs",
+		Long: `Lock outputs, so that the wallet does not choose them to fund transactions.
+Locked outputs can still be spent with 'siac wallet send siacoins --outputs'.`,
+		Run: walletoutputslockcmd,
+	}
+
+	walletOutputsUnlockCmd = &cobra.Command{
+		Use:   "unlock [id...]",
+		Short: "Unlock outputs",
+		Long:  "Unlock outputs that were locked with 'siac wallet outputs lock'.",
+		Run:   walletoutputsunlockcmd,
+	}
+
 	walletSendCmd = &cobra.Command{
 		Use:   "send",
 		Short: "Send either siacoins or siafunds to an address",
@@ -136,7 +219,10 @@ By default the wallet encryption / unlock password is the same as the generated
 'amount' can be specified in units, e.g. 1.23KS. Run 'wallet --help' for a list of units.
 If no unit is supplied, hastings will be assumed.
 
-A dynamic transaction fee is applied depending on the size of the transaction and how busy the network is.`,
+A dynamic transaction fee is applied depending on the size of the transaction and how busy the network is.
+
+The --outputs flag funds the transaction from a comma-separated list of output
+IDs, as shown by 'siac wallet outputs', instead of letting the wallet choose.`,
 		Run: wrap(walletsendsiacoinscmd),
 	}
 
@@ -148,6 +234,79 @@ Run 'wallet send --help' to see a list of available units.`,
 		Run: wrap(walletsendsiafundscmd),
 	}
 
+	walletCoinSelectionCmd = &cobra.Command{
+		Use:   "coinselection [strategy]",
+		Short: "View or change the coin selection strategy",
+		Long: `View or change the strategy that the wallet uses to choose the outputs that
+fund a transaction. The available strategies are:
+	largest-first    spend the largest outputs first (default)
+	smallest-first   spend the smallest outputs first, consolidating them
+	branch-and-bound look for outputs that need no change output, and spend
+	                 the largest outputs first if there are none`,
+		Run: walletcoinselectioncmd,
+	}
+
+	walletMultisigCmd = &cobra.Command{
+		Use:   "multisig",
+		Short: "View the multisig addresses of the wallet",
+		Long: `View the multisig addresses of the wallet. A multisig address requires M of
+N signatures to spend its outputs. The wallet tracks the balance of its
+multisig addresses, and can build and co-sign transactions that spend from them.`,
+		Run: wrap(walletmultisigcmd),
+	}
+
+	walletMultisigCreateCmd = &cobra.Command{
+		Use:   "create [required] [publickey...]",
+		Short: "Create a multisig address",
+		Long: `Create a multisig address that requires 'required' signatures from the
+public keys, e.g.:
+	siac wallet multisig create 2 ed25519:4e9d... ed25519:a2f1... ed25519:77c0...
+Every signer has to create the address with the same public keys in the same
+order. The public keys of this wallet are generated with
+'siac wallet multisig publickey'. The wallet rescans the blockchain for the
+address unless the --unused flag is set.`,
+		Run: walletmultisigcreatecmd,
+	}
+
+	walletMultisigPublicKeyCmd = &cobra.Command{
+		Use:   "publickey",
+		Short: "Get a new public key of the wallet",
+		Long:  "Generate a new public key of the wallet to share with the other signers of a multisig address.",
+		Run:   wrap(walletmultisigpublickeycmd),
+	}
+
+	walletMultisigSignCmd = &cobra.Command{
+		Use:   "sign [file]",
+		Short: "Co-sign a multisig transaction",
+		Long: `Add the signatures of the wallet to the transaction in 'file', which contains
+the JSON output of 'siac wallet multisig spend' or 'siac wallet multisig sign'.
+If the transaction is complete, it is broadcast. Otherwise the JSON encoded
+transaction is printed, to be passed on to the next signer.`,
+		Run: wrap(walletmultisigsigncmd),
+	}
+
+	walletMultisigSpendCmd = &cobra.Command{
+		Use:   "spend [address] [amount] [dest]",
+		Short: "Send siacoins from a multisig address",
+		Long: `Build a transaction that sends 'amount' from the multisig address 'address' to
+'dest', and sign it with the keys of the wallet. The JSON encoded transaction
+is printed, to be passed on to the other signers, e.g.:
+	siac wallet multisig spend [address] 10SC [dest] > txn.json
+Run 'wallet send --help' to see a list of available units.`,
+		Run: wrap(walletmultisigspendcmd),
+	}
+
+	walletSignCmd = &cobra.Command{
+		Use:   "sign [file]",
+		Short: "Sign a prepared transaction offline",
+		Long: `Sign a transaction with the keys of a seed, without connecting to siad.
+'file' contains the JSON response of /wallet/prepare, or a JSON encoded
+transaction. Every signature of the transaction that belongs to a key of the
+seed is signed. The signed transaction is printed base64 encoded, and can be
+broadcast with 'siac wallet broadcast' or the /tpool/raw endpoint.`,
+		Run: wrap(walletsigncmd),
+	}
+
 	walletSweepCmd = &cobra.Command{
 		Use:   "sweep",
 		Short: "Sweep siacoins and siafunds from a seed.",
@@ -156,11 +315,60 @@ will be sent to your wallet.`,
 		Run: wrap(walletsweepcmd),
 	}
 
+	walletTimelockCmd = &cobra.Command{
+		Use:   "timelock",
+		Short: "View the timelocked addresses of the wallet",
+		Long: `View the timelocked addresses of the wallet with their unlock height and
+balance. Siacoins sent to a timelocked address cannot be spent before the
+unlock height, and are not part of the confirmed balance until then.`,
+		Run: wrap(wallettimelockcmd),
+	}
+
+	walletTimelockCreateCmd = &cobra.Command{
+		Use:   "create [height]",
+		Short: "Create a timelocked address",
+		Long: `Create an address of the wallet whose outputs cannot be spent before block
+'height'. Once the height is reached, the wallet spends them like any other
+output.`,
+		Run: wrap(wallettimelockcreatecmd),
+	}
+
 	walletTransactionsCmd = &cobra.Command{
 		Use:   "transactions",
 		Short: "View transactions",
-		Long:  "View transactions related to addresses spendable by the wallet, providing a net flow of siacoins and siafunds for each transaction",
-		Run:   wrap(wallettransactionscmd),
+		Long: `View transactions related to addresses spendable by the wallet, providing a net
+flow of siacoins and siafunds, the category and the label of each transaction.
+With --csv, the confirmed transactions are printed as comma separated values,
+suitable for accounting software.`,
+		Run: wrap(wallettransactionscmd),
+	}
+
+	walletWebhooksCmd = &cobra.Command{
+		Use:   "webhooks",
+		Short: "View the webhooks of the wallet",
+		Long: `View the webhooks of the wallet. The wallet posts its events to each webhook
+as signed JSON: a transaction entering the transaction pool, being confirmed,
+being reverted by a reorg, or reaching a number of confirmations.`,
+		Run: wrap(walletwebhookscmd),
+	}
+
+	walletWebhooksAddCmd = &cobra.Command{
+		Use:   "add [url]",
+		Short: "Add a webhook",
+		Long: `Add a webhook that the wallet posts its events to. If --secret is set, every
+request carries the hex encoded HMAC-SHA256 of its body in the Sia-Signature
+header. --events limits the webhook to a comma-separated list of event types
+(unconfirmed, confirmed, reverted, confirmations). Confirmations events are only
+posted when a transaction reaches the number of confirmations given by
+--confirmations, which must be between 2 and 144.`,
+		Run: wrap(walletwebhooksaddcmd),
+	}
+
+	walletWebhooksRemoveCmd = &cobra.Command{
+		Use:   "remove [url]",
+		Short: "Remove a webhook",
+		Long:  "Remove the webhook with the given URL.",
+		Run:   wrap(walletwebhooksremovecmd),
 	}
 
 	walletUnlockCmd = &cobra.Command{
@@ -172,6 +380,20 @@ SIA_WALLET_PASSWORD environment variable is set, the unlock command will
 use it instead of displaying the typical interactive prompt.`,
 		Run: wrap(walletunlockcmd),
 	}
+
+	walletWatchCmd = &cobra.Command{
+		Use:   "watch [addr...]",
+		Short: "View or change the watch-only addresses",
+		Long: `View or change the watch-only addresses of the wallet. The wallet tracks the
+outputs and transactions of watch-only addresses, but cannot spend them.
+
+Without arguments, the watch-only addresses and their balance are shown. With
+arguments, the addresses are added and the wallet rescans the blockchain for
+them. Use the --unused flag to skip the rescan for addresses that have never
+been used, and the --remove flag to remove addresses, e.g.:
+	siac wallet watch --remove [addr]`,
+		Run: walletwatchcmd,
+	}
 )
 
 const askPasswordText = "We need to encrypt the new data using the current wallet password, please provide: "
@@ -215,6 +437,20 @@ func walletaddresscmd() {
 
 // walletaddressescmd fetches the list of addresses that the wallet knows.
 func walletaddressescmd() {
+	if walletAddressesBalance {
+		addrs, err := httpClient.WalletAddressesBalanceGet()
+		if err != nil {
+			die("Failed to fetch addresses:", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Address\tConfirmed\tOutputs\tUnconfirmed In\tUnconfirmed Out")
+		for _, b := range addrs.Balances {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", b.Address, currencyUnits(b.ConfirmedSiacoinBalance), b.ConfirmedSiacoinOutputs,
+				currencyUnits(b.UnconfirmedIncomingSiacoins), currencyUnits(b.UnconfirmedOutgoingSiacoins))
+		}
+		w.Flush()
+		return
+	}
 	addrs, err := httpClient.WalletAddressesGet()
 	if err != nil {
 		die("Failed to fetch addresses:", err)
@@ -224,6 +460,83 @@ func walletaddressescmd() {
 	}
 }
 
+// walletbumpfeecmd raises the fee of an unconfirmed transaction.
+func walletbumpfeecmd(txidStr string) {
+	var txid types.TransactionID
+	if err := txid.UnmarshalJSON([]byte("\"" + txidStr + "\"")); err != nil {
+		die("Could not parse transaction ID:", err)
+	}
+	var fee types.Currency
+	if walletBumpFeeFee != "" {
+		hastings, err := parseCurrency(walletBumpFeeFee)
+		if err != nil {
+			die("Could not parse fee:", err)
+		}
+		if _, err := fmt.Sscan(hastings, &fee); err != nil {
+			die("Could not parse fee:", err)
+		}
+	}
+	setSpendingPassword()
+	wbfp, err := httpClient.WalletBumpFeePost(txid, fee, modules.BumpFeeMethod(walletBumpFeeMethod))
+	if err != nil {
+		die("Could not bump fee:", err)
+	}
+	for _, id := range wbfp.TransactionIDs {
+		fmt.Println("Submitted transaction", id)
+	}
+}
+
+// walletlabelcmd lists the labels of the wallet, or labels a transaction or
+// an address.
+func walletlabelcmd(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		wlg, err := httpClient.WalletLabelsGet()
+		if err != nil {
+			die("Could not fetch labels:", err)
+		}
+		if len(wlg.Transactions) == 0 && len(wlg.Addresses) == 0 {
+			fmt.Println("No labels.")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tLabel\tNote")
+		for _, l := range wlg.Transactions {
+			fmt.Fprintf(w, "%v\t%v\t%v\n", l.TransactionID, l.Label, l.Note)
+		}
+		for _, l := range wlg.Addresses {
+			fmt.Fprintf(w, "%v\t%v\t%v\n", l.Address, l.Label, l.Note)
+		}
+		w.Flush()
+		return
+	}
+	if len(args) < 2 || len(args) > 3 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	label := modules.WalletLabel{Label: args[1]}
+	if len(args) == 3 {
+		label.Note = args[2]
+	}
+	// Addresses contain a checksum, so an ID that parses as an address is
+	// not a transaction ID.
+	var addr types.UnlockHash
+	if err := addr.LoadString(args[0]); err == nil {
+		if err := httpClient.WalletAddressLabelPost(addr, label); err != nil {
+			die("Could not label address:", err)
+		}
+		fmt.Println("Labeled address", addr)
+		return
+	}
+	var txid types.TransactionID
+	if err := txid.UnmarshalJSON([]byte("\"" + args[0] + "\"")); err != nil {
+		die("Could not parse transaction ID or address:", err)
+	}
+	if err := httpClient.WalletTransactionLabelPost(txid, label); err != nil {
+		die("Could not label transaction:", err)
+	}
+	fmt.Println("Labeled transaction", txid)
+}
+
 // walletchangepasswordcmd changes the password of the wallet.
 func walletchangepasswordcmd() {
 	currentPassword, err := passwordPrompt(currentPasswordText)
@@ -243,6 +556,54 @@ func walletchangepasswordcmd() {
 	fmt.Println("Password changed successfully.")
 }
 
+// walletspendingpasswordcmd sets or removes the spending password of the
+// wallet.
+func walletspendingpasswordcmd() {
+	password, err := passwordPrompt("Wallet password: ")
+	if err != nil {
+		die("Reading password failed:", err)
+	}
+	spendingPassword, err := passwordPrompt("Spending password (leave blank to remove): ")
+	if err != nil {
+		die("Reading password failed:", err)
+	} else if spendingPassword != "" {
+		if err = confirmPassword(spendingPassword); err != nil {
+			die(err)
+		}
+	}
+	err = httpClient.WalletSpendingPasswordPost(password, spendingPassword)
+	if err != nil {
+		die("Setting the spending password failed:", err)
+	}
+	if spendingPassword == "" {
+		fmt.Println("Spending password removed.")
+	} else {
+		fmt.Println("Spending password set.")
+	}
+}
+
+// setSpendingPassword sets the spending password of the client if the wallet
+// requires one, reading it from the SIA_SPENDING_PASSWORD environment
+// variable or prompting for it.
+func setSpendingPassword() {
+	if password := os.Getenv("SIA_SPENDING_PASSWORD"); password != "" {
+		httpClient.SpendingPassword = password
+		return
+	}
+	status, err := httpClient.WalletGet()
+	if err != nil {
+		die("Could not get wallet status:", err)
+	}
+	if !status.SpendingPasswordRequired {
+		return
+	}
+	password, err := passwordPrompt("Spending password: ")
+	if err != nil {
+		die("Reading password failed:", err)
+	}
+	httpClient.SpendingPassword = password
+}
+
 // walletinitcmd encrypts the wallet with the given password
 func walletinitcmd() {
 	var password string
@@ -346,6 +707,7 @@ func walletlockcmd() {
 
 // walletseedcmd returns the current seed {
 func walletseedscmd() {
+	setSpendingPassword()
 	seedInfo, err := httpClient.WalletSeedsGet()
 	if err != nil {
 		die("Error retrieving the current seed:", err)
@@ -381,7 +743,18 @@ func walletsendsiacoinscmd(amount, dest string) {
 	if _, err := fmt.Sscan(dest, &hash); err != nil {
 		die("Failed to parse destination address", err)
 	}
-	_, err = httpClient.WalletSiacoinsPost(value, hash)
+	setSpendingPassword()
+	if walletSendOutputs != "" && walletSendTarget != 0 {
+		die("--outputs and --target cannot be used together")
+	}
+	if walletSendOutputs != "" {
+		ids := parseOutputIDs(strings.Split(walletSendOutputs, ","))
+		_, err = httpClient.WalletSiacoinsFromOutputsPost(value, hash, ids)
+	} else if walletSendTarget != 0 {
+		_, err = httpClient.WalletSiacoinsTargetPost(value, hash, types.BlockHeight(walletSendTarget))
+	} else {
+		_, err = httpClient.WalletSiacoinsPost(value, hash)
+	}
 	if err != nil {
 		die("Could not send siacoins:", err)
 	}
@@ -398,6 +771,7 @@ func walletsendsiafundscmd(amount, dest string) {
 	if _, err := fmt.Sscan(dest, &hash); err != nil {
 		die("Failed to parse destination address", err)
 	}
+	setSpendingPassword()
 	_, err := httpClient.WalletSiafundsPost(value, hash)
 	if err != nil {
 		die("Could not send siafunds:", err)
@@ -441,13 +815,400 @@ Height:              %v
 Confirmed Balance:   %v
 Unconfirmed Delta:  %v
 Exact:               %v H
+Timelocked:          %v
 Siafunds:            %v SF
 Siafund Claims:      %v H
 
 Estimated Fee:       %v / KB
 `, encStatus, status.Height, currencyUnits(status.ConfirmedSiacoinBalance), delta,
-		status.ConfirmedSiacoinBalance, status.SiafundBalance, status.SiacoinClaimBalance,
-		fees.Maximum.Mul64(1e3).HumanString())
+		status.ConfirmedSiacoinBalance, currencyUnits(status.TimelockedSiacoinBalance),
+		status.SiafundBalance, status.SiacoinClaimBalance, fees.Maximum.Mul64(1e3).HumanString())
+}
+
+// walletsigncmd signs a prepared transaction with the keys of a seed. It does
+// not use the API, so it can be run on an offline machine.
+func walletsigncmd(path string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		die("Could not read transaction file:", err)
+	}
+	var pt modules.PreparedTransaction
+	if err := json.Unmarshal(data, &pt); err != nil {
+		die("Could not decode transaction:", err)
+	}
+	txn := pt.Transaction
+	if len(txn.TransactionSignatures) == 0 {
+		// The file may contain a bare transaction.
+		if err := json.Unmarshal(data, &txn); err != nil {
+			die("Could not decode transaction:", err)
+		}
+	}
+
+	// Show what is being signed.
+	if len(pt.ParentOutputs) == len(txn.SiacoinInputs) {
+		var inputs types.Currency
+		for _, sco := range pt.ParentOutputs {
+			inputs = inputs.Add(sco.Value)
+		}
+		fmt.Println("Inputs: ", currencyUnits(inputs))
+	}
+	for _, sco := range txn.SiacoinOutputs {
+		fmt.Printf("Output:  %v to %v\n", currencyUnits(sco.Value), sco.UnlockHash)
+	}
+	for _, fee := range txn.MinerFees {
+		fmt.Println("Fee:    ", currencyUnits(fee))
+	}
+
+	seedStr, err := passwordPrompt("Seed: ")
+	if err != nil {
+		die("Reading seed failed:", err)
+	}
+	seed, err := modules.StringToSeed(seedStr, mnemonics.English)
+	if err != nil {
+		die("Invalid seed:", err)
+	}
+	signed, err := wallet.SignTransaction(&txn, seed, walletSignKeys)
+	if err != nil {
+		die("Could not sign transaction:", err)
+	}
+	var unsigned int
+	for _, sig := range txn.TransactionSignatures {
+		if len(sig.Signature) == 0 {
+			unsigned++
+		}
+	}
+	fmt.Printf("Added %v signatures, %v signatures are still missing.\n", signed, unsigned)
+	fmt.Println(base64.StdEncoding.EncodeToString(encoding.Marshal(txn)))
+}
+
+// parseOutputIDs parses siacoin output IDs, exiting on failure.
+func parseOutputIDs(strs []string) []types.SiacoinOutputID {
+	ids := make([]types.SiacoinOutputID, len(strs))
+	for i, str := range strs {
+		var h crypto.Hash
+		if err := h.LoadString(strings.TrimSpace(str)); err != nil {
+			die("Could not parse output ID:", err)
+		}
+		ids[i] = types.SiacoinOutputID(h)
+	}
+	return ids
+}
+
+// walletoutputscmd lists the spendable outputs of the wallet.
+func walletoutputscmd() {
+	wog, err := httpClient.WalletOutputsGet()
+	if err != nil {
+		die("Could not get outputs:", err)
+	}
+	if len(wog.Outputs) == 0 {
+		fmt.Println("No spendable outputs.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tValue\tAddress\tLocked")
+	for _, so := range wog.Outputs {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", so.ID, currencyUnits(so.Value), so.UnlockHash, so.Locked)
+	}
+	w.Flush()
+}
+
+// walletoutputslockcmd locks outputs of the wallet.
+func walletoutputslockcmd(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	if err := httpClient.WalletOutputsLockPost(parseOutputIDs(args)); err != nil {
+		die("Could not lock outputs:", err)
+	}
+	fmt.Printf("Locked %v outputs.\n", len(args))
+}
+
+// walletoutputsunlockcmd unlocks outputs of the wallet.
+func walletoutputsunlockcmd(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	if err := httpClient.WalletOutputsUnlockPost(parseOutputIDs(args)); err != nil {
+		die("Could not unlock outputs:", err)
+	}
+	fmt.Printf("Unlocked %v outputs.\n", len(args))
+}
+
+// walletcoinselectioncmd views or changes the coin selection strategy.
+func walletcoinselectioncmd(cmd *cobra.Command, args []string) {
+	switch len(args) {
+	case 0:
+		wsg, err := httpClient.WalletSettingsGet()
+		if err != nil {
+			die("Could not get wallet settings:", err)
+		}
+		fmt.Println("Coin selection strategy:", wsg.CoinSelection)
+	case 1:
+		if err := httpClient.WalletCoinSelectionPost(modules.CoinSelectionStrategy(args[0])); err != nil {
+			die("Could not change the coin selection strategy:", err)
+		}
+		fmt.Println("Coin selection strategy set to", args[0])
+	default:
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+}
+
+// walletautolockcmd views or changes the auto-lock timeout of the wallet.
+func walletautolockcmd(cmd *cobra.Command, args []string) {
+	switch len(args) {
+	case 0:
+		wsg, err := httpClient.WalletSettingsGet()
+		if err != nil {
+			die("Could not get wallet settings:", err)
+		}
+		if wsg.AutoLockTimeout == 0 {
+			fmt.Println("Auto-lock is disabled")
+		} else {
+			fmt.Printf("Auto-lock timeout: %v\n", time.Duration(wsg.AutoLockTimeout)*time.Second)
+		}
+	case 1:
+		var seconds uint64
+		if _, err := fmt.Sscan(args[0], &seconds); err != nil {
+			die("Could not parse timeout:", err)
+		}
+		timeout := time.Duration(seconds) * time.Second
+		if err := httpClient.WalletAutoLockPost(timeout); err != nil {
+			die("Could not change the auto-lock timeout:", err)
+		}
+		if timeout == 0 {
+			fmt.Println("Auto-lock disabled")
+		} else {
+			fmt.Println("Auto-lock timeout set to", timeout)
+		}
+	default:
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+}
+
+// walletmultisigcmd lists the multisig addresses of the wallet.
+func walletmultisigcmd() {
+	wmg, err := httpClient.WalletMultisigGet()
+	if err != nil {
+		die("Could not get multisig addresses:", err)
+	}
+	if len(wmg.Addresses) == 0 {
+		fmt.Println("No multisig addresses.")
+		return
+	}
+	for _, ma := range wmg.Addresses {
+		fmt.Printf("%v (%v-of-%v)\n", ma.Address, ma.UnlockConditions.SignaturesRequired, len(ma.UnlockConditions.PublicKeys))
+		for _, pk := range ma.UnlockConditions.PublicKeys {
+			fmt.Println("\t" + pk.String())
+		}
+	}
+}
+
+// wallettimelockcmd lists the timelocked addresses of the wallet.
+func wallettimelockcmd() {
+	wtg, err := httpClient.WalletTimelockedGet()
+	if err != nil {
+		die("Could not get timelocked addresses:", err)
+	}
+	if len(wtg.Addresses) == 0 {
+		fmt.Println("No timelocked addresses.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Address\tUnlock Height\tBalance")
+	for _, ta := range wtg.Addresses {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", ta.Address, ta.UnlockHeight, currencyUnits(ta.Balance))
+	}
+	w.Flush()
+}
+
+// wallettimelockcreatecmd creates a timelocked address.
+func wallettimelockcreatecmd(heightStr string) {
+	var height types.BlockHeight
+	if _, err := fmt.Sscan(heightStr, &height); err != nil {
+		die("Could not parse height:", err)
+	}
+	ta, err := httpClient.WalletTimelockedPost(height)
+	if err != nil {
+		die("Could not create timelocked address:", err)
+	}
+	fmt.Printf("Created timelocked address %v, unlocking at height %v\n", ta.Address, ta.UnlockHeight)
+}
+
+// walletwebhookscmd lists the webhooks of the wallet.
+func walletwebhookscmd() {
+	wwg, err := httpClient.WalletWebhooksGet()
+	if err != nil {
+		die("Could not get webhooks:", err)
+	}
+	if len(wwg.Webhooks) == 0 {
+		fmt.Println("No webhooks.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tEvents\tConfirmations")
+	for _, hook := range wwg.Webhooks {
+		events := "all"
+		if len(hook.Events) != 0 {
+			var eventTypes []string
+			for _, t := range hook.Events {
+				eventTypes = append(eventTypes, string(t))
+			}
+			events = strings.Join(eventTypes, ",")
+		}
+		confirmations := "none"
+		if hook.Confirmations != 0 {
+			confirmations = fmt.Sprint(hook.Confirmations)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\n", hook.URL, events, confirmations)
+	}
+	w.Flush()
+}
+
+// walletwebhooksaddcmd adds a webhook to the wallet.
+func walletwebhooksaddcmd(url string) {
+	var events []modules.WalletEventType
+	if walletWebhookEvents != "" {
+		for _, t := range strings.Split(walletWebhookEvents, ",") {
+			events = append(events, modules.WalletEventType(strings.TrimSpace(t)))
+		}
+	}
+	err := httpClient.WalletWebhookPost(modules.WalletWebhook{
+		URL:           url,
+		Secret:        walletWebhookSecret,
+		Events:        events,
+		Confirmations: walletWebhookConfirmations,
+	})
+	if err != nil {
+		die("Could not add webhook:", err)
+	}
+	fmt.Println("Added webhook", url)
+}
+
+// walletwebhooksremovecmd removes a webhook from the wallet.
+func walletwebhooksremovecmd(url string) {
+	if err := httpClient.WalletWebhookRemovePost(url); err != nil {
+		die("Could not remove webhook:", err)
+	}
+	fmt.Println("Removed webhook", url)
+}
+
+// walletmultisigcreatecmd creates a multisig address.
+func walletmultisigcreatecmd(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	var required uint64
+	if _, err := fmt.Sscan(args[0], &required); err != nil {
+		die("Could not parse the number of required signatures:", err)
+	}
+	pks := make([]types.SiaPublicKey, len(args)-1)
+	for i, arg := range args[1:] {
+		pks[i].LoadString(arg)
+		if len(pks[i].Key) == 0 {
+			die("Could not parse public key", arg)
+		}
+	}
+	wma, err := httpClient.WalletMultisigPost(pks, required, walletMultisigUnused)
+	if err != nil {
+		die("Could not create multisig address:", err)
+	}
+	fmt.Printf("Created %v-of-%v multisig address %v\n", required, len(pks), wma.Address)
+}
+
+// walletmultisigpublickeycmd prints a new public key of the wallet.
+func walletmultisigpublickeycmd() {
+	wpkg, err := httpClient.WalletPublicKeyGet()
+	if err != nil {
+		die("Could not generate public key:", err)
+	}
+	fmt.Println(wpkg.PublicKey)
+}
+
+// walletmultisigspendcmd builds and signs a transaction that spends from a
+// multisig address.
+func walletmultisigspendcmd(addrStr, amount, destStr string) {
+	var addr, dest types.UnlockHash
+	if err := addr.LoadString(addrStr); err != nil {
+		die("Could not parse address:", err)
+	}
+	if err := dest.LoadString(destStr); err != nil {
+		die("Could not parse destination:", err)
+	}
+	hastings, err := parseCurrency(amount)
+	if err != nil {
+		die("Could not parse amount:", err)
+	}
+	var value types.Currency
+	if _, err := fmt.Sscan(hastings, &value); err != nil {
+		die("Could not parse amount:", err)
+	}
+	setSpendingPassword()
+	wmsp, err := httpClient.WalletMultisigSpendPost(addr, []types.SiacoinOutput{{Value: value, UnlockHash: dest}})
+	if err != nil {
+		die("Could not prepare transaction:", err)
+	}
+	printMultisigTransaction(wmsp.Transaction)
+}
+
+// walletmultisigsigncmd co-signs a multisig transaction and broadcasts it if
+// it is complete.
+func walletmultisigsigncmd(path string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		die("Could not read transaction:", err)
+	}
+	var txn types.Transaction
+	if err := json.Unmarshal(data, &txn); err != nil {
+		die("Could not decode transaction:", err)
+	}
+	setSpendingPassword()
+	wsp, err := httpClient.WalletSignPost(txn, true)
+	if err != nil {
+		die("Could not sign transaction:", err)
+	}
+	if wsp.Broadcast {
+		fmt.Println("Transaction complete and broadcast, ID:", wsp.Transaction.ID())
+		return
+	}
+	printMultisigTransaction(wsp.Transaction)
+}
+
+// printMultisigTransaction prints the JSON encoding of a partially signed
+// transaction to stdout, and the number of missing signatures to stderr.
+func printMultisigTransaction(txn types.Transaction) {
+	var missing int
+	for _, sig := range txn.TransactionSignatures {
+		if len(sig.Signature) == 0 {
+			missing++
+		}
+	}
+	txnJSON, err := json.MarshalIndent(txn, "", "\t")
+	if err != nil {
+		die("Could not encode transaction:", err)
+	}
+	fmt.Fprintf(os.Stderr, "%v signatures are still missing.\n", missing)
+	fmt.Println(string(txnJSON))
+}
+
+// walletbroadcastcmd submits a signed transaction to the transaction pool.
+func walletbroadcastcmd(txnStr string) {
+	txnBytes, err := base64.StdEncoding.DecodeString(txnStr)
+	if err != nil {
+		die("Could not decode transaction:", err)
+	}
+	var txn types.Transaction
+	if err := encoding.Unmarshal(txnBytes, &txn); err != nil {
+		die("Could not decode transaction:", err)
+	}
+	if err := httpClient.TransactionPoolRawPost(txn, nil); err != nil {
+		die("Could not broadcast transaction:", err)
+	}
+	fmt.Println("Broadcast transaction", txn.ID())
 }
 
 // walletsweepcmd sweeps coins and funds from a seed.
@@ -457,6 +1218,7 @@ func walletsweepcmd() {
 		die("Reading seed failed:", err)
 	}
 
+	setSpendingPassword()
 	swept, err := httpClient.WalletSweepPost(seed)
 	if err != nil {
 		die("Could not sweep seed:", err)
@@ -467,11 +1229,19 @@ func walletsweepcmd() {
 // wallettransactionscmd lists all of the transactions related to the wallet,
 // providing a net flow of siacoins and siafunds for each.
 func wallettransactionscmd() {
+	if walletTransactionsCSV {
+		csv, err := httpClient.WalletTransactionsCSVGet(0, math.MaxInt64)
+		if err != nil {
+			die("Could not fetch transaction history:", err)
+		}
+		os.Stdout.Write(csv)
+		return
+	}
 	wtg, err := httpClient.WalletTransactionsGet(0, math.MaxInt64)
 	if err != nil {
 		die("Could not fetch transaction history:", err)
 	}
-	fmt.Println("             [timestamp]    [height]                                                   [transaction id]    [net siacoins]   [net siafunds]")
+	fmt.Println("             [timestamp]    [height]                                                   [transaction id]    [net siacoins]   [net siafunds]  [category]       [label]")
 	txns := append(wtg.ConfirmedTransactions, wtg.UnconfirmedTransactions...)
 	for _, txn := range txns {
 		// Determine the number of outgoing siacoins and siafunds.
@@ -519,9 +1289,18 @@ func wallettransactionscmd() {
 		fmt.Printf("%67v%15.2f SC", txn.TransactionID, incomingSiacoinsFloat-outgoingSiacoinsFloat)
 		// For siafunds, need to avoid having a negative types.Currency.
 		if incomingSiafunds.Cmp(outgoingSiafunds) >= 0 {
-			fmt.Printf("%14v SF\n", incomingSiafunds.Sub(outgoingSiafunds))
+			fmt.Printf("%14v SF", incomingSiafunds.Sub(outgoingSiafunds))
 		} else {
-			fmt.Printf("-%14v SF\n", outgoingSiafunds.Sub(incomingSiafunds))
+			fmt.Printf("-%14v SF", outgoingSiafunds.Sub(incomingSiafunds))
+		}
+		fmt.Printf("  %-16v %v\n", txn.Category, txn.Label)
+	}
+
+	if len(wtg.Replacements) != 0 {
+		fmt.Println()
+		fmt.Println("Replaced transactions:")
+		for _, r := range wtg.Replacements {
+			fmt.Printf("%v replaced by %v at height %v\n", r.ReplacedID, r.ReplacementID, r.Height)
 		}
 	}
 }
@@ -550,3 +1329,46 @@ func walletunlockcmd() {
 		die("Could not unlock wallet:", err)
 	}
 }
+
+// walletwatchcmd shows the watch-only addresses of the wallet, or adds or
+// removes the provided addresses.
+func walletwatchcmd(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		wwg, err := httpClient.WalletWatchGet()
+		if err != nil {
+			die("Could not get watch-only addresses:", err)
+		}
+		if len(wwg.Addresses) == 0 {
+			fmt.Println("No watch-only addresses.")
+			return
+		}
+		fmt.Printf(`Watch-only balance:
+Siacoins:  %v
+Siafunds:  %v SF
+
+Addresses:
+`, currencyUnits(wwg.SiacoinBalance), wwg.SiafundBalance)
+		for _, addr := range wwg.Addresses {
+			fmt.Println(addr)
+		}
+		return
+	}
+
+	addrs := make([]types.UnlockHash, len(args))
+	for i, arg := range args {
+		if err := addrs[i].LoadString(arg); err != nil {
+			die("Could not parse address:", err)
+		}
+	}
+	if walletWatchRemove {
+		if err := httpClient.WalletWatchRemovePost(addrs); err != nil {
+			die("Could not remove watch-only addresses:", err)
+		}
+		fmt.Printf("Removed %v watch-only addresses.\n", len(addrs))
+		return
+	}
+	if err := httpClient.WalletWatchAddPost(addrs, walletWatchUnused); err != nil {
+		die("Could not add watch-only addresses:", err)
+	}
+	fmt.Printf("Added %v watch-only addresses.\n", len(addrs))
+}
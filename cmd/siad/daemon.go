@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -13,7 +14,9 @@ import (
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/consensus"
 	"github.com/NebulousLabs/Sia/profile"
+	"github.com/NebulousLabs/Sia/types"
 	mnemonics "github.com/NebulousLabs/entropy-mnemonics"
 
 	"github.com/spf13/cobra"
@@ -103,13 +106,64 @@ func processConfig(config Config) (Config, error) {
 	config.Siad.Modules, err1 = processModules(config.Siad.Modules)
 	config.Siad.Profile, err2 = processProfileFlags(config.Siad.Profile)
 	err3 := verifyAPISecurity(config)
-	err := build.JoinErrors([]error{err1, err2, err3}, ", and ")
+	_, err4 := processCheckpoint(config.Siad.Checkpoint)
+	_, err5 := processSnapshotFlags(config.Siad.SnapshotFile, config.Siad.SnapshotID)
+	err6 := processPruneFlag(config.Siad.PruneConsensus, config.Siad.Modules)
+	err := build.JoinErrors([]error{err1, err2, err3, err4, err5, err6}, ", and ")
 	if err != nil {
 		return Config{}, err
 	}
 	return config, nil
 }
 
+// processCheckpoint parses a checkpoint passed to the --checkpoint flag. The
+// checkpoint is expected in the form 'height:blockid'. An empty string
+// results in no checkpoints.
+func processCheckpoint(checkpoint string) ([]modules.Checkpoint, error) {
+	if checkpoint == "" {
+		return nil, nil
+	}
+	parts := strings.Split(checkpoint, ":")
+	if len(parts) != 2 {
+		return nil, errors.New("checkpoint must be of the form 'height:blockid'")
+	}
+	height, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint height: %v", err)
+	}
+	var id types.BlockID
+	if err := id.LoadString(parts[1]); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint block id: %v", err)
+	}
+	return []modules.Checkpoint{{Height: types.BlockHeight(height), ID: id}}, nil
+}
+
+// processSnapshotFlags checks the --bootstrap-from-snapshot and --snapshot-id
+// flags, returning the parsed snapshot id. A snapshot id is required if a
+// snapshot file is given.
+func processSnapshotFlags(file, id string) (types.BlockID, error) {
+	if file == "" && id == "" {
+		return types.BlockID{}, nil
+	}
+	if file == "" {
+		return types.BlockID{}, errors.New("--snapshot-id requires --bootstrap-from-snapshot")
+	}
+	var bid types.BlockID
+	if err := bid.LoadString(id); err != nil {
+		return types.BlockID{}, fmt.Errorf("--bootstrap-from-snapshot requires a valid --snapshot-id: %v", err)
+	}
+	return bid, nil
+}
+
+// processPruneFlag checks that the --prune-consensus flag is not combined with
+// the explorer module, which requires every block of the blockchain.
+func processPruneFlag(pruneBlocks uint64, modules string) error {
+	if pruneBlocks > 0 && strings.Contains(modules, "e") {
+		return errors.New("--prune-consensus cannot be used with the explorer module")
+	}
+	return nil
+}
+
 // unlockWallet is called on siad startup and attempts to automatically
 // unlock the wallet with the given password string.
 func unlockWallet(w modules.Wallet, password string) error {
@@ -241,3 +295,31 @@ func startDaemonCmd(cmd *cobra.Command, _ []string) {
 	// Daemon seems to have closed cleanly. Print a 'closed' mesasge.
 	fmt.Println("Shutdown complete.")
 }
+
+// consensusExportCmd exports a snapshot of the consensus set at the given
+// height to a file.
+func consensusExportCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	height, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		die("Invalid height:", err)
+	}
+	f, err := os.Create(args[1])
+	if err != nil {
+		die("Could not create snapshot file:", err)
+	}
+	id, err := consensus.ExportSnapshot(filepath.Join(globalConfig.Siad.SiaDir, modules.ConsensusDir), types.BlockHeight(height), f)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(args[1])
+		die("Could not export consensus snapshot:", err)
+	}
+	fmt.Printf("Exported consensus snapshot at height %v to %v.\nLoad it with --bootstrap-from-snapshot %v --snapshot-id %v\n", height, args[1], args[1], id)
+}
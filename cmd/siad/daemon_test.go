@@ -2,6 +2,8 @@ package main
 
 import (
 	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
 )
 
 // TestUnitProcessNetAddr probes the 'processNetAddr' function.
@@ -200,3 +202,60 @@ func TestVerifyAPISecurity(t *testing.T) {
 		t.Error("public + securityOff with authentication was rejected:", err)
 	}
 }
+
+// TestUnitProcessCheckpoint probes the 'processCheckpoint' function.
+func TestUnitProcessCheckpoint(t *testing.T) {
+	cps, err := processCheckpoint("")
+	if err != nil || cps != nil {
+		t.Fatal("empty checkpoint should produce no checkpoints:", cps, err)
+	}
+	id := types.BlockID{1, 2, 3}
+	cps, err = processCheckpoint("100:" + id.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cps) != 1 || cps[0].Height != 100 || cps[0].ID != id {
+		t.Fatal("checkpoint was parsed incorrectly:", cps)
+	}
+	invalidCheckpoints := []string{"100", "abc:" + id.String(), "100:abc", "1:2:3"}
+	for _, cp := range invalidCheckpoints {
+		if _, err := processCheckpoint(cp); err == nil {
+			t.Error("processCheckpoint didn't error on invalid checkpoint:", cp)
+		}
+	}
+}
+
+// TestUnitProcessSnapshotFlags probes the 'processSnapshotFlags' function.
+func TestUnitProcessSnapshotFlags(t *testing.T) {
+	id := types.BlockID{1, 2, 3}
+	if _, err := processSnapshotFlags("", ""); err != nil {
+		t.Fatal("no snapshot flags should be valid:", err)
+	}
+	bid, err := processSnapshotFlags("snapshot.dat", id.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bid != id {
+		t.Fatal("snapshot id was parsed incorrectly")
+	}
+	if _, err := processSnapshotFlags("snapshot.dat", ""); err == nil {
+		t.Error("snapshot file without an id should be rejected")
+	}
+	if _, err := processSnapshotFlags("", id.String()); err == nil {
+		t.Error("snapshot id without a file should be rejected")
+	}
+}
+
+// TestUnitProcessPruneFlag checks that pruning cannot be combined with the
+// explorer module.
+func TestUnitProcessPruneFlag(t *testing.T) {
+	if err := processPruneFlag(0, "cgte"); err != nil {
+		t.Error("the explorer should be allowed without pruning:", err)
+	}
+	if err := processPruneFlag(144, "cgtw"); err != nil {
+		t.Error("pruning should be allowed without the explorer:", err)
+	}
+	if err := processPruneFlag(144, "cgte"); err == nil {
+		t.Error("pruning should be rejected together with the explorer")
+	}
+}
@@ -41,6 +41,10 @@ type Config struct {
 		NoBootstrap       bool
 		RequiredUserAgent string
 		AuthenticateAPI   bool
+		Checkpoint        string
+		SnapshotFile      string
+		SnapshotID        string
+		PruneConsensus    uint64
 
 		Profile    string
 		ProfileDir string
@@ -156,6 +160,23 @@ func main() {
 		Run:   modulesCmd,
 	})
 
+	consensusCmd := &cobra.Command{
+		Use:   "consensus",
+		Short: "Perform actions on the consensus database",
+		Long:  "Perform actions on the consensus database while siad is not running.",
+	}
+	exportCmd := &cobra.Command{
+		Use:   "export [height] [file]",
+		Short: "Export a consensus snapshot",
+		Long: `Export a snapshot of the consensus set at the given height to a file. The
+snapshot can be loaded by a new node with --bootstrap-from-snapshot, together
+with the block id that is printed by this command.`,
+		Run: consensusExportCmd,
+	}
+	exportCmd.Flags().StringVarP(&globalConfig.Siad.SiaDir, "sia-directory", "d", "", "location of the sia directory")
+	consensusCmd.AddCommand(exportCmd)
+	root.AddCommand(consensusCmd)
+
 	// Set default values, which have the lowest priority.
 	root.Flags().StringVarP(&globalConfig.Siad.RequiredUserAgent, "agent", "", "Sia-Agent", "required substring for the user agent")
 	root.Flags().StringVarP(&globalConfig.Siad.HostAddr, "host-addr", "", ":9982", "which port the host listens on")
@@ -163,6 +184,10 @@ func main() {
 	root.Flags().StringVarP(&globalConfig.Siad.APIaddr, "api-addr", "", "localhost:9980", "which host:port the API server listens on")
 	root.Flags().StringVarP(&globalConfig.Siad.SiaDir, "sia-directory", "d", "", "location of the sia directory")
 	root.Flags().BoolVarP(&globalConfig.Siad.NoBootstrap, "no-bootstrap", "", false, "disable bootstrapping on this run")
+	root.Flags().StringVarP(&globalConfig.Siad.SnapshotFile, "bootstrap-from-snapshot", "", "", "create the consensus database from a snapshot file, requires --snapshot-id")
+	root.Flags().StringVarP(&globalConfig.Siad.SnapshotID, "snapshot-id", "", "", "block id that the snapshot passed to --bootstrap-from-snapshot must end at")
+	root.Flags().Uint64VarP(&globalConfig.Siad.PruneConsensus, "prune-consensus", "", 0, "only keep the bodies of this many recent blocks, disables the explorer and wallet rescans")
+	root.Flags().StringVarP(&globalConfig.Siad.Checkpoint, "checkpoint", "", "", "trusted checkpoint as 'height:blockid', blocks below it skip signature checks during sync")
 	root.Flags().StringVarP(&globalConfig.Siad.Profile, "profile", "", "", "enable profiling with flags 'cmt' for CPU, memory, trace")
 	root.Flags().StringVarP(&globalConfig.Siad.RPCaddr, "rpc-addr", "", ":9981", "which port the gateway listens on")
 	root.Flags().StringVarP(&globalConfig.Siad.Modules, "modules", "M", "cghrtw", "enabled modules, see 'siad modules' for more info")
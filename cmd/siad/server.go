@@ -478,6 +478,28 @@ func isAddrInUseErr(err error) bool {
 	return false
 }
 
+// importConsensusSnapshot creates the consensus database in dir from the
+// snapshot file. The import is skipped if the consensus database already
+// exists, so that siad can be restarted with the same flags.
+func importConsensusSnapshot(file, id, dir string) error {
+	bid, err := processSnapshotFlags(file, id)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Println("Importing consensus snapshot...")
+	err = consensus.ImportSnapshot(dir, bid, f)
+	if err == consensus.ErrConsensusDBExists {
+		fmt.Println("Consensus database already exists, ignoring --bootstrap-from-snapshot")
+		return nil
+	}
+	return err
+}
+
 // loadModules loads the modules defined by the server's config and makes their
 // API routes available.
 func (srv *Server) loadModules() error {
@@ -501,7 +523,22 @@ func (srv *Server) loadModules() error {
 	if strings.Contains(srv.config.Siad.Modules, "c") {
 		i++
 		fmt.Printf("(%d/%d) Loading consensus...\n", i, len(srv.config.Siad.Modules))
-		cs, err = consensus.New(g, !srv.config.Siad.NoBootstrap, filepath.Join(srv.config.Siad.SiaDir, modules.ConsensusDir))
+		checkpoints, err := processCheckpoint(srv.config.Siad.Checkpoint)
+		if err != nil {
+			return err
+		}
+		if srv.config.Siad.SnapshotFile != "" {
+			err = importConsensusSnapshot(srv.config.Siad.SnapshotFile, srv.config.Siad.SnapshotID, filepath.Join(srv.config.Siad.SiaDir, modules.ConsensusDir))
+			if err != nil {
+				return err
+			}
+		}
+		csDir := filepath.Join(srv.config.Siad.SiaDir, modules.ConsensusDir)
+		if srv.config.Siad.PruneConsensus > 0 {
+			cs, err = consensus.NewPruned(g, !srv.config.Siad.NoBootstrap, csDir, checkpoints, types.BlockHeight(srv.config.Siad.PruneConsensus))
+		} else {
+			cs, err = consensus.NewWithCheckpoints(g, !srv.config.Siad.NoBootstrap, csDir, checkpoints)
+		}
 		if err != nil {
 			return err
 		}
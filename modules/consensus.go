@@ -31,6 +31,10 @@ var (
 	// starting from a specific value (which may not be known to the caller).
 	ConsensusChangeRecent = ConsensusChangeID{1}
 
+	// ErrBlockPruned indicates that a block is part of the blockchain, but
+	// its body is no longer stored because the consensus set is pruned.
+	ErrBlockPruned = errors.New("block has been pruned from the consensus set")
+
 	// ErrBlockKnown is an error indicating that a block is already in the
 	// database.
 	ErrBlockKnown = errors.New("block already present in database")
@@ -56,6 +60,15 @@ type (
 	// ConsensusChangeID is the id of a consensus change.
 	ConsensusChangeID crypto.Hash
 
+	// A Checkpoint pins the block at a given height to a trusted block ID. The
+	// consensus set rejects any block at that height with a different ID, and
+	// does not verify the transaction signatures of blocks that the checkpoint
+	// commits to.
+	Checkpoint struct {
+		Height types.BlockHeight `json:"height"`
+		ID     types.BlockID     `json:"id"`
+	}
+
 	// A DiffDirection indicates the "direction" of a diff, either applied or
 	// reverted. A bool is used to restrict the value to these two possibilities.
 	DiffDirection bool
@@ -88,6 +101,14 @@ type (
 		// applied.
 		AppliedBlocks []types.Block
 
+		// AppliedBlockIDs contains the ids of the applied blocks, in the same
+		// order as AppliedBlocks. Blocks whose bodies are not stored by the
+		// consensus set, such as the blocks below the height of an imported
+		// snapshot, are presented as header-only blocks without payouts or
+		// transactions. The ID of a header-only block does not match its
+		// real id, so subscribers should use AppliedBlockIDs instead.
+		AppliedBlockIDs []types.BlockID
+
 		// SiacoinOutputDiffs contains the set of siacoin diffs that were applied
 		// to the consensus set in the recent change. The direction for the set of
 		// diffs is 'DiffApply'.
@@ -194,6 +215,10 @@ type (
 		// a bool to indicate whether that block exists.
 		BlockByID(types.BlockID) (types.Block, types.BlockHeight, bool)
 
+		// BlockPruned returns true if the block with the given id is in the
+		// current path, but its body has been pruned from the consensus set.
+		BlockPruned(types.BlockID) bool
+
 		// ChildTarget returns the target required to extend the current heaviest
 		// fork. This function is typically used by miners looking to extend the
 		// heaviest fork.
@@ -234,6 +259,13 @@ type (
 		// risk of mining invalid blocks.
 		MinimumValidChildTimestamp(types.BlockID) (types.Timestamp, bool)
 
+		// Pruned returns true if the consensus set prunes the bodies of old
+		// blocks. A pruned consensus set cannot provide the full history of
+		// the blockchain, which is required to rescan it. A consensus set
+		// that was created from a snapshot can be rescanned, because its
+		// first consensus change contains the state at the snapshot height.
+		Pruned() bool
+
 		// StorageProofSegment returns the segment to be used in the storage proof for
 		// a given file contract.
 		StorageProofSegment(types.FileContractID) (uint64, error)
@@ -259,6 +291,7 @@ func (cc ConsensusChange) Append(cc2 ConsensusChange) ConsensusChange {
 	return ConsensusChange{
 		RevertedBlocks:            append(cc.RevertedBlocks, cc2.RevertedBlocks...),
 		AppliedBlocks:             append(cc.AppliedBlocks, cc2.AppliedBlocks...),
+		AppliedBlockIDs:           append(cc.AppliedBlockIDs, cc2.AppliedBlockIDs...),
 		SiacoinOutputDiffs:        append(cc.SiacoinOutputDiffs, cc2.SiacoinOutputDiffs...),
 		FileContractDiffs:         append(cc.FileContractDiffs, cc2.FileContractDiffs...),
 		SiafundOutputDiffs:        append(cc.SiafundOutputDiffs, cc2.SiafundOutputDiffs...),
@@ -58,8 +58,13 @@ func (cs *ConsensusSet) validateHeaderAndBlock(tx dbTx, b types.Block, id types.
 	if err != nil {
 		return nil, err
 	}
+	// Check that the block does not conflict with a checkpoint.
+	err = cs.checkCheckpoint(parent.Height+1, id)
+	if err != nil {
+		return nil, err
+	}
 	// Check that the timestamp is not too far in the past to be acceptable.
-	minTimestamp := cs.blockRuleHelper.minimumValidChildTimestamp(blockMap, parent)
+	minTimestamp := cs.blockRuleHelper.minimumValidChildTimestamp(blockMapWithHeaders(tx), parent)
 
 	err = cs.blockValidator.ValidateBlock(b, id, minTimestamp, parent.ChildTarget, parent.Height+1, cs.log)
 	if err != nil {
@@ -106,6 +111,11 @@ func (cs *ConsensusSet) validateHeader(tx dbTx, h types.BlockHeader) error {
 	if err != nil {
 		return err
 	}
+	// Check that the block does not conflict with a checkpoint.
+	err = cs.checkCheckpoint(parent.Height+1, id)
+	if err != nil {
+		return err
+	}
 
 	// Check that the target of the new block is sufficient.
 	if !checkHeaderTarget(h, parent.ChildTarget) {
@@ -116,7 +126,7 @@ func (cs *ConsensusSet) validateHeader(tx dbTx, h types.BlockHeader) error {
 	// downloads are implemented.
 
 	// Check that the timestamp is not too far in the past to be acceptable.
-	minTimestamp := cs.blockRuleHelper.minimumValidChildTimestamp(blockMap, &parent)
+	minTimestamp := cs.blockRuleHelper.minimumValidChildTimestamp(blockMapWithHeaders(tx), &parent)
 	if minTimestamp > h.Timestamp {
 		return errEarlyTimestamp
 	}
@@ -307,6 +317,13 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 	for i := 0; i < len(changes); i++ {
 		cs.updateSubscribers(changes[i])
 	}
+	// Prune the blocks that are too deep now. Pruning happens after the
+	// changes have been sent, because they may contain the pruned blocks.
+	if cs.pruneDepth > 0 {
+		if err := cs.db.Update(cs.pruneBlocks); err != nil {
+			cs.log.Println("WARN: unable to prune blocks:", err)
+		}
+	}
 	return chainExtended, nil
 }
 
@@ -160,3 +160,12 @@ func (cs *ConsensusSet) genesisEntry() changeEntry {
 		AppliedBlocks: []types.BlockID{cs.blockRoot.Block.ID()},
 	}
 }
+
+// firstEntry returns the first entry of the change log, which is the genesis
+// entry unless the database was created from a snapshot.
+func (cs *ConsensusSet) firstEntry(tx *bolt.Tx) changeEntry {
+	if ce, exists := getSnapshotEntry(tx); exists {
+		return ce
+	}
+	return cs.genesisEntry()
+}
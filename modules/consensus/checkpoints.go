@@ -0,0 +1,53 @@
+package consensus
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errCheckpointMismatch    = errors.New("block does not match the checkpoint at its height")
+	errConflictingCheckpoint = errors.New("two checkpoints were provided for the same height with different block ids")
+	errGenesisCheckpoint     = errors.New("checkpoint at height 0 does not match the genesis block")
+)
+
+// hardcodedCheckpoints are the checkpoints that every consensus set enforces
+// in addition to any checkpoints supplied by the user. Blocks committed to by
+// the highest checkpoint are accepted during header-first sync without
+// verifying their transaction signatures.
+//
+// Checkpoints are only ever added to this list at release time, using block
+// ids that are buried deep enough to never be reorged.
+var hardcodedCheckpoints = build.Select(build.Var{
+	Standard: []modules.Checkpoint(nil),
+	Dev:      []modules.Checkpoint(nil),
+	Testing:  []modules.Checkpoint(nil),
+}).([]modules.Checkpoint)
+
+// buildCheckpoints merges the hardcoded checkpoints with the user supplied
+// checkpoints, returning an error if any two of them conflict.
+func buildCheckpoints(userCheckpoints []modules.Checkpoint) (map[types.BlockHeight]types.BlockID, error) {
+	checkpoints := make(map[types.BlockHeight]types.BlockID)
+	for _, cp := range append(append([]modules.Checkpoint(nil), hardcodedCheckpoints...), userCheckpoints...) {
+		if cp.Height == 0 && cp.ID != types.GenesisID {
+			return nil, errGenesisCheckpoint
+		}
+		if id, exists := checkpoints[cp.Height]; exists && id != cp.ID {
+			return nil, errConflictingCheckpoint
+		}
+		checkpoints[cp.Height] = cp.ID
+	}
+	return checkpoints, nil
+}
+
+// checkCheckpoint returns errCheckpointMismatch if there is a checkpoint at
+// the given height that does not match the given block id.
+func (cs *ConsensusSet) checkCheckpoint(height types.BlockHeight, id types.BlockID) error {
+	if cpID, exists := cs.checkpoints[height]; exists && cpID != id {
+		return errCheckpointMismatch
+	}
+	return nil
+}
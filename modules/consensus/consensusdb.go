@@ -33,6 +33,12 @@ var (
 	// transaction, therefore cannot be assumed reliable.
 	BlockHeight = []byte("BlockHeight")
 
+	// BlockHeaders is a database bucket containing the headers of blocks in
+	// the current path whose processed blocks are not stored in BlockMap,
+	// keyed by their id. It only exists in databases that were created from a
+	// consensus snapshot or whose old blocks have been pruned.
+	BlockHeaders = []byte("BlockHeaders")
+
 	// BlockMap is a database bucket containing all of the processed blocks,
 	// keyed by their id. This includes blocks that are not currently in the
 	// consensus set, and blocks that may not have been fully validated yet.
@@ -58,6 +64,12 @@ var (
 	// contracts.
 	FileContracts = []byte("FileContracts")
 
+	// Pruning is a database bucket that only exists in pruned databases and
+	// databases created from a snapshot. It contains the height of the
+	// highest block whose processed block has been removed from BlockMap, and
+	// whether the database is pruned.
+	Pruning = []byte("Pruning")
+
 	// SiacoinOutputs is a database bucket that contains all of the unspent
 	// siacoin outputs.
 	SiacoinOutputs = []byte("SiacoinOutputs")
@@ -69,12 +81,35 @@ var (
 	// SiafundPool is a database bucket storing the current value of the
 	// siafund pool.
 	SiafundPool = []byte("SiafundPool")
+
+	// Snapshot is a database bucket that only exists in databases that were
+	// created from a consensus snapshot. It contains the id of the synthetic
+	// change entry that replaces the genesis entry in the change log, and the
+	// diffs that the synthetic change presents to subscribers.
+	Snapshot = []byte("Snapshot")
 )
 
 var (
 	// FieldOakInit is a field in BucketOak that gets set to "true" after the
 	// oak initialiation process has completed.
 	FieldOakInit = []byte("OakInit")
+
+	// FieldPrunedHeight is a field in the Pruning bucket that contains the
+	// height of the highest pruned block.
+	FieldPrunedHeight = []byte("PrunedHeight")
+
+	// FieldPruningEnabled is a field in the Pruning bucket that is set once
+	// the database has been opened by a pruned consensus set. The database
+	// stays pruned if it is opened without pruning later on.
+	FieldPruningEnabled = []byte("PruningEnabled")
+
+	// FieldSnapshotEntry is a field in the Snapshot bucket that contains the
+	// id of the synthetic change entry created by the snapshot import.
+	FieldSnapshotEntry = []byte("SnapshotEntry")
+
+	// FieldSnapshotDiffs is a field in the Snapshot bucket that contains the
+	// diffs of the synthetic change entry created by the snapshot import.
+	FieldSnapshotDiffs = []byte("SnapshotDiffs")
 )
 
 var (
@@ -198,6 +233,67 @@ func getBlockMap(tx *bolt.Tx, id types.BlockID) (*processedBlock, error) {
 	return &pb, nil
 }
 
+// getBlockHeader returns the header of the block with the input id. The
+// header is taken from the block map if possible, and from the BlockHeaders
+// bucket otherwise.
+func getBlockHeader(tx *bolt.Tx, id types.BlockID) (types.BlockHeader, error) {
+	pb, err := getBlockMap(tx, id)
+	if err == nil {
+		return pb.Block.Header(), nil
+	}
+	headers := tx.Bucket(BlockHeaders)
+	if headers == nil {
+		return types.BlockHeader{}, errNilItem
+	}
+	headerBytes := headers.Get(id[:])
+	if headerBytes == nil {
+		return types.BlockHeader{}, errNilItem
+	}
+	var h types.BlockHeader
+	err = encoding.Unmarshal(headerBytes, &h)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return h, nil
+}
+
+// isPrunedBlock returns true if only the header of the block with the input
+// id is known to the database.
+func isPrunedBlock(tx *bolt.Tx, id types.BlockID) bool {
+	headers := tx.Bucket(BlockHeaders)
+	return headers != nil && headers.Get(id[:]) != nil && tx.Bucket(BlockMap).Get(id[:]) == nil
+}
+
+// headerFallbackBucket is a dbBucket that looks up keys in the block map, and
+// falls back to the BlockHeaders bucket for blocks that are only known by
+// their header.
+type headerFallbackBucket struct {
+	blockMap dbBucket
+	headers  dbBucket
+}
+
+// Get returns the encoded processed block or header with the given id.
+func (b headerFallbackBucket) Get(key []byte) []byte {
+	if v := b.blockMap.Get(key); v != nil {
+		return v
+	}
+	return b.headers.Get(key)
+}
+
+// blockMapWithHeaders returns a dbBucket that can be passed to
+// minimumValidChildTimestamp in place of the block map. The encoding of a
+// header is a prefix of the encoding of a processed block, so the parent id
+// and timestamp of a pruned block can be read the same way as those of a
+// stored block.
+func blockMapWithHeaders(tx dbTx) dbBucket {
+	blockMap := tx.Bucket(BlockMap)
+	headers := tx.Bucket(BlockHeaders)
+	if headers == nil {
+		return blockMap
+	}
+	return headerFallbackBucket{blockMap: blockMap, headers: headers}
+}
+
 // addBlockMap adds a processed block to the block map.
 func addBlockMap(tx *bolt.Tx, pb *processedBlock) {
 	id := pb.Block.ID()
@@ -81,6 +81,24 @@ type ConsensusSet struct {
 	// whether the consensus set is synced with the network.
 	synced bool
 
+	// checkpoints maps block heights to the only block ids that are acceptable
+	// at those heights. They are a combination of the hardcoded checkpoints
+	// and any checkpoints supplied by the user, and never change after
+	// startup.
+	checkpoints map[types.BlockHeight]types.BlockID
+
+	// checkpointedBlocks contains the ids of blocks in the header chain that
+	// is currently being downloaded, but only those committed to by a
+	// checkpoint. Their transaction signatures are not verified when they are
+	// applied. The set is cleared once the block bodies have been downloaded.
+	checkpointedBlocks map[types.BlockID]struct{}
+
+	// pruneDepth is the number of most recent blocks in the current path
+	// whose processed blocks are kept. Older processed blocks are removed
+	// from the database as new blocks arrive. A pruneDepth of zero disables
+	// pruning.
+	pruneDepth types.BlockHeight
+
 	// Interfaces to abstract the dependencies of the ConsensusSet.
 	marshaler       marshaler
 	blockRuleHelper blockRuleHelper
@@ -102,28 +120,41 @@ func New(gateway modules.Gateway, bootstrap bool, persistDir string) (*Consensus
 	return NewCustomConsensusSet(gateway, bootstrap, persistDir, modules.ProdDependencies)
 }
 
+// NewWithCheckpoints returns a new ConsensusSet that enforces the provided
+// checkpoints in addition to the hardcoded ones.
+func NewWithCheckpoints(gateway modules.Gateway, bootstrap bool, persistDir string, checkpoints []modules.Checkpoint) (*ConsensusSet, error) {
+	return newConsensusSet(gateway, bootstrap, persistDir, checkpoints, 0, modules.ProdDependencies)
+}
+
 // NewCustomConsensusSet returns a new ConsensusSet, containing at least the genesis block. If
 // there is an existing block database present in the persist directory, it
 // will be loaded.
 func NewCustomConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir string, deps modules.Dependencies) (*ConsensusSet, error) {
+	return newConsensusSet(gateway, bootstrap, persistDir, nil, 0, deps)
+}
+
+// newConsensusSet returns a new ConsensusSet that enforces the provided
+// checkpoints, prunes blocks that are deeper than pruneDepth and uses the
+// provided dependencies.
+func newConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir string, userCheckpoints []modules.Checkpoint, pruneDepth types.BlockHeight, deps modules.Dependencies) (*ConsensusSet, error) {
 	// Check for nil dependencies.
 	if gateway == nil {
 		return nil, errNilGateway
 	}
+	checkpoints, err := buildCheckpoints(userCheckpoints)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create the ConsensusSet object.
 	cs := &ConsensusSet{
 		gateway: gateway,
 
-		blockRoot: processedBlock{
-			Block:       types.GenesisBlock,
-			ChildTarget: types.RootTarget,
-			Depth:       types.RootDepth,
+		blockRoot: genesisProcessedBlock(),
 
-			DiffsGenerated: true,
-		},
-
-		dosBlocks: make(map[types.BlockID]struct{}),
+		dosBlocks:   make(map[types.BlockID]struct{}),
+		checkpoints: checkpoints,
+		pruneDepth:  pruneDepth,
 
 		marshaler:       stdMarshaler{},
 		blockRuleHelper: stdBlockRuleHelper{},
@@ -133,19 +164,8 @@ func NewCustomConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir s
 		persistDir: persistDir,
 	}
 
-	// Create the diffs for the genesis siafund outputs.
-	for i, siafundOutput := range types.GenesisBlock.Transactions[0].SiafundOutputs {
-		sfid := types.GenesisBlock.Transactions[0].SiafundOutputID(uint64(i))
-		sfod := modules.SiafundOutputDiff{
-			Direction:     modules.DiffApply,
-			ID:            sfid,
-			SiafundOutput: siafundOutput,
-		}
-		cs.blockRoot.SiafundOutputDiffs = append(cs.blockRoot.SiafundOutputDiffs, sfod)
-	}
-
 	// Initialize the consensus persistence structures.
-	err := cs.initPersist()
+	err = cs.initPersist()
 	if err != nil {
 		return nil, err
 	}
@@ -176,11 +196,15 @@ func NewCustomConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir s
 		gateway.RegisterRPC("SendBlocks", cs.rpcSendBlocks)
 		gateway.RegisterRPC("RelayHeader", cs.threadedRPCRelayHeader)
 		gateway.RegisterRPC("SendBlk", cs.rpcSendBlk)
+		gateway.RegisterRPC("SendHeaders", cs.rpcSendHeaders)
+		gateway.RegisterRPC("SendBodies", cs.rpcSendBodies)
 		gateway.RegisterConnectCall("SendBlocks", cs.threadedReceiveBlocks)
 		cs.tg.OnStop(func() {
 			cs.gateway.UnregisterRPC("SendBlocks")
 			cs.gateway.UnregisterRPC("RelayHeader")
 			cs.gateway.UnregisterRPC("SendBlk")
+			cs.gateway.UnregisterRPC("SendHeaders")
+			cs.gateway.UnregisterRPC("SendBodies")
 			cs.gateway.UnregisterConnectCall("SendBlocks")
 		})
 
@@ -193,7 +217,30 @@ func NewCustomConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir s
 	return cs, nil
 }
 
-// BlockAtHeight returns the block at a given height.
+// genesisProcessedBlock returns the processed block of the genesis block,
+// including the diffs for the genesis siafund outputs.
+func genesisProcessedBlock() processedBlock {
+	pb := processedBlock{
+		Block:       types.GenesisBlock,
+		ChildTarget: types.RootTarget,
+		Depth:       types.RootDepth,
+
+		DiffsGenerated: true,
+	}
+	for i, siafundOutput := range types.GenesisBlock.Transactions[0].SiafundOutputs {
+		sfid := types.GenesisBlock.Transactions[0].SiafundOutputID(uint64(i))
+		sfod := modules.SiafundOutputDiff{
+			Direction:     modules.DiffApply,
+			ID:            sfid,
+			SiafundOutput: siafundOutput,
+		}
+		pb.SiafundOutputDiffs = append(pb.SiafundOutputDiffs, sfod)
+	}
+	return pb
+}
+
+// BlockAtHeight returns the block at a given height. Blocks that have been
+// pruned are reported as nonexistent.
 func (cs *ConsensusSet) BlockAtHeight(height types.BlockHeight) (block types.Block, exists bool) {
 	_ = cs.db.View(func(tx *bolt.Tx) error {
 		id, err := getPath(tx, height)
@@ -211,7 +258,8 @@ func (cs *ConsensusSet) BlockAtHeight(height types.BlockHeight) (block types.Blo
 	return block, exists
 }
 
-// BlockByID returns the block for a given BlockID.
+// BlockByID returns the block for a given BlockID. Blocks that have been
+// pruned are reported as nonexistent.
 func (cs *ConsensusSet) BlockByID(id types.BlockID) (block types.Block, height types.BlockHeight, exists bool) {
 	_ = cs.db.View(func(tx *bolt.Tx) error {
 		pb, err := getBlockMap(tx, id)
@@ -327,6 +375,11 @@ func (cs *ConsensusSet) InCurrentPath(id types.BlockID) (inPath bool) {
 	defer cs.tg.Done()
 
 	_ = cs.db.View(func(tx *bolt.Tx) error {
+		// Only blocks of the current path are pruned.
+		if isPrunedBlock(tx, id) {
+			inPath = true
+			return nil
+		}
 		pb, err := getBlockMap(tx, id)
 		if err != nil {
 			inPath = false
@@ -359,7 +412,7 @@ func (cs *ConsensusSet) MinimumValidChildTimestamp(id types.BlockID) (timestamp
 		if err != nil {
 			return err
 		}
-		timestamp = cs.blockRuleHelper.minimumValidChildTimestamp(tx.Bucket(BlockMap), pb)
+		timestamp = cs.blockRuleHelper.minimumValidChildTimestamp(blockMapWithHeaders(boltTxWrapper{tx}), pb)
 		exists = true
 		return nil
 	})
@@ -48,9 +48,13 @@ type (
 	}
 )
 
-// Bucket returns the dbBucket associated with the given bucket name.
+// Bucket returns the dbBucket associated with the given bucket name. If the
+// bucket does not exist, nil is returned.
 func (b boltTxWrapper) Bucket(name []byte) dbBucket {
-	return b.tx.Bucket(name)
+	if bucket := b.tx.Bucket(name); bucket != nil {
+		return bucket
+	}
+	return nil
 }
 
 // replaceDatabase backs up the existing database and creates a new one.
@@ -151,10 +151,9 @@ func (cs *ConsensusSet) getBlockTotals(tx *bolt.Tx, id types.BlockID) (totalTime
 	return
 }
 
-// storeBlockTotals computes the new total time and total target for the current
-// block and stores that new time in the database. It also returns the new
-// totals.
-func (cs *ConsensusSet) storeBlockTotals(tx *bolt.Tx, currentHeight types.BlockHeight, currentBlockID types.BlockID, prevTotalTime int64, parentTimestamp, currentTimestamp types.Timestamp, prevTotalTarget, targetOfCurrentBlock types.Target) (newTotalTime int64, newTotalTarget types.Target, err error) {
+// blockTotals computes the new total time and total target for the current
+// block from the totals of its parent.
+func blockTotals(currentHeight types.BlockHeight, prevTotalTime int64, parentTimestamp, currentTimestamp types.Timestamp, prevTotalTarget, targetOfCurrentBlock types.Target) (newTotalTime int64, newTotalTarget types.Target) {
 	// Reset the prevTotalTime to a delta of zero just before the hardfork.
 	//
 	// NOTICE: This code is broken, an incorrectly executed hardfork. The
@@ -179,19 +178,89 @@ func (cs *ConsensusSet) storeBlockTotals(tx *bolt.Tx, currentHeight types.BlockH
 	// delta.
 	newTotalTime = (prevTotalTime * types.OakDecayNum / types.OakDecayDenom) + (int64(currentTimestamp) - int64(parentTimestamp))
 	newTotalTarget = prevTotalTarget.MulDifficulty(big.NewRat(types.OakDecayNum, types.OakDecayDenom)).AddDifficulties(targetOfCurrentBlock)
+	return newTotalTime, newTotalTarget
+}
+
+// storeBlockTotals computes the new total time and total target for the current
+// block and stores that new time in the database. It also returns the new
+// totals.
+func (cs *ConsensusSet) storeBlockTotals(tx *bolt.Tx, currentHeight types.BlockHeight, currentBlockID types.BlockID, prevTotalTime int64, parentTimestamp, currentTimestamp types.Timestamp, prevTotalTarget, targetOfCurrentBlock types.Target) (newTotalTime int64, newTotalTarget types.Target, err error) {
+	newTotalTime, newTotalTarget = blockTotals(currentHeight, prevTotalTime, parentTimestamp, currentTimestamp, prevTotalTarget, targetOfCurrentBlock)
 
 	// Store the new total time and total target in the database at the
 	// appropriate id.
-	bytes := make([]byte, 40)
-	binary.LittleEndian.PutUint64(bytes[:8], uint64(newTotalTime))
-	copy(bytes[8:], newTotalTarget[:])
-	err = tx.Bucket(BucketOak).Put(currentBlockID[:], bytes)
+	err = putBlockTotals(tx, currentBlockID, newTotalTime, newTotalTarget)
 	if err != nil {
-		return 0, types.Target{}, errors.Extend(errors.New("unable to store total time values"), err)
+		return 0, types.Target{}, err
 	}
 	return newTotalTime, newTotalTarget, nil
 }
 
+// putBlockTotals stores the total time and total target of a block in the oak
+// bucket.
+func putBlockTotals(tx *bolt.Tx, id types.BlockID, totalTime int64, totalTarget types.Target) error {
+	bytes := make([]byte, 40)
+	binary.LittleEndian.PutUint64(bytes[:8], uint64(totalTime))
+	copy(bytes[8:], totalTarget[:])
+	err := tx.Bucket(BucketOak).Put(id[:], bytes)
+	if err != nil {
+		return errors.Extend(errors.New("unable to store total time values"), err)
+	}
+	return nil
+}
+
+// headerNode holds the difficulty state of a header in a header chain. It is
+// the header-only equivalent of the fields that newChild computes for a
+// processedBlock, which allows proof-of-work and difficulty to be verified
+// before the corresponding block bodies have been downloaded.
+type headerNode struct {
+	Height      types.BlockHeight
+	Timestamp   types.Timestamp
+	ChildTarget types.Target
+	Depth       types.Target
+
+	TotalTime   int64
+	TotalTarget types.Target
+}
+
+// childHeaderNode computes the difficulty state of a header that builds on
+// 'parent', following the same rules that newChild applies to full blocks.
+// ancestorTimestamp must return the timestamp of the ancestor of the header at
+// the given height, and is only consulted by the pre-oak difficulty
+// adjustment.
+func (cs *ConsensusSet) childHeaderNode(parent headerNode, h types.BlockHeader, ancestorTimestamp func(types.BlockHeight) types.Timestamp) headerNode {
+	child := headerNode{
+		Height:    parent.Height + 1,
+		Timestamp: h.Timestamp,
+		Depth:     parent.Depth.AddDifficulties(parent.ChildTarget),
+	}
+	child.TotalTime, child.TotalTarget = blockTotals(child.Height, parent.TotalTime, parent.Timestamp, h.Timestamp, parent.TotalTarget, parent.ChildTarget)
+
+	// Past the hardfork, the oak algorithm only needs the totals of the
+	// parent.
+	if parent.Height >= types.OakHardforkBlock {
+		child.ChildTarget = cs.childTargetOak(parent.TotalTime, parent.TotalTarget, parent.ChildTarget, parent.Height, parent.Timestamp)
+		return child
+	}
+
+	// Before the hardfork, the target only changes every TargetWindow/2
+	// blocks, based on the time passed since the TargetWindow'th ancestor. See
+	// setChildTarget and targetAdjustmentBase.
+	child.ChildTarget = parent.ChildTarget
+	if child.Height%(types.TargetWindow/2) != 0 {
+		return child
+	}
+	windowSize := types.TargetWindow
+	if child.Height < windowSize {
+		windowSize = child.Height
+	}
+	timePassed := h.Timestamp - ancestorTimestamp(child.Height-windowSize)
+	expectedTimePassed := types.BlockFrequency * windowSize
+	adjustment := clampTargetAdjustment(big.NewRat(int64(timePassed), int64(expectedTimePassed)))
+	child.ChildTarget = types.RatToTarget(new(big.Rat).Mul(parent.ChildTarget.Rat(), adjustment))
+	return child
+}
+
 // initOak will initialize all of the oak difficulty adjustment related fields.
 // This is separate from the initialization process for compatibility reasons -
 // some databases will not have these fields at start, so it much be checked.
@@ -201,7 +201,10 @@ func commitDiffSet(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
 // transactions are allowed to depend on each other. We can't be sure that a
 // transaction is valid unless we have applied all of the previous transactions
 // in the block, which means we need to apply while we verify.
-func generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock) error {
+//
+// If checkpointed is set, the block is committed to by a trusted checkpoint and
+// the signatures of its transactions are not verified.
+func generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock, checkpointed bool) error {
 	// Sanity check - the block being applied should have the current block as
 	// a parent.
 	if build.DEBUG && pb.Block.ParentID != currentBlockID(tx) {
@@ -217,7 +220,12 @@ func generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock) error {
 	// validated all at once because some transactions may not be valid until
 	// previous transactions have been applied.
 	for _, txn := range pb.Block.Transactions {
-		err := validTransaction(tx, txn)
+		var err error
+		if checkpointed {
+			err = validCheckpointedTransaction(tx, txn)
+		} else {
+			err = validTransaction(tx, txn)
+		}
 		if err != nil {
 			return err
 		}
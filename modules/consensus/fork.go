@@ -11,12 +11,13 @@ import (
 
 var (
 	errExternalRevert = errors.New("cannot revert to block outside of current path")
+	errPrunedFork     = errors.New("fork branches off from a block that has been pruned")
 )
 
 // backtrackToCurrentPath traces backwards from 'pb' until it reaches a block
 // in the ConsensusSet's current path (the "common parent"). It returns the
 // (inclusive) set of blocks between the common parent and 'pb', starting from
-// the former.
+// the former. If the common parent has been pruned, nil is returned.
 func backtrackToCurrentPath(tx *bolt.Tx, pb *processedBlock) []*processedBlock {
 	path := []*processedBlock{pb}
 	for {
@@ -34,7 +35,11 @@ func backtrackToCurrentPath(tx *bolt.Tx, pb *processedBlock) []*processedBlock {
 
 		// Prepend the next block to the list of blocks leading from the
 		// current path to the input block.
-		pb, err = getBlockMap(tx, pb.Block.ParentID)
+		parentID := pb.Block.ParentID
+		pb, err = getBlockMap(tx, parentID)
+		if err != nil && isPrunedBlock(tx, parentID) {
+			return nil
+		}
 		if build.DEBUG && err != nil {
 			panic(err)
 		}
@@ -85,7 +90,8 @@ func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock) (applie
 		if block.DiffsGenerated {
 			commitDiffSet(tx, block, modules.DiffApply)
 		} else {
-			err := generateAndApplyDiff(tx, block)
+			_, checkpointed := cs.checkpointedBlocks[block.Block.ID()]
+			err := generateAndApplyDiff(tx, block, checkpointed)
 			if err != nil {
 				// Mark the block as invalid.
 				cs.dosBlocks[block.Block.ID()] = struct{}{}
@@ -110,7 +116,11 @@ func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock) (applie
 // found to be invalid. forkBlockchain is atomic; the ConsensusSet is only
 // updated if the function returns nil.
 func (cs *ConsensusSet) forkBlockchain(tx *bolt.Tx, newBlock *processedBlock) (revertedBlocks, appliedBlocks []*processedBlock, err error) {
-	commonParent := backtrackToCurrentPath(tx, newBlock)[0]
+	path := backtrackToCurrentPath(tx, newBlock)
+	if path == nil {
+		return nil, nil, errPrunedFork
+	}
+	commonParent := path[0]
 	revertedBlocks = cs.revertToBlock(tx, commonParent)
 	appliedBlocks, err = cs.applyUntilBlock(tx, newBlock)
 	if err != nil {
@@ -0,0 +1,624 @@
+package consensus
+
+// headers.go implements header-first synchronization. During IBD, the
+// consensus set first downloads the chain of block headers from a single peer
+// and verifies proof-of-work, difficulty and timestamps along the whole chain.
+// The block bodies are then downloaded in parallel from several peers, and
+// applied in order. Block bodies are verified against the header chain by id,
+// so a peer cannot substitute a different block for the one requested.
+//
+// If a checkpoint is contained in the header chain, every block at or below
+// the checkpoint is committed to by the checkpoint, and the signatures of the
+// transactions in those blocks are not verified.
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	errBodyDownloadIncomplete = errors.New("all peers failed before the block bodies of the header chain were downloaded")
+	errBodyMismatch           = errors.New("peer sent block bodies that do not match the requested ids")
+	errTooManyBodies          = errors.New("more block bodies were requested than allowed")
+)
+
+// A headerChain is a chain of block headers that has been verified for
+// proof-of-work, difficulty, timestamps and checkpoints, but whose block
+// bodies have not been downloaded yet. The first header in the chain builds on
+// a block that is known to the consensus set.
+type headerChain struct {
+	// ids contains the ids of the headers in the chain, in order. The first id
+	// is at height base.Height+1.
+	ids []types.BlockID
+
+	// base is the difficulty state of the known block that the chain builds
+	// on, and tip is the difficulty state of the last header in the chain.
+	baseID types.BlockID
+	base   headerNode
+	tip    headerNode
+
+	// timestamps contains the timestamps of the ancestors of the base block,
+	// the base block itself, and every header in the chain. The timestamp at
+	// index 0 belongs to the block at height timestampsStart.
+	timestamps      []types.Timestamp
+	timestampsStart types.BlockHeight
+}
+
+// timestampAt returns the timestamp of the block at the given height in the
+// header chain or among the ancestors of its base.
+func (hc *headerChain) timestampAt(height types.BlockHeight) types.Timestamp {
+	if height < hc.timestampsStart {
+		// Heights before the loaded ancestors are never needed, the
+		// difficulty and timestamp rules only look back a fixed number of
+		// blocks.
+		return hc.timestamps[0]
+	}
+	return hc.timestamps[height-hc.timestampsStart]
+}
+
+// minimumValidChildTimestamp returns the earliest timestamp that the child of
+// the tip of the header chain can have, following the same rules as
+// stdBlockRuleHelper.minimumValidChildTimestamp.
+func (hc *headerChain) minimumValidChildTimestamp() types.Timestamp {
+	windowTimes := make(types.TimestampSlice, types.MedianTimestampWindow)
+	height := hc.tip.Height
+	for i := range windowTimes {
+		windowTimes[i] = hc.timestampAt(height)
+		// If the genesis block has been reached, use the genesis block
+		// timestamp for all remaining times.
+		if height > 0 {
+			height--
+		}
+	}
+	sort.Sort(windowTimes)
+	return windowTimes[len(windowTimes)/2]
+}
+
+// newHeaderChain creates an empty header chain that builds on the known block
+// with the given id.
+func (cs *ConsensusSet) newHeaderChain(tx *bolt.Tx, baseID types.BlockID) (*headerChain, error) {
+	pb, err := getBlockMap(tx, baseID)
+	if err != nil {
+		return nil, errOrphan
+	}
+	totalTime, totalTarget := cs.getBlockTotals(tx, baseID)
+	base := headerNode{
+		Height:      pb.Height,
+		Timestamp:   pb.Block.Timestamp,
+		ChildTarget: pb.ChildTarget,
+		Depth:       pb.Depth,
+		TotalTime:   totalTime,
+		TotalTarget: totalTarget,
+	}
+
+	// Load the timestamps of enough ancestors of the base block to cover both
+	// the median timestamp window and the pre-oak target window.
+	lookback := types.BlockHeight(types.MedianTimestampWindow)
+	if lookback < types.TargetWindow {
+		lookback = types.TargetWindow
+	}
+	timestamps := []types.Timestamp{pb.Block.Timestamp}
+	parentID := pb.Block.ParentID
+	for i := types.BlockHeight(0); i < lookback && parentID != (types.BlockID{}); i++ {
+		parent, err := getBlockHeader(tx, parentID)
+		if err != nil {
+			return nil, err
+		}
+		timestamps = append(timestamps, parent.Timestamp)
+		parentID = parent.ParentID
+	}
+	// The timestamps were collected from newest to oldest.
+	for i, j := 0, len(timestamps)-1; i < j; i, j = i+1, j-1 {
+		timestamps[i], timestamps[j] = timestamps[j], timestamps[i]
+	}
+
+	return &headerChain{
+		baseID:          baseID,
+		base:            base,
+		tip:             base,
+		timestamps:      timestamps,
+		timestampsStart: pb.Height - types.BlockHeight(len(timestamps)-1),
+	}, nil
+}
+
+// extendHeaderChain verifies that h is a valid child of the tip of the header
+// chain and then appends it to the chain. Validation mirrors validateHeader,
+// except that the parent is the tip of the header chain rather than a block in
+// the database.
+func (cs *ConsensusSet) extendHeaderChain(hc *headerChain, h types.BlockHeader) error {
+	id := h.ID()
+	tipID := hc.baseID
+	if len(hc.ids) > 0 {
+		tipID = hc.ids[len(hc.ids)-1]
+	}
+	if h.ParentID != tipID {
+		return errNonLinearChain
+	}
+	if _, exists := cs.dosBlocks[id]; exists {
+		return errDoSBlock
+	}
+	if err := cs.checkCheckpoint(hc.tip.Height+1, id); err != nil {
+		return err
+	}
+	if !checkHeaderTarget(h, hc.tip.ChildTarget) {
+		return modules.ErrBlockUnsolved
+	}
+	if hc.minimumValidChildTimestamp() > h.Timestamp {
+		return errEarlyTimestamp
+	}
+	if h.Timestamp > types.CurrentTimestamp()+types.ExtremeFutureThreshold {
+		return errExtremeFutureTimestamp
+	}
+
+	hc.tip = cs.childHeaderNode(hc.tip, h, hc.timestampAt)
+	hc.ids = append(hc.ids, id)
+	hc.timestamps = append(hc.timestamps, h.Timestamp)
+	return nil
+}
+
+// managedReceiveHeaders is the calling end of the SendHeaders RPC. It returns
+// the verified header chain sent by the peer, which is nil if the peer has no
+// headers that the consensus set is missing.
+func (cs *ConsensusSet) managedReceiveHeaders(conn modules.PeerConn) (*headerChain, error) {
+	err := conn.SetDeadline(time.Now().Add(sendHeadersTimeout))
+	if err != nil {
+		return nil, err
+	}
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+
+	// Send the block history so that the peer can find a common block.
+	var history [32]types.BlockID
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		history = blockHistory(tx)
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := encoding.WriteObject(conn, history); err != nil {
+		return nil, err
+	}
+
+	// Read batches of headers off of the wire and verify each of them against
+	// the chain built so far.
+	var hc *headerChain
+	moreAvailable := true
+	for moreAvailable {
+		var headers []types.BlockHeader
+		if err := encoding.ReadObject(conn, &headers, uint64(MaxCatchUpHeaders)*types.BlockHeaderSize+8); err != nil {
+			return nil, err
+		}
+		if err := encoding.ReadObject(conn, &moreAvailable, 1); err != nil {
+			return nil, err
+		}
+		if len(headers) == 0 {
+			continue
+		}
+
+		cs.mu.RLock()
+		err = cs.db.View(func(tx *bolt.Tx) error {
+			if hc == nil {
+				var err error
+				hc, err = cs.newHeaderChain(tx, headers[0].ParentID)
+				if err != nil {
+					return err
+				}
+			}
+			for _, h := range headers {
+				if err := cs.extendHeaderChain(hc, h); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		cs.mu.RUnlock()
+		if err != nil {
+			if isInvalidBlockErr(err) {
+				cs.gateway.ReportMisbehavior(conn.RPCAddr(), modules.MisbehaviorInvalidBlock, err)
+			}
+			return nil, err
+		}
+	}
+	return hc, nil
+}
+
+// rpcSendHeaders is the receiving end of the SendHeaders RPC. Like
+// rpcSendBlocks, it reads 32 block ids known to the caller and finds the most
+// recent one in the current path. All headers that follow it are then sent in
+// batches of up to MaxCatchUpHeaders, each followed by a boolean indicating
+// whether more headers are available.
+func (cs *ConsensusSet) rpcSendHeaders(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(sendHeadersTimeout))
+	if err != nil {
+		return err
+	}
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	var knownBlocks [32]types.BlockID
+	err = encoding.ReadObject(conn, &knownBlocks, 32*crypto.HashSize)
+	if err != nil {
+		return err
+	}
+	found := false
+	var start types.BlockHeight
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		start, found = catchUpStart(tx, knownBlocks)
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if !found {
+		if err := encoding.WriteObject(conn, []types.BlockHeader{}); err != nil {
+			return err
+		}
+		return encoding.WriteObject(conn, false)
+	}
+
+	moreAvailable := true
+	for moreAvailable {
+		var headers []types.BlockHeader
+		cs.mu.RLock()
+		err = cs.db.View(func(tx *bolt.Tx) error {
+			height := blockHeight(tx)
+			for i := start; i <= height && i < start+MaxCatchUpHeaders; i++ {
+				id, err := getPath(tx, i)
+				if err != nil {
+					return err
+				}
+				h, err := getBlockHeader(tx, id)
+				if err != nil {
+					return err
+				}
+				headers = append(headers, h)
+			}
+			moreAvailable = start+MaxCatchUpHeaders <= height
+			start += MaxCatchUpHeaders
+			return nil
+		})
+		cs.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		if err := encoding.WriteObject(conn, headers); err != nil {
+			return err
+		}
+		if err := encoding.WriteObject(conn, moreAvailable); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// managedReceiveBodies returns an RPCFunc that is the calling end of the
+// SendBodies RPC. It requests the blocks with the given ids and stores
+// them in 'blocks' after checking that they match the requested ids.
+func (cs *ConsensusSet) managedReceiveBodies(ids []types.BlockID, blocks *[]types.Block) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		err := conn.SetDeadline(time.Now().Add(sendBodiesTimeout))
+		if err != nil {
+			return err
+		}
+		finishedChan := make(chan struct{})
+		defer close(finishedChan)
+		go func() {
+			select {
+			case <-cs.tg.StopChan():
+			case <-finishedChan:
+			}
+			conn.Close()
+		}()
+
+		if err := encoding.WriteObject(conn, ids); err != nil {
+			return err
+		}
+		var bodies []types.Block
+		if err := encoding.ReadObject(conn, &bodies, uint64(MaxCatchUpBlocks)*types.BlockSizeLimit); err != nil {
+			return err
+		}
+		if len(bodies) != len(ids) {
+			return errBodyMismatch
+		}
+		for i := range bodies {
+			if bodies[i].ID() != ids[i] {
+				cs.gateway.ReportMisbehavior(conn.RPCAddr(), modules.MisbehaviorInvalidBlock, errBodyMismatch)
+				return errBodyMismatch
+			}
+		}
+		*blocks = bodies
+		return nil
+	}
+}
+
+// rpcSendBodies is the receiving end of the SendBodies RPC. It reads
+// up to MaxCatchUpBlocks block ids and sends the corresponding blocks. Unknown
+// blocks are omitted, which the caller treats as a failure.
+func (cs *ConsensusSet) rpcSendBodies(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(sendBodiesTimeout))
+	if err != nil {
+		return err
+	}
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	var ids []types.BlockID
+	err = encoding.ReadObject(conn, &ids, uint64(MaxCatchUpBlocks)*crypto.HashSize+8)
+	if err != nil {
+		return err
+	}
+	if types.BlockHeight(len(ids)) > MaxCatchUpBlocks {
+		return errTooManyBodies
+	}
+	var blocks []types.Block
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		for _, id := range ids {
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				continue
+			}
+			blocks = append(blocks, pb.Block)
+		}
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return encoding.WriteObject(conn, blocks)
+}
+
+// managedSetCheckpointedBlocks marks the blocks of the header chain that are
+// committed to by the highest checkpoint within the chain, so that their
+// transaction signatures are not verified when they are applied.
+func (cs *ConsensusSet) managedSetCheckpointedBlocks(hc *headerChain) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.checkpointedBlocks = nil
+	var trustedHeight types.BlockHeight
+	for height := range cs.checkpoints {
+		if height > hc.base.Height && height <= hc.tip.Height && height > trustedHeight {
+			trustedHeight = height
+		}
+	}
+	if trustedHeight == 0 {
+		return
+	}
+	// extendHeaderChain has verified that the header at trustedHeight matches
+	// the checkpoint, and that header commits to all of its ancestors.
+	cs.checkpointedBlocks = make(map[types.BlockID]struct{})
+	for _, id := range hc.ids[:trustedHeight-hc.base.Height] {
+		cs.checkpointedBlocks[id] = struct{}{}
+	}
+}
+
+// bodyBatch is a batch of consecutive block bodies of a header chain that is
+// downloaded from a single peer.
+type bodyBatch struct {
+	index  int
+	ids    []types.BlockID
+	blocks []types.Block
+	peer   modules.NetAddress
+}
+
+// managedDownloadBlockBodies downloads the block bodies of the header chain in
+// parallel from the provided peers and applies them to the consensus set in
+// order. A batch that fails to download is handed to another peer, and the
+// peer that failed is not used again. If all of the peers fail,
+// errBodyDownloadIncomplete is returned after all batches that could be
+// applied in order have been applied.
+func (cs *ConsensusSet) managedDownloadBlockBodies(hc *headerChain, peers []modules.Peer) error {
+	var batches []bodyBatch
+	for i := 0; i < len(hc.ids); i += int(MaxCatchUpBlocks) {
+		end := i + int(MaxCatchUpBlocks)
+		if end > len(hc.ids) {
+			end = len(hc.ids)
+		}
+		batches = append(batches, bodyBatch{
+			index: len(batches),
+			ids:   hc.ids[i:end],
+		})
+	}
+	if len(peers) > maxBodyDownloadPeers {
+		peers = peers[:maxBodyDownloadPeers]
+	}
+
+	// Both channels can hold every batch, so that neither the workers nor the
+	// dispatcher ever block on a send.
+	work := make(chan bodyBatch, len(batches))
+	results := make(chan bodyBatch, len(batches))
+	stop := make(chan struct{})
+	defer close(stop)
+
+	var wg sync.WaitGroup
+	for _, p := range peers {
+		wg.Add(1)
+		go func(addr modules.NetAddress) {
+			defer wg.Done()
+			for {
+				var batch bodyBatch
+				select {
+				case batch = <-work:
+				case <-stop:
+					return
+				case <-cs.tg.StopChan():
+					return
+				}
+				err := cs.gateway.RPC(addr, "SendBodies", cs.managedReceiveBodies(batch.ids, &batch.blocks))
+				if err != nil {
+					cs.log.Debugf("WARN: failed to download block bodies from %v: %v", addr, err)
+					work <- batch
+					return
+				}
+				batch.peer = addr
+				results <- batch
+			}
+		}(p.NetAddress)
+	}
+	workersDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(workersDone)
+	}()
+
+	// Only hand out batches within a window ahead of the next batch to be
+	// applied, so that a single slow peer cannot cause an unbounded number of
+	// downloaded blocks to be held in memory.
+	window := 2 * len(peers)
+	next, dispatched := 0, 0
+	dispatch := func() {
+		for dispatched < len(batches) && dispatched < next+window {
+			work <- batches[dispatched]
+			dispatched++
+		}
+	}
+	dispatch()
+
+	pending := make(map[int]bodyBatch)
+	for next < len(batches) {
+		select {
+		case batch := <-results:
+			pending[batch.index] = batch
+		case <-workersDone:
+			for len(results) > 0 {
+				batch := <-results
+				pending[batch.index] = batch
+			}
+			workersDone = nil
+		case <-cs.tg.StopChan():
+			return errEarlyStop
+		}
+
+		// Apply every batch that is next in line.
+		for {
+			batch, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			_, err := cs.managedAcceptBlocks(batch.blocks)
+			if err != nil && err != modules.ErrNonExtendingBlock && err != modules.ErrBlockKnown {
+				if isInvalidBlockErr(err) {
+					cs.gateway.ReportMisbehavior(batch.peer, modules.MisbehaviorInvalidBlock, err)
+				}
+				return err
+			}
+			next++
+		}
+		if workersDone == nil && next < len(batches) {
+			return errBodyDownloadIncomplete
+		}
+		dispatch()
+	}
+	return nil
+}
+
+// managedHeaderFirstSync downloads the header chain from the first outbound
+// peer that has a chain heavier than the current one, and then downloads the
+// corresponding block bodies in parallel from all outbound peers. Peers that
+// do not support header-first sync are skipped; any blocks that are not
+// fetched here are picked up by the SendBlocks RPC.
+func (cs *ConsensusSet) managedHeaderFirstSync() error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	var outbound []modules.Peer
+	for _, p := range cs.gateway.Peers() {
+		if !p.Inbound {
+			outbound = append(outbound, p)
+		}
+	}
+
+	var hc *headerChain
+	for _, p := range outbound {
+		var chain *headerChain
+		err := cs.gateway.RPC(p.NetAddress, "SendHeaders", func(conn modules.PeerConn) error {
+			var err error
+			chain, err = cs.managedReceiveHeaders(conn)
+			return err
+		})
+		if err != nil {
+			cs.log.Debugf("WARN: failed to download headers from %v: %v", p.NetAddress, err)
+			continue
+		}
+		if chain == nil {
+			continue
+		}
+		// Only download the bodies if the header chain would become the
+		// heaviest chain.
+		cs.mu.RLock()
+		var current *processedBlock
+		_ = cs.db.View(func(tx *bolt.Tx) error {
+			current = currentProcessedBlock(tx)
+			return nil
+		})
+		cs.mu.RUnlock()
+		tip := processedBlock{Depth: chain.tip.Depth}
+		if !tip.heavierThan(current) {
+			continue
+		}
+		hc = chain
+		break
+	}
+	if hc == nil {
+		return nil
+	}
+	cs.log.Printf("INFO: downloaded %v headers up to height %v, fetching block bodies from %v peers", len(hc.ids), hc.tip.Height, len(outbound))
+
+	cs.managedSetCheckpointedBlocks(hc)
+	defer func() {
+		cs.mu.Lock()
+		cs.checkpointedBlocks = nil
+		cs.mu.Unlock()
+	}()
+	return cs.managedDownloadBlockBodies(hc, outbound)
+}
@@ -0,0 +1,232 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// TestChildHeaderNode checks that the difficulty state computed along a
+// header chain matches the state that the consensus set computes for the
+// corresponding processed blocks.
+func TestChildHeaderNode(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	err = cst.cs.db.View(func(tx *bolt.Tx) error {
+		hc, err := cst.cs.newHeaderChain(tx, types.GenesisID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for height := types.BlockHeight(1); height <= blockHeight(tx); height++ {
+			id, err := getPath(tx, height)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cst.cs.extendHeaderChain(hc, pb.Block.Header()); err != nil {
+				t.Fatalf("header at height %v was rejected: %v", height, err)
+			}
+			totalTime, totalTarget := cst.cs.getBlockTotals(tx, id)
+			switch {
+			case hc.tip.Height != pb.Height:
+				t.Fatalf("height mismatch at %v: got %v", height, hc.tip.Height)
+			case hc.tip.ChildTarget != pb.ChildTarget:
+				t.Fatalf("child target mismatch at height %v", height)
+			case hc.tip.Depth != pb.Depth:
+				t.Fatalf("depth mismatch at height %v", height)
+			case hc.tip.TotalTime != totalTime || hc.tip.TotalTarget != totalTarget:
+				t.Fatalf("block totals mismatch at height %v", height)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestExtendHeaderChain probes the validation performed by
+// extendHeaderChain.
+func TestExtendHeaderChain(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := blankConsensusSetTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	b, err := cst.miner.FindBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newChain := func() *headerChain {
+		var hc *headerChain
+		err := cst.cs.db.View(func(tx *bolt.Tx) error {
+			var err error
+			hc, err = cst.cs.newHeaderChain(tx, types.GenesisID)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hc
+	}
+
+	// A header that does not build on the tip should be rejected.
+	h := b.Header()
+	h.ParentID = types.BlockID{1}
+	if err := cst.cs.extendHeaderChain(newChain(), h); err != errNonLinearChain {
+		t.Fatal("expected errNonLinearChain, got", err)
+	}
+
+	// A header that does not meet the target should be rejected.
+	unsolved := b
+	for checkTarget(unsolved, unsolved.ID(), types.RootTarget) {
+		unsolved.Nonce[0]++
+	}
+	if err := cst.cs.extendHeaderChain(newChain(), unsolved.Header()); err != modules.ErrBlockUnsolved {
+		t.Fatal("expected ErrBlockUnsolved, got", err)
+	}
+
+	// A header that conflicts with a checkpoint should be rejected.
+	cst.cs.checkpoints = map[types.BlockHeight]types.BlockID{1: {1}}
+	if err := cst.cs.extendHeaderChain(newChain(), b.Header()); err != errCheckpointMismatch {
+		t.Fatal("expected errCheckpointMismatch, got", err)
+	}
+
+	// A valid header that matches the checkpoint should be accepted.
+	cst.cs.checkpoints = map[types.BlockHeight]types.BlockID{1: b.ID()}
+	hc := newChain()
+	if err := cst.cs.extendHeaderChain(hc, b.Header()); err != nil {
+		t.Fatal(err)
+	}
+	if len(hc.ids) != 1 || hc.ids[0] != b.ID() || hc.tip.Height != 1 {
+		t.Fatal("header chain was not extended correctly")
+	}
+}
+
+// TestBuildCheckpoints checks that conflicting checkpoints are rejected.
+func TestBuildCheckpoints(t *testing.T) {
+	cps, err := buildCheckpoints([]modules.Checkpoint{{Height: 5, ID: types.BlockID{1}}, {Height: 5, ID: types.BlockID{1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cps[5] != (types.BlockID{1}) {
+		t.Fatal("checkpoint was not added")
+	}
+	_, err = buildCheckpoints([]modules.Checkpoint{{Height: 5, ID: types.BlockID{1}}, {Height: 5, ID: types.BlockID{2}}})
+	if err != errConflictingCheckpoint {
+		t.Fatal("expected errConflictingCheckpoint, got", err)
+	}
+	_, err = buildCheckpoints([]modules.Checkpoint{{Height: 0, ID: types.BlockID{1}}})
+	if err != errGenesisCheckpoint {
+		t.Fatal("expected errGenesisCheckpoint, got", err)
+	}
+}
+
+// TestIntegrationHeaderFirstSync checks that a consensus set can catch up to a
+// peer using header-first sync, and that only the blocks committed to by a
+// checkpoint skip signature verification.
+func TestIntegrationHeaderFirstSync(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	local, err := blankConsensusSetTester(t.Name()+"-local", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+	remote, err := blankConsensusSetTester(t.Name()+"-remote", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	err = local.gateway.Connect(remote.gateway.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Give the OnConnect RPCs time to finish.
+	time.Sleep(500 * time.Millisecond)
+
+	// Extend the remote chain by more than a batch of headers without
+	// broadcasting the blocks.
+	numBlocks := int(MaxCatchUpHeaders)*2 + 5
+	for i := 0; i < numBlocks; i++ {
+		b, err := remote.miner.FindBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := remote.cs.managedAcceptBlocks([]types.Block{b}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if local.cs.dbBlockHeight() != 0 {
+		t.Fatal("local consensus set received blocks before the sync")
+	}
+
+	// Fetch the header chain directly to check the checkpointed blocks.
+	cpHeight := MaxCatchUpHeaders + 1
+	cpID, err := remote.cs.dbGetPath(cpHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local.cs.checkpoints = map[types.BlockHeight]types.BlockID{cpHeight: cpID}
+	var hc *headerChain
+	err = local.gateway.RPC(remote.gateway.Address(), "SendHeaders", func(conn modules.PeerConn) error {
+		var err error
+		hc, err = local.cs.managedReceiveHeaders(conn)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hc == nil {
+		t.Fatal("no headers were received")
+	}
+	if hc.tip.Height != remote.cs.dbBlockHeight() {
+		t.Fatalf("header chain reaches height %v, expected %v", hc.tip.Height, remote.cs.dbBlockHeight())
+	}
+	local.cs.managedSetCheckpointedBlocks(hc)
+	if len(local.cs.checkpointedBlocks) != int(cpHeight) {
+		t.Fatalf("expected %v checkpointed blocks, got %v", cpHeight, len(local.cs.checkpointedBlocks))
+	}
+	if _, exists := local.cs.checkpointedBlocks[cpID]; !exists {
+		t.Fatal("checkpoint block is not checkpointed")
+	}
+	local.cs.checkpointedBlocks = nil
+
+	// Perform the full header-first sync.
+	if err := local.cs.managedHeaderFirstSync(); err != nil {
+		t.Fatal(err)
+	}
+	if local.cs.dbCurrentBlockID() != remote.cs.dbCurrentBlockID() {
+		t.Fatal("header-first sync did not catch up to the remote peer")
+	}
+	if local.cs.checkpointedBlocks != nil {
+		t.Fatal("checkpointed blocks were not cleared after the sync")
+	}
+
+	// Syncing again should be a no-op.
+	if err := local.cs.managedHeaderFirstSync(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -47,6 +47,14 @@ func (cs *ConsensusSet) loadDB() error {
 			return err
 		}
 
+		// Remember that the database is pruned, so that it is still known
+		// to be pruned if it is opened without pruning.
+		if cs.pruneDepth > 0 {
+			if err := putPruningEnabled(tx); err != nil {
+				return err
+			}
+		}
+
 		// Check that the genesis block is correct - typically only incorrect
 		// in the event of developer binaries vs. release binaires.
 		genesisID, err := getPath(tx, 0)
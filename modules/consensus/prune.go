@@ -0,0 +1,153 @@
+package consensus
+
+// prune.go implements pruned consensus sets. A pruned consensus set only keeps
+// the processed blocks of the most recent blocks in the current path, which
+// contain the diffs that are needed to revert them. The processed blocks of
+// older blocks are removed from the block map, and only their headers are
+// kept in the BlockHeaders bucket.
+//
+// A pruned consensus set cannot process reorgs that branch off below the kept
+// blocks, cannot serve old blocks to peers, and cannot provide consensus
+// changes that contain pruned blocks to subscribers. A database that was
+// created from a snapshot does not store the bodies of the blocks below the
+// snapshot either, but it is not pruned: its first consensus change contains
+// the whole consensus state, so subscribers can still rescan from the
+// beginning.
+
+import (
+	"fmt"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	errPruneDepth = fmt.Errorf("a pruned consensus set must keep at least %v blocks", snapshotBlocks)
+)
+
+// NewPruned returns a new ConsensusSet that enforces the provided checkpoints
+// and only keeps the bodies of the most recent keepBlocks blocks. Older blocks
+// are pruned as new blocks arrive.
+func NewPruned(gateway modules.Gateway, bootstrap bool, persistDir string, checkpoints []modules.Checkpoint, keepBlocks types.BlockHeight) (*ConsensusSet, error) {
+	if keepBlocks < snapshotBlocks {
+		return nil, errPruneDepth
+	}
+	return newConsensusSet(gateway, bootstrap, persistDir, checkpoints, keepBlocks, modules.ProdDependencies)
+}
+
+// getPrunedHeight returns the height of the highest pruned block. Zero is
+// returned if no block has been pruned.
+func getPrunedHeight(tx *bolt.Tx) (height types.BlockHeight, err error) {
+	b := tx.Bucket(Pruning)
+	if b == nil || b.Get(FieldPrunedHeight) == nil {
+		return 0, nil
+	}
+	err = encoding.Unmarshal(b.Get(FieldPrunedHeight), &height)
+	return height, err
+}
+
+// isPruningEnabled returns true if the database has been opened by a pruned
+// consensus set.
+func isPruningEnabled(tx *bolt.Tx) bool {
+	b := tx.Bucket(Pruning)
+	return b != nil && b.Get(FieldPruningEnabled) != nil
+}
+
+// putPruningEnabled marks the database as pruned.
+func putPruningEnabled(tx *bolt.Tx) error {
+	b, err := tx.CreateBucketIfNotExists(Pruning)
+	if err != nil {
+		return err
+	}
+	return b.Put(FieldPruningEnabled, encoding.Marshal(true))
+}
+
+// putPrunedHeight sets the height of the highest pruned block.
+func putPrunedHeight(tx *bolt.Tx, height types.BlockHeight) error {
+	b, err := tx.CreateBucketIfNotExists(Pruning)
+	if err != nil {
+		return err
+	}
+	return b.Put(FieldPrunedHeight, encoding.Marshal(height))
+}
+
+// pruneBlocks removes the processed blocks of the current path that are deeper
+// than pruneDepth from the block map, keeping only their headers. Nothing is
+// pruned until the oldest kept block is past the oak hardfork, because the
+// difficulty adjustment before the hardfork requires the ancestors of a block.
+func (cs *ConsensusSet) pruneBlocks(tx *bolt.Tx) error {
+	height := blockHeight(tx)
+	if cs.pruneDepth == 0 || height < cs.pruneDepth || height-cs.pruneDepth+1 < types.OakHardforkBlock {
+		return nil
+	}
+	prunedHeight, err := getPrunedHeight(tx)
+	if err != nil {
+		return err
+	}
+	pruneHeight := height - cs.pruneDepth
+	if pruneHeight <= prunedHeight {
+		return nil
+	}
+
+	headers, err := tx.CreateBucketIfNotExists(BlockHeaders)
+	if err != nil {
+		return err
+	}
+	blockMap := tx.Bucket(BlockMap)
+	// The genesis block is never pruned.
+	for h := prunedHeight + 1; h <= pruneHeight; h++ {
+		id, err := getPath(tx, h)
+		if err != nil {
+			return err
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := headers.Put(id[:], encoding.Marshal(pb.Block.Header())); err != nil {
+			return err
+		}
+		if err := blockMap.Delete(id[:]); err != nil {
+			return err
+		}
+	}
+	return putPrunedHeight(tx, pruneHeight)
+}
+
+// BlockPruned returns true if the block with the given id is in the current
+// path, but its body has been pruned from the consensus set.
+func (cs *ConsensusSet) BlockPruned(id types.BlockID) (pruned bool) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return false
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		pruned = isPrunedBlock(tx, id)
+		return nil
+	})
+	return pruned
+}
+
+// Pruned returns true if the consensus set prunes the bodies of old blocks,
+// or has pruned them before. The consensus set is pruned as soon as it is
+// created with NewPruned, even if no block has been pruned yet.
+func (cs *ConsensusSet) Pruned() (pruned bool) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return false
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		pruned = cs.pruneDepth > 0 || isPruningEnabled(tx)
+		return nil
+	})
+	return pruned
+}
@@ -0,0 +1,155 @@
+package consensus
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// TestPrunedConsensusSet checks that a pruned consensus set removes the bodies
+// of old blocks, keeps the recent ones, reports pruned blocks, and refuses
+// reorgs that branch off from a pruned block.
+func TestPrunedConsensusSet(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cst.cs.mu.Lock()
+	cst.cs.pruneDepth = snapshotBlocks
+	cst.cs.mu.Unlock()
+	if !cst.cs.Pruned() {
+		t.Fatal("consensus set is not pruned before the first block was pruned")
+	}
+
+	// Create a stale block that will be pruned together with its parent.
+	child0, _ := cst.miner.FindBlock()
+	child1, _ := cst.miner.FindBlock()
+	if err := cst.cs.AcceptBlock(child0); err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.cs.AcceptBlock(child1); err != modules.ErrNonExtendingBlock {
+		t.Fatal(err)
+	}
+	for cst.cs.Height() < types.OakHardforkBlock+2*snapshotBlocks {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Blocks deeper than the prune depth are only known by their header.
+	height := cst.cs.Height()
+	oldID := child0.ParentID
+	if _, exists := cst.cs.BlockAtHeight(1); exists {
+		t.Error("old block was not pruned")
+	}
+	if _, _, exists := cst.cs.BlockByID(oldID); exists {
+		t.Error("old block was not pruned")
+	}
+	if !cst.cs.BlockPruned(oldID) || !cst.cs.InCurrentPath(oldID) {
+		t.Error("pruned block is not reported as pruned block of the current path")
+	}
+	if _, exists := cst.cs.BlockAtHeight(0); !exists {
+		t.Error("genesis block was pruned")
+	}
+	for h := height - snapshotBlocks + 1; h <= height; h++ {
+		b, exists := cst.cs.BlockAtHeight(h)
+		if !exists {
+			t.Fatal("recent block was pruned at height", h)
+		}
+		if cst.cs.BlockPruned(b.ID()) {
+			t.Fatal("recent block is reported as pruned")
+		}
+	}
+	if _, exists := cst.cs.BlockAtHeight(height - snapshotBlocks); exists {
+		t.Error("block below the prune depth was not pruned")
+	}
+
+	// The stale block is kept, but a fork from it cannot be applied because
+	// its parent has been pruned.
+	err = cst.cs.db.Update(func(tx *bolt.Tx) error {
+		pb, err := getBlockMap(tx, child1.ID())
+		if err != nil {
+			return err
+		}
+		if backtrackToCurrentPath(tx, pb) != nil {
+			t.Error("backtracking past a pruned block should fail")
+		}
+		if _, _, err := cst.cs.forkBlockchain(tx, pb); err != errPrunedFork {
+			t.Error("expected errPrunedFork, got", err)
+		}
+		cst.cs.checkConsistency(tx)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Subscribing from the beginning requires the pruned blocks.
+	ms := newMockSubscriber()
+	err = cst.cs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan())
+	if err != modules.ErrBlockPruned {
+		t.Fatal("expected ErrBlockPruned, got", err)
+	}
+
+	// New blocks are still accepted, and pruning keeps up with them.
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := cst.cs.BlockAtHeight(height - snapshotBlocks + 1); exists {
+		t.Error("block was not pruned after a new block arrived")
+	}
+}
+
+// TestNewPrunedDepth checks that a pruned consensus set has to keep at least
+// as many blocks as a snapshot.
+func TestNewPrunedDepth(t *testing.T) {
+	_, err := NewPruned(nil, false, "", nil, snapshotBlocks-1)
+	if err != errPruneDepth {
+		t.Fatal("expected errPruneDepth, got", err)
+	}
+}
+
+// TestPrunedPersist checks that a consensus set is pruned as soon as it is
+// created with NewPruned, and stays pruned when it is opened without pruning.
+func TestPrunedPersist(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	testdir := build.TempDir(modules.ConsensusDir, t.Name())
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	csDir := filepath.Join(testdir, modules.ConsensusDir)
+	cs, err := NewPruned(g, false, csDir, nil, snapshotBlocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cs.Pruned() {
+		t.Fatal("new pruned consensus set is not pruned")
+	}
+	if err := cs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	cs, err = New(g, false, csDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	if !cs.Pruned() {
+		t.Fatal("pruned consensus set is not pruned after reopening it without pruning")
+	}
+}
@@ -0,0 +1,582 @@
+package consensus
+
+// snapshot.go implements consensus snapshots. A snapshot contains the headers
+// of every block in the current path, the processed blocks of the most recent
+// snapshotBlocks blocks, and the buckets that make up the consensus state at
+// the height of the snapshot. A new node can import a snapshot instead of
+// replaying the whole blockchain.
+//
+// The header chain of a snapshot is fully verified during import: the
+// proof-of-work, difficulty, timestamps and checkpoints of every header are
+// checked, and the final header must have the block id supplied by the user.
+// The consensus state itself cannot be derived from the headers, so it is
+// trusted to be correct for the block id. The consensus checksum only protects
+// against corruption.
+//
+// A node that was created from a snapshot only stores the block bodies of the
+// retained blocks, and cannot process reorgs that are deeper than the retained
+// blocks. Subscribers receive the state at the snapshot height as a single
+// synthetic consensus change.
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// ErrConsensusDBExists is returned when importing a snapshot into a
+	// directory that already contains a consensus database.
+	ErrConsensusDBExists = errors.New("a consensus database already exists")
+
+	errSnapshotBlockMismatch = errors.New("snapshot contains a processed block that does not match its header chain")
+	errSnapshotBucket        = errors.New("snapshot contains a bucket that is not part of the consensus state")
+	errSnapshotChecksum      = errors.New("snapshot consensus checksum does not match the imported state")
+	errSnapshotHeight        = errors.New("snapshot height is either above the current height or too low to retain only blocks past the oak hardfork")
+	errSnapshotIDMismatch    = errors.New("snapshot does not end at the expected block")
+	errSnapshotMetadata      = errors.New("file is not a consensus snapshot or has an unsupported version")
+	errSnapshotNoDatabase    = errors.New("no consensus database exists to export a snapshot from")
+	errSnapshotRollback      = errors.New("snapshot export finished")
+)
+
+var (
+	snapshotMetadata = persist.Metadata{
+		Header:  "Consensus Set Snapshot",
+		Version: "1.0",
+	}
+
+	// snapshotBlocks is the number of most recent blocks whose processed
+	// blocks are included in a snapshot. The retained blocks allow the
+	// importing node to process reorgs, to compute the minimum timestamp of
+	// the next block, and to serve recent blocks to peers.
+	snapshotBlocks = build.Select(build.Var{
+		Standard: types.BlockHeight(144),
+		Dev:      types.BlockHeight(20),
+		Testing:  types.BlockHeight(12),
+	}).(types.BlockHeight)
+)
+
+type (
+	// snapshotHeader is written at the start of a snapshot, after the
+	// metadata.
+	snapshotHeader struct {
+		Height            types.BlockHeight
+		ID                types.BlockID
+		ConsensusChecksum crypto.Hash
+	}
+
+	// snapshotEntry is a key/value pair of a bucket in a snapshot. The
+	// entries of each bucket are terminated by an entry with an empty key.
+	snapshotEntry struct {
+		Key   []byte
+		Value []byte
+	}
+
+	// snapshotDiffs contains the diffs of the synthetic consensus change
+	// that is presented to subscribers of a database created from a
+	// snapshot.
+	snapshotDiffs struct {
+		SiacoinOutputDiffs        []modules.SiacoinOutputDiff
+		FileContractDiffs         []modules.FileContractDiff
+		SiafundOutputDiffs        []modules.SiafundOutputDiff
+		DelayedSiacoinOutputDiffs []modules.DelayedSiacoinOutputDiff
+		SiafundPoolDiffs          []modules.SiafundPoolDiff
+	}
+)
+
+// isSnapshotBucket returns true if the bucket with the given name is part of
+// the consensus state that is included in a snapshot.
+func isSnapshotBucket(name []byte) bool {
+	for _, bucket := range [][]byte{SiacoinOutputs, FileContracts, SiafundOutputs, SiafundPool} {
+		if bytes.Equal(name, bucket) {
+			return true
+		}
+	}
+	return bytes.HasPrefix(name, prefixDSCO) || bytes.HasPrefix(name, prefixFCEX)
+}
+
+// validSnapshotHeight returns true if a snapshot can be taken at the given
+// height. All retained blocks need to be past the oak hardfork, because the
+// pre-oak difficulty adjustment of their children requires blocks that are not
+// retained.
+func validSnapshotHeight(height types.BlockHeight) bool {
+	return height+1 >= types.OakHardforkBlock+snapshotBlocks && height+1 > snapshotBlocks
+}
+
+// newSnapshotConsensusSet returns a consensus set that can operate on a
+// consensus database without a gateway, for use by snapshot export and import.
+func newSnapshotConsensusSet(persistDir string) (*ConsensusSet, error) {
+	checkpoints, err := buildCheckpoints(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsensusSet{
+		blockRoot:       genesisProcessedBlock(),
+		dosBlocks:       make(map[types.BlockID]struct{}),
+		checkpoints:     checkpoints,
+		marshaler:       stdMarshaler{},
+		blockRuleHelper: stdBlockRuleHelper{},
+		blockValidator:  NewBlockValidator(),
+		staticDeps:      modules.ProdDependencies,
+		persistDir:      persistDir,
+	}, nil
+}
+
+// getSnapshotEntry returns the synthetic change entry of a database that was
+// created from a snapshot, using a bool to indicate existence.
+func getSnapshotEntry(tx *bolt.Tx) (changeEntry, bool) {
+	b := tx.Bucket(Snapshot)
+	if b == nil {
+		return changeEntry{}, false
+	}
+	var id modules.ConsensusChangeID
+	copy(id[:], b.Get(FieldSnapshotEntry))
+	return getEntry(tx, id)
+}
+
+// isSnapshotEntry returns true if ce is the synthetic change entry created by
+// a snapshot import.
+func isSnapshotEntry(tx *bolt.Tx, ce changeEntry) bool {
+	b := tx.Bucket(Snapshot)
+	if b == nil {
+		return false
+	}
+	id := ce.ID()
+	return bytes.Equal(b.Get(FieldSnapshotEntry), id[:])
+}
+
+// computeSnapshotChange computes the consensus change of the synthetic change
+// entry created by a snapshot import. The change applies every block up to the
+// snapshot height, and its diffs create the whole consensus state at that
+// height. Blocks whose bodies are not stored are presented as header-only
+// blocks, with the parent id, nonce and timestamp of their header and without
+// any payouts or transactions. Their real ids are listed in AppliedBlockIDs.
+func (cs *ConsensusSet) computeSnapshotChange(tx *bolt.Tx, ce changeEntry) (modules.ConsensusChange, error) {
+	var diffs snapshotDiffs
+	err := encoding.Unmarshal(tx.Bucket(Snapshot).Get(FieldSnapshotDiffs), &diffs)
+	if err != nil {
+		return modules.ConsensusChange{}, err
+	}
+	cc := modules.ConsensusChange{
+		ID:                        ce.ID(),
+		SiacoinOutputDiffs:        diffs.SiacoinOutputDiffs,
+		FileContractDiffs:         diffs.FileContractDiffs,
+		SiafundOutputDiffs:        diffs.SiafundOutputDiffs,
+		DelayedSiacoinOutputDiffs: diffs.DelayedSiacoinOutputDiffs,
+		SiafundPoolDiffs:          diffs.SiafundPoolDiffs,
+	}
+	cc.AppliedBlockIDs = append(cc.AppliedBlockIDs, ce.AppliedBlocks...)
+	for _, id := range ce.AppliedBlocks {
+		if pb, err := getBlockMap(tx, id); err == nil {
+			cc.AppliedBlocks = append(cc.AppliedBlocks, pb.Block)
+			continue
+		}
+		h, err := getBlockHeader(tx, id)
+		if err != nil {
+			return modules.ConsensusChange{}, err
+		}
+		cc.AppliedBlocks = append(cc.AppliedBlocks, types.Block{
+			ParentID:  h.ParentID,
+			Nonce:     h.Nonce,
+			Timestamp: h.Timestamp,
+		})
+	}
+	cs.completeConsensusChange(tx, ce, &cc)
+	return cc, nil
+}
+
+// ExportSnapshot writes a snapshot of the consensus set at the given height
+// to w, and returns the id of the block at that height. The consensus database
+// in persistDir must not be in use by a running consensus set.
+func ExportSnapshot(persistDir string, height types.BlockHeight, w io.Writer) (types.BlockID, error) {
+	filename := filepath.Join(persistDir, DatabaseFilename)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return types.BlockID{}, errSnapshotNoDatabase
+	} else if err != nil {
+		return types.BlockID{}, err
+	}
+	cs, err := newSnapshotConsensusSet(persistDir)
+	if err != nil {
+		return types.BlockID{}, err
+	}
+	cs.db, err = persist.OpenDatabase(dbMetadata, filename)
+	if err != nil {
+		return types.BlockID{}, errors.New("error opening consensus database: " + err.Error())
+	}
+
+	// The consensus set is reverted to the snapshot height inside of a
+	// database transaction that is always rolled back.
+	var id types.BlockID
+	err = cs.db.Update(func(tx *bolt.Tx) error {
+		if height > blockHeight(tx) || !validSnapshotHeight(height) {
+			return errSnapshotHeight
+		}
+		// Every block that gets reverted or retained needs to be stored.
+		for h := height - snapshotBlocks + 1; h <= blockHeight(tx); h++ {
+			pathID, err := getPath(tx, h)
+			if err != nil {
+				return err
+			}
+			if _, err := getBlockMap(tx, pathID); err != nil {
+				return modules.ErrBlockPruned
+			}
+		}
+		id, _ = getPath(tx, height)
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return err
+		}
+		cs.revertToBlock(tx, pb)
+		if err := writeSnapshot(tx, w); err != nil {
+			return err
+		}
+		return errSnapshotRollback
+	})
+	if err == errSnapshotRollback {
+		err = nil
+	}
+	if closeErr := cs.db.Close(); err == nil {
+		err = closeErr
+	}
+	return id, err
+}
+
+// writeSnapshot writes a snapshot of the current consensus state to w.
+func writeSnapshot(tx *bolt.Tx, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := encoding.NewEncoder(bw)
+	height := blockHeight(tx)
+	err := enc.EncodeAll(snapshotMetadata, snapshotHeader{
+		Height:            height,
+		ID:                currentBlockID(tx),
+		ConsensusChecksum: consensusChecksum(tx),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write the header of every block after the genesis block, followed by
+	// the retained processed blocks.
+	for h := types.BlockHeight(1); h <= height; h++ {
+		id, err := getPath(tx, h)
+		if err != nil {
+			return err
+		}
+		header, err := getBlockHeader(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(header); err != nil {
+			return err
+		}
+	}
+	for h := height - snapshotBlocks + 1; h <= height; h++ {
+		id, err := getPath(tx, h)
+		if err != nil {
+			return err
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(*pb); err != nil {
+			return err
+		}
+	}
+
+	// Write the consensus state buckets. The list of buckets is terminated by
+	// an empty bucket name.
+	err = tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if !isSnapshotBucket(name) {
+			return nil
+		}
+		if err := enc.Encode(name); err != nil {
+			return err
+		}
+		err := b.ForEach(func(k, v []byte) error {
+			return enc.Encode(snapshotEntry{Key: k, Value: v})
+		})
+		if err != nil {
+			return err
+		}
+		return enc.Encode(snapshotEntry{})
+	})
+	if err != nil {
+		return err
+	}
+	if err := enc.Encode([]byte{}); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ImportSnapshot creates a new consensus database in persistDir from the
+// snapshot read from r. The snapshot must end at the block with the given id.
+// ErrConsensusDBExists is returned if persistDir already contains a consensus
+// database.
+func ImportSnapshot(persistDir string, id types.BlockID, r io.Reader) error {
+	filename := filepath.Join(persistDir, DatabaseFilename)
+	if _, err := os.Stat(filename); err == nil {
+		return ErrConsensusDBExists
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	err := os.MkdirAll(persistDir, 0700)
+	if err != nil {
+		return err
+	}
+	cs, err := newSnapshotConsensusSet(persistDir)
+	if err != nil {
+		return err
+	}
+
+	// The database is built under a temporary name so that a failed import
+	// does not leave a partial database behind.
+	tmpFilename := filename + "_snapshot"
+	if err := os.RemoveAll(tmpFilename); err != nil {
+		return err
+	}
+	cs.db, err = persist.OpenDatabase(dbMetadata, tmpFilename)
+	if err != nil {
+		return errors.New("error opening consensus database: " + err.Error())
+	}
+	err = cs.db.Update(func(tx *bolt.Tx) error {
+		return cs.importSnapshot(tx, id, r)
+	})
+	if closeErr := cs.db.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.RemoveAll(tmpFilename)
+		return err
+	}
+	return os.Rename(tmpFilename, filename)
+}
+
+// importSnapshot reads a snapshot from r and fills out an empty consensus
+// database with it.
+func (cs *ConsensusSet) importSnapshot(tx *bolt.Tx, id types.BlockID, r io.Reader) error {
+	dec := encoding.NewDecoder(bufio.NewReader(r))
+	var md persist.Metadata
+	var sh snapshotHeader
+	if err := dec.DecodeAll(&md, &sh); err != nil {
+		return err
+	}
+	if md != snapshotMetadata {
+		return errSnapshotMetadata
+	}
+	if sh.ID != id {
+		return errSnapshotIDMismatch
+	}
+	if !validSnapshotHeight(sh.Height) {
+		return errSnapshotHeight
+	}
+
+	// Create the buckets and add the genesis block.
+	buckets := [][]byte{
+		BlockHeight,
+		BlockMap,
+		BlockPath,
+		BlockHeaders,
+		BucketOak,
+		Consistency,
+		Pruning,
+		SiacoinOutputs,
+		FileContracts,
+		SiafundOutputs,
+		SiafundPool,
+		Snapshot,
+	}
+	for _, bucket := range buckets {
+		if _, err := tx.CreateBucket(bucket); err != nil {
+			return err
+		}
+	}
+	underflow := types.BlockHeight(0)
+	if err := tx.Bucket(BlockHeight).Put(BlockHeight, encoding.Marshal(underflow-1)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(Consistency).Put(Consistency, encoding.Marshal(false)); err != nil {
+		return err
+	}
+	pushPath(tx, cs.blockRoot.Block.ID())
+	addBlockMap(tx, &cs.blockRoot)
+	_, _, err := cs.storeBlockTotals(tx, 0, types.GenesisID, 0, types.GenesisTimestamp, types.GenesisTimestamp, types.RootDepth, types.RootTarget)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(BucketOak).Put(FieldOakInit, ValueOakInit); err != nil {
+		return err
+	}
+
+	// Verify the header chain and add it to the block path. Only the headers
+	// of blocks that are not retained are stored separately.
+	hc, err := cs.newHeaderChain(tx, types.GenesisID)
+	if err != nil {
+		return err
+	}
+	firstRetained := sh.Height - snapshotBlocks + 1
+	retained := make(map[types.BlockHeight]headerNode)
+	for height := types.BlockHeight(1); height <= sh.Height; height++ {
+		var h types.BlockHeader
+		if err := dec.Decode(&h); err != nil {
+			return err
+		}
+		if err := cs.extendHeaderChain(hc, h); err != nil {
+			return err
+		}
+		hid := hc.ids[len(hc.ids)-1]
+		pushPath(tx, hid)
+		if err := putBlockTotals(tx, hid, hc.tip.TotalTime, hc.tip.TotalTarget); err != nil {
+			return err
+		}
+		if height < firstRetained {
+			if err := tx.Bucket(BlockHeaders).Put(hid[:], encoding.Marshal(h)); err != nil {
+				return err
+			}
+		} else {
+			retained[height] = hc.tip
+		}
+	}
+	if hc.ids[len(hc.ids)-1] != id {
+		return errSnapshotIDMismatch
+	}
+	if err := putPrunedHeight(tx, firstRetained-1); err != nil {
+		return err
+	}
+
+	// Add the retained processed blocks after checking them against the
+	// header chain.
+	for height := firstRetained; height <= sh.Height; height++ {
+		var pb processedBlock
+		if err := dec.Decode(&pb); err != nil {
+			return err
+		}
+		node := retained[height]
+		if pb.Block.ID() != hc.ids[height-1] || pb.Height != height || pb.ChildTarget != node.ChildTarget || pb.Depth != node.Depth || !pb.DiffsGenerated {
+			return errSnapshotBlockMismatch
+		}
+		addBlockMap(tx, &pb)
+	}
+
+	// Add the consensus state buckets.
+	for {
+		var name []byte
+		if err := dec.Decode(&name); err != nil {
+			return err
+		}
+		if len(name) == 0 {
+			break
+		}
+		if !isSnapshotBucket(name) {
+			return errSnapshotBucket
+		}
+		b, err := tx.CreateBucketIfNotExists(name)
+		if err != nil {
+			return err
+		}
+		for {
+			var entry snapshotEntry
+			if err := dec.Decode(&entry); err != nil {
+				return err
+			}
+			if len(entry.Key) == 0 {
+				break
+			}
+			if err := b.Put(entry.Key, entry.Value); err != nil {
+				return err
+			}
+		}
+	}
+	if consensusChecksum(tx) != sh.ConsensusChecksum {
+		return errSnapshotChecksum
+	}
+
+	// Start the change log with a synthetic entry that applies every block in
+	// the path.
+	diffs, err := stateDiffs(tx)
+	if err != nil {
+		return err
+	}
+	ce := changeEntry{AppliedBlocks: append([]types.BlockID{types.GenesisID}, hc.ids...)}
+	ceid := ce.ID()
+	cl, err := tx.CreateBucket(ChangeLog)
+	if err != nil {
+		return err
+	}
+	if err := cl.Put(ceid[:], encoding.Marshal(changeNode{Entry: ce})); err != nil {
+		return err
+	}
+	if err := cl.Put(ChangeLogTailID, ceid[:]); err != nil {
+		return err
+	}
+	if err := tx.Bucket(Snapshot).Put(FieldSnapshotEntry, ceid[:]); err != nil {
+		return err
+	}
+	return tx.Bucket(Snapshot).Put(FieldSnapshotDiffs, encoding.Marshal(diffs))
+}
+
+// stateDiffs returns the diffs that create the current consensus state from an
+// empty consensus set.
+func stateDiffs(tx *bolt.Tx) (diffs snapshotDiffs, err error) {
+	err = tx.Bucket(SiacoinOutputs).ForEach(func(k, v []byte) error {
+		scod := modules.SiacoinOutputDiff{Direction: modules.DiffApply}
+		copy(scod.ID[:], k)
+		diffs.SiacoinOutputDiffs = append(diffs.SiacoinOutputDiffs, scod)
+		return encoding.Unmarshal(v, &diffs.SiacoinOutputDiffs[len(diffs.SiacoinOutputDiffs)-1].SiacoinOutput)
+	})
+	if err != nil {
+		return snapshotDiffs{}, err
+	}
+	err = tx.Bucket(FileContracts).ForEach(func(k, v []byte) error {
+		fcd := modules.FileContractDiff{Direction: modules.DiffApply}
+		copy(fcd.ID[:], k)
+		diffs.FileContractDiffs = append(diffs.FileContractDiffs, fcd)
+		return encoding.Unmarshal(v, &diffs.FileContractDiffs[len(diffs.FileContractDiffs)-1].FileContract)
+	})
+	if err != nil {
+		return snapshotDiffs{}, err
+	}
+	err = tx.Bucket(SiafundOutputs).ForEach(func(k, v []byte) error {
+		sfod := modules.SiafundOutputDiff{Direction: modules.DiffApply}
+		copy(sfod.ID[:], k)
+		diffs.SiafundOutputDiffs = append(diffs.SiafundOutputDiffs, sfod)
+		return encoding.Unmarshal(v, &diffs.SiafundOutputDiffs[len(diffs.SiafundOutputDiffs)-1].SiafundOutput)
+	})
+	if err != nil {
+		return snapshotDiffs{}, err
+	}
+	err = tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if !bytes.HasPrefix(name, prefixDSCO) {
+			return nil
+		}
+		maturityHeight := types.BlockHeight(encoding.DecUint64(name[len(prefixDSCO):]))
+		return b.ForEach(func(k, v []byte) error {
+			dscod := modules.DelayedSiacoinOutputDiff{Direction: modules.DiffApply, MaturityHeight: maturityHeight}
+			copy(dscod.ID[:], k)
+			diffs.DelayedSiacoinOutputDiffs = append(diffs.DelayedSiacoinOutputDiffs, dscod)
+			return encoding.Unmarshal(v, &diffs.DelayedSiacoinOutputDiffs[len(diffs.DelayedSiacoinOutputDiffs)-1].SiacoinOutput)
+		})
+	})
+	if err != nil {
+		return snapshotDiffs{}, err
+	}
+	diffs.SiafundPoolDiffs = []modules.SiafundPoolDiff{{
+		Direction: modules.DiffApply,
+		Previous:  types.ZeroCurrency,
+		Adjusted:  getSiafundPool(tx),
+	}}
+	return diffs, nil
+}
@@ -0,0 +1,247 @@
+package consensus
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/modules/miner"
+	"github.com/NebulousLabs/Sia/modules/transactionpool"
+	"github.com/NebulousLabs/Sia/modules/wallet"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// TestSnapshotExportImport exports a snapshot of a consensus set below its
+// current height, imports it into a new consensus set and checks that the new
+// consensus set is consistent and can continue from the snapshot.
+func TestSnapshotExportImport(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for !validSnapshotHeight(cst.cs.Height() - 3) {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	height := cst.cs.Height() - 3
+	var laterBlocks []types.Block
+	for h := height + 1; h <= cst.cs.Height(); h++ {
+		b, _ := cst.cs.BlockAtHeight(h)
+		laterBlocks = append(laterBlocks, b)
+	}
+	tipID := cst.cs.CurrentBlock().ID()
+	if err := cst.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Export the snapshot.
+	var snapshot bytes.Buffer
+	id, err := ExportSnapshot(filepath.Join(cst.persistDir, modules.ConsensusDir), height, &snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != laterBlocks[0].ParentID {
+		t.Fatal("snapshot was exported at the wrong block")
+	}
+
+	// Importing with the wrong id or a truncated snapshot should fail
+	// without leaving a database behind.
+	testdir := build.TempDir(modules.ConsensusDir, t.Name()+"-import")
+	csDir := filepath.Join(testdir, modules.ConsensusDir)
+	if err := ImportSnapshot(csDir, tipID, bytes.NewReader(snapshot.Bytes())); err != errSnapshotIDMismatch {
+		t.Fatal("expected errSnapshotIDMismatch, got", err)
+	}
+	if err := ImportSnapshot(csDir, id, bytes.NewReader(snapshot.Bytes()[:snapshot.Len()-100])); err == nil {
+		t.Fatal("truncated snapshot was imported")
+	}
+	if _, err := os.Stat(filepath.Join(csDir, DatabaseFilename)); !os.IsNotExist(err) {
+		t.Fatal("failed import left a database behind")
+	}
+
+	// Import the snapshot.
+	if err := ImportSnapshot(csDir, id, bytes.NewReader(snapshot.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportSnapshot(csDir, id, bytes.NewReader(snapshot.Bytes())); err != ErrConsensusDBExists {
+		t.Fatal("expected ErrConsensusDBExists, got", err)
+	}
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := New(g, false, csDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	if cs.Height() != height || cs.CurrentBlock().ID() != id {
+		t.Fatal("imported consensus set is not at the snapshot block")
+	}
+	if cs.Pruned() {
+		t.Fatal("imported consensus set should not be pruned")
+	}
+	err = cs.db.Update(func(tx *bolt.Tx) error {
+		cs.checkConsistency(tx)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Subscribers should receive a single change covering every block.
+	ms := newMockSubscriber()
+	err = cs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning, cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms.updates) != 1 {
+		t.Fatalf("expected 1 consensus change, got %v", len(ms.updates))
+	}
+	cc := ms.updates[0]
+	if types.BlockHeight(len(cc.AppliedBlocks)) != height+1 || cc.AppliedBlocks[len(cc.AppliedBlocks)-1].ID() != id {
+		t.Fatal("snapshot consensus change has the wrong applied blocks")
+	}
+	if len(cc.SiacoinOutputDiffs) == 0 || len(cc.SiafundOutputDiffs) == 0 || len(cc.DelayedSiacoinOutputDiffs) == 0 {
+		t.Fatal("snapshot consensus change is missing diffs")
+	}
+
+	// Blocks that were pruned by the snapshot are not available.
+	if _, exists := cs.BlockAtHeight(1); exists {
+		t.Fatal("block below the snapshot should not be available")
+	}
+
+	// The imported consensus set should accept the blocks that followed the
+	// snapshot.
+	for _, b := range laterBlocks {
+		if err := cs.AcceptBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cs.CurrentBlock().ID() != tipID {
+		t.Fatal("imported consensus set did not reach the original tip")
+	}
+	if len(ms.updates) != 1+len(laterBlocks) {
+		t.Fatal("subscriber did not receive the later blocks")
+	}
+}
+
+// TestSnapshotSubscribers checks that the applied blocks of the synthetic
+// consensus change of an imported snapshot form a chain, and that a
+// transaction pool and a wallet can subscribe to the imported consensus set.
+func TestSnapshotSubscribers(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for !validSnapshotHeight(cst.cs.Height()) {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	height := cst.cs.Height()
+	if err := cst.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var snapshot bytes.Buffer
+	id, err := ExportSnapshot(filepath.Join(cst.persistDir, modules.ConsensusDir), height, &snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testdir := build.TempDir(modules.ConsensusDir, t.Name()+"-import")
+	csDir := filepath.Join(testdir, modules.ConsensusDir)
+	if err := ImportSnapshot(csDir, id, bytes.NewReader(snapshot.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := New(g, false, csDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	// The applied blocks should be linked by their ids, and the blocks below
+	// the retained blocks should be header-only.
+	ms := newMockSubscriber()
+	err = cs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning, cs.tg.StopChan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc := ms.updates[0]
+	if len(cc.AppliedBlockIDs) != len(cc.AppliedBlocks) || cc.AppliedBlockIDs[0] != types.GenesisID || cc.AppliedBlockIDs[len(cc.AppliedBlockIDs)-1] != id {
+		t.Fatal("snapshot consensus change has the wrong applied block ids")
+	}
+	for i := 1; i < len(cc.AppliedBlocks); i++ {
+		if cc.AppliedBlocks[i].ParentID != cc.AppliedBlockIDs[i-1] {
+			t.Fatal("applied block", i, "is not a child of the previous block")
+		}
+		headerOnly := types.BlockHeight(i) <= height-snapshotBlocks
+		if headerOnly != (cc.AppliedBlocks[i].ID() != cc.AppliedBlockIDs[i]) {
+			t.Fatal("applied block", i, "has the wrong id")
+		}
+		if headerOnly && (len(cc.AppliedBlocks[i].MinerPayouts) != 0 || len(cc.AppliedBlocks[i].Transactions) != 0) {
+			t.Fatal("header-only block", i, "has a body")
+		}
+	}
+
+	// A transaction pool, wallet and miner should be able to subscribe to the
+	// imported consensus set and extend it.
+	tp, err := transactionpool.New(cs, g, filepath.Join(testdir, modules.TransactionPoolDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tp.Close()
+	w, err := wallet.New(cs, tp, filepath.Join(testdir, modules.WalletDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	key := crypto.GenerateTwofishKey()
+	if _, err := w.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Unlock(key); err != nil {
+		t.Fatal(err)
+	}
+
+	// The wallet can rescan the imported consensus set to find the outputs of
+	// a watch-only address. The genesis miner payout is sent to the void
+	// address.
+	if err := w.AddWatchAddresses([]types.UnlockHash{{}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if siacoins, _, err := w.WatchBalance(); err != nil || siacoins.IsZero() {
+		t.Fatal("rescan did not find the outputs of the watch-only address:", siacoins, err)
+	}
+	m, err := miner.New(cs, tp, w, filepath.Join(testdir, modules.MinerDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, err := m.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if cs.Height() != height+1 {
+		t.Fatal("imported consensus set was not extended")
+	}
+}
@@ -14,12 +14,20 @@ import (
 // computeConsensusChange computes the consensus change from the change entry
 // at index 'i' in the change log. If i is out of bounds, an error is returned.
 func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (modules.ConsensusChange, error) {
+	// The change entry created by a snapshot import presents the whole
+	// consensus state at the snapshot height as a single change.
+	if isSnapshotEntry(tx, ce) {
+		return cs.computeSnapshotChange(tx, ce)
+	}
+
 	cc := modules.ConsensusChange{
 		ID: ce.ID(),
 	}
 	for _, revertedBlockID := range ce.RevertedBlocks {
 		revertedBlock, err := getBlockMap(tx, revertedBlockID)
-		if err != nil {
+		if err != nil && isPrunedBlock(tx, revertedBlockID) {
+			return modules.ConsensusChange{}, modules.ErrBlockPruned
+		} else if err != nil {
 			cs.log.Critical("getBlockMap failed in computeConsensusChange:", err)
 			return modules.ConsensusChange{}, err
 		}
@@ -55,12 +63,15 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 	}
 	for _, appliedBlockID := range ce.AppliedBlocks {
 		appliedBlock, err := getBlockMap(tx, appliedBlockID)
-		if err != nil {
+		if err != nil && isPrunedBlock(tx, appliedBlockID) {
+			return modules.ConsensusChange{}, modules.ErrBlockPruned
+		} else if err != nil {
 			cs.log.Critical("getBlockMap failed in computeConsensusChange:", err)
 			return modules.ConsensusChange{}, err
 		}
 
 		cc.AppliedBlocks = append(cc.AppliedBlocks, appliedBlock.Block)
+		cc.AppliedBlockIDs = append(cc.AppliedBlockIDs, appliedBlockID)
 		for _, scod := range appliedBlock.SiacoinOutputDiffs {
 			cc.SiacoinOutputDiffs = append(cc.SiacoinOutputDiffs, scod)
 		}
@@ -78,6 +89,13 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 		}
 	}
 
+	cs.completeConsensusChange(tx, ce, &cc)
+	return cc, nil
+}
+
+// completeConsensusChange fills out the fields of a consensus change that
+// depend on the most recent block applied by the change entry.
+func (cs *ConsensusSet) completeConsensusChange(tx *bolt.Tx, ce changeEntry, cc *modules.ConsensusChange) {
 	// Grab the child target and the minimum valid child timestamp.
 	recentBlock := ce.AppliedBlocks[len(ce.AppliedBlocks)-1]
 	pb, err := getBlockMap(tx, recentBlock)
@@ -85,7 +103,7 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 		cs.log.Critical("could not find process block for known block")
 	}
 	cc.ChildTarget = pb.ChildTarget
-	cc.MinimumValidChildTimestamp = cs.blockRuleHelper.minimumValidChildTimestamp(tx.Bucket(BlockMap), pb)
+	cc.MinimumValidChildTimestamp = cs.blockRuleHelper.minimumValidChildTimestamp(blockMapWithHeaders(boltTxWrapper{tx}), pb)
 
 	currentBlock := currentBlockID(tx)
 	if cs.synced && recentBlock == currentBlock {
@@ -94,8 +112,6 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 
 	// Add the unexported tryTransactionSet function.
 	cc.TryTransactionSet = cs.tryTransactionSet
-
-	return cc, nil
 }
 
 // updateSubscribers will inform all subscribers of a new update to the
@@ -146,8 +162,9 @@ func (cs *ConsensusSet) managedInitializeSubscribe(subscriber modules.ConsensusS
 			// Special case: for modules.ConsensusChangeBeginning, create an
 			// initial node pointing to the genesis block. The subscriber will
 			// receive the diffs for all blocks in the consensus set, including
-			// the genesis block.
-			entry = cs.genesisEntry()
+			// the genesis block. Databases created from a snapshot start with
+			// the snapshot entry instead.
+			entry = cs.firstEntry(tx)
 			exists = true
 		} else {
 			// The subscriber has provided an existing consensus change.
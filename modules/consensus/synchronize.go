@@ -10,6 +10,7 @@ import (
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
+	siasync "github.com/NebulousLabs/Sia/sync"
 	"github.com/NebulousLabs/Sia/types"
 
 	"github.com/coreos/bbolt"
@@ -19,6 +20,10 @@ const (
 	// minNumOutbound is the minimum number of outbound peers required before ibd
 	// is confident we are synced.
 	minNumOutbound = 5
+
+	// maxBodyDownloadPeers is the maximum number of outbound peers that block
+	// bodies are downloaded from in parallel during header-first sync.
+	maxBodyDownloadPeers = 8
 )
 
 var (
@@ -44,6 +49,14 @@ var (
 		Testing:  types.BlockHeight(3),
 	}).(types.BlockHeight)
 
+	// MaxCatchUpHeaders is the maximum number of headers that are sent in a
+	// single batch of the SendHeaders RPC.
+	MaxCatchUpHeaders = build.Select(build.Var{
+		Standard: types.BlockHeight(2000),
+		Dev:      types.BlockHeight(500),
+		Testing:  types.BlockHeight(10),
+	}).(types.BlockHeight)
+
 	// minIBDWaitTime is the time threadedInitialBlockchainDownload waits before
 	// exiting if there are >= 1 and <= minNumOutbound peers synced. This timeout
 	// will primarily affect miners who have multiple nodes daisy chained off each
@@ -69,6 +82,20 @@ var (
 		Testing:  4 * time.Second,
 	}).(time.Duration)
 
+	// sendBodiesTimeout is the timeout for the SendBodies RPC.
+	sendBodiesTimeout = build.Select(build.Var{
+		Standard: 120 * time.Second,
+		Dev:      30 * time.Second,
+		Testing:  4 * time.Second,
+	}).(time.Duration)
+
+	// sendHeadersTimeout is the timeout for the SendHeaders RPC.
+	sendHeadersTimeout = build.Select(build.Var{
+		Standard: 180 * time.Second,
+		Dev:      40 * time.Second,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+
 	// sendBlocksTimeout is the timeout for the SendBlocks RPC.
 	sendBlocksTimeout = build.Select(build.Var{
 		Standard: 180 * time.Second,
@@ -90,6 +117,43 @@ func isTimeoutErr(err error) bool {
 	return (err.Error() == "Read timeout" || err.Error() == "Write timeout")
 }
 
+// isInvalidBlockErr is a helper function that returns true if err indicates
+// that a block received from a peer is invalid. Only errors that prove that
+// the peer sent a block or a header that breaks the consensus rules are
+// considered invalid. Errors that can be caused by an honest peer, such as a
+// skewed clock or a race with another peer, and errors of the local node, such
+// as database errors or a shutdown, are not.
+func isInvalidBlockErr(err error) bool {
+	switch err {
+	// Block and header validity errors.
+	case errDoSBlock, errNonLinearChain, errEarlyTimestamp, errLargeBlock,
+		errBadMinerPayouts, modules.ErrBlockUnsolved:
+		return true
+
+	// Transaction validity errors.
+	case errAlteredRevisionPayouts, errInvalidStorageProof, errLateRevision,
+		errLowRevisionNumber, errMissingSiacoinOutput, errMissingSiafundOutput,
+		errSiacoinInputOutputMismatch, errSiafundInputOutputMismatch,
+		errUnfinishedFileContract, errUnrecognizedFileContractID,
+		errWrongUnlockConditions:
+		return true
+	case types.ErrDoubleSpend, types.ErrFileContractOutputSumViolation,
+		types.ErrFileContractWindowEndViolation,
+		types.ErrFileContractWindowStartViolation, types.ErrNonZeroClaimStart,
+		types.ErrNonZeroRevision, types.ErrStorageProofWithOutputs,
+		types.ErrTimelockNotSatisfied, types.ErrTransactionTooLarge,
+		types.ErrZeroMinerFee, types.ErrZeroOutput, types.ErrZeroRevision:
+		return true
+	case types.ErrEntropyKey, types.ErrFrivolousSignature,
+		types.ErrInvalidPubKeyIndex, types.ErrMissingSignatures,
+		types.ErrPrematureSignature, types.ErrPublicKeyOveruse,
+		types.ErrSortedUniqueViolation, types.ErrWholeTransactionViolation,
+		crypto.ErrInvalidSignature:
+		return true
+	}
+	return false
+}
+
 // blockHistory returns up to 32 block ids, starting with recent blocks and
 // then proving exponentially increasingly less recent blocks. The genesis
 // block is always included as the last block. This block history can be used
@@ -132,6 +196,33 @@ func blockHistory(tx *bolt.Tx) (blockIDs [32]types.BlockID) {
 	return blockIDs
 }
 
+// catchUpStart finds the most recent block of knownBlocks that is in the
+// current path and returns the height of its child. If none of the blocks are
+// in the current path, or if the most recent one is the current block, false
+// is returned.
+func catchUpStart(tx *bolt.Tx, knownBlocks [32]types.BlockID) (start types.BlockHeight, found bool) {
+	csHeight := blockHeight(tx)
+	for _, id := range knownBlocks {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			continue
+		}
+		pathID, err := getPath(tx, pb.Height)
+		if err != nil {
+			continue
+		}
+		if pathID != pb.Block.ID() {
+			continue
+		}
+		if pb.Height == csHeight {
+			return 0, false
+		}
+		// Start from the child of the common block.
+		return pb.Height + 1, true
+	}
+	return 0, false
+}
+
 // managedReceiveBlocks is the calling end of the SendBlocks RPC, without the
 // threadgroup wrapping.
 func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr error) {
@@ -230,6 +321,9 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 		// sharing is implemented, block already in database should also be
 		// ignored.
 		if acceptErr != nil && acceptErr != modules.ErrNonExtendingBlock && acceptErr != modules.ErrBlockKnown {
+			if isInvalidBlockErr(acceptErr) {
+				cs.gateway.ReportMisbehavior(conn.RPCAddr(), modules.MisbehaviorInvalidBlock, acceptErr)
+			}
 			return acceptErr
 		}
 	}
@@ -295,30 +389,9 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 	// Find the most recent block from knownBlocks in the current path.
 	found := false
 	var start types.BlockHeight
-	var csHeight types.BlockHeight
 	cs.mu.RLock()
 	err = cs.db.View(func(tx *bolt.Tx) error {
-		csHeight = blockHeight(tx)
-		for _, id := range knownBlocks {
-			pb, err := getBlockMap(tx, id)
-			if err != nil {
-				continue
-			}
-			pathID, err := getPath(tx, pb.Height)
-			if err != nil {
-				continue
-			}
-			if pathID != pb.Block.ID() {
-				continue
-			}
-			if pb.Height == csHeight {
-				break
-			}
-			found = true
-			// Start from the child of the common block.
-			start = pb.Height + 1
-			break
-		}
+		start, found = catchUpStart(tx, knownBlocks)
 		return nil
 	})
 	cs.mu.RUnlock()
@@ -353,7 +426,9 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 					return err
 				}
 				pb, err := getBlockMap(tx, id)
-				if err != nil {
+				if err != nil && isPrunedBlock(tx, id) {
+					return modules.ErrBlockPruned
+				} else if err != nil {
 					cs.log.Critical("Unable to get block from block map: height", height, ":: request", i, ":: id", id)
 					return err
 				}
@@ -496,7 +571,9 @@ func (cs *ConsensusSet) rpcSendBlk(conn modules.PeerConn) error {
 	cs.mu.RLock()
 	err = cs.db.View(func(tx *bolt.Tx) error {
 		pb, err := getBlockMap(tx, id)
-		if err != nil {
+		if err != nil && isPrunedBlock(tx, id) {
+			return modules.ErrBlockPruned
+		} else if err != nil {
 			return err
 		}
 		b = pb.Block
@@ -559,6 +636,17 @@ func (cs *ConsensusSet) threadedInitialBlockchainDownload() error {
 	for {
 		numOutboundSynced = 0
 		numOutboundNotSynced = 0
+
+		// Fetch and verify the header chain first, and then download the
+		// block bodies in parallel. Whatever this misses is picked up by the
+		// SendBlocks RPC below, which also decides whether IBD is complete.
+		err := cs.managedHeaderFirstSync()
+		if err == errEarlyStop || err == siasync.ErrStopped {
+			return err
+		} else if err != nil {
+			cs.log.Println("WARN: header-first sync failed:", err)
+		}
+
 		for _, p := range cs.gateway.Peers() {
 			// We only sync on outbound peers at first to make IBD less susceptible to
 			// fast-mining and other attacks, as outbound peers are more difficult to
@@ -16,6 +16,7 @@ import (
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/gateway"
+	siasync "github.com/NebulousLabs/Sia/sync"
 	"github.com/NebulousLabs/Sia/types"
 
 	"github.com/coreos/bbolt"
@@ -108,6 +109,45 @@ func TestSynchronize(t *testing.T) {
 	}
 }
 
+// TestIsInvalidBlockErr checks that only errors that prove that a peer sent
+// an invalid block are considered invalid.
+func TestIsInvalidBlockErr(t *testing.T) {
+	invalid := []error{
+		errBadMinerPayouts,
+		errDoSBlock,
+		errEarlyTimestamp,
+		errMissingSiacoinOutput,
+		modules.ErrBlockUnsolved,
+		types.ErrDoubleSpend,
+		crypto.ErrInvalidSignature,
+	}
+	for _, err := range invalid {
+		if !isInvalidBlockErr(err) {
+			t.Errorf("%v should be considered invalid", err)
+		}
+	}
+	valid := []error{
+		nil,
+		errors.New("unknown error"),
+		bolt.ErrDatabaseNotOpen,
+		siasync.ErrStopped,
+		errEarlyStop,
+		errFutureTimestamp,
+		errExtremeFutureTimestamp,
+		errOrphan,
+		errCheckpointMismatch,
+		errPrunedFork,
+		modules.ErrBlockKnown,
+		modules.ErrBlockPruned,
+		modules.ErrNonExtendingBlock,
+	}
+	for _, err := range valid {
+		if isInvalidBlockErr(err) {
+			t.Errorf("%v should not be considered invalid", err)
+		}
+	}
+}
+
 // TestBlockHistory tests that blockHistory returns the expected sequence of
 // block IDs.
 func TestBlockHistory(t *testing.T) {
@@ -291,10 +291,26 @@ func validTransaction(tx *bolt.Tx, t types.Transaction) error {
 	if err != nil {
 		return err
 	}
+	return validTransactionContext(tx, t)
+}
+
+// validCheckpointedTransaction is validTransaction without the signature
+// checks. It is only used for transactions in blocks that are committed to by
+// a trusted checkpoint, where the signatures are known to be valid.
+func validCheckpointedTransaction(tx *bolt.Tx, t types.Transaction) error {
+	err := t.StandaloneValidWithoutSignatures(blockHeight(tx))
+	if err != nil {
+		return err
+	}
+	return validTransactionContext(tx, t)
+}
 
+// validTransactionContext checks that a transaction is valid given the
+// current consensus set.
+func validTransactionContext(tx *bolt.Tx, t types.Transaction) error {
 	// Check that each portion of the transaction is legal given the current
 	// consensus set.
-	err = validSiacoins(tx, t)
+	err := validSiacoins(tx, t)
 	if err != nil {
 		return err
 	}
@@ -45,6 +45,49 @@ type (
 		TotalRevisionVolume types.Currency `json:"totalrevisionvolume"`
 	}
 
+	// ExplorerAddressBalance is the balance of an address, which is the sum of
+	// the unspent outputs that the address holds in the current blockchain.
+	// Immature outputs, such as recent miner payouts, are not included.
+	ExplorerAddressBalance struct {
+		UnlockHash     types.UnlockHash `json:"unlockhash"`
+		SiacoinBalance types.Currency   `json:"siacoinbalance"`
+		SiafundBalance types.Currency   `json:"siafundbalance"`
+	}
+
+	// ExplorerSupply contains statistics about the siacoin supply at the
+	// current height of the blockchain.
+	ExplorerSupply struct {
+		Height types.BlockHeight `json:"height"`
+
+		// Mined is the total number of siacoins that have been created by
+		// block subsidies.
+		Mined types.Currency `json:"mined"`
+
+		// DelayedOutputs is the value of the delayed siacoin outputs that have
+		// not matured yet, such as miner payouts and file contract payouts.
+		DelayedOutputs types.Currency `json:"delayedoutputs"`
+
+		// SiafundPool is the value of the siafund pool, the total tax that
+		// was paid on file contracts. Like the siafund pool of the consensus
+		// set, it never decreases when siafund claims are paid, so it counts
+		// the claimed siacoins a second time.
+		SiafundPool types.Currency `json:"siafundpool"`
+
+		// UnclaimedSiafundPool is the part of the siafund pool that has not
+		// been paid out as siafund claims. Together with the burned,
+		// circulating and delayed siacoins and the siacoins held by file
+		// contracts, it adds up to the mined siacoins.
+		UnclaimedSiafundPool types.Currency `json:"unclaimedsiafundpool"`
+
+		// Burned is the value of the unspent outputs that were sent to the
+		// void, the empty unlock hash. These siacoins can never be spent.
+		Burned types.Currency `json:"burned"`
+
+		// Circulating is the value of all spendable siacoin outputs, not
+		// including the burned siacoins.
+		Circulating types.Currency `json:"circulating"`
+	}
+
 	// Explorer tracks the blockchain and provides tools for gathering
 	// statistics and finding objects or patterns within the blockchain.
 	Explorer interface {
@@ -99,6 +142,19 @@ type (
 		// the provided siafund output id.
 		SiafundOutputID(types.SiafundOutputID) []types.TransactionID
 
+		// AddressBalance returns the balance of an address. The bool indicates
+		// whether the address appears in the blockchain.
+		AddressBalance(types.UnlockHash) (ExplorerAddressBalance, bool)
+
+		// RichList returns the n addresses with the largest siacoin
+		// balances, ordered from largest to smallest. The void address is
+		// not included.
+		RichList(n int) []ExplorerAddressBalance
+
+		// Supply returns statistics about the siacoin supply at the current
+		// height.
+		Supply() ExplorerSupply
+
 		Close() error
 	}
 )
@@ -11,6 +11,7 @@ import (
 
 var (
 	// database buckets
+	bucketAddressBalances       = []byte("AddressBalances")
 	bucketBlockFacts            = []byte("BlockFacts")
 	bucketBlockIDs              = []byte("BlockIDs")
 	bucketBlocksDifficulty      = []byte("BlocksDifficulty")
@@ -21,6 +22,11 @@ var (
 	bucketInternal         = []byte("Internal")
 	bucketSiacoinOutputIDs = []byte("SiacoinOutputIDs")
 	bucketSiacoinOutputs   = []byte("SiacoinOutputs")
+	// bucketSiacoinRichList indexes the addresses by siacoin balance. The
+	// keys are the balance, encoded as a fixed size big-endian number,
+	// followed by the unlock hash, so that iterating over the bucket visits
+	// the addresses in order of balance.
+	bucketSiacoinRichList  = []byte("SiacoinRichList")
 	bucketSiafundOutputIDs = []byte("SiafundOutputIDs")
 	bucketSiafundOutputs   = []byte("SiafundOutputs")
 	bucketTransactionIDs   = []byte("TransactionIDs")
@@ -29,8 +35,12 @@ var (
 	errNotExist = errors.New("entry does not exist")
 
 	// keys for bucketInternal
-	internalBlockHeight  = []byte("BlockHeight")
-	internalRecentChange = []byte("RecentChange")
+	internalBlockHeight     = []byte("BlockHeight")
+	internalDelayedSiacoins = []byte("DelayedSiacoins")
+	internalRecentChange    = []byte("RecentChange")
+	internalSiacoinBalance  = []byte("SiacoinBalance")
+	internalSiafundClaims   = []byte("SiafundClaims")
+	internalSiafundPool     = []byte("SiafundPool")
 )
 
 // These functions all return a 'func(*bolt.Tx) error', which, allows them to
@@ -14,10 +14,16 @@ const (
 	// hashrateEstimationBlocks is the number of blocks that are used to
 	// estimate the current hashrate.
 	hashrateEstimationBlocks = 200 // 33 hours
+
+	// richListBalanceSize is the number of bytes used to encode a balance in
+	// the keys of the rich list. It is large enough to hold any siacoin
+	// balance.
+	richListBalanceSize = 32
 )
 
 var (
-	errNilCS = errors.New("explorer cannot use a nil consensus set")
+	errNilCS    = errors.New("explorer cannot use a nil consensus set")
+	errPrunedCS = errors.New("explorer cannot use a pruned consensus set or one created from a snapshot, it requires every block of the blockchain")
 )
 
 type (
@@ -30,6 +36,13 @@ type (
 		StorageProof types.StorageProof
 	}
 
+	// addressBalance is the balance of an address, as stored in the
+	// database.
+	addressBalance struct {
+		Siacoins types.Currency
+		Siafunds types.Currency
+	}
+
 	// blockFacts contains a set of facts about the consensus set related to a
 	// certain block. The explorer needs some additional information in the
 	// history so that it can calculate certain values, which is one of the
@@ -57,6 +70,11 @@ func New(cs modules.ConsensusSet, persistDir string) (*Explorer, error) {
 	if cs == nil {
 		return nil, errNilCS
 	}
+	// The explorer needs the body of every block, which is neither stored by
+	// a pruned consensus set nor by one that was created from a snapshot.
+	if _, exists := cs.BlockAtHeight(1); cs.Pruned() || (cs.Height() > 0 && !exists) {
+		return nil, errPrunedCS
+	}
 
 	// Initialize the explorer.
 	e := &Explorer{
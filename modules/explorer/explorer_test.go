@@ -134,7 +134,7 @@ func (et *explorerTester) reorgToBlank() error {
 
 	// Mine blocks until the height is higher than the existing consensus,
 	// submitting each block to the explorerTester.
-	currentHeight := cs.Height()
+	currentHeight := et.cs.Height()
 	for i := types.BlockHeight(0); i <= currentHeight+1; i++ {
 		block, err := m.AddBlock()
 		if err != nil {
@@ -2,6 +2,7 @@ package explorer
 
 import (
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 	"github.com/coreos/bbolt"
@@ -184,3 +185,92 @@ func (e *Explorer) SiafundOutputID(id types.SiafundOutputID) []types.Transaction
 	}
 	return ids
 }
+
+// AddressBalance returns the balance of an address. The bool indicates whether
+// the address appears in the blockchain.
+func (e *Explorer) AddressBalance(uh types.UnlockHash) (modules.ExplorerAddressBalance, bool) {
+	var ab addressBalance
+	var exists bool
+	err := e.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(bucketUnlockHashes).Bucket(encoding.Marshal(uh)) != nil
+		err := dbGetAndDecode(bucketAddressBalances, uh, &ab)(tx)
+		if err == errNotExist {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		build.Critical(err)
+	}
+	return modules.ExplorerAddressBalance{
+		UnlockHash:     uh,
+		SiacoinBalance: ab.Siacoins,
+		SiafundBalance: ab.Siafunds,
+	}, exists
+}
+
+// RichList returns the n addresses with the largest siacoin balances, ordered
+// from largest to smallest. The void address is not included.
+func (e *Explorer) RichList(n int) []modules.ExplorerAddressBalance {
+	var balances []modules.ExplorerAddressBalance
+	err := e.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketSiacoinRichList).Cursor()
+		for k, _ := c.Last(); k != nil && len(balances) < n; k, _ = c.Prev() {
+			var uh types.UnlockHash
+			copy(uh[:], k[richListBalanceSize:])
+			if uh == (types.UnlockHash{}) {
+				continue
+			}
+			var ab addressBalance
+			if err := dbGetAndDecode(bucketAddressBalances, uh, &ab)(tx); err != nil {
+				return err
+			}
+			balances = append(balances, modules.ExplorerAddressBalance{
+				UnlockHash:     uh,
+				SiacoinBalance: ab.Siacoins,
+				SiafundBalance: ab.Siafunds,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		build.Critical(err)
+	}
+	return balances
+}
+
+// Supply returns statistics about the siacoin supply at the current height.
+func (e *Explorer) Supply() (s modules.ExplorerSupply) {
+	var total, claims types.Currency
+	err := e.db.View(func(tx *bolt.Tx) error {
+		if err := dbGetInternal(internalBlockHeight, &s.Height)(tx); err != nil {
+			return err
+		}
+		if err := dbGetInternal(internalSiacoinBalance, &total)(tx); err != nil {
+			return err
+		}
+		if err := dbGetInternal(internalSiafundPool, &s.SiafundPool)(tx); err != nil {
+			return err
+		}
+		if err := dbGetInternal(internalSiafundClaims, &claims)(tx); err != nil {
+			return err
+		}
+		if err := dbGetInternal(internalDelayedSiacoins, &s.DelayedOutputs)(tx); err != nil {
+			return err
+		}
+		var void addressBalance
+		err := dbGetAndDecode(bucketAddressBalances, types.UnlockHash{}, &void)(tx)
+		if err != nil && err != errNotExist {
+			return err
+		}
+		s.Burned = void.Siacoins
+		return nil
+	})
+	if err != nil {
+		build.Critical(err)
+	}
+	s.Mined = types.CalculateNumSiacoins(s.Height)
+	s.Circulating = total.Sub(s.Burned)
+	s.UnclaimedSiafundPool = s.SiafundPool.Sub(claims)
+	return s
+}
@@ -268,3 +268,257 @@ func TestFileContractsPayoutValidProof(t *testing.T) {
 		t.Errorf("expected %v, got %v ", fc.MissedProofOutputs, outputs)
 	}
 }
+
+// checkSupply checks that the siacoins created by block subsidies are either
+// spendable, burned, locked in delayed outputs, held by active file contracts
+// or paid as tax to the siafund pool and not claimed yet. contracts is the
+// value of the outputs of the active file contracts.
+func checkSupply(t *testing.T, e *Explorer, contracts types.Currency) {
+	s := e.Supply()
+	if s.Height != e.cs.Height() {
+		t.Fatalf("supply is reported at height %v, consensus is at height %v", s.Height, e.cs.Height())
+	}
+	if !s.Circulating.Add(s.Burned).Add(s.DelayedOutputs).Add(contracts).Add(s.UnclaimedSiafundPool).Equals(s.Mined) {
+		t.Fatalf("supply does not add up: %v circulating, %v burned, %v delayed, %v in contracts, %v unclaimed in the siafund pool, %v mined", s.Circulating, s.Burned, s.DelayedOutputs, contracts, s.UnclaimedSiafundPool, s.Mined)
+	}
+}
+
+// TestAddressBalance checks that the explorer tracks the balance of each
+// address, ranks the addresses by balance, and rolls the balances back when
+// blocks are reverted.
+func TestAddressBalance(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	et, err := createExplorerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSupply(t, et.explorer, types.ZeroCurrency)
+
+	// The genesis siafunds are held by the genesis addresses.
+	for _, sfo := range types.GenesisSiafundAllocation {
+		ab, exists := et.explorer.AddressBalance(sfo.UnlockHash)
+		if !exists || ab.SiafundBalance.Cmp(sfo.Value) < 0 {
+			t.Fatal("genesis siafund allocation is missing from the address balance")
+		}
+	}
+
+	// Send coins to a new address.
+	uc, err := et.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := uc.UnlockHash()
+	if _, exists := et.explorer.AddressBalance(addr); exists {
+		t.Fatal("unused address appears in the blockchain")
+	}
+	balance, _, _, err := et.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := balance.Div64(4)
+	if _, err := et.wallet.SendSiacoins(amount, addr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := et.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	ab, exists := et.explorer.AddressBalance(addr)
+	if !exists || !ab.SiacoinBalance.Equals(amount) || !ab.SiafundBalance.IsZero() {
+		t.Fatalf("expected a balance of %v, got %v", amount, ab.SiacoinBalance)
+	}
+	checkSupply(t, et.explorer, types.ZeroCurrency)
+
+	// The rich list is ordered by balance and contains the address.
+	richList := et.explorer.RichList(1000)
+	var found bool
+	for i, rab := range richList {
+		if i > 0 && rab.SiacoinBalance.Cmp(richList[i-1].SiacoinBalance) > 0 {
+			t.Fatal("rich list is not ordered by balance")
+		}
+		if rab.UnlockHash == addr {
+			found = rab.SiacoinBalance.Equals(amount)
+		}
+	}
+	if !found {
+		t.Fatal("address is missing from the rich list")
+	}
+	if len(et.explorer.RichList(1)) != 1 {
+		t.Fatal("rich list did not return the requested number of addresses")
+	}
+
+	// Reorg to a chain of blank blocks. The coins sent to the address are
+	// reverted.
+	if err := et.reorgToBlank(); err != nil {
+		t.Fatal(err)
+	}
+	if ab, _ := et.explorer.AddressBalance(addr); !ab.SiacoinBalance.IsZero() {
+		t.Fatal("balance was not reverted, got", ab.SiacoinBalance)
+	}
+	for _, rab := range et.explorer.RichList(1000) {
+		if rab.UnlockHash == addr {
+			t.Fatal("reverted address is still in the rich list")
+		}
+	}
+	checkSupply(t, et.explorer, types.ZeroCurrency)
+}
+
+// TestSupplyFileContract checks that the supply accounts for the siacoins
+// that are held by a file contract and for its delayed payouts.
+func TestSupplyFileContract(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	et, err := createExplorerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a file contract that expires without a storage proof.
+	payout := types.NewCurrency64(400e6)
+	outputs := types.PostTax(et.cs.Height(), payout)
+	fc := types.FileContract{
+		WindowStart:        et.cs.Height() + 2,
+		WindowEnd:          et.cs.Height() + 3,
+		Payout:             payout,
+		ValidProofOutputs:  []types.SiacoinOutput{{Value: outputs}},
+		MissedProofOutputs: []types.SiacoinOutput{{Value: outputs}},
+	}
+	builder, err := et.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.FundSiacoins(payout); err != nil {
+		t.Fatal(err)
+	}
+	builder.AddFileContract(fc)
+	tSet, err := builder.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := et.tpool.AcceptTransactionSet(tSet); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := et.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if et.explorer.Supply().SiafundPool.IsZero() {
+		t.Fatal("file contract did not pay tax to the siafund pool")
+	}
+	checkSupply(t, et.explorer, outputs)
+
+	// Once the contract expires, its missed proof outputs are delayed until
+	// they mature.
+	for et.cs.Height() <= fc.WindowEnd {
+		if _, err := et.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var minerPayouts types.Currency
+	for h := et.cs.Height() - types.MaturityDelay + 1; h <= et.cs.Height(); h++ {
+		b, exists := et.cs.BlockAtHeight(h)
+		if !exists {
+			t.Fatal("block is missing at height", h)
+		}
+		for _, payout := range b.MinerPayouts {
+			minerPayouts = minerPayouts.Add(payout.Value)
+		}
+	}
+	if delayed := et.explorer.Supply().DelayedOutputs; !delayed.Equals(minerPayouts.Add(outputs)) {
+		t.Fatalf("expected %v in delayed outputs, got %v", minerPayouts.Add(outputs), delayed)
+	}
+	checkSupply(t, et.explorer, types.ZeroCurrency)
+
+	// The matured outputs of the contract are burned.
+	for i := types.BlockHeight(0); i <= types.MaturityDelay; i++ {
+		if _, err := et.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if et.explorer.Supply().Burned.Cmp(outputs) < 0 {
+		t.Fatal("matured contract outputs were not burned")
+	}
+	checkSupply(t, et.explorer, types.ZeroCurrency)
+}
+
+// TestSupplySiafundClaim checks that the siacoins paid out as a siafund claim
+// are removed from the unclaimed part of the siafund pool.
+func TestSupplySiafundClaim(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	et, err := createExplorerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pay tax to the siafund pool with a file contract that expires long
+	// after the test.
+	payout := types.NewCurrency64(400e6)
+	outputs := types.PostTax(et.cs.Height(), payout)
+	fc := types.FileContract{
+		WindowStart:        et.cs.Height() + 100,
+		WindowEnd:          et.cs.Height() + 101,
+		Payout:             payout,
+		ValidProofOutputs:  []types.SiacoinOutput{{Value: outputs}},
+		MissedProofOutputs: []types.SiacoinOutput{{Value: outputs}},
+	}
+	builder, err := et.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.FundSiacoins(payout); err != nil {
+		t.Fatal(err)
+	}
+	builder.AddFileContract(fc)
+	tSet, err := builder.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := et.tpool.AcceptTransactionSet(tSet); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := et.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	pool := et.explorer.Supply().SiafundPool
+	if pool.IsZero() || !et.explorer.Supply().UnclaimedSiafundPool.Equals(pool) {
+		t.Fatal("unexpected siafund pool:", et.explorer.Supply())
+	}
+	checkSupply(t, et.explorer, outputs)
+
+	// Spend the 2000 genesis siafunds of the 1of1 key, which pays their claim
+	// on the siafund pool.
+	err = et.wallet.LoadSiagKeys(et.walletKey, []string{"../../types/siag0of1of1.siakey"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc, err := et.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := et.wallet.SendSiafunds(types.NewCurrency64(2000), uc.UnlockHash()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := et.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	claim := pool.Mul64(2000).Div(types.SiafundCount)
+	s := et.explorer.Supply()
+	if !s.SiafundPool.Equals(pool) {
+		t.Fatalf("siafund pool changed from %v to %v", pool, s.SiafundPool)
+	}
+	if !s.UnclaimedSiafundPool.Equals(pool.Sub(claim)) {
+		t.Fatalf("expected %v unclaimed in the siafund pool, got %v", pool.Sub(claim), s.UnclaimedSiafundPool)
+	}
+	checkSupply(t, et.explorer, outputs)
+
+	// The claim is delayed until it matures, and is spendable afterwards.
+	for i := types.BlockHeight(0); i <= types.MaturityDelay; i++ {
+		if _, err := et.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkSupply(t, et.explorer, outputs)
+}
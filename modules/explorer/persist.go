@@ -35,6 +35,7 @@ func (e *Explorer) initPersist() error {
 	// Initialize the database
 	err = e.db.Update(func(tx *bolt.Tx) error {
 		buckets := [][]byte{
+			bucketAddressBalances,
 			bucketBlockFacts,
 			bucketBlockIDs,
 			bucketBlocksDifficulty,
@@ -44,11 +45,26 @@ func (e *Explorer) initPersist() error {
 			bucketInternal,
 			bucketSiacoinOutputIDs,
 			bucketSiacoinOutputs,
+			bucketSiacoinRichList,
 			bucketSiafundOutputIDs,
 			bucketSiafundOutputs,
 			bucketTransactionIDs,
 			bucketUnlockHashes,
 		}
+
+		// Databases that were created before the address balances, the
+		// delayed siacoins and the siafund claims were tracked are rebuilt
+		// from the beginning of the blockchain.
+		if internal := tx.Bucket(bucketInternal); internal != nil && (tx.Bucket(bucketAddressBalances) == nil || internal.Get(internalDelayedSiacoins) == nil || internal.Get(internalSiafundClaims) == nil) {
+			for _, b := range buckets {
+				if tx.Bucket(b) == nil {
+					continue
+				}
+				if err := tx.DeleteBucket(b); err != nil {
+					return err
+				}
+			}
+		}
 		for _, b := range buckets {
 			_, err := tx.CreateBucketIfNotExists(b)
 			if err != nil {
@@ -61,7 +77,11 @@ func (e *Explorer) initPersist() error {
 			key, val []byte
 		}{
 			{internalBlockHeight, encoding.Marshal(types.BlockHeight(0))},
+			{internalDelayedSiacoins, encoding.Marshal(types.ZeroCurrency)},
 			{internalRecentChange, encoding.Marshal(modules.ConsensusChangeID{})},
+			{internalSiacoinBalance, encoding.Marshal(types.ZeroCurrency)},
+			{internalSiafundClaims, encoding.Marshal(types.ZeroCurrency)},
+			{internalSiafundPool, encoding.Marshal(types.ZeroCurrency)},
 		}
 		b := tx.Bucket(bucketInternal)
 		for _, d := range internalDefaults {
@@ -33,6 +33,14 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 			return err
 		}
 
+		// The claim of a spent siafund output is paid as a delayed siacoin
+		// output. Its value is looked up when the siafund input is processed.
+		claimValues := make(map[types.SiacoinOutputID]types.Currency)
+		for _, dscod := range cc.DelayedSiacoinOutputDiffs {
+			claimValues[dscod.ID] = dscod.SiacoinOutput.Value
+		}
+		var claimsPaid, claimsReverted types.Currency
+
 		// Update cumulative stats for reverted blocks.
 		for _, block := range cc.RevertedBlocks {
 			bid := block.ID()
@@ -110,6 +118,7 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 					dbRemoveSiafundOutputID(tx, sfi.ParentID, txid)
 					dbRemoveUnlockHash(tx, sfi.UnlockConditions.UnlockHash(), txid)
 					dbRemoveUnlockHash(tx, sfi.ClaimUnlockHash, txid)
+					claimsReverted = claimsReverted.Add(claimValues[sfi.ParentID.SiaClaimOutputID()])
 				}
 				for k, sfo := range txn.SiafundOutputs {
 					sfoid := txn.SiafundOutputID(uint64(k))
@@ -205,6 +214,7 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 					dbAddSiafundOutputID(tx, sfi.ParentID, txid)
 					dbAddUnlockHash(tx, sfi.UnlockConditions.UnlockHash(), txid)
 					dbAddUnlockHash(tx, sfi.ClaimUnlockHash, txid)
+					claimsPaid = claimsPaid.Add(claimValues[sfi.ParentID.SiaClaimOutputID()])
 				}
 				for k, sfo := range txn.SiafundOutputs {
 					sfoid := txn.SiafundOutputID(uint64(k))
@@ -220,20 +230,53 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 			}
 		}
 
-		// Update stats according to SiacoinOutputDiffs
+		// Update stats according to SiacoinOutputDiffs. The diffs of reverted
+		// blocks have their direction inverted, so the address balances are
+		// rolled back as well.
 		for _, scod := range cc.SiacoinOutputDiffs {
 			if scod.Direction == modules.DiffApply {
 				dbAddSiacoinOutput(tx, scod.ID, scod.SiacoinOutput)
 			}
+			dbUpdateSiacoinBalance(tx, scod.SiacoinOutput.UnlockHash, scod.SiacoinOutput.Value, scod.Direction)
+		}
+
+		// Update the value of the delayed siacoin outputs according to
+		// DelayedSiacoinOutputDiffs. An output is created before it matures,
+		// so the additions are applied first.
+		var delayed, added, removed types.Currency
+		assertNil(dbGetInternal(internalDelayedSiacoins, &delayed)(tx))
+		for _, dscod := range cc.DelayedSiacoinOutputDiffs {
+			if dscod.Direction == modules.DiffApply {
+				added = added.Add(dscod.SiacoinOutput.Value)
+			} else {
+				removed = removed.Add(dscod.SiacoinOutput.Value)
+			}
 		}
+		assertNil(dbSetInternal(internalDelayedSiacoins, delayed.Add(added).Sub(removed))(tx))
 
 		// Update stats according to SiafundOutputDiffs
 		for _, sfod := range cc.SiafundOutputDiffs {
 			if sfod.Direction == modules.DiffApply {
 				dbAddSiafundOutput(tx, sfod.ID, sfod.SiafundOutput)
 			}
+			dbUpdateSiafundBalance(tx, sfod.SiafundOutput.UnlockHash, sfod.SiafundOutput.Value, sfod.Direction)
+		}
+
+		// Update the siafund pool according to SiafundPoolDiffs
+		for _, sfpd := range cc.SiafundPoolDiffs {
+			pool := sfpd.Adjusted
+			if sfpd.Direction == modules.DiffRevert {
+				pool = sfpd.Previous
+			}
+			assertNil(dbSetInternal(internalSiafundPool, pool)(tx))
 		}
 
+		// Update the total value of the siafund claims. The siafund pool
+		// never decreases, so the claims are tracked separately.
+		var claims types.Currency
+		assertNil(dbGetInternal(internalSiafundClaims, &claims)(tx))
+		assertNil(dbSetInternal(internalSiafundClaims, claims.Add(claimsPaid).Sub(claimsReverted))(tx))
+
 		// Compute the changes in the active set. Note, because this is calculated
 		// at the end instead of in a loop, the historic facts may contain
 		// inaccuracies about the active set. This should not be a problem except
@@ -411,6 +454,71 @@ func dbRemoveSiafundOutputID(tx *bolt.Tx, id types.SiafundOutputID, txid types.T
 	}
 }
 
+// richListKey returns the key of an address in the siacoin rich list.
+func richListKey(uh types.UnlockHash, balance types.Currency) []byte {
+	b := balance.Big().Bytes()
+	if len(b) > richListBalanceSize {
+		panic("balance is too large for the rich list")
+	}
+	key := make([]byte, richListBalanceSize+len(uh))
+	copy(key[richListBalanceSize-len(b):], b)
+	copy(key[richListBalanceSize:], uh[:])
+	return key
+}
+
+// Get/Put address balance. Addresses with an empty balance are removed from
+// the database, and the rich list is updated together with the balance.
+func dbGetAddressBalance(tx *bolt.Tx, uh types.UnlockHash) (ab addressBalance) {
+	err := dbGetAndDecode(bucketAddressBalances, uh, &ab)(tx)
+	if err != nil && err != errNotExist {
+		panic(err)
+	}
+	return ab
+}
+func dbPutAddressBalance(tx *bolt.Tx, uh types.UnlockHash, old, ab addressBalance) {
+	richList := tx.Bucket(bucketSiacoinRichList)
+	if !old.Siacoins.IsZero() {
+		assertNil(richList.Delete(richListKey(uh, old.Siacoins)))
+	}
+	if !ab.Siacoins.IsZero() {
+		assertNil(richList.Put(richListKey(uh, ab.Siacoins), nil))
+	}
+	if ab.Siacoins.IsZero() && ab.Siafunds.IsZero() {
+		mustDelete(tx.Bucket(bucketAddressBalances), uh)
+		return
+	}
+	mustPut(tx.Bucket(bucketAddressBalances), uh, ab)
+}
+
+// Update siacoin balance of an address, and the sum of all balances
+func dbUpdateSiacoinBalance(tx *bolt.Tx, uh types.UnlockHash, value types.Currency, dir modules.DiffDirection) {
+	var total types.Currency
+	assertNil(dbGetInternal(internalSiacoinBalance, &total)(tx))
+	old := dbGetAddressBalance(tx, uh)
+	ab := old
+	if dir == modules.DiffApply {
+		ab.Siacoins = ab.Siacoins.Add(value)
+		total = total.Add(value)
+	} else {
+		ab.Siacoins = ab.Siacoins.Sub(value)
+		total = total.Sub(value)
+	}
+	dbPutAddressBalance(tx, uh, old, ab)
+	assertNil(dbSetInternal(internalSiacoinBalance, total)(tx))
+}
+
+// Update siafund balance of an address
+func dbUpdateSiafundBalance(tx *bolt.Tx, uh types.UnlockHash, value types.Currency, dir modules.DiffDirection) {
+	old := dbGetAddressBalance(tx, uh)
+	ab := old
+	if dir == modules.DiffApply {
+		ab.Siafunds = ab.Siafunds.Add(value)
+	} else {
+		ab.Siafunds = ab.Siafunds.Sub(value)
+	}
+	dbPutAddressBalance(tx, uh, old, ab)
+}
+
 // Add/Remove storage proof
 func dbAddStorageProof(tx *bolt.Tx, fcid types.FileContractID, sp types.StorageProof) {
 	var history fileContractHistory
@@ -548,4 +656,11 @@ func dbAddGenesisBlock(tx *bolt.Tx) {
 		},
 		Timestamp: types.GenesisBlock.Timestamp,
 	})
+
+	// The consensus set adds the genesis miner payout to the delayed siacoin
+	// outputs without reporting a diff, but reports its removal once it
+	// matures.
+	var delayed types.Currency
+	assertNil(dbGetInternal(internalDelayedSiacoins, &delayed)(tx))
+	assertNil(dbSetInternal(internalDelayedSiacoins, delayed.Add(types.CalculateCoinbase(0)))(tx))
 }
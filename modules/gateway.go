@@ -1,9 +1,14 @@
 package modules
 
 import (
+	"encoding/json"
+	"fmt"
 	"net"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 const (
@@ -12,6 +17,23 @@ const (
 	GatewayDir = "gateway"
 )
 
+// Misbehavior scores that are reported to the gateway when a peer misbehaves.
+// A peer whose score reaches MisbehaviorBanThreshold is banned for a while.
+const (
+	// MisbehaviorBanThreshold is the score at which a peer is banned.
+	MisbehaviorBanThreshold = 100
+
+	// MisbehaviorInvalidBlock is reported for a peer that sent an invalid
+	// block. Blocks are expensive to produce and to validate, so a single
+	// invalid block gets the peer banned.
+	MisbehaviorInvalidBlock = MisbehaviorBanThreshold
+
+	// MisbehaviorInvalidTransactionSet is reported for a peer that relayed a
+	// transaction set that is invalid regardless of the state of the
+	// consensus set and the transaction pool.
+	MisbehaviorInvalidTransactionSet = 20
+)
+
 var (
 	// BootstrapPeers is a list of peers that can be used to find other peers -
 	// when a client first connects to the network, the only options for
@@ -129,12 +151,57 @@ var (
 )
 
 type (
+	// A NodeID identifies a gateway across restarts and address changes. It
+	// is the hash of the gateway's persistent public key.
+	NodeID crypto.Hash
+
 	// Peer contains all the info necessary to Broadcast to a peer.
 	Peer struct {
 		Inbound    bool       `json:"inbound"`
 		Local      bool       `json:"local"`
 		NetAddress NetAddress `json:"netaddress"`
 		Version    string     `json:"version"`
+
+		// Encrypted is true if the connection to the peer is encrypted and
+		// the peer proved that it owns the key of NodeID. NodeID is empty
+		// for peers that do not support encrypted connections.
+		Encrypted bool   `json:"encrypted"`
+		NodeID    NodeID `json:"nodeid"`
+
+		// Upload and Download are the number of bytes sent to and received
+		// from the peer, including previous connections to the peer.
+		Upload   uint64 `json:"upload"`
+		Download uint64 `json:"download"`
+	}
+
+	// GatewayBandwidth contains the number of bytes that the gateway sent to
+	// and received from its peers, in total and per RPC.
+	GatewayBandwidth struct {
+		Upload   uint64         `json:"upload"`
+		Download uint64         `json:"download"`
+		RPCs     []RPCBandwidth `json:"rpcs"`
+	}
+
+	// RPCBandwidth contains the number of bytes that were sent and received
+	// by the calls of an RPC.
+	RPCBandwidth struct {
+		Name     string `json:"name"`
+		Upload   uint64 `json:"upload"`
+		Download uint64 `json:"download"`
+	}
+
+	// BlacklistEntry is a host that the gateway refuses to connect to, either
+	// because it was added by the user or because it misbehaved.
+	BlacklistEntry struct {
+		// Host is the IP address of the blacklisted peers.
+		Host string `json:"host"`
+
+		// Expiry is the time when the ban ends. A zero expiry means that the
+		// host stays blacklisted until it is removed.
+		Expiry types.Timestamp `json:"expiry"`
+
+		// Reason describes why the host was blacklisted.
+		Reason string `json:"reason"`
 	}
 
 	// A PeerConn is the connection type used when communicating with peers during
@@ -168,6 +235,9 @@ type (
 		// Address returns the Gateway's address.
 		Address() NetAddress
 
+		// ID returns the NodeID of the Gateway.
+		ID() NodeID
+
 		// Peers returns the addresses that the Gateway is currently connected to.
 		Peers() []Peer
 
@@ -202,7 +272,49 @@ type (
 		// Online returns true if the gateway is connected to remote hosts
 		Online() bool
 
+		// ReportMisbehavior adds score to the misbehavior score of the host of
+		// a peer. Once the score reaches MisbehaviorBanThreshold, the host is
+		// blacklisted for a while and all of its peers are disconnected.
+		ReportMisbehavior(addr NetAddress, score int, reason error)
+
+		// Blacklist returns the hosts that the gateway refuses to connect to.
+		Blacklist() []BlacklistEntry
+
+		// AddToBlacklist blacklists the given hosts for the given duration. A
+		// duration of zero blacklists the hosts until they are removed.
+		AddToBlacklist(hosts []string, duration time.Duration) error
+
+		// RemoveFromBlacklist removes the given hosts from the blacklist.
+		RemoveFromBlacklist(hosts []string) error
+
+		// Bandwidth returns the number of bytes that the gateway sent to and
+		// received from its peers.
+		Bandwidth() GatewayBandwidth
+
+		// RateLimits returns the limits in bytes per second that apply to all
+		// traffic of the gateway. Zero means unlimited.
+		RateLimits() (downloadSpeed, uploadSpeed int64)
+
+		// SetRateLimits sets the limits in bytes per second that apply to all
+		// traffic of the gateway. Zero means unlimited.
+		SetRateLimits(downloadSpeed, uploadSpeed int64) error
+
 		// Close safely stops the Gateway's listener process.
 		Close() error
 	}
 )
+
+// MarshalJSON marshals an id as a hex string.
+func (id NodeID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(id.String())
+}
+
+// String prints the id in hex.
+func (id NodeID) String() string {
+	return fmt.Sprintf("%x", id[:])
+}
+
+// UnmarshalJSON decodes the json hex string of the id.
+func (id *NodeID) UnmarshalJSON(b []byte) error {
+	return (*crypto.Hash)(id).UnmarshalJSON(b)
+}
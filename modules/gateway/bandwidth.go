@@ -0,0 +1,161 @@
+package gateway
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"sync/atomic"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/ratelimit"
+)
+
+var errNegativeRateLimit = errors.New("rate limits must not be negative")
+
+// bandwidthCounter counts the bytes sent and received over connections. The
+// fields are accessed atomically.
+type bandwidthCounter struct {
+	Upload   uint64 `json:"upload"`
+	Download uint64 `json:"download"`
+}
+
+// load returns a copy of the counter.
+func (bc *bandwidthCounter) load() bandwidthCounter {
+	return bandwidthCounter{
+		Upload:   atomic.LoadUint64(&bc.Upload),
+		Download: atomic.LoadUint64(&bc.Download),
+	}
+}
+
+// countingConn is a net.Conn that adds the bytes it reads and writes to a set
+// of counters.
+type countingConn struct {
+	net.Conn
+	counters []*bandwidthCounter
+}
+
+// Read implements net.Conn.
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	for _, bc := range c.counters {
+		atomic.AddUint64(&bc.Download, uint64(n))
+	}
+	return n, err
+}
+
+// Write implements net.Conn.
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	for _, bc := range c.counters {
+		atomic.AddUint64(&bc.Upload, uint64(n))
+	}
+	return n, err
+}
+
+// peerCounter returns the bandwidth counter of a peer, creating it if
+// necessary.
+func (g *Gateway) peerCounter(addr modules.NetAddress) *bandwidthCounter {
+	bc, exists := g.peerBandwidth[addr]
+	if !exists {
+		bc = new(bandwidthCounter)
+		g.peerBandwidth[addr] = bc
+	}
+	return bc
+}
+
+// rpcCounter returns the bandwidth counter of an RPC, creating it if
+// necessary.
+func (g *Gateway) rpcCounter(name string) *bandwidthCounter {
+	bc, exists := g.rpcBandwidth[name]
+	if !exists {
+		bc = new(bandwidthCounter)
+		g.rpcBandwidth[name] = bc
+	}
+	return bc
+}
+
+// managedCountPeerConn wraps the connection of a peer so that its traffic is
+// counted and subject to the rate limits of the gateway.
+func (g *Gateway) managedCountPeerConn(conn net.Conn, addr modules.NetAddress) net.Conn {
+	g.mu.Lock()
+	counters := []*bandwidthCounter{&g.totalBandwidth, g.peerCounter(addr)}
+	g.mu.Unlock()
+	return &countingConn{
+		Conn:     ratelimit.NewRLConn(conn, g.rl, g.threads.StopChan()),
+		counters: counters,
+	}
+}
+
+// managedCountRPCConn wraps the stream of an RPC call so that its traffic is
+// counted.
+func (g *Gateway) managedCountRPCConn(conn modules.PeerConn, name string) modules.PeerConn {
+	g.mu.Lock()
+	counter := g.rpcCounter(name)
+	g.mu.Unlock()
+	return &peerConn{
+		Conn:         &countingConn{Conn: conn, counters: []*bandwidthCounter{counter}},
+		dialbackAddr: conn.RPCAddr(),
+	}
+}
+
+// setRateLimits sets the limits of the rate limiter that is shared by all of
+// the gateway's connections.
+func (g *Gateway) setRateLimits(downloadSpeed, uploadSpeed int64) {
+	g.maxDownloadSpeed, g.maxUploadSpeed = downloadSpeed, uploadSpeed
+	// Check for sentinel "no limits" value.
+	if downloadSpeed == 0 && uploadSpeed == 0 {
+		g.rl.SetLimits(0, 0, 0)
+	} else {
+		g.rl.SetLimits(downloadSpeed, uploadSpeed, 4*4096)
+	}
+}
+
+// Bandwidth returns the number of bytes that the gateway sent to and received
+// from its peers, in total and per RPC. RPCs are ordered by name.
+func (g *Gateway) Bandwidth() modules.GatewayBandwidth {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	total := g.totalBandwidth.load()
+	gb := modules.GatewayBandwidth{
+		Upload:   total.Upload,
+		Download: total.Download,
+		RPCs:     make([]modules.RPCBandwidth, 0, len(g.rpcBandwidth)),
+	}
+	for name, bc := range g.rpcBandwidth {
+		counter := bc.load()
+		gb.RPCs = append(gb.RPCs, modules.RPCBandwidth{
+			Name:     name,
+			Upload:   counter.Upload,
+			Download: counter.Download,
+		})
+	}
+	sort.Slice(gb.RPCs, func(i, j int) bool {
+		return gb.RPCs[i].Name < gb.RPCs[j].Name
+	})
+	return gb
+}
+
+// RateLimits returns the limits in bytes per second that apply to all traffic
+// of the gateway. Zero means unlimited.
+func (g *Gateway) RateLimits() (downloadSpeed, uploadSpeed int64) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.maxDownloadSpeed, g.maxUploadSpeed
+}
+
+// SetRateLimits sets the limits in bytes per second that apply to all traffic
+// of the gateway. Zero means unlimited. The limits are persisted.
+func (g *Gateway) SetRateLimits(downloadSpeed, uploadSpeed int64) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	if downloadSpeed < 0 || uploadSpeed < 0 {
+		return errNegativeRateLimit
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.setRateLimits(downloadSpeed, uploadSpeed)
+	return g.saveSync()
+}
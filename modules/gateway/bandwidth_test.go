@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestBandwidthCounters checks that the gateway counts the traffic of its
+// peers and RPCs, and that the counters survive a restart.
+func TestBandwidthCounters(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	data := fastrand.Bytes(10e3)
+	received := make(chan []byte, 1)
+	g2.RegisterRPC("Foo", func(conn modules.PeerConn) error {
+		var b []byte
+		err := encoding.ReadObject(conn, &b, 20e3)
+		received <- b
+		return err
+	})
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err := g1.RPC(g2.Address(), "Foo", func(conn modules.PeerConn) error {
+		return encoding.WriteObject(conn, data)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-received
+
+	size := uint64(len(encoding.Marshal(data)))
+	gb := g1.Bandwidth()
+	if gb.Upload < size || len(gb.RPCs) == 0 {
+		t.Fatal("upload was not counted:", gb)
+	}
+	var found bool
+	for _, rpc := range gb.RPCs {
+		if rpc.Name == "Foo" {
+			found = rpc.Upload >= size
+		}
+	}
+	if !found {
+		t.Fatal("upload of the RPC was not counted:", gb.RPCs)
+	}
+	peers := g1.Peers()
+	if len(peers) != 1 || peers[0].Upload < size {
+		t.Fatal("upload of the peer was not counted:", peers)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		for _, rpc := range g2.Bandwidth().RPCs {
+			if rpc.Name == "Foo" && rpc.Download >= size {
+				return nil
+			}
+		}
+		return errors.New("download of the RPC was not counted")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The counters survive a restart.
+	if err := g1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g1, err = New("localhost:0", false, g1.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restarted := g1.Bandwidth(); restarted.Upload < gb.Upload || len(restarted.RPCs) < len(gb.RPCs) {
+		t.Fatal("counters were not persisted:", restarted, gb)
+	}
+}
+
+// TestSetRateLimits checks that the rate limits of the gateway can be changed
+// and are persisted.
+func TestSetRateLimits(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+
+	if err := g.SetRateLimits(-1, 0); err != errNegativeRateLimit {
+		t.Fatal("expected errNegativeRateLimit, got", err)
+	}
+	if err := g.SetRateLimits(1e6, 2e6); err != nil {
+		t.Fatal(err)
+	}
+	if down, up, _ := g.rl.Limits(); down != 1e6 || up != 2e6 {
+		t.Fatal("rate limiter was not updated:", down, up)
+	}
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g, err := New("localhost:0", false, g.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if down, up := g.RateLimits(); down != 1e6 || up != 2e6 {
+		t.Fatal("rate limits were not persisted:", down, up)
+	}
+	if down, up, _ := g.rl.Limits(); down != 1e6 || up != 2e6 {
+		t.Fatal("rate limiter was not updated after loading:", down, up)
+	}
+}
@@ -0,0 +1,184 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errBlacklistedPeer = errors.New("peer is blacklisted")
+	errInvalidHost     = errors.New("blacklisted hosts must be IP addresses")
+)
+
+// misbehavior tracks the misbehavior score of a host. The score decays over
+// time, so that occasional protocol errors of honest peers do not add up to a
+// ban.
+type misbehavior struct {
+	score      int
+	lastReport time.Time
+}
+
+// currentScore returns the score of the host after applying the decay since
+// the last report.
+func (m misbehavior) currentScore(now time.Time) int {
+	decay := int(now.Sub(m.lastReport) / misbehaviorDecayInterval)
+	if decay >= m.score {
+		return 0
+	}
+	return m.score - decay
+}
+
+// pruneMisbehavior removes the hosts whose misbehavior score has decayed to
+// zero, so that the misbehavior map does not grow without bound.
+func (g *Gateway) pruneMisbehavior(now time.Time) {
+	for host, m := range g.misbehavior {
+		if m.currentScore(now) == 0 {
+			delete(g.misbehavior, host)
+		}
+	}
+}
+
+// isBlacklisted returns true if the host is blacklisted and the ban has not
+// expired.
+func (g *Gateway) isBlacklisted(host string) bool {
+	entry, exists := g.blacklist[host]
+	if !exists {
+		return false
+	}
+	return entry.Expiry == 0 || types.Timestamp(time.Now().Unix()) < entry.Expiry
+}
+
+// blacklistHost adds a host to the blacklist, disconnects all of its peers and
+// removes its nodes from the node list.
+func (g *Gateway) blacklistHost(host string, expiry types.Timestamp, reason string) {
+	g.blacklist[host] = modules.BlacklistEntry{
+		Host:   host,
+		Expiry: expiry,
+		Reason: reason,
+	}
+	delete(g.misbehavior, host)
+	for addr, p := range g.peers {
+		if addr.Host() == host {
+			p.sess.Close()
+			delete(g.peers, addr)
+			g.log.Println("INFO: disconnected from blacklisted peer", addr)
+		}
+	}
+	for addr := range g.nodes {
+		if addr.Host() == host {
+			delete(g.nodes, addr)
+		}
+	}
+}
+
+// ReportMisbehavior adds score to the misbehavior score of the host of a peer.
+// Once the score reaches modules.MisbehaviorBanThreshold, the host is
+// blacklisted for banDuration.
+func (g *Gateway) ReportMisbehavior(addr modules.NetAddress, score int, reason error) {
+	if err := g.threads.Add(); err != nil {
+		return
+	}
+	defer g.threads.Done()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	host := addr.Host()
+	if host == "" || g.isBlacklisted(host) {
+		return
+	}
+	now := time.Now()
+	m := g.misbehavior[host]
+	m.score = m.currentScore(now) + score
+	m.lastReport = now
+	g.misbehavior[host] = m
+	g.pruneMisbehavior(now)
+	g.log.Printf("WARN: peer %v misbehaved (score %v): %v", addr, m.score, reason)
+	if m.score < modules.MisbehaviorBanThreshold {
+		return
+	}
+
+	expiry := types.Timestamp(now.Add(banDuration).Unix())
+	g.blacklistHost(host, expiry, fmt.Sprint("misbehavior: ", reason))
+	g.log.Printf("INFO: banned %v until %v", host, time.Unix(int64(expiry), 0))
+	if err := g.saveSync(); err != nil {
+		g.log.Println("ERROR: Unable to save gateway blacklist:", err)
+	}
+}
+
+// Blacklist returns the hosts that the gateway refuses to connect to, ordered
+// by host.
+func (g *Gateway) Blacklist() []modules.BlacklistEntry {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.blacklistEntries()
+}
+
+// blacklistEntries returns the entries of the blacklist that have not
+// expired, ordered by host.
+func (g *Gateway) blacklistEntries() []modules.BlacklistEntry {
+	entries := make([]modules.BlacklistEntry, 0, len(g.blacklist))
+	for host, entry := range g.blacklist {
+		if g.isBlacklisted(host) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Host < entries[j].Host
+	})
+	return entries
+}
+
+// AddToBlacklist blacklists the given hosts for the given duration. A
+// duration of zero blacklists the hosts until they are removed. Peers of the
+// hosts are disconnected.
+func (g *Gateway) AddToBlacklist(hosts []string, duration time.Duration) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	for _, host := range hosts {
+		if net.ParseIP(host) == nil {
+			return errInvalidHost
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var expiry types.Timestamp
+	if duration != 0 {
+		expiry = types.Timestamp(time.Now().Add(duration).Unix())
+	}
+	for _, host := range hosts {
+		g.blacklistHost(host, expiry, "added by user")
+	}
+	g.log.Println("INFO: blacklisted", hosts)
+	return g.saveSync()
+}
+
+// RemoveFromBlacklist removes the given hosts from the blacklist.
+func (g *Gateway) RemoveFromBlacklist(hosts []string) error {
+	if err := g.threads.Add(); err != nil {
+		return err
+	}
+	defer g.threads.Done()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, host := range hosts {
+		if _, exists := g.blacklist[host]; !exists {
+			return fmt.Errorf("%v is not blacklisted", host)
+		}
+	}
+	for _, host := range hosts {
+		delete(g.blacklist, host)
+		delete(g.misbehavior, host)
+	}
+	g.log.Println("INFO: removed", hosts, "from the blacklist")
+	return g.saveSync()
+}
@@ -0,0 +1,172 @@
+package gateway
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestMisbehaviorScore checks that misbehavior scores decay over time.
+func TestMisbehaviorScore(t *testing.T) {
+	now := time.Now()
+	m := misbehavior{score: 10, lastReport: now}
+	if score := m.currentScore(now); score != 10 {
+		t.Fatal("expected score 10, got", score)
+	}
+	if score := m.currentScore(now.Add(3 * misbehaviorDecayInterval)); score != 7 {
+		t.Fatal("expected score 7, got", score)
+	}
+	if score := m.currentScore(now.Add(20 * misbehaviorDecayInterval)); score != 0 {
+		t.Fatal("expected score 0, got", score)
+	}
+}
+
+// TestPruneMisbehavior checks that hosts whose misbehavior score has decayed
+// to zero are removed when misbehavior is reported.
+func TestPruneMisbehavior(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+
+	g.mu.Lock()
+	g.misbehavior["1.2.3.4"] = misbehavior{score: 1, lastReport: time.Now().Add(-2 * misbehaviorDecayInterval)}
+	g.misbehavior["1.2.3.5"] = misbehavior{score: 10, lastReport: time.Now()}
+	g.mu.Unlock()
+	g.ReportMisbehavior(modules.NetAddress("1.2.3.6:9981"), 1, errors.New("test misbehavior"))
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if _, exists := g.misbehavior["1.2.3.4"]; exists {
+		t.Error("decayed misbehavior was not pruned")
+	}
+	if _, exists := g.misbehavior["1.2.3.5"]; !exists {
+		t.Error("current misbehavior was pruned")
+	}
+	if _, exists := g.misbehavior["1.2.3.6"]; !exists {
+		t.Error("reported misbehavior is missing")
+	}
+}
+
+// TestReportMisbehavior checks that a peer is banned once its misbehavior
+// score reaches the threshold, and that the ban is persisted.
+func TestReportMisbehavior(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	host := g2.Address().Host()
+	reason := errors.New("test misbehavior")
+
+	// A score below the threshold does not get the peer banned.
+	g1.ReportMisbehavior(g2.Address(), modules.MisbehaviorBanThreshold/2, reason)
+	if len(g1.Peers()) != 1 || len(g1.Blacklist()) != 0 {
+		t.Fatal("peer should not be banned yet")
+	}
+	g1.ReportMisbehavior(g2.Address(), modules.MisbehaviorBanThreshold/2, reason)
+	blacklist := g1.Blacklist()
+	if len(blacklist) != 1 || blacklist[0].Host != host || blacklist[0].Expiry == 0 {
+		t.Fatal("expected a temporary ban of the peer, got", blacklist)
+	}
+	if len(g1.Peers()) != 0 {
+		t.Fatal("banned peer should have been disconnected")
+	}
+	if err := g1.Connect(g2.Address()); err != errBlacklistedPeer {
+		t.Fatal("expected errBlacklistedPeer, got", err)
+	}
+	err := build.Retry(50, 100*time.Millisecond, func() error {
+		if len(g2.Peers()) != 0 {
+			return errors.New("banned peer is still connected")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g2.Connect(g1.Address()); err == nil {
+		t.Fatal("banned peer should not be able to connect")
+	}
+
+	// The ban survives a restart.
+	if err := g1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g1, err = New("localhost:0", false, g1.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blacklist := g1.Blacklist(); len(blacklist) != 1 || blacklist[0].Host != host {
+		t.Fatal("ban was not persisted:", blacklist)
+	}
+
+	// Removing the host from the blacklist allows connections again.
+	if err := g1.RemoveFromBlacklist([]string{host}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestAddToBlacklist checks that hosts can be blacklisted by the user.
+func TestAddToBlacklist(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	if err := g1.AddToBlacklist([]string{"not an ip"}, 0); err != errInvalidHost {
+		t.Fatal("expected errInvalidHost, got", err)
+	}
+	if err := g1.RemoveFromBlacklist([]string{"1.2.3.4"}); err == nil {
+		t.Fatal("expected an error when removing a host that is not blacklisted")
+	}
+
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	host := g2.Address().Host()
+	if err := g1.AddToBlacklist([]string{host}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if blacklist := g1.Blacklist(); len(blacklist) != 1 || blacklist[0].Expiry != 0 {
+		t.Fatal("expected a permanent entry, got", blacklist)
+	}
+	if len(g1.Peers()) != 0 {
+		t.Fatal("blacklisted peer should have been disconnected")
+	}
+
+	// Temporary entries expire.
+	if err := g1.AddToBlacklist([]string{host}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	err := build.Retry(50, 100*time.Millisecond, func() error {
+		if len(g1.Blacklist()) != 0 {
+			return errors.New("entry did not expire")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 )
 
@@ -14,7 +15,11 @@ const (
 
 	// maxEncodedSessionHeaderSize is the maximum allowed size of an encoded
 	// sessionHeader object.
-	maxEncodedSessionHeaderSize = 40 + modules.MaxEncodedNetAddressLength
+	maxEncodedSessionHeaderSize = maxEncodedLegacySessionHeaderSize + crypto.PublicKeySize + 32
+
+	// maxEncodedLegacySessionHeaderSize is the maximum size of an encoded
+	// sessionHeader object that peers without support for encryption accept.
+	maxEncodedLegacySessionHeaderSize = 40 + modules.MaxEncodedNetAddressLength
 
 	// maxLocalOutbound is currently set to 3, meaning the gateway will not
 	// consider a local node to be an outbound peer if the gateway already has
@@ -182,6 +187,24 @@ var (
 	}).(int)
 )
 
+var (
+	// banDuration defines how long a host is blacklisted after its
+	// misbehavior score reached modules.MisbehaviorBanThreshold.
+	banDuration = build.Select(build.Var{
+		Standard: 24 * time.Hour,
+		Dev:      10 * time.Minute,
+		Testing:  10 * time.Second,
+	}).(time.Duration)
+
+	// misbehaviorDecayInterval defines how long it takes for the misbehavior
+	// score of a host to decrease by one.
+	misbehaviorDecayInterval = build.Select(build.Var{
+		Standard: time.Minute,
+		Dev:      10 * time.Second,
+		Testing:  time.Second,
+	}).(time.Duration)
+)
+
 var (
 	// connStdDeadline defines the standard deadline that should be used for
 	// all temporary connections to the gateway.
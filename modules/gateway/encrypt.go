@@ -0,0 +1,256 @@
+package gateway
+
+// encrypt.go implements the encrypted transport of peer connections. Peers
+// that support it append their persistent node key and an ephemeral key to
+// the sessionHeader. If both peers sent keys, they derive a shared secret from
+// the ephemeral keys, encrypt all further traffic of the connection, and prove
+// that they own their node keys by signing the handshake. Peers that do not
+// support encryption ignore the keys, and the connection stays in plaintext.
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+const (
+	// maxFrameSize is the maximum size of the plaintext of a single frame
+	// of an encrypted connection.
+	maxFrameSize = 1 << 14
+
+	// frameHeaderSize is the size of the length prefix of a frame.
+	frameHeaderSize = 4
+)
+
+var (
+	errFrameTooLarge    = errors.New("encrypted frame exceeds the maximum size")
+	errInvalidNodeProof = errors.New("peer did not prove that it owns its node key")
+	errWeakEphemeralKey = errors.New("peer sent a weak ephemeral key")
+)
+
+// ephemeralKey is a curve25519 key that is only used for a single connection.
+type ephemeralKey [32]byte
+
+// newEphemeralKeyPair generates a new curve25519 key pair.
+func newEphemeralKeyPair() (sk, pk ephemeralKey) {
+	fastrand.Read(sk[:])
+	curve25519.ScalarBaseMult((*[32]byte)(&pk), (*[32]byte)(&sk))
+	return
+}
+
+// nodeID returns the NodeID that belongs to a node key.
+func nodeID(pk crypto.PublicKey) modules.NodeID {
+	return modules.NodeID(crypto.HashObject(pk))
+}
+
+// supportsEncryption returns true if the peer that sent the header offered an
+// encrypted connection.
+func (sh sessionHeader) supportsEncryption() bool {
+	return sh.EphemeralKey != ephemeralKey{}
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface. The keys are only
+// written if the header offers encryption.
+func (sh sessionHeader) MarshalSia(w io.Writer) error {
+	e := encoding.NewEncoder(w)
+	e.EncodeAll(sh.GenesisID, sh.UniqueID, sh.NetAddress)
+	if sh.supportsEncryption() {
+		e.EncodeAll(sh.NodeKey, sh.EphemeralKey)
+	}
+	return e.Err()
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface. Headers of
+// peers that do not support encryption end before the keys.
+func (sh *sessionHeader) UnmarshalSia(r io.Reader) error {
+	d := encoding.NewDecoder(r)
+	if err := d.DecodeAll(&sh.GenesisID, &sh.UniqueID, &sh.NetAddress); err != nil {
+		return err
+	}
+	keys := make([]byte, len(sh.NodeKey)+len(sh.EphemeralKey))
+	n, err := io.ReadFull(r, keys)
+	if n == 0 && err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return encoding.UnmarshalAll(keys, &sh.NodeKey, &sh.EphemeralKey)
+}
+
+// newSessionHeader returns the sessionHeader that the gateway sends to a peer,
+// along with the secret ephemeral key of the connection.
+func (g *Gateway) newSessionHeader(addr modules.NetAddress) (sessionHeader, ephemeralKey) {
+	sk, pk := newEphemeralKeyPair()
+	header := sessionHeader{
+		GenesisID:    types.GenesisID,
+		UniqueID:     g.staticId,
+		NetAddress:   addr,
+		NodeKey:      g.staticNodeKey.PublicKey(),
+		EphemeralKey: pk,
+	}
+	// Older peers reject headers that exceed their size limit, so the keys
+	// are only offered if they fit.
+	if uint64(len(encoding.Marshal(header))) > maxEncodedLegacySessionHeaderSize {
+		header.NodeKey, header.EphemeralKey = crypto.PublicKey{}, ephemeralKey{}
+	}
+	return header, sk
+}
+
+// staticEncryptConn performs the encryption handshake with a peer after both
+// peers accepted each other's sessionHeader. The initiator is the peer that
+// made the connection request. The returned connection is authenticated as
+// the node that sent remoteHeader.
+func (g *Gateway) staticEncryptConn(conn net.Conn, ourHeader, remoteHeader sessionHeader, ourKey ephemeralKey, initiator bool) (*encryptedConn, error) {
+	var secret [32]byte
+	curve25519.ScalarMult(&secret, (*[32]byte)(&ourKey), (*[32]byte)(&remoteHeader.EphemeralKey))
+	if secret == [32]byte{} {
+		return nil, errWeakEphemeralKey
+	}
+
+	// Derive a key for each direction from the shared secret and the
+	// handshake.
+	initiatorHeader, responderHeader := ourHeader, remoteHeader
+	ourRole, remoteRole := "initiator", "responder"
+	if !initiator {
+		initiatorHeader, responderHeader = remoteHeader, ourHeader
+		ourRole, remoteRole = remoteRole, ourRole
+	}
+	handshake := crypto.HashAll(initiatorHeader.NodeKey, initiatorHeader.EphemeralKey, responderHeader.NodeKey, responderHeader.EphemeralKey)
+	ec := newEncryptedConn(conn,
+		crypto.TwofishKey(crypto.HashAll(ourRole, secret, handshake)),
+		crypto.TwofishKey(crypto.HashAll(remoteRole, secret, handshake)),
+	)
+	ec.remoteID = nodeID(remoteHeader.NodeKey)
+
+	// Prove that we own our node key and check the proof of the peer. The
+	// proofs cover the ephemeral keys, so they cannot be replayed on another
+	// connection.
+	ourProof := crypto.SignHash(crypto.HashAll(ourRole, handshake), g.staticNodeKey)
+	if err := encoding.WriteObject(ec, ourProof); err != nil {
+		return nil, fmt.Errorf("failed to write node proof: %v", err)
+	}
+	var remoteProof crypto.Signature
+	if err := encoding.ReadObject(ec, &remoteProof, crypto.SignatureSize); err != nil {
+		return nil, fmt.Errorf("failed to read node proof: %v", err)
+	}
+	if crypto.VerifyHash(crypto.HashAll(remoteRole, handshake), remoteHeader.NodeKey, remoteProof) != nil {
+		return nil, errInvalidNodeProof
+	}
+	return ec, nil
+}
+
+// encryptedConn is a net.Conn that encrypts and authenticates its traffic.
+// Data is sent in frames that are prefixed by the length of their ciphertext
+// and encrypted with a counter nonce, so frames cannot be reordered or
+// replayed.
+type encryptedConn struct {
+	net.Conn
+	remoteID modules.NodeID
+
+	readMu    sync.Mutex
+	recvAEAD  cipher.AEAD
+	recvNonce uint64
+	recvBuf   []byte // decrypted data that was not read yet
+
+	writeMu   sync.Mutex
+	sendAEAD  cipher.AEAD
+	sendNonce uint64
+}
+
+// newEncryptedConn returns an encryptedConn that encrypts the data it sends
+// with sendKey and decrypts the data it receives with recvKey.
+func newEncryptedConn(conn net.Conn, sendKey, recvKey crypto.TwofishKey) *encryptedConn {
+	// NOTE: NewGCM only returns an error if twofishCipher.BlockSize != 16.
+	sendAEAD, _ := cipher.NewGCM(sendKey.NewCipher())
+	recvAEAD, _ := cipher.NewGCM(recvKey.NewCipher())
+	return &encryptedConn{
+		Conn:     conn,
+		recvAEAD: recvAEAD,
+		sendAEAD: sendAEAD,
+	}
+}
+
+// frameNonce returns the nonce of the frame with the given counter.
+func frameNonce(aead cipher.AEAD, counter uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.LittleEndian.PutUint64(nonce, counter)
+	return nonce
+}
+
+// wrap stacks ec on top of conn and returns the resulting connection. The
+// handshake is performed on the raw connection, but afterwards the encrypted
+// traffic should pass through the counted and rate limited connection. If ec
+// is nil, the connection is not encrypted and conn is returned.
+func (ec *encryptedConn) wrap(conn net.Conn) net.Conn {
+	if ec == nil {
+		return conn
+	}
+	ec.Conn = conn
+	return ec
+}
+
+// Read implements net.Conn.
+func (ec *encryptedConn) Read(b []byte) (int, error) {
+	ec.readMu.Lock()
+	defer ec.readMu.Unlock()
+
+	if len(ec.recvBuf) == 0 {
+		var prefix [frameHeaderSize]byte
+		if _, err := io.ReadFull(ec.Conn, prefix[:]); err != nil {
+			return 0, err
+		}
+		size := binary.LittleEndian.Uint32(prefix[:])
+		if size > maxFrameSize+uint32(ec.recvAEAD.Overhead()) {
+			return 0, errFrameTooLarge
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(ec.Conn, frame); err != nil {
+			return 0, err
+		}
+		plaintext, err := ec.recvAEAD.Open(frame[:0], frameNonce(ec.recvAEAD, ec.recvNonce), frame, nil)
+		if err != nil {
+			return 0, err
+		}
+		ec.recvNonce++
+		ec.recvBuf = plaintext
+	}
+	n := copy(b, ec.recvBuf)
+	ec.recvBuf = ec.recvBuf[n:]
+	return n, nil
+}
+
+// Write implements net.Conn.
+func (ec *encryptedConn) Write(b []byte) (int, error) {
+	ec.writeMu.Lock()
+	defer ec.writeMu.Unlock()
+
+	var written int
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > maxFrameSize {
+			chunk = chunk[:maxFrameSize]
+		}
+		frame := make([]byte, frameHeaderSize, frameHeaderSize+len(chunk)+ec.sendAEAD.Overhead())
+		frame = ec.sendAEAD.Seal(frame, frameNonce(ec.sendAEAD, ec.sendNonce), chunk, nil)
+		binary.LittleEndian.PutUint32(frame, uint32(len(frame)-frameHeaderSize))
+		if _, err := ec.Conn.Write(frame); err != nil {
+			return written, err
+		}
+		ec.sendNonce++
+		written += len(chunk)
+		b = b[len(chunk):]
+	}
+	return written, nil
+}
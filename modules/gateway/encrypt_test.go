@@ -0,0 +1,314 @@
+package gateway
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// legacySessionHeader is the sessionHeader of peers that do not support
+// encrypted connections.
+type legacySessionHeader struct {
+	GenesisID  types.BlockID
+	UniqueID   gatewayID
+	NetAddress modules.NetAddress
+}
+
+// TestSessionHeaderEncoding checks that the keys of a sessionHeader are
+// ignored by peers that do not support encryption, and that headers without
+// keys can still be decoded.
+func TestSessionHeaderEncoding(t *testing.T) {
+	sk, _ := crypto.GenerateKeyPair()
+	_, ephemeral := newEphemeralKeyPair()
+	header := sessionHeader{
+		GenesisID:    types.GenesisID,
+		UniqueID:     gatewayID{1, 2, 3},
+		NetAddress:   "123.123.123.123:9981",
+		NodeKey:      sk.PublicKey(),
+		EphemeralKey: ephemeral,
+	}
+	b := encoding.Marshal(header)
+	if uint64(len(b)) > maxEncodedLegacySessionHeaderSize {
+		t.Fatal("header exceeds the size limit of older peers:", len(b))
+	}
+	var decoded sessionHeader
+	if err := encoding.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	} else if decoded != header {
+		t.Fatal("header did not survive encoding:", decoded, header)
+	}
+	var legacy legacySessionHeader
+	if err := encoding.Unmarshal(b, &legacy); err != nil {
+		t.Fatal(err)
+	} else if legacy.NetAddress != header.NetAddress {
+		t.Fatal("older peers decode the wrong address:", legacy.NetAddress)
+	}
+
+	// Headers without keys do not offer encryption.
+	b = encoding.Marshal(legacySessionHeader{
+		GenesisID:  header.GenesisID,
+		UniqueID:   header.UniqueID,
+		NetAddress: header.NetAddress,
+	})
+	decoded = sessionHeader{}
+	if err := encoding.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	} else if decoded.supportsEncryption() || decoded.NetAddress != header.NetAddress {
+		t.Fatal("header of an older peer was decoded incorrectly:", decoded)
+	}
+	header.NodeKey, header.EphemeralKey = crypto.PublicKey{}, ephemeralKey{}
+	if len(encoding.Marshal(header)) != len(b) {
+		t.Fatal("header without keys should be encoded like the header of older peers")
+	}
+
+	// Truncated keys are rejected.
+	b = encoding.Marshal(legacySessionHeader{})
+	b = append(b, 1, 2, 3)
+	if err := encoding.Unmarshal(b, &decoded); err == nil {
+		t.Fatal("expected an error when decoding truncated keys")
+	}
+}
+
+// TestEncryptedConn checks that data sent over an encryptedConn arrives
+// intact and that tampered frames are rejected.
+func TestEncryptedConn(t *testing.T) {
+	key1, key2 := crypto.GenerateTwofishKey(), crypto.GenerateTwofishKey()
+
+	// Send data that spans multiple frames.
+	c1, c2 := net.Pipe()
+	sender, receiver := newEncryptedConn(c1, key1, key2), newEncryptedConn(c2, key2, key1)
+	data := fastrand.Bytes(3*maxFrameSize + 100)
+	go func() {
+		encoding.WriteObject(sender, data)
+	}()
+	var received []byte
+	if err := encoding.ReadObject(receiver, &received, uint64(len(data))+8); err != nil {
+		t.Fatal(err)
+	} else if string(received) != string(data) {
+		t.Fatal("data did not survive encryption")
+	}
+
+	// A receiver with the wrong key cannot decrypt the data.
+	c1, c2 = net.Pipe()
+	sender, receiver = newEncryptedConn(c1, key1, key2), newEncryptedConn(c2, key2, key2)
+	go sender.Write([]byte("foo"))
+	if _, err := receiver.Read(make([]byte, 3)); err == nil {
+		t.Fatal("expected an error when decrypting with the wrong key")
+	}
+
+	// Tampered frames are rejected.
+	c1, c2 = net.Pipe()
+	receiver = newEncryptedConn(c2, key2, key1)
+	go func() {
+		var buf []byte
+		sender := newEncryptedConn(&recordConn{Conn: c1, buf: &buf}, key1, key2)
+		sender.Write([]byte("foo"))
+		buf[len(buf)-1] ^= 1
+		c1.Write(buf)
+	}()
+	if _, err := receiver.Read(make([]byte, 3)); err == nil {
+		t.Fatal("expected an error when decrypting a tampered frame")
+	}
+}
+
+// recordConn is a net.Conn that records the data written to it instead of
+// sending it.
+type recordConn struct {
+	net.Conn
+	buf *[]byte
+}
+
+// Write implements net.Conn.
+func (rc *recordConn) Write(b []byte) (int, error) {
+	*rc.buf = append(*rc.buf, b...)
+	return len(b), nil
+}
+
+// TestEncryptedPeers checks that two gateways encrypt their connection in
+// both directions and learn each other's NodeID.
+func TestEncryptedPeers(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+	if g1.ID() == g2.ID() {
+		t.Fatal("gateways should have different ids")
+	}
+
+	received := make(chan string, 1)
+	rpc := func(conn modules.PeerConn) error {
+		var s string
+		err := encoding.ReadObject(conn, &s, 100)
+		received <- s
+		return err
+	}
+	g1.RegisterRPC("Foo", rpc)
+	g2.RegisterRPC("Foo", rpc)
+
+	for _, gs := range [][2]*Gateway{{g1, g2}, {g2, g1}} {
+		dialer, listener := gs[0], gs[1]
+		if err := dialer.Connect(listener.Address()); err != nil {
+			t.Fatal(err)
+		}
+		err := build.Retry(50, 100*time.Millisecond, func() error {
+			for _, pair := range [][2]*Gateway{{dialer, listener}, {listener, dialer}} {
+				peers := pair[0].Peers()
+				if len(peers) != 1 {
+					return errors.New("gateways are not connected")
+				} else if !peers[0].Encrypted || peers[0].NodeID != pair[1].ID() {
+					return errors.New("connection is not encrypted")
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// RPCs work over the encrypted connection.
+		err = dialer.RPC(listener.Address(), "Foo", func(conn modules.PeerConn) error {
+			return encoding.WriteObject(conn, "bar")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := <-received; s != "bar" {
+			t.Fatal("RPC received wrong data:", s)
+		}
+
+		if err := dialer.Disconnect(listener.Address()); err != nil {
+			t.Fatal(err)
+		}
+		err = build.Retry(50, 100*time.Millisecond, func() error {
+			if len(listener.Peers()) != 0 {
+				return errors.New("peer is still connected")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestLegacyPeers checks that the gateway still connects to peers that do
+// not support encryption, in both directions.
+func TestLegacyPeers(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+
+	legacyHeader := func(conn net.Conn) sessionHeader {
+		return sessionHeader{
+			GenesisID:  types.GenesisID,
+			UniqueID:   gatewayID{1},
+			NetAddress: modules.NetAddress(conn.LocalAddr().String()),
+		}
+	}
+	// readLegacyHeader reads the header of g the way older peers do.
+	readLegacyHeader := func(conn net.Conn) error {
+		var remoteHeader legacySessionHeader
+		if err := encoding.ReadObject(conn, &remoteHeader, maxEncodedLegacySessionHeaderSize); err != nil {
+			return err
+		}
+		return encoding.WriteObject(conn, modules.AcceptResponse)
+	}
+
+	// A legacy peer connects to the gateway.
+	conn, err := net.Dial("tcp", string(g.Address()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := connectVersionHandshake(conn, build.Version); err != nil {
+		t.Fatal(err)
+	} else if err := exchangeOurHeader(conn, legacyHeader(conn)); err != nil {
+		t.Fatal(err)
+	} else if err := readLegacyHeader(conn); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		peers := g.Peers()
+		if len(peers) != 1 {
+			return errors.New("legacy peer was not accepted")
+		} else if peers[0].Encrypted {
+			return errors.New("connection to legacy peer should not be encrypted")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The gateway connects to a legacy peer.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	errChan := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			errChan <- err
+			return
+		}
+		if _, err := acceptVersionHandshake(conn, build.Version); err != nil {
+			errChan <- err
+			return
+		}
+		if err := readLegacyHeader(conn); err != nil {
+			errChan <- err
+			return
+		}
+		errChan <- exchangeOurHeader(conn, legacyHeader(conn))
+	}()
+	addr := modules.NetAddress(listener.Addr().String())
+	if err := g.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range g.Peers() {
+		if p.NetAddress == addr && p.Encrypted {
+			t.Fatal("connection to legacy peer should not be encrypted")
+		}
+	}
+}
+
+// TestNodeIDPersist checks that the NodeID of the gateway survives a restart.
+func TestNodeIDPersist(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	id := g.ID()
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g, err := New("localhost:0", false, g.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	if g.ID() != id {
+		t.Fatal("NodeID changed after restart:", g.ID(), id)
+	}
+}
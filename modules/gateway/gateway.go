@@ -74,9 +74,6 @@ import (
 // peers of the same IP address, it should favor kicking peers of the same ip
 // address range.
 //
-// TODO: There is no public key exchange, so communications cannot be
-// effectively encrypted or authenticated.
-//
 // TODO: Gateway hostname discovery currently has significant centralization,
 // namely the fallback is a single third-party website that can easily form any
 // response it wants. Instead, multiple TLS-protected third party websites
@@ -89,11 +86,16 @@ import (
 // hostname, which means they will not be able to dial you back, which means
 // they will not add you to their node list.
 //
-// TODO: The gateway should encrypt and authenticate all communications. Though
-// the gateway participates in a flood network, practical attacks have been
-// demonstrated which have been able to confuse nodes by manipulating messages
-// from their peers. Encryption + authentication would have made the attack
-// more difficult.
+// The gateway encrypts and authenticates its connections to peers that
+// support it. Though the gateway participates in a flood network, practical
+// attacks have been demonstrated which have been able to confuse nodes by
+// manipulating messages from their peers. Encryption + authentication make
+// these attacks more difficult. Each gateway has a persistent node key, and
+// peers prove that they own their keys during the handshake, so a peer can be
+// recognized by its NodeID.
+//
+// TODO: Connections to peers that do not support encryption are still
+// accepted in plaintext.
 
 import (
 	"errors"
@@ -103,10 +105,12 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
 	siasync "github.com/NebulousLabs/Sia/sync"
 	"github.com/NebulousLabs/fastrand"
+	"github.com/NebulousLabs/ratelimit"
 )
 
 var (
@@ -145,6 +149,24 @@ type Gateway struct {
 	peers  map[modules.NetAddress]*peer
 	peerTG siasync.ThreadGroup
 
+	// blacklist contains the hosts that the gateway refuses to connect to.
+	//
+	// misbehavior contains the misbehavior scores of hosts that are not
+	// blacklisted yet.
+	blacklist   map[string]modules.BlacklistEntry
+	misbehavior map[string]misbehavior
+
+	// Bandwidth accounting and rate limiting. All peer connections share
+	// the rate limiter rl. The counters count the traffic of all peers, of
+	// each peer and of each RPC.
+	maxDownloadSpeed int64
+	maxUploadSpeed   int64
+	peerBandwidth    map[modules.NetAddress]*bandwidthCounter
+	rl               *ratelimit.RateLimit
+	rpcBandwidth     map[string]*bandwidthCounter
+	rpcNames         map[rpcID]string
+	totalBandwidth   bandwidthCounter
+
 	// Utilities.
 	log        *persist.Logger
 	mu         sync.RWMutex
@@ -153,6 +175,10 @@ type Gateway struct {
 
 	// Unique ID
 	staticId gatewayID
+
+	// staticNodeKey is the persistent key that identifies the gateway to
+	// peers with encrypted connections.
+	staticNodeKey crypto.SecretKey
 }
 
 type gatewayID [8]byte
@@ -176,6 +202,12 @@ func (g *Gateway) Address() modules.NetAddress {
 	return g.myAddr
 }
 
+// ID returns the NodeID of the Gateway, which is derived from its persistent
+// node key.
+func (g *Gateway) ID() modules.NodeID {
+	return nodeID(g.staticNodeKey.PublicKey())
+}
+
 // Close saves the state of the Gateway and stops its listener process.
 func (g *Gateway) Close() error {
 	if err := g.threads.Stop(); err != nil {
@@ -201,6 +233,14 @@ func New(addr string, bootstrap bool, persistDir string) (*Gateway, error) {
 		nodes: make(map[modules.NetAddress]*node),
 		peers: make(map[modules.NetAddress]*peer),
 
+		blacklist:   make(map[string]modules.BlacklistEntry),
+		misbehavior: make(map[string]misbehavior),
+
+		peerBandwidth: make(map[modules.NetAddress]*bandwidthCounter),
+		rl:            ratelimit.NewRateLimit(0, 0, 0),
+		rpcBandwidth:  make(map[string]*bandwidthCounter),
+		rpcNames:      make(map[rpcID]string),
+
 		persistDir: persistDir,
 	}
 
@@ -247,6 +287,11 @@ func New(addr string, bootstrap bool, persistDir string) (*Gateway, error) {
 	if loadErr := g.load(); loadErr != nil && !os.IsNotExist(loadErr) {
 		return nil, loadErr
 	}
+	// Generate the node key if the gateway is new or was created by an older
+	// version.
+	if g.staticNodeKey == (crypto.SecretKey{}) {
+		g.staticNodeKey, _ = crypto.GenerateKeyPair()
+	}
 	// Spawn the thread to periodically save the gateway.
 	go g.threadedSaveLoop()
 	// Make sure that the gateway saves after shutdown.
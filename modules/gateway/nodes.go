@@ -36,6 +36,8 @@ func (g *Gateway) addNode(addr modules.NetAddress) error {
 		return errors.New("address is not valid: " + string(addr))
 	} else if net.ParseIP(addr.Host()) == nil {
 		return errors.New("address must be an IP address: " + string(addr))
+	} else if g.isBlacklisted(addr.Host()) {
+		return errBlacklistedPeer
 	}
 	g.nodes[addr] = &node{
 		NetAddress:      addr,
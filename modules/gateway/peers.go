@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
@@ -46,6 +47,12 @@ type sessionHeader struct {
 	GenesisID  types.BlockID
 	UniqueID   gatewayID
 	NetAddress modules.NetAddress
+
+	// NodeKey and EphemeralKey are only sent by peers that support encrypted
+	// connections. They are appended to the encoded header, where older
+	// peers ignore them.
+	NodeKey      crypto.PublicKey
+	EphemeralKey ephemeralKey
 }
 
 func (p *peer) open() (modules.PeerConn, error) {
@@ -146,6 +153,15 @@ func (g *Gateway) threadedAcceptConn(conn net.Conn) {
 	addr := modules.NetAddress(conn.RemoteAddr().String())
 	g.log.Debugf("INFO: %v wants to connect", addr)
 
+	g.mu.RLock()
+	blacklisted := g.isBlacklisted(addr.Host())
+	g.mu.RUnlock()
+	if blacklisted {
+		g.log.Debugf("INFO: rejected connection from blacklisted peer %v", addr)
+		conn.Close()
+		return
+	}
+
 	remoteVersion, err := acceptVersionHandshake(conn, build.Version)
 	if err != nil {
 		g.log.Debugf("INFO: %v wanted to connect but version handshake failed: %v", addr, err)
@@ -190,20 +206,28 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 	g.log.Debugln("Sending sessionHeader with address", g.myAddr, g.myAddr.IsLocal())
 	// Perform header handshake.
 	g.mu.RLock()
-	ourHeader := sessionHeader{
-		GenesisID:  types.GenesisID,
-		UniqueID:   g.staticId,
-		NetAddress: g.myAddr,
-	}
+	ourHeader, ourKey := g.newSessionHeader(g.myAddr)
 	g.mu.RUnlock()
 
 	remoteHeader, err := exchangeRemoteHeader(conn, ourHeader)
 	if err != nil {
 		return err
 	}
+	// There is no point in offering encryption to a peer that does not
+	// support it.
+	if !remoteHeader.supportsEncryption() {
+		ourHeader.NodeKey, ourHeader.EphemeralKey = crypto.PublicKey{}, ephemeralKey{}
+	}
 	if err := exchangeOurHeader(conn, ourHeader); err != nil {
 		return err
 	}
+	var ec *encryptedConn
+	if ourHeader.supportsEncryption() && remoteHeader.supportsEncryption() {
+		ec, err = g.staticEncryptConn(conn, ourHeader, remoteHeader, ourKey, false)
+		if err != nil {
+			return err
+		}
+	}
 
 	// Get the remote address on which the connecting peer is listening on.
 	// This means we need to combine the incoming connections ip address with
@@ -224,7 +248,10 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 			NetAddress: remoteAddr,
 			Version:    remoteVersion,
 		},
-		sess: newServerStream(conn, remoteVersion),
+		sess: newServerStream(ec.wrap(g.managedCountPeerConn(conn, remoteAddr)), remoteVersion),
+	}
+	if ec != nil {
+		peer.Encrypted, peer.NodeID = true, ec.remoteID
 	}
 	g.mu.Lock()
 	g.acceptPeer(peer)
@@ -380,25 +407,27 @@ func exchangeRemoteHeader(conn net.Conn, ourHeader sessionHeader) (sessionHeader
 	return remoteHeader, nil
 }
 
-// managedConnectPeer connects to peers >= v1.3.1. The peer is added as a
-// node and a peer. The peer is only added if a nil error is returned.
-func (g *Gateway) managedConnectPeer(conn net.Conn, remoteVersion string, remoteAddr modules.NetAddress) error {
+// managedConnectPeer performs the header handshake with peers >= v1.3.1. If
+// both peers support encryption, the returned encryptedConn must be used for
+// all further traffic. Otherwise it is nil.
+func (g *Gateway) managedConnectPeer(conn net.Conn, remoteVersion string, remoteAddr modules.NetAddress) (*encryptedConn, error) {
 	g.log.Debugln("Sending sessionHeader with address", g.myAddr, g.myAddr.IsLocal())
 	// Perform header handshake.
 	g.mu.RLock()
-	ourHeader := sessionHeader{
-		GenesisID:  types.GenesisID,
-		UniqueID:   g.staticId,
-		NetAddress: g.myAddr,
-	}
+	ourHeader, ourKey := g.newSessionHeader(g.myAddr)
 	g.mu.RUnlock()
 
 	if err := exchangeOurHeader(conn, ourHeader); err != nil {
-		return err
-	} else if _, err := exchangeRemoteHeader(conn, ourHeader); err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	remoteHeader, err := exchangeRemoteHeader(conn, ourHeader)
+	if err != nil {
+		return nil, err
+	}
+	if !ourHeader.supportsEncryption() || !remoteHeader.supportsEncryption() {
+		return nil, nil
+	}
+	return g.staticEncryptConn(conn, ourHeader, remoteHeader, ourKey, true)
 }
 
 // managedConnect establishes a persistent connection to a peer, and adds it to
@@ -419,9 +448,12 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 	}
 	g.mu.RLock()
 	_, exists := g.peers[addr]
+	blacklisted := g.isBlacklisted(addr.Host())
 	g.mu.RUnlock()
 	if exists {
 		return errPeerExists
+	} else if blacklisted {
+		return errBlacklistedPeer
 	}
 
 	// Dial the peer and perform peer initialization.
@@ -437,8 +469,9 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 		return err
 	}
 
+	var ec *encryptedConn
 	if build.VersionCmp(remoteVersion, minimumAcceptablePeerVersion) >= 0 {
-		err = g.managedConnectPeer(conn, remoteVersion, addr)
+		ec, err = g.managedConnectPeer(conn, remoteVersion, addr)
 	} else {
 		err = errors.New("version number is below threshold")
 	}
@@ -450,22 +483,29 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 	// Connection successful, clear the timeout as to maintain a persistent
 	// connection to this peer.
 	conn.SetDeadline(time.Time{})
-
-	// Add the peer.
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	g.addPeer(&peer{
+	streamConn := ec.wrap(g.managedCountPeerConn(conn, addr))
+	p := &peer{
 		Peer: modules.Peer{
 			Inbound:    false,
 			Local:      addr.IsLocal(),
 			NetAddress: addr,
 			Version:    remoteVersion,
 		},
-		sess: newClientStream(conn, remoteVersion),
-	})
+		sess: newClientStream(streamConn, remoteVersion),
+	}
+	if ec != nil {
+		p.Encrypted, p.NodeID = true, ec.remoteID
+	}
+
+	// Add the peer.
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.addPeer(p)
 	g.addNode(addr)
-	g.nodes[addr].WasOutboundPeer = true
+	if n, exists := g.nodes[addr]; exists {
+		n.WasOutboundPeer = true
+	}
 
 	if err := g.saveSync(); err != nil {
 		g.log.Println("ERROR: Unable to save new outbound peer to gateway:", err)
@@ -521,8 +561,13 @@ func (g *Gateway) Peers() []modules.Peer {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 	var peers []modules.Peer
-	for _, p := range g.peers {
-		peers = append(peers, p.Peer)
+	for addr, p := range g.peers {
+		peer := p.Peer
+		if bc, exists := g.peerBandwidth[addr]; exists {
+			counter := bc.load()
+			peer.Upload, peer.Download = counter.Upload, counter.Download
+		}
+		peers = append(peers, peer)
 	}
 	return peers
 }
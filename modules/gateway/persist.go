@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
 )
@@ -20,27 +21,74 @@ const (
 // gateway persist file.
 var persistMetadata = persist.Metadata{
 	Header:  "Sia Node List",
-	Version: "1.3.0",
+	Version: "1.3.3",
+}
+
+// persistence contains the data of the Gateway that is saved to disk.
+type persistence struct {
+	Nodes     []*node                  `json:"nodes"`
+	Blacklist []modules.BlacklistEntry `json:"blacklist"`
+	NodeKey   crypto.SecretKey         `json:"nodekey"`
+
+	MaxDownloadSpeed int64                                   `json:"maxdownloadspeed"`
+	MaxUploadSpeed   int64                                   `json:"maxuploadspeed"`
+	Bandwidth        bandwidthCounter                        `json:"bandwidth"`
+	PeerBandwidth    map[modules.NetAddress]bandwidthCounter `json:"peerbandwidth"`
+	RPCBandwidth     map[string]bandwidthCounter             `json:"rpcbandwidth"`
 }
 
 // persistData returns the data in the Gateway that will be saved to disk.
-func (g *Gateway) persistData() (nodes []*node) {
+// Expired bans are not saved, and neither are the bandwidth counters of peers
+// that are no longer known.
+func (g *Gateway) persistData() (p persistence) {
 	for _, node := range g.nodes {
-		nodes = append(nodes, node)
+		p.Nodes = append(p.Nodes, node)
+	}
+	p.Blacklist = g.blacklistEntries()
+	p.NodeKey = g.staticNodeKey
+
+	p.MaxDownloadSpeed = g.maxDownloadSpeed
+	p.MaxUploadSpeed = g.maxUploadSpeed
+	p.Bandwidth = g.totalBandwidth.load()
+	p.PeerBandwidth = make(map[modules.NetAddress]bandwidthCounter)
+	for addr, bc := range g.peerBandwidth {
+		_, isNode := g.nodes[addr]
+		_, isPeer := g.peers[addr]
+		if isNode || isPeer {
+			p.PeerBandwidth[addr] = bc.load()
+		}
+	}
+	p.RPCBandwidth = make(map[string]bandwidthCounter)
+	for name, bc := range g.rpcBandwidth {
+		p.RPCBandwidth[name] = bc.load()
 	}
 	return
 }
 
 // load loads the Gateway's persistent data from disk.
 func (g *Gateway) load() error {
-	var nodes []*node
-	err := persist.LoadJSON(persistMetadata, &nodes, filepath.Join(g.persistDir, nodesFile))
+	var p persistence
+	err := persist.LoadJSON(persistMetadata, &p, filepath.Join(g.persistDir, nodesFile))
 	if err != nil {
-		// COMPATv1.3.0
-		return g.loadv033persist()
+		// COMPATv1.3.3
+		return g.loadv130persist()
 	}
-	for i := range nodes {
-		g.nodes[nodes[i].NetAddress] = nodes[i]
+	for i := range p.Nodes {
+		g.nodes[p.Nodes[i].NetAddress] = p.Nodes[i]
+	}
+	for _, entry := range p.Blacklist {
+		g.blacklist[entry.Host] = entry
+	}
+	g.staticNodeKey = p.NodeKey
+	g.setRateLimits(p.MaxDownloadSpeed, p.MaxUploadSpeed)
+	g.totalBandwidth = p.Bandwidth
+	for addr, bc := range p.PeerBandwidth {
+		bc := bc
+		g.peerBandwidth[addr] = &bc
+	}
+	for name, bc := range p.RPCBandwidth {
+		bc := bc
+		g.rpcBandwidth[name] = &bc
 	}
 	return nil
 }
@@ -77,6 +125,24 @@ func (g *Gateway) threadedSaveLoop() {
 	}
 }
 
+// loadv130persist loads the v1.3.0 Gateway's persistent data from disk. The
+// v1.3.0 persist file only contains the node list.
+func (g *Gateway) loadv130persist() error {
+	var nodes []*node
+	err := persist.LoadJSON(persist.Metadata{
+		Header:  "Sia Node List",
+		Version: "1.3.0",
+	}, &nodes, filepath.Join(g.persistDir, nodesFile))
+	if err != nil {
+		// COMPATv1.3.0
+		return g.loadv033persist()
+	}
+	for i := range nodes {
+		g.nodes[nodes[i].NetAddress] = nodes[i]
+	}
+	return nil
+}
+
 // loadv033persist loads the v0.3.3 Gateway's persistent data from disk.
 func (g *Gateway) loadv033persist() error {
 	var nodes []modules.NetAddress
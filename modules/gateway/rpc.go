@@ -63,7 +63,7 @@ func (g *Gateway) managedRPC(addr modules.NetAddress, name string, fn modules.RP
 	}
 	conn.SetDeadline(time.Time{})
 	// call fn
-	return fn(conn)
+	return fn(g.managedCountRPCConn(conn, name))
 }
 
 // RPC calls an RPC on the given address. RPC cannot be called on an address
@@ -88,6 +88,7 @@ func (g *Gateway) RegisterRPC(name string, fn modules.RPCFunc) {
 		build.Critical("RPC already registered: " + name)
 	}
 	g.handlers[handlerName(name)] = fn
+	g.rpcNames[handlerName(name)] = name
 }
 
 // UnregisterRPC unregisters an RPC and removes the corresponding RPCFunc from
@@ -99,6 +100,7 @@ func (g *Gateway) UnregisterRPC(name string) {
 		build.Critical("RPC not registered: " + name)
 	}
 	delete(g.handlers, handlerName(name))
+	delete(g.rpcNames, handlerName(name))
 }
 
 // RegisterConnectCall registers a name and RPCFunc to be called on a peer
@@ -209,15 +211,18 @@ func (g *Gateway) threadedHandleConn(conn modules.PeerConn) {
 	// call registered handler for this ID
 	g.mu.RLock()
 	fn, ok := g.handlers[id]
+	name := g.rpcNames[id]
 	g.mu.RUnlock()
 	if !ok {
+		// Peers running a newer version may call RPCs that this node does not
+		// know about, so the stream is closed without scoring the peer.
 		g.log.Debugf("WARN: incoming conn %v requested unknown RPC \"%v\"", conn.RPCAddr(), id)
 		return
 	}
 	g.log.Debugf("INFO: incoming conn %v requested RPC \"%v\"", conn.RPCAddr(), id)
 
 	// call fn
-	err = fn(conn)
+	err = fn(g.managedCountRPCConn(conn, name))
 	// don't log benign errors
 	if err == modules.ErrDuplicateTransactionSet || err == modules.ErrBlockKnown {
 		err = nil
@@ -46,6 +46,35 @@ var (
 	HostWorkingStatusWorking = HostWorkingStatus("working")
 )
 
+var (
+	// HostLedgerContractFormed is recorded when a storage obligation is added
+	// to the host. The amount is the contract cost paid by the renter.
+	HostLedgerContractFormed = HostLedgerEventType("contractformed")
+
+	// HostLedgerRevisionIncome is recorded when a file contract revision
+	// increases the potential revenue of a storage obligation. The amount is
+	// the increase in potential revenue.
+	HostLedgerRevisionIncome = HostLedgerEventType("revisionincome")
+
+	// HostLedgerProofSubmitted is recorded when the storage proof of an
+	// obligation is confirmed on the blockchain. The amount is the valid proof
+	// output that the proof unlocks for the host. The entry is removed if the
+	// block containing the proof is reverted.
+	HostLedgerProofSubmitted = HostLedgerEventType("proofsubmitted")
+
+	// HostLedgerPayoutReceived is recorded when a storage obligation
+	// completes successfully. The amount is the revenue earned by the host.
+	HostLedgerPayoutReceived = HostLedgerEventType("payoutreceived")
+
+	// HostLedgerCollateralLost is recorded when a storage obligation fails.
+	// The amount is the collateral that the host has lost.
+	HostLedgerCollateralLost = HostLedgerEventType("collaterallost")
+
+	// HostLedgerTransactionFees is recorded when the host pays a miner fee on
+	// behalf of a storage obligation. The amount is the fee paid.
+	HostLedgerTransactionFees = HostLedgerEventType("transactionfees")
+)
+
 type (
 	// HostFinancialMetrics provides financial statistics for the host,
 	// including money that is locked in contracts. Though verbose, these
@@ -94,6 +123,130 @@ type (
 		MinDownloadBandwidthPrice types.Currency `json:"mindownloadbandwidthprice"`
 		MinStoragePrice           types.Currency `json:"minstorageprice"`
 		MinUploadBandwidthPrice   types.Currency `json:"minuploadbandwidthprice"`
+
+		// Network limits of the host. The download and upload speeds are in
+		// bytes per second and limit the data received and sent by the host
+		// across all renter connections combined. The connection limits cap
+		// the number of concurrent connections in total and per IP address.
+		// A value of zero means that the resource is not limited.
+		MaxConnections      uint64 `json:"maxconnections"`
+		MaxConnectionsPerIP uint64 `json:"maxconnectionsperip"`
+		MaxDownloadSpeed    int64  `json:"maxdownloadspeed"`
+		MaxUploadSpeed      int64  `json:"maxuploadspeed"`
+
+		// Renewal policy of the host. MaxRenterCollateral is the total
+		// collateral that the host will lock in the contracts of a single
+		// renter, MinRenewFileSize is the amount of data a contract needs to
+		// store to be renewed, and MaxRenewExtension is the number of blocks
+		// by which a renewal may extend the end of the renewed contract.
+		// Renewals that violate the policy are declined. A value of zero
+		// disables the check.
+		MaxRenterCollateral types.Currency    `json:"maxrentercollateral"`
+		MinRenewFileSize    uint64            `json:"minrenewfilesize"`
+		MaxRenewExtension   types.BlockHeight `json:"maxrenewextension"`
+	}
+
+	// HostLedgerEventType identifies the kind of financial event that a
+	// HostLedgerEntry records.
+	HostLedgerEventType string
+
+	// HostLedgerEntry is a single revenue or collateral event in the host's
+	// ledger. Unlike the running totals in HostFinancialMetrics, every entry
+	// is tied to the storage obligation and block height that caused it, which
+	// allows the ledger to be reconciled against the blockchain.
+	HostLedgerEntry struct {
+		Type         HostLedgerEventType  `json:"type"`
+		ObligationID types.FileContractID `json:"obligationid"`
+		BlockHeight  types.BlockHeight    `json:"blockheight"`
+		Amount       types.Currency       `json:"amount"`
+	}
+
+	// HostLedgerSummary totals the host's ledger entries over a range of
+	// block heights. Both StartHeight and EndHeight are inclusive.
+	HostLedgerSummary struct {
+		StartHeight types.BlockHeight `json:"startheight"`
+		EndHeight   types.BlockHeight `json:"endheight"`
+
+		ContractsFormed uint64 `json:"contractsformed"`
+		ProofsSubmitted uint64 `json:"proofssubmitted"`
+
+		ContractCompensation types.Currency `json:"contractcompensation"`
+		RevisionIncome       types.Currency `json:"revisionincome"`
+		ProofPayouts         types.Currency `json:"proofpayouts"`
+		PayoutsReceived      types.Currency `json:"payoutsreceived"`
+		CollateralLost       types.Currency `json:"collaterallost"`
+		TransactionFees      types.Currency `json:"transactionfees"`
+	}
+
+	// HostRenterQuota limits the resources that a single renter may use on the
+	// host. A value of zero means that the resource is not limited.
+	HostRenterQuota struct {
+		// MaxStorage is the number of bytes that the renter may store across
+		// all of its active contracts.
+		MaxStorage uint64 `json:"maxstorage"`
+
+		// MaxBandwidth is the number of bytes that the renter may upload and
+		// download combined during a single bandwidth period.
+		// BandwidthPeriod is the length of that period in blocks, a period of
+		// zero uses the host's default period.
+		MaxBandwidth    uint64            `json:"maxbandwidth"`
+		BandwidthPeriod types.BlockHeight `json:"bandwidthperiod"`
+
+		// MaxConnections is the number of RPCs that the renter may have open
+		// with the host at the same time.
+		MaxConnections uint64 `json:"maxconnections"`
+	}
+
+	// HostRenter contains the quota and resource usage of a renter that has
+	// formed contracts with the host. Renters are identified by the public key
+	// that they use in the unlock conditions of their file contracts.
+	HostRenter struct {
+		PublicKey types.SiaPublicKey `json:"publickey"`
+		Quota     HostRenterQuota    `json:"quota"`
+
+		// ContractCount is the number of unresolved storage obligations held
+		// for the renter, and StorageUsed is the amount of data stored in
+		// those obligations. Data carried over by a renewal is counted
+		// against both contracts until the old contract expires.
+		ContractCount uint64 `json:"contractcount"`
+		StorageUsed   uint64 `json:"storageused"`
+
+		// LockedCollateral is the collateral that the host has locked in the
+		// unresolved storage obligations of the renter.
+		LockedCollateral types.Currency `json:"lockedcollateral"`
+
+		// Bandwidth used in the bandwidth period that started at PeriodStart.
+		PeriodStart       types.BlockHeight `json:"periodstart"`
+		UploadBandwidth   uint64            `json:"uploadbandwidth"`
+		DownloadBandwidth uint64            `json:"downloadbandwidth"`
+
+		// Connections is the number of RPCs that the renter currently has open
+		// with the host. The value is not persistent.
+		Connections uint64 `json:"connections"`
+	}
+
+	// HostDowntimeWindow is a range of block heights during which none of the
+	// host's storage obligations need the host to be online. Start is
+	// inclusive and End is exclusive. An End of zero means that no storage
+	// obligation needs the host after Start.
+	HostDowntimeWindow struct {
+		Start types.BlockHeight `json:"start"`
+		End   types.BlockHeight `json:"end"`
+	}
+
+	// HostMaintenanceStatus reports the maintenance state of the host. While
+	// in maintenance mode the host refuses new contracts, renewals and
+	// uploads, but keeps serving downloads and submitting storage proofs.
+	HostMaintenanceStatus struct {
+		Enabled        bool              `json:"enabled"`
+		EnabledHeight  types.BlockHeight `json:"enabledheight"`
+		AnnounceOnExit bool              `json:"announceonexit"`
+
+		// ActiveObligations is the number of storage obligations that still
+		// need the host to submit a revision or a storage proof, and
+		// NextDowntime is the next window in which none of them do.
+		ActiveObligations uint64             `json:"activeobligations"`
+		NextDowntime      HostDowntimeWindow `json:"nextdowntime"`
 	}
 
 	// HostNetworkMetrics reports the quantity of each type of RPC call that
@@ -106,6 +259,13 @@ type (
 		ReviseCalls       uint64 `json:"revisecalls"`
 		SettingsCalls     uint64 `json:"settingscalls"`
 		UnrecognizedCalls uint64 `json:"unrecognizedcalls"`
+
+		// Decisions made by the renewal policy of the host. Declined
+		// renewals are counted per reason.
+		RenewalsAccepted           uint64 `json:"renewalsaccepted"`
+		RenewalsDeclinedCollateral uint64 `json:"renewalsdeclinedcollateral"`
+		RenewalsDeclinedExtension  uint64 `json:"renewalsdeclinedextension"`
+		RenewalsDeclinedFileSize   uint64 `json:"renewalsdeclinedfilesize"`
 	}
 
 	// StorageObligation contains information about a storage obligation that
@@ -169,6 +329,20 @@ type (
 		// potentially private or sensitive information.
 		InternalSettings() HostInternalSettings
 
+		// Ledger returns the ledger entries recorded between the start and
+		// end heights, inclusive, ordered by block height.
+		Ledger(start, end types.BlockHeight) ([]HostLedgerEntry, error)
+
+		// LedgerSummary totals the ledger entries recorded between the start
+		// and end heights, inclusive, in consecutive periods of the provided
+		// number of blocks.
+		LedgerSummary(start, end, period types.BlockHeight) ([]HostLedgerSummary, error)
+
+		// MaintenanceStatus returns the maintenance state of the host and the
+		// next window of at least the provided number of blocks in which the
+		// host can safely go offline.
+		MaintenanceStatus(minDuration types.BlockHeight) (HostMaintenanceStatus, error)
+
 		// NetworkMetrics returns information on the types of RPC calls that
 		// have been made to the host.
 		NetworkMetrics() HostNetworkMetrics
@@ -176,9 +350,22 @@ type (
 		// PublicKey returns the public key of the host.
 		PublicKey() types.SiaPublicKey
 
+		// Renters returns the quota and resource usage of every renter known
+		// to the host.
+		Renters() ([]HostRenter, error)
+
 		// SetInternalSettings sets the hosting parameters of the host.
 		SetInternalSettings(HostInternalSettings) error
 
+		// SetMaintenance enables or disables maintenance mode. If
+		// announceOnExit is set when enabling maintenance mode, the host
+		// re-announces itself when maintenance mode is disabled.
+		SetMaintenance(enabled, announceOnExit bool) error
+
+		// SetRenterQuota sets the quota of the renter with the provided public
+		// key. The renter does not need to be known to the host yet.
+		SetRenterQuota(types.SiaPublicKey, HostRenterQuota) error
+
 		// StorageObligations returns the set of storage obligations held by
 		// the host.
 		StorageObligations() []StorageObligation
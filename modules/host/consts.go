@@ -114,6 +114,15 @@ var (
 	// with a number like 65 MiB.
 	defaultMaxReviseBatchSize = 17 * (1 << 20)
 
+	// defaultRenterBandwidthPeriod is the length of the bandwidth period used
+	// for renter quotas that do not specify a period. The bandwidth used by a
+	// renter is reset at the start of every period.
+	defaultRenterBandwidthPeriod = build.Select(build.Var{
+		Dev:      types.BlockHeight(144),  // 14.4 minutes.
+		Standard: types.BlockHeight(4320), // 1 month.
+		Testing:  types.BlockHeight(10),
+	}).(types.BlockHeight)
+
 	// defaultStoragePrice defines the starting price for hosts selling
 	// storage. We try to match a number that is both reasonably profitable and
 	// reasonably competitive.
@@ -234,6 +243,18 @@ var (
 	// using the id.
 	bucketActionItems = []byte("BucketActionItems")
 
+	// bucketLedger contains the host's revenue and collateral ledger. Each
+	// key is the big endian block height of the entry followed by a big endian
+	// sequence number, so that bolt keeps the entries sorted by height and
+	// then by insertion order. The values are json encoded
+	// 'modules.HostLedgerEntry' objects.
+	bucketLedger = []byte("BucketLedger")
+
+	// bucketRenters contains the quota and resource usage of every renter
+	// known to the host. The keys are the string form of the renter's public
+	// key and the values are json encoded 'modules.HostRenter' objects.
+	bucketRenters = []byte("BucketRenters")
+
 	// bucketStorageObligations contains a set of serialized
 	// 'storageObligations' sorted by their file contract id.
 	bucketStorageObligations = []byte("BucketStorageObligations")
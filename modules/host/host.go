@@ -77,6 +77,8 @@ import (
 	"github.com/NebulousLabs/Sia/persist"
 	siasync "github.com/NebulousLabs/Sia/sync"
 	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/ratelimit"
 )
 
 const (
@@ -103,6 +105,10 @@ var (
 	errNilTpool  = errors.New("host cannot use a nil transaction pool")
 	errNilWallet = errors.New("host cannot use a nil wallet")
 
+	// errPrunedSubscription is returned if the consensus set has pruned blocks
+	// that the host has not processed yet.
+	errPrunedSubscription = errors.New("host cannot subscribe to the consensus set because it has pruned blocks that the host has not processed")
+
 	// persistMetadata is the header that gets written to the persist file, and is
 	// used to recognize other persist files.
 	persistMetadata = persist.Metadata{
@@ -124,6 +130,12 @@ type Host struct {
 	atomicSettingsCalls     uint64
 	atomicUnrecognizedCalls uint64
 
+	// Renewal policy metrics. These values are not persistent.
+	atomicRenewalsAccepted           uint64
+	atomicRenewalsDeclinedCollateral uint64
+	atomicRenewalsDeclinedExtension  uint64
+	atomicRenewalsDeclinedFileSize   uint64
+
 	// Error management. There are a few different types of errors returned by
 	// the host. These errors intentionally not persistent, so that the logging
 	// limits of each error type will be reset each time the host is reset.
@@ -165,6 +177,24 @@ type Host struct {
 	// be locked separately.
 	lockedStorageObligations map[types.FileContractID]*siasync.TryMutex
 
+	// connections counts the connections that are currently open with the
+	// host, in total and per IP address. rl is shared by all connections and
+	// limits their combined bandwidth.
+	connections      uint64
+	connectionsPerIP map[string]uint64
+	rl               *ratelimit.RateLimit
+
+	// maintenance is set while the host is in maintenance mode, which it
+	// entered at maintenanceHeight. If maintenanceAnnounce is set, the host
+	// re-announces itself when maintenance mode is disabled.
+	maintenance         bool
+	maintenanceAnnounce bool
+	maintenanceHeight   types.BlockHeight
+
+	// renterConnections counts the RPCs that each renter currently has open
+	// with the host, keyed by the string form of the renter's public key.
+	renterConnections map[string]uint64
+
 	// Utilities.
 	db         *persist.BoltDatabase
 	listener   net.Listener
@@ -234,6 +264,9 @@ func newHost(dependencies modules.Dependencies, cs modules.ConsensusSet, tpool m
 		dependencies: dependencies,
 
 		lockedStorageObligations: make(map[types.FileContractID]*siasync.TryMutex),
+		connectionsPerIP:         make(map[string]uint64),
+		renterConnections:        make(map[string]uint64),
+		rl:                       ratelimit.NewRateLimit(0, 0, 0),
 
 		persistDir: persistDir,
 	}
@@ -287,6 +320,7 @@ func newHost(dependencies modules.Dependencies, cs modules.ConsensusSet, tpool m
 	if err != nil {
 		return nil, err
 	}
+	h.setRateLimits(h.settings.MaxDownloadSpeed, h.settings.MaxUploadSpeed)
 	h.tg.AfterStop(func() {
 		err = h.saveSync()
 		if err != nil {
@@ -387,6 +421,10 @@ func (h *Host) SetInternalSettings(settings modules.HostInternalSettings) error
 		}
 	}
 
+	if settings.MaxDownloadSpeed < 0 || settings.MaxUploadSpeed < 0 {
+		return errors.New("internal settings not updated, download/upload rate limit can't be below 0")
+	}
+
 	if settings.NetAddress != "" {
 		err := settings.NetAddress.IsValid()
 		if err != nil {
@@ -403,6 +441,7 @@ func (h *Host) SetInternalSettings(settings modules.HostInternalSettings) error
 
 	h.settings = settings
 	h.revisionNumber++
+	h.setRateLimits(settings.MaxDownloadSpeed, settings.MaxUploadSpeed)
 
 	err = h.saveSync()
 	if err != nil {
@@ -0,0 +1,184 @@
+package host
+
+// ledger.go keeps a persistent record of every revenue and collateral event
+// of the host. The financial metrics of the host are running totals which
+// cannot be reconciled against the blockchain after the fact, the ledger
+// records the storage obligation, block height and amount of every event so
+// that operators can audit where the money of the host went.
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// errLedgerInvalidPeriod is returned when a ledger summary is requested
+	// with a period of zero blocks.
+	errLedgerInvalidPeriod = errors.New("ledger summary period must be at least one block")
+
+	// errLedgerInvalidRange is returned when a ledger query has a start
+	// height that is greater than the end height.
+	errLedgerInvalidRange = errors.New("ledger start height must not be greater than the end height")
+)
+
+// ledgerKey returns the database key of a ledger entry at the provided height
+// with the provided sequence number.
+func ledgerKey(height types.BlockHeight, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(height))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+// recordLedgerEntry appends an entry to the ledger in the database tx. Entries
+// with a zero amount are only recorded for events that are meaningful without
+// an amount.
+func recordLedgerEntry(tx *bolt.Tx, entry modules.HostLedgerEntry) error {
+	if entry.Amount.IsZero() && entry.Type != modules.HostLedgerContractFormed && entry.Type != modules.HostLedgerProofSubmitted {
+		return nil
+	}
+	b := tx.Bucket(bucketLedger)
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return b.Put(ledgerKey(entry.BlockHeight, seq), entryBytes)
+}
+
+// removeLedgerEntries removes all ledger entries at the provided height that
+// match the event type and storage obligation. It is used to undo entries
+// that were created by blocks that have since been reverted.
+func removeLedgerEntries(tx *bolt.Tx, height types.BlockHeight, t modules.HostLedgerEventType, soid types.FileContractID) error {
+	var staleKeys [][]byte
+	c := tx.Bucket(bucketLedger).Cursor()
+	prefix := ledgerKey(height, 0)[:8]
+	for k, v := c.Seek(prefix); k != nil && string(k[:8]) == string(prefix); k, v = c.Next() {
+		var entry modules.HostLedgerEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return err
+		}
+		if entry.Type == t && entry.ObligationID == soid {
+			staleKeys = append(staleKeys, append([]byte(nil), k...))
+		}
+	}
+	for _, k := range staleKeys {
+		if err := tx.Bucket(bucketLedger).Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ledgerEntries returns all of the ledger entries between the start and end
+// heights, inclusive.
+func (h *Host) ledgerEntries(start, end types.BlockHeight) (entries []modules.HostLedgerEntry, err error) {
+	if start > end {
+		return nil, errLedgerInvalidRange
+	}
+	err = h.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketLedger).Cursor()
+		for k, v := c.Seek(ledgerKey(start, 0)); k != nil; k, v = c.Next() {
+			if types.BlockHeight(binary.BigEndian.Uint64(k[:8])) > end {
+				break
+			}
+			var entry modules.HostLedgerEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// summarizeLedger totals the provided entries into consecutive periods of
+// 'period' blocks, starting at 'start' and ending at 'end'. Entries outside of
+// the range are ignored.
+func summarizeLedger(entries []modules.HostLedgerEntry, start, end, period types.BlockHeight) []modules.HostLedgerSummary {
+	var summaries []modules.HostLedgerSummary
+	for periodStart := start; periodStart <= end; periodStart += period {
+		periodEnd := periodStart + period - 1
+		if periodEnd > end || periodEnd < periodStart {
+			periodEnd = end
+		}
+		summaries = append(summaries, modules.HostLedgerSummary{
+			StartHeight: periodStart,
+			EndHeight:   periodEnd,
+		})
+		// Stop explicitly on the final period, a large period could otherwise
+		// overflow the next start height.
+		if periodEnd == end {
+			break
+		}
+	}
+	for _, entry := range entries {
+		if entry.BlockHeight < start || entry.BlockHeight > end {
+			continue
+		}
+		s := &summaries[(entry.BlockHeight-start)/period]
+		switch entry.Type {
+		case modules.HostLedgerContractFormed:
+			s.ContractsFormed++
+			s.ContractCompensation = s.ContractCompensation.Add(entry.Amount)
+		case modules.HostLedgerRevisionIncome:
+			s.RevisionIncome = s.RevisionIncome.Add(entry.Amount)
+		case modules.HostLedgerProofSubmitted:
+			s.ProofsSubmitted++
+			s.ProofPayouts = s.ProofPayouts.Add(entry.Amount)
+		case modules.HostLedgerPayoutReceived:
+			s.PayoutsReceived = s.PayoutsReceived.Add(entry.Amount)
+		case modules.HostLedgerCollateralLost:
+			s.CollateralLost = s.CollateralLost.Add(entry.Amount)
+		case modules.HostLedgerTransactionFees:
+			s.TransactionFees = s.TransactionFees.Add(entry.Amount)
+		}
+	}
+	return summaries
+}
+
+// Ledger returns the ledger entries recorded between the start and end
+// heights, inclusive, ordered by block height.
+func (h *Host) Ledger(start, end types.BlockHeight) ([]modules.HostLedgerEntry, error) {
+	if err := h.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer h.tg.Done()
+	return h.ledgerEntries(start, end)
+}
+
+// LedgerSummary totals the ledger entries recorded between the start and end
+// heights, inclusive, in consecutive periods of the provided number of
+// blocks. The end height is capped at the current block height of the host.
+func (h *Host) LedgerSummary(start, end, period types.BlockHeight) ([]modules.HostLedgerSummary, error) {
+	if err := h.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer h.tg.Done()
+	if period == 0 {
+		return nil, errLedgerInvalidPeriod
+	}
+	h.mu.RLock()
+	if end > h.blockHeight {
+		end = h.blockHeight
+	}
+	h.mu.RUnlock()
+	if start > end {
+		return nil, nil
+	}
+	entries, err := h.ledgerEntries(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeLedger(entries, start, end, period), nil
+}
@@ -0,0 +1,182 @@
+package host
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// TestSummarizeLedger checks that ledger entries are totalled into the
+// correct periods.
+func TestSummarizeLedger(t *testing.T) {
+	t.Parallel()
+	entries := []modules.HostLedgerEntry{
+		{Type: modules.HostLedgerContractFormed, BlockHeight: 10, Amount: types.NewCurrency64(5)},
+		{Type: modules.HostLedgerRevisionIncome, BlockHeight: 14, Amount: types.NewCurrency64(7)},
+		{Type: modules.HostLedgerTransactionFees, BlockHeight: 15, Amount: types.NewCurrency64(2)},
+		{Type: modules.HostLedgerProofSubmitted, BlockHeight: 21, Amount: types.NewCurrency64(30)},
+		{Type: modules.HostLedgerPayoutReceived, BlockHeight: 24, Amount: types.NewCurrency64(12)},
+		{Type: modules.HostLedgerCollateralLost, BlockHeight: 40, Amount: types.NewCurrency64(100)},
+	}
+	summaries := summarizeLedger(entries, 10, 24, 5)
+	if len(summaries) != 3 {
+		t.Fatal("expected 3 periods, got", len(summaries))
+	}
+	if summaries[0].StartHeight != 10 || summaries[0].EndHeight != 14 || summaries[2].StartHeight != 20 || summaries[2].EndHeight != 24 {
+		t.Fatal("periods have the wrong boundaries:", summaries)
+	}
+	if summaries[0].ContractsFormed != 1 || !summaries[0].ContractCompensation.Equals64(5) || !summaries[0].RevisionIncome.Equals64(7) {
+		t.Error("first period totalled incorrectly:", summaries[0])
+	}
+	if !summaries[1].TransactionFees.Equals64(2) {
+		t.Error("second period totalled incorrectly:", summaries[1])
+	}
+	if summaries[2].ProofsSubmitted != 1 || !summaries[2].ProofPayouts.Equals64(30) || !summaries[2].PayoutsReceived.Equals64(12) {
+		t.Error("third period totalled incorrectly:", summaries[2])
+	}
+	for _, s := range summaries {
+		if !s.CollateralLost.IsZero() {
+			t.Error("entry outside of the range was included in a summary")
+		}
+	}
+
+	// A period larger than the range should produce a single summary.
+	summaries = summarizeLedger(entries, 5, 30, types.BlockHeight(1<<63))
+	if len(summaries) != 1 || summaries[0].EndHeight != 30 {
+		t.Fatal("large period did not produce a single summary:", summaries)
+	}
+}
+
+// TestLedgerRemoveEntries checks that entries can be removed from the ledger
+// without affecting other entries at the same height.
+func TestLedgerRemoveEntries(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := blankHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	soid := types.FileContractID{1}
+	err = ht.host.db.Update(func(tx *bolt.Tx) error {
+		for _, e := range []modules.HostLedgerEntry{
+			{Type: modules.HostLedgerProofSubmitted, ObligationID: soid, BlockHeight: 3, Amount: types.NewCurrency64(1)},
+			{Type: modules.HostLedgerProofSubmitted, ObligationID: types.FileContractID{2}, BlockHeight: 3, Amount: types.NewCurrency64(1)},
+			{Type: modules.HostLedgerTransactionFees, ObligationID: soid, BlockHeight: 3, Amount: types.NewCurrency64(1)},
+			{Type: modules.HostLedgerProofSubmitted, ObligationID: soid, BlockHeight: 4, Amount: types.NewCurrency64(1)},
+		} {
+			if err := recordLedgerEntry(tx, e); err != nil {
+				return err
+			}
+		}
+		return removeLedgerEntries(tx, 3, modules.HostLedgerProofSubmitted, soid)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ht.host.Ledger(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatal("expected 3 remaining entries, got", len(entries))
+	}
+	for _, e := range entries {
+		if e.Type == modules.HostLedgerProofSubmitted && e.ObligationID == soid && e.BlockHeight == 3 {
+			t.Error("entry was not removed from the ledger")
+		}
+	}
+
+	// Zero value fees should not be recorded.
+	err = ht.host.db.Update(func(tx *bolt.Tx) error {
+		return recordLedgerEntry(tx, modules.HostLedgerEntry{Type: modules.HostLedgerTransactionFees, BlockHeight: 5})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ = ht.host.Ledger(5, 5); len(entries) != 0 {
+		t.Error("zero value fee was recorded in the ledger")
+	}
+}
+
+// TestLedgerStorageObligation checks that the lifecycle of a storage
+// obligation is recorded in the ledger.
+func TestLedgerStorageObligation(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	// Add a storage obligation with some contract compensation.
+	so, err := ht.newTesterStorageObligation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	so.ContractCost = types.SiacoinPrecision
+	ht.host.managedLockStorageObligation(so.id())
+	err = ht.host.managedAddStorageObligation(so)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.host.managedUnlockStorageObligation(so.id())
+
+	// Add a sector to the obligation, increasing the potential revenue.
+	sectorRoot, sectorData := randSector()
+	so.SectorRoots = []crypto.Hash{sectorRoot}
+	sectorCost := types.SiacoinPrecision.Mul64(550)
+	so.PotentialStorageRevenue = so.PotentialStorageRevenue.Add(sectorCost)
+	so.RiskedCollateral = types.SiacoinPrecision.Mul64(3)
+	ht.host.managedLockStorageObligation(so.id())
+	ht.host.mu.Lock()
+	err = ht.host.modifyStorageObligation(so, nil, []crypto.Hash{sectorRoot}, [][]byte{sectorData})
+	ht.host.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.host.managedUnlockStorageObligation(so.id())
+
+	entries, err := ht.host.Ledger(0, ht.host.blockHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatal("expected 2 ledger entries, got", len(entries))
+	}
+	if entries[0].Type != modules.HostLedgerContractFormed || entries[0].ObligationID != so.id() || !entries[0].Amount.Equals(so.ContractCost) {
+		t.Error("contract formation was not recorded correctly:", entries[0])
+	}
+	if entries[1].Type != modules.HostLedgerRevisionIncome || !entries[1].Amount.Equals(sectorCost) {
+		t.Error("revision income was not recorded correctly:", entries[1])
+	}
+
+	// Remove the obligation as failed, the risked collateral should show up
+	// as lost in the ledger.
+	ht.host.mu.Lock()
+	err = ht.host.removeStorageObligation(so, obligationFailed)
+	ht.host.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	summaries, err := ht.host.LedgerSummary(0, ht.host.blockHeight, ht.host.blockHeight+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 1 {
+		t.Fatal("expected a single summary, got", len(summaries))
+	}
+	if summaries[0].ContractsFormed != 1 || !summaries[0].CollateralLost.Equals(so.RiskedCollateral) || !summaries[0].RevisionIncome.Equals(sectorCost) {
+		t.Error("ledger summary is incorrect:", summaries[0])
+	}
+}
@@ -0,0 +1,157 @@
+package host
+
+// maintenance.go implements maintenance mode, which allows a host operator to
+// take the host offline without losing collateral. While in maintenance mode
+// the host refuses new contracts, renewals and uploads, but keeps serving
+// downloads and submitting revisions and storage proofs for its existing
+// obligations. The host also computes the next window in which none of its
+// obligations need it to be online, so that the operator knows when it is
+// safe to shut the host down.
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// errMaintenance is returned if a renter tries to form or renew a
+	// contract, or to upload data, while the host is in maintenance mode.
+	errMaintenance = ErrorCommunication("host is in maintenance mode and is not accepting new contracts or uploads")
+)
+
+// busyRange is a range of block heights, inclusive on both ends, during which
+// a storage obligation needs the host to be online.
+type busyRange struct {
+	start types.BlockHeight
+	end   types.BlockHeight
+}
+
+// obligationBusyRange returns the range of heights during which the storage
+// obligation needs the host to be online, and false if the obligation does
+// not need the host anymore. The host has to submit the final revision before
+// the proof window opens, and the storage proof before the proof deadline.
+func obligationBusyRange(so storageObligation, height types.BlockHeight) (busyRange, bool) {
+	if so.ObligationStatus != obligationUnresolved || so.ProofConfirmed || so.proofDeadline() < height {
+		return busyRange{}, false
+	}
+	start := so.expiration()
+	if !so.RevisionConfirmed {
+		if start > revisionSubmissionBuffer {
+			start -= revisionSubmissionBuffer
+		} else {
+			start = 0
+		}
+	}
+	return busyRange{start: start, end: so.proofDeadline()}, true
+}
+
+// nextDowntimeWindow returns the first window starting at or after the
+// provided height that is at least minDuration blocks long and does not
+// overlap any of the busy ranges.
+func nextDowntimeWindow(busy []busyRange, height, minDuration types.BlockHeight) modules.HostDowntimeWindow {
+	if minDuration == 0 {
+		minDuration = 1
+	}
+	sort.Slice(busy, func(i, j int) bool {
+		return busy[i].start < busy[j].start
+	})
+	start := height
+	for _, br := range busy {
+		if br.end < start {
+			continue
+		}
+		if br.start >= start+minDuration {
+			return modules.HostDowntimeWindow{Start: start, End: br.start}
+		}
+		if br.end+1 > start {
+			start = br.end + 1
+		}
+	}
+	return modules.HostDowntimeWindow{Start: start}
+}
+
+// MaintenanceStatus returns the maintenance state of the host and the next
+// window of at least minDuration blocks in which the host can go offline
+// without missing a revision or a storage proof.
+func (h *Host) MaintenanceStatus(minDuration types.BlockHeight) (modules.HostMaintenanceStatus, error) {
+	if err := h.tg.Add(); err != nil {
+		return modules.HostMaintenanceStatus{}, err
+	}
+	defer h.tg.Done()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var busy []busyRange
+	err := h.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketStorageObligations).ForEach(func(_, soBytes []byte) error {
+			var so storageObligation
+			err := json.Unmarshal(soBytes, &so)
+			if err != nil {
+				return build.ExtendErr("unable to unmarshal storage obligation:", err)
+			}
+			if br, ok := obligationBusyRange(so, h.blockHeight); ok {
+				busy = append(busy, br)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return modules.HostMaintenanceStatus{}, err
+	}
+	return modules.HostMaintenanceStatus{
+		Enabled:        h.maintenance,
+		EnabledHeight:  h.maintenanceHeight,
+		AnnounceOnExit: h.maintenanceAnnounce,
+
+		ActiveObligations: uint64(len(busy)),
+		NextDowntime:      nextDowntimeWindow(busy, h.blockHeight, minDuration),
+	}, nil
+}
+
+// SetMaintenance enables or disables maintenance mode. Enabling maintenance
+// mode while it is already enabled only updates announceOnExit. If
+// announceOnExit was set, disabling maintenance mode makes a new host
+// announcement.
+func (h *Host) SetMaintenance(enabled, announceOnExit bool) error {
+	if err := h.tg.Add(); err != nil {
+		return err
+	}
+	defer h.tg.Done()
+
+	h.mu.Lock()
+	if !enabled && !h.maintenance {
+		h.mu.Unlock()
+		return nil
+	}
+	announce := !enabled && h.maintenanceAnnounce
+	if enabled && !h.maintenance {
+		h.maintenanceHeight = h.blockHeight
+		h.log.Println("INFO: entering maintenance mode at height", h.blockHeight)
+	} else if !enabled {
+		h.maintenanceHeight = 0
+		h.log.Println("INFO: leaving maintenance mode at height", h.blockHeight)
+	}
+	h.maintenance = enabled
+	h.maintenanceAnnounce = enabled && announceOnExit
+	h.revisionNumber++
+	err := h.saveSync()
+	h.mu.Unlock()
+	if err != nil {
+		return errors.New("maintenance mode updated, but failed saving to disk: " + err.Error())
+	}
+
+	if announce {
+		err = h.Announce()
+		if err != nil {
+			return build.ExtendErr("maintenance mode disabled, but the host could not re-announce", err)
+		}
+	}
+	return nil
+}
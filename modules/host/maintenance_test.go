@@ -0,0 +1,142 @@
+package host
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestNextDowntimeWindow probes the computation of the next window in which
+// the host can safely go offline.
+func TestNextDowntimeWindow(t *testing.T) {
+	t.Parallel()
+	busy := []busyRange{
+		{start: 30, end: 40},
+		{start: 12, end: 20},
+		{start: 22, end: 25},
+		{start: 1, end: 5},
+	}
+	tests := []struct {
+		height, minDuration types.BlockHeight
+		window              modules.HostDowntimeWindow
+	}{
+		{0, 1, modules.HostDowntimeWindow{Start: 0, End: 1}},
+		{0, 2, modules.HostDowntimeWindow{Start: 6, End: 12}},
+		{6, 0, modules.HostDowntimeWindow{Start: 6, End: 12}},
+		{6, 6, modules.HostDowntimeWindow{Start: 6, End: 12}},
+		{6, 7, modules.HostDowntimeWindow{Start: 41}},
+		{13, 1, modules.HostDowntimeWindow{Start: 21, End: 22}},
+		{15, 3, modules.HostDowntimeWindow{Start: 26, End: 30}},
+		{15, 5, modules.HostDowntimeWindow{Start: 41}},
+		{50, 100, modules.HostDowntimeWindow{Start: 50}},
+	}
+	for _, test := range tests {
+		window := nextDowntimeWindow(busy, test.height, test.minDuration)
+		if window != test.window {
+			t.Errorf("height %v, duration %v: expected %v, got %v", test.height, test.minDuration, test.window, window)
+		}
+	}
+	if window := nextDowntimeWindow(nil, 7, 10); window != (modules.HostDowntimeWindow{Start: 7}) {
+		t.Error("host without obligations should be able to go offline immediately:", window)
+	}
+}
+
+// TestMaintenanceMode checks that the host refuses new contracts while in
+// maintenance mode, that the mode persists across restarts, and that the
+// host reports the next safe downtime window.
+func TestMaintenanceMode(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	settings := ht.host.InternalSettings()
+	settings.AcceptingContracts = true
+	err = ht.host.SetInternalSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ht.host.SetMaintenance(true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ht.host.ExternalSettings().AcceptingContracts {
+		t.Fatal("host is advertising that it accepts contracts while in maintenance mode")
+	}
+
+	// Add a storage obligation, the host should report the window before
+	// the obligation needs a revision as the next downtime window.
+	so, err := ht.newTesterStorageObligation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.host.managedLockStorageObligation(so.id())
+	err = ht.host.managedAddStorageObligation(so)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.host.managedUnlockStorageObligation(so.id())
+	status, err := ht.host.MaintenanceStatus(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	height := ht.cs.Height()
+	if !status.Enabled || !status.AnnounceOnExit || status.EnabledHeight != height || status.ActiveObligations != 1 {
+		t.Fatal("unexpected maintenance status:", status)
+	}
+	if status.NextDowntime.Start != height || status.NextDowntime.End != so.expiration()-revisionSubmissionBuffer {
+		t.Fatal("unexpected downtime window:", status.NextDowntime)
+	}
+	status, err = ht.host.MaintenanceStatus(revisionSubmissionBuffer * 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.NextDowntime.Start != so.proofDeadline()+1 || status.NextDowntime.End != 0 {
+		t.Fatal("unexpected downtime window:", status.NextDowntime)
+	}
+
+	// Maintenance mode should survive a restart.
+	err = ht.host.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.host, err = New(ht.cs, ht.tpool, ht.wallet, "localhost:0", filepath.Join(ht.persistDir, modules.HostDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err = ht.host.MaintenanceStatus(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Enabled || !status.AnnounceOnExit {
+		t.Fatal("maintenance mode was not persisted:", status)
+	}
+
+	// Leaving maintenance mode should re-announce the host and accept
+	// contracts again.
+	txnCount := len(ht.tpool.TransactionList())
+	err = ht.host.SetMaintenance(false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ht.tpool.TransactionList()) <= txnCount {
+		t.Fatal("host did not re-announce when leaving maintenance mode")
+	}
+	if !ht.host.ExternalSettings().AcceptingContracts {
+		t.Fatal("host is not accepting contracts after leaving maintenance mode")
+	}
+	status, err = ht.host.MaintenanceStatus(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Enabled || status.AnnounceOnExit {
+		t.Fatal("maintenance mode was not disabled:", status)
+	}
+}
@@ -65,11 +65,12 @@ func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) er
 	// Verify that the request is acceptable, and then fetch all of the data
 	// for the renter.
 	existingRevision := so.RevisionTransactionSet[len(so.RevisionTransactionSet)-1].FileContractRevisions[0]
+	renterKey, _ := so.renterKey()
 	var payload [][]byte
+	var totalSize uint64
 	err = func() error {
 		// Check that the length of each file is in-bounds, and that the total
 		// size being requested is acceptable.
-		var totalSize uint64
 		for _, request := range requests {
 			if request.Length > modules.SectorSize || request.Offset+request.Length > modules.SectorSize {
 				return extendErr("download iteration request failed: ", errRequestOutOfBounds)
@@ -79,6 +80,10 @@ func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) er
 		if totalSize > settings.MaxDownloadBatchSize {
 			return extendErr("download iteration batch failed: ", errLargeDownloadBatch)
 		}
+		err = h.managedCheckRenterBandwidth(renterKey, totalSize)
+		if err != nil {
+			return extendErr("download iteration batch failed: ", err)
+		}
 
 		// Verify that the correct amount of money has been moved from the
 		// renter's contract funds to the host's contract funds.
@@ -129,6 +134,10 @@ func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) er
 	if err != nil {
 		return extendErr("failed to modify storage obligation: ", ErrorInternal(modules.WriteNegotiationRejection(conn, err).Error()))
 	}
+	err = h.managedRecordRenterBandwidth(renterKey, 0, totalSize)
+	if err != nil {
+		h.log.Println("WARN: could not record renter bandwidth:", err)
+	}
 
 	// Write acceptance to the renter - the data request can be fulfilled by
 	// the host, the payment is satisfactory, signature is correct. Then send
@@ -257,9 +266,11 @@ func (h *Host) managedRPCDownload(conn net.Conn) error {
 		return extendErr("failed RPCRecentRevision during RPCDownload: ", err)
 	}
 	// The storage obligation is returned with a lock on it. Defer a call to
-	// unlock the storage obligation.
+	// unlock the storage obligation and to unregister the renter connection.
+	renterKey, _ := so.renterKey()
 	defer func() {
 		h.managedUnlockStorageObligation(so.id())
+		h.managedDisconnectRenter(renterKey)
 	}()
 
 	// Perform a loop that will allow downloads to happen until the maximum
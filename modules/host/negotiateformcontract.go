@@ -109,6 +109,16 @@ func (h *Host) managedRPCFormContract(conn net.Conn) error {
 		return extendErr("could not read renter public key: ", ErrorConnection(err.Error()))
 	}
 
+	// Register the connection with the renter, refusing the renter if it
+	// already has too many connections open.
+	renterKey := types.Ed25519PublicKey(renterPK)
+	err = h.managedConnectRenter(renterKey)
+	if err != nil {
+		modules.WriteNegotiationRejection(conn, err) // Error ignored to preserve type in extendErr
+		return extendErr("renter connection refused: ", err)
+	}
+	defer h.managedDisconnectRenter(renterKey)
+
 	// The host verifies that the file contract coming over the wire is
 	// acceptable.
 	err = h.managedVerifyNewContract(txnSet, renterPK, settings)
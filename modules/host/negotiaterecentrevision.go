@@ -96,7 +96,9 @@ func (h *Host) managedVerifyChallengeResponse(fcid types.FileContractID, challen
 // revision, including signatures, to the renter, for the file contract with
 // the id given by the renter.
 //
-// The storage obligation is returned under a storage obligation lock.
+// The storage obligation is returned under a storage obligation lock, and the
+// connection is registered with the renter of the obligation. The caller must
+// call managedDisconnectRenter when the connection is finished.
 func (h *Host) managedRPCRecentRevision(conn net.Conn) (types.FileContractID, storageObligation, error) {
 	// Set the negotiation deadline.
 	conn.SetDeadline(time.Now().Add(modules.NegotiateRecentRevisionTime))
@@ -139,6 +141,20 @@ func (h *Host) managedRPCRecentRevision(conn net.Conn) (types.FileContractID, st
 		}
 	}()
 
+	// Register the connection with the renter, refusing the renter if it
+	// already has too many connections open.
+	renterKey, _ := so.renterKey()
+	err = h.managedConnectRenter(renterKey)
+	if err != nil {
+		modules.WriteNegotiationRejection(conn, err) // Error ignored to preserve type in extendErr
+		return types.FileContractID{}, storageObligation{}, extendErr("renter connection refused: ", err)
+	}
+	defer func() {
+		if err != nil {
+			h.managedDisconnectRenter(renterKey)
+		}
+	}()
+
 	// Send the file contract revision and the corresponding signatures to the
 	// renter.
 	err = modules.WriteNegotiationAcceptance(conn)
@@ -2,19 +2,37 @@ package host
 
 import (
 	"errors"
+	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
 )
 
 var (
 	// errRenewDoesNotExtend is returned if a file contract renewal is
 	// presented which does not extend the existing file contract.
 	errRenewDoesNotExtend = errors.New("file contract renewal does not extend the existing file contract")
+
+	// errRenewPolicyCollateral is returned if a renewal is declined because
+	// the host would lock more collateral for the renter than its renewal
+	// policy allows.
+	errRenewPolicyCollateral = ErrorCommunication("renewal declined: the host's collateral limit for the renter would be exceeded")
+
+	// errRenewPolicyExtension is returned if a renewal is declined because it
+	// extends the contract further than the renewal policy of the host
+	// allows.
+	errRenewPolicyExtension = ErrorCommunication("renewal declined: the renewal extends the contract further than the host allows")
+
+	// errRenewPolicyFileSize is returned if a renewal is declined because the
+	// contract stores less data than the renewal policy of the host requires.
+	errRenewPolicyFileSize = ErrorCommunication("renewal declined: the contract stores less data than the host requires for a renewal")
 )
 
 // renewBaseCollateral returns the base collateral on the storage in the file
@@ -44,6 +62,53 @@ func renewContractCollateral(so storageObligation, settings modules.HostExternal
 	return fc.ValidProofOutputs[1].Value.Sub(settings.ContractPrice).Sub(renewBasePrice(so, settings, fc))
 }
 
+// managedCheckRenewPolicy checks the renewal of the storage obligation against
+// the renewal policy in the host's internal settings. The decision and its
+// reason are written to the host log and counted in the network metrics.
+func (h *Host) managedCheckRenewPolicy(so storageObligation, settings modules.HostExternalSettings, fc types.FileContract) error {
+	renterKey, _ := so.renterKey()
+	var renter modules.HostRenter
+	h.mu.RLock()
+	policy := h.settings
+	err := h.db.View(func(tx *bolt.Tx) error {
+		var err error
+		renter, err = getRenter(tx, renterKey)
+		return err
+	})
+	h.mu.RUnlock()
+	if err != nil {
+		return extendErr("could not load renter: ", ErrorInternal(err.Error()))
+	}
+
+	var extension types.BlockHeight
+	if fc.WindowEnd > so.proofDeadline() {
+		extension = fc.WindowEnd - so.proofDeadline()
+	}
+	renterCollateral := renter.LockedCollateral.Add(renewContractCollateral(so, settings, fc))
+
+	var reason string
+	switch {
+	case policy.MinRenewFileSize != 0 && fc.FileSize < policy.MinRenewFileSize:
+		atomic.AddUint64(&h.atomicRenewalsDeclinedFileSize, 1)
+		err = errRenewPolicyFileSize
+		reason = fmt.Sprintf("file size %v is below the minimum of %v", fc.FileSize, policy.MinRenewFileSize)
+	case policy.MaxRenewExtension != 0 && extension > policy.MaxRenewExtension:
+		atomic.AddUint64(&h.atomicRenewalsDeclinedExtension, 1)
+		err = errRenewPolicyExtension
+		reason = fmt.Sprintf("extension of %v blocks is above the maximum of %v", extension, policy.MaxRenewExtension)
+	case !policy.MaxRenterCollateral.IsZero() && renterCollateral.Cmp(policy.MaxRenterCollateral) > 0:
+		atomic.AddUint64(&h.atomicRenewalsDeclinedCollateral, 1)
+		err = errRenewPolicyCollateral
+		reason = fmt.Sprintf("renter collateral of %v is above the maximum of %v", renterCollateral, policy.MaxRenterCollateral)
+	default:
+		atomic.AddUint64(&h.atomicRenewalsAccepted, 1)
+		h.log.Printf("INFO: renewal policy accepted renewal of contract %v for renter %v: file size %v, extension of %v blocks, renter collateral %v", so.id(), renterKey, fc.FileSize, extension, renterCollateral)
+		return nil
+	}
+	h.log.Printf("INFO: renewal policy declined renewal of contract %v for renter %v: %v", so.id(), renterKey, reason)
+	return err
+}
+
 // managedAddRenewCollateral adds the host's collateral to the renewed file
 // contract.
 func (h *Host) managedAddRenewCollateral(so storageObligation, settings modules.HostExternalSettings, txnSet []types.Transaction) (builder modules.TransactionBuilder, newParents []types.Transaction, newInputs []types.SiacoinInput, newOutputs []types.SiacoinOutput, err error) {
@@ -85,9 +150,11 @@ func (h *Host) managedRPCRenewContract(conn net.Conn) error {
 		return extendErr("failed RPCRecentRevision during RPCRenewContract: ", err)
 	}
 	// The storage obligation is received with a lock. Defer a call to unlock
-	// the storage obligation.
+	// the storage obligation and to unregister the renter connection.
+	renterKey, _ := so.renterKey()
 	defer func() {
 		h.managedUnlockStorageObligation(so.id())
+		h.managedDisconnectRenter(renterKey)
 	}()
 
 	// Perform the host settings exchange with the renter.
@@ -123,14 +190,27 @@ func (h *Host) managedRPCRenewContract(conn net.Conn) error {
 
 	h.mu.Lock()
 	settings := h.externalSettings()
+	maintenance := h.maintenance
 	h.mu.Unlock()
 
+	// Renewals form a new contract, which the host does not accept while in
+	// maintenance mode.
+	if maintenance {
+		modules.WriteNegotiationRejection(conn, errMaintenance) // Error is ignored to preserve type for extendErr
+		return extendErr("renewal refused: ", errMaintenance)
+	}
+
 	// Verify that the transaction coming over the wire is a proper renewal.
 	err = h.managedVerifyRenewedContract(so, txnSet, renterPK)
 	if err != nil {
 		modules.WriteNegotiationRejection(conn, err) // Error is ignored to preserve type for extendErr
 		return extendErr("verification of renewal failed: ", err)
 	}
+	err = h.managedCheckRenewPolicy(so, settings, txnSet[len(txnSet)-1].FileContracts[0])
+	if err != nil {
+		modules.WriteNegotiationRejection(conn, err) // Error is ignored to preserve type for extendErr
+		return extendErr("renewal policy check failed: ", err)
+	}
 	txnBuilder, newParents, newInputs, newOutputs, err := h.managedAddRenewCollateral(so, settings, txnSet)
 	if err != nil {
 		modules.WriteNegotiationRejection(conn, err) // Error is ignored to preserve type for extendErr
@@ -0,0 +1,86 @@
+package host
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestRenewPolicy checks that the host declines renewals that violate its
+// renewal policy, and that every decision is counted in the metrics.
+func TestRenewPolicy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+	h := ht.host
+
+	// Add a storage obligation with some locked collateral for a renter.
+	_, pk := crypto.GenerateKeyPair()
+	renterKey := types.Ed25519PublicKey(pk)
+	so, err := ht.newTesterStorageObligation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	so.LockedCollateral = types.NewCurrency64(100)
+	so.RevisionTransactionSet = testRenterRevision(so, renterKey, 0)
+	h.managedLockStorageObligation(so.id())
+	err = h.managedAddStorageObligation(so)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.managedUnlockStorageObligation(so.id())
+	if r := findRenter(t, h, renterKey); !r.LockedCollateral.Equals64(100) {
+		t.Fatal("locked collateral of the renter was not recorded:", r.LockedCollateral)
+	}
+
+	// Renew the contract for 20 blocks, 1000 bytes and 50 hastings of
+	// collateral. Without a policy the renewal is accepted.
+	var settings modules.HostExternalSettings
+	fc := types.FileContract{
+		FileSize:          1000,
+		WindowEnd:         so.proofDeadline() + 20,
+		ValidProofOutputs: []types.SiacoinOutput{{}, {Value: types.NewCurrency64(50)}},
+	}
+	if err := h.managedCheckRenewPolicy(so, settings, fc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		policy func(*modules.HostInternalSettings)
+		err    error
+	}{
+		{func(is *modules.HostInternalSettings) { is.MinRenewFileSize = 1001 }, errRenewPolicyFileSize},
+		{func(is *modules.HostInternalSettings) { is.MinRenewFileSize = 1000 }, nil},
+		{func(is *modules.HostInternalSettings) { is.MaxRenewExtension = 19 }, errRenewPolicyExtension},
+		{func(is *modules.HostInternalSettings) { is.MaxRenewExtension = 20 }, nil},
+		{func(is *modules.HostInternalSettings) { is.MaxRenterCollateral = types.NewCurrency64(149) }, errRenewPolicyCollateral},
+		{func(is *modules.HostInternalSettings) { is.MaxRenterCollateral = types.NewCurrency64(150) }, nil},
+	}
+	for i, test := range tests {
+		is := h.InternalSettings()
+		is.MinRenewFileSize = 0
+		is.MaxRenewExtension = 0
+		is.MaxRenterCollateral = types.ZeroCurrency
+		test.policy(&is)
+		err = h.SetInternalSettings(is)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.managedCheckRenewPolicy(so, settings, fc); err != test.err {
+			t.Errorf("test %v: expected %v, got %v", i, test.err, err)
+		}
+	}
+
+	nm := h.NetworkMetrics()
+	if nm.RenewalsAccepted != 4 || nm.RenewalsDeclinedFileSize != 1 || nm.RenewalsDeclinedExtension != 1 || nm.RenewalsDeclinedCollateral != 1 {
+		t.Fatal("renewal decisions were not counted:", nm)
+	}
+}
@@ -42,6 +42,7 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 	settings := h.externalSettings()
 	secretKey := h.secretKey
 	blockHeight := h.blockHeight
+	maintenance := h.maintenance
 	h.mu.Unlock()
 
 	// The renter is going to send its intended modifications, followed by the
@@ -66,8 +67,16 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 	var sectorsRemoved []crypto.Hash
 	var sectorsGained []crypto.Hash
 	var gainedSectorData [][]byte
+	var uploadBandwidth uint64
+	renterKey, _ := so.renterKey()
+	oldSectorCount := uint64(len(so.SectorRoots))
 	err = func() error {
 		for _, modification := range modifications {
+			// Uploads are refused while the host is in maintenance mode,
+			// deleting sectors is still allowed.
+			if maintenance && modification.Type != modules.ActionDelete {
+				return errMaintenance
+			}
 			// Check that the index points to an existing sector root. If the type
 			// is ActionInsert, we permit inserting at the end.
 			if modification.Type == modules.ActionInsert {
@@ -101,6 +110,8 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 				storageRevenue = storageRevenue.Add(settings.StoragePrice.Mul(blockBytesCurrency))
 				newCollateral = newCollateral.Add(settings.Collateral.Mul(blockBytesCurrency))
 
+				uploadBandwidth += modules.SectorSize
+
 				// Insert the sector into the root list.
 				newRoot := crypto.MerkleRoot(modification.Data)
 				sectorsGained = append(sectorsGained, newRoot)
@@ -124,6 +135,7 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 
 				// Update finances.
 				bandwidthRevenue = bandwidthRevenue.Add(settings.UploadBandwidthPrice.Mul64(uint64(len(modification.Data))))
+				uploadBandwidth += uint64(len(modification.Data))
 
 				// Update the sectors removed and gained to indicate that the old
 				// sector has been replaced with a new sector.
@@ -136,6 +148,19 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 				return errUnknownModification
 			}
 		}
+
+		// Check that the modifications fit in the quota of the renter.
+		err := h.managedCheckRenterBandwidth(renterKey, uploadBandwidth)
+		if err != nil {
+			return err
+		}
+		if newSectorCount := uint64(len(so.SectorRoots)); newSectorCount > oldSectorCount {
+			err = h.managedCheckRenterStorage(renterKey, (newSectorCount-oldSectorCount)*modules.SectorSize)
+			if err != nil {
+				return err
+			}
+		}
+
 		newRevenue := storageRevenue.Add(bandwidthRevenue)
 		return extendErr("unable to verify updated contract: ", verifyRevision(*so, revision, blockHeight, newRevenue, newCollateral))
 	}()
@@ -173,6 +198,10 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 		modules.WriteNegotiationRejection(conn, err) // Error is ignored so that the error type can be preserved in extendErr.
 		return extendErr("could not modify storage obligation: ", ErrorInternal(err.Error()))
 	}
+	err = h.managedRecordRenterBandwidth(renterKey, uploadBandwidth, 0)
+	if err != nil {
+		h.log.Println("WARN: could not record renter bandwidth:", err)
+	}
 
 	// Host will now send acceptance and its signature to the renter. This
 	// iteration is complete. If the finalIter flag is set, StopResponse will
@@ -206,9 +235,11 @@ func (h *Host) managedRPCReviseContract(conn net.Conn) error {
 		return extendErr("failed RPCRecentRevision during RPCReviseContract: ", err)
 	}
 	// The storage obligation is received with a lock on it. Defer a call to
-	// unlock the storage obligation.
+	// unlock the storage obligation and to unregister the renter connection.
+	renterKey, _ := so.renterKey()
 	defer func() {
 		h.managedUnlockStorageObligation(so.id())
+		h.managedDisconnectRenter(renterKey)
 	}()
 
 	// Begin the revision loop. The host will process revisions until a
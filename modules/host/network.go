@@ -15,6 +15,7 @@ package host
 // have to keep all the files following a renew in order to get the money.
 
 import (
+	"errors"
 	"net"
 	"sync/atomic"
 	"time"
@@ -23,10 +24,75 @@ import (
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/ratelimit"
 )
 
-// rpcSettingsDeprecated is a specifier for a deprecated settings request.
-var rpcSettingsDeprecated = types.Specifier{'S', 'e', 't', 't', 'i', 'n', 'g', 's'}
+var (
+	// errMaxConnections is returned if a connection is refused because the
+	// host already has as many connections open as its settings allow.
+	errMaxConnections = errors.New("host has reached its maximum number of concurrent connections")
+
+	// errMaxConnectionsPerIP is returned if a connection is refused because
+	// the host already has as many connections open with the remote IP
+	// address as its settings allow.
+	errMaxConnectionsPerIP = errors.New("host has reached its maximum number of concurrent connections for the remote IP address")
+
+	// rpcSettingsDeprecated is a specifier for a deprecated settings request.
+	rpcSettingsDeprecated = types.Specifier{'S', 'e', 't', 't', 'i', 'n', 'g', 's'}
+)
+
+// connIP returns the IP address of the remote end of the connection, which is
+// used to enforce the per-IP connection limit.
+func connIP(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
+
+// setRateLimits sets the limits of the rate limiter that is shared by all of
+// the host's connections.
+func (h *Host) setRateLimits(downloadSpeed, uploadSpeed int64) {
+	// Check for sentinel "no limits" value.
+	if downloadSpeed == 0 && uploadSpeed == 0 {
+		h.rl.SetLimits(0, 0, 0)
+	} else {
+		h.rl.SetLimits(downloadSpeed, uploadSpeed, 4*4096)
+	}
+}
+
+// managedAddConnection registers a new connection with the host, returning an
+// error if the connection would exceed the connection limits of the host.
+// Every successful call must be followed by a call to managedRemoveConnection
+// once the connection is closed.
+func (h *Host) managedAddConnection(ip string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.settings.MaxConnections != 0 && h.connections >= h.settings.MaxConnections {
+		return errMaxConnections
+	}
+	if h.settings.MaxConnectionsPerIP != 0 && h.connectionsPerIP[ip] >= h.settings.MaxConnectionsPerIP {
+		return errMaxConnectionsPerIP
+	}
+	h.connections++
+	h.connectionsPerIP[ip]++
+	return nil
+}
+
+// managedRemoveConnection unregisters a connection that was registered by
+// managedAddConnection.
+func (h *Host) managedRemoveConnection(ip string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.connections--
+	if h.connectionsPerIP[ip] <= 1 {
+		delete(h.connectionsPerIP, ip)
+		return
+	}
+	h.connectionsPerIP[ip]--
+}
 
 // threadedUpdateHostname periodically runs 'managedLearnHostname', which
 // checks if the host's hostname has changed, and makes an updated host
@@ -249,6 +315,19 @@ func (h *Host) threadedHandleConn(conn net.Conn) {
 	}
 	defer h.tg.Done()
 
+	// Refuse the connection if the host already has too many connections
+	// open, either in total or with the remote IP address.
+	ip := connIP(conn)
+	if err := h.managedAddConnection(ip); err != nil {
+		h.log.Debugf("WARN: refused incoming conn %v: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	defer h.managedRemoveConnection(ip)
+
+	// All connections share the host's rate limit.
+	conn = ratelimit.NewRLConn(conn, h.rl, h.tg.StopChan())
+
 	// Close the conn on host.Close or when the method terminates, whichever comes
 	// first.
 	connCloseChan := make(chan struct{})
@@ -353,5 +432,10 @@ func (h *Host) NetworkMetrics() modules.HostNetworkMetrics {
 		ReviseCalls:       atomic.LoadUint64(&h.atomicReviseCalls),
 		SettingsCalls:     atomic.LoadUint64(&h.atomicSettingsCalls),
 		UnrecognizedCalls: atomic.LoadUint64(&h.atomicUnrecognizedCalls),
+
+		RenewalsAccepted:           atomic.LoadUint64(&h.atomicRenewalsAccepted),
+		RenewalsDeclinedCollateral: atomic.LoadUint64(&h.atomicRenewalsDeclinedCollateral),
+		RenewalsDeclinedExtension:  atomic.LoadUint64(&h.atomicRenewalsDeclinedExtension),
+		RenewalsDeclinedFileSize:   atomic.LoadUint64(&h.atomicRenewalsDeclinedFileSize),
 	}
 }
@@ -1,6 +1,9 @@
 package host
 
 import (
+	"errors"
+	"net"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -143,3 +146,116 @@ func TestHostConnectabilityStatus(t *testing.T) {
 		t.Fatal("expected connectability state to flip to HostConnectabilityStatusConnectable")
 	}
 }
+
+// TestHostConnectionLimits checks that the host refuses connections that
+// would exceed the connection limits in its internal settings.
+func TestHostConnectionLimits(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := blankHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+	h := ht.host
+
+	// Limit the host to two connections, and one connection per IP.
+	settings := h.InternalSettings()
+	settings.MaxConnections = 2
+	settings.MaxConnectionsPerIP = 1
+	err = h.SetInternalSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.managedAddConnection("1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.managedAddConnection("1.2.3.4"); err != errMaxConnectionsPerIP {
+		t.Fatal("expected per IP connection limit error, got", err)
+	}
+	if err := h.managedAddConnection("5.6.7.8"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.managedAddConnection("9.10.11.12"); err != errMaxConnections {
+		t.Fatal("expected connection limit error, got", err)
+	}
+	h.managedRemoveConnection("1.2.3.4")
+	h.managedRemoveConnection("5.6.7.8")
+
+	// Open a connection with the host, which the host will keep open while
+	// waiting for an RPC. A second connection from the same IP should be
+	// closed by the host immediately.
+	conn, err := net.Dial("tcp", h.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = build.Retry(100, 10*time.Millisecond, func() error {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+		if h.connections != 1 {
+			return errors.New("connection was not registered")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn2, err := net.Dial("tcp", h.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn2.Close()
+	conn2.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, err := conn2.Read(make([]byte, 1)); err == nil {
+		t.Fatal("connection over the limit was not closed by the host")
+	} else if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		t.Fatal("connection over the limit was not closed by the host")
+	}
+}
+
+// TestHostRateLimitSettings checks that the host rejects invalid speed limits
+// and persists valid ones.
+func TestHostRateLimitSettings(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := blankHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	settings := ht.host.InternalSettings()
+	settings.MaxDownloadSpeed = -1
+	if err := ht.host.SetInternalSettings(settings); err == nil {
+		t.Fatal("negative download speed was accepted")
+	}
+	settings.MaxDownloadSpeed = 0
+	settings.MaxUploadSpeed = -1
+	if err := ht.host.SetInternalSettings(settings); err == nil {
+		t.Fatal("negative upload speed was accepted")
+	}
+	settings.MaxDownloadSpeed = 1e6
+	settings.MaxUploadSpeed = 2e6
+	if err := ht.host.SetInternalSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	// The limits should survive a restart.
+	err = ht.host.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.host, err = New(ht.cs, ht.tpool, ht.wallet, "localhost:0", filepath.Join(ht.persistDir, modules.HostDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings = ht.host.InternalSettings()
+	if settings.MaxDownloadSpeed != 1e6 || settings.MaxUploadSpeed != 2e6 {
+		t.Fatal("speed limits were not persisted:", settings.MaxDownloadSpeed, settings.MaxUploadSpeed)
+	}
+}
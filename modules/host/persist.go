@@ -29,6 +29,11 @@ type persistence struct {
 	SecretKey        crypto.SecretKey             `json:"secretkey"`
 	Settings         modules.HostInternalSettings `json:"settings"`
 	UnlockHash       types.UnlockHash             `json:"unlockhash"`
+
+	// Maintenance Mode.
+	Maintenance         bool              `json:"maintenance"`
+	MaintenanceAnnounce bool              `json:"maintenanceannounce"`
+	MaintenanceHeight   types.BlockHeight `json:"maintenanceheight"`
 }
 
 // persistData returns the data in the Host that will be saved to disk.
@@ -47,6 +52,11 @@ func (h *Host) persistData() persistence {
 		SecretKey:        h.secretKey,
 		Settings:         h.settings,
 		UnlockHash:       h.unlockHash,
+
+		// Maintenance Mode.
+		Maintenance:         h.maintenance,
+		MaintenanceAnnounce: h.maintenanceAnnounce,
+		MaintenanceHeight:   h.maintenanceHeight,
 	}
 }
 
@@ -107,6 +117,11 @@ func (h *Host) loadPersistObject(p *persistence) {
 		h.settings.NetAddress = ""
 	}
 	h.unlockHash = p.UnlockHash
+
+	// Copy over the maintenance mode.
+	h.maintenance = p.Maintenance
+	h.maintenanceAnnounce = p.MaintenanceAnnounce
+	h.maintenanceHeight = p.MaintenanceHeight
 }
 
 // initDB will check that the database has been initialized and if not, will
@@ -129,6 +144,8 @@ func (h *Host) initDB() (err error) {
 		// database needs to be initialized. Create the database buckets.
 		buckets := [][]byte{
 			bucketActionItems,
+			bucketLedger,
+			bucketRenters,
 			bucketStorageObligations,
 		}
 		for _, bucket := range buckets {
@@ -197,6 +214,12 @@ func (h *Host) load() error {
 		return err
 	}
 
+	// Recompute the storage used by each renter from the same obligations.
+	err = h.rebuildRenterUsage()
+	if err != nil {
+		return err
+	}
+
 	return h.initConsensusSubscription()
 }
 
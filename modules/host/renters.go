@@ -0,0 +1,331 @@
+package host
+
+// renters.go keeps a registry of the renters that have formed contracts with
+// the host. Renters are identified by the public key in the unlock conditions
+// of their file contracts. Operators can give each renter a quota for storage,
+// bandwidth and concurrent connections, and RPCs that would exceed the quota
+// are refused, which prevents a single renter from consuming all of the
+// resources of the host.
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// errInvalidRenterKey is returned when a renter quota is set for a public
+	// key that cannot belong to a renter.
+	errInvalidRenterKey = errors.New("renter public key must be a non-empty ed25519 key")
+
+	// errRenterBandwidthQuota is returned if a renter requests more bandwidth
+	// than is left in its quota for the current bandwidth period.
+	errRenterBandwidthQuota = ErrorCommunication("renter has exceeded its bandwidth quota for the current period")
+
+	// errRenterConnectionQuota is returned if a renter opens more concurrent
+	// connections with the host than its quota allows.
+	errRenterConnectionQuota = ErrorCommunication("renter has reached its limit of concurrent connections")
+
+	// errRenterStorageQuota is returned if a renter tries to upload more data
+	// than its storage quota allows.
+	errRenterStorageQuota = ErrorCommunication("renter has exceeded its storage quota")
+)
+
+// getRenter fetches the renter with the provided public key from the
+// database. An empty renter is returned if the renter is not known yet.
+func getRenter(tx *bolt.Tx, spk types.SiaPublicKey) (modules.HostRenter, error) {
+	r := modules.HostRenter{PublicKey: spk}
+	renterBytes := tx.Bucket(bucketRenters).Get([]byte(spk.String()))
+	if renterBytes == nil {
+		return r, nil
+	}
+	err := json.Unmarshal(renterBytes, &r)
+	return r, err
+}
+
+// putRenter stores the renter in the database. The number of connections is
+// not persistent.
+func putRenter(tx *bolt.Tx, r modules.HostRenter) error {
+	r.Connections = 0
+	renterBytes, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketRenters).Put([]byte(r.PublicKey.String()), renterBytes)
+}
+
+// updateRenter fetches the renter with the provided public key, applies fn to
+// it, and stores the result in the database.
+func updateRenter(tx *bolt.Tx, spk types.SiaPublicKey, fn func(*modules.HostRenter)) error {
+	r, err := getRenter(tx, spk)
+	if err != nil {
+		return err
+	}
+	fn(&r)
+	return putRenter(tx, r)
+}
+
+// renterPeriodStart returns the height at which the bandwidth period of the
+// renter that contains the provided height started.
+func renterPeriodStart(quota modules.HostRenterQuota, height types.BlockHeight) types.BlockHeight {
+	period := quota.BandwidthPeriod
+	if period == 0 {
+		period = defaultRenterBandwidthPeriod
+	}
+	return height - height%period
+}
+
+// rolloverRenterPeriod resets the bandwidth usage of the renter if a new
+// bandwidth period has started since the usage was last updated.
+func rolloverRenterPeriod(r *modules.HostRenter, height types.BlockHeight) {
+	periodStart := renterPeriodStart(r.Quota, height)
+	if periodStart != r.PeriodStart {
+		r.PeriodStart = periodStart
+		r.UploadBandwidth = 0
+		r.DownloadBandwidth = 0
+	}
+}
+
+// addRenterObligation adds the storage obligation to the resource usage of
+// the renter that formed it.
+func addRenterObligation(tx *bolt.Tx, so storageObligation) error {
+	spk, ok := so.renterKey()
+	if !ok {
+		return nil
+	}
+	return updateRenter(tx, spk, func(r *modules.HostRenter) {
+		r.ContractCount++
+		r.StorageUsed += so.fileSize()
+		r.LockedCollateral = r.LockedCollateral.Add(so.LockedCollateral)
+	})
+}
+
+// modifyRenterObligation updates the resource usage of the renter that formed
+// the storage obligation to account for a change in the size of its data.
+func modifyRenterObligation(tx *bolt.Tx, oldSO, so storageObligation) error {
+	spk, ok := so.renterKey()
+	if !ok || oldSO.fileSize() == so.fileSize() {
+		return nil
+	}
+	return updateRenter(tx, spk, func(r *modules.HostRenter) {
+		releaseRenterStorage(r, oldSO.fileSize())
+		r.StorageUsed += so.fileSize()
+	})
+}
+
+// removeRenterObligation removes the storage obligation from the resource
+// usage of the renter that formed it.
+func removeRenterObligation(tx *bolt.Tx, so storageObligation) error {
+	spk, ok := so.renterKey()
+	if !ok {
+		return nil
+	}
+	return updateRenter(tx, spk, func(r *modules.HostRenter) {
+		if r.ContractCount > 0 {
+			r.ContractCount--
+		}
+		releaseRenterStorage(r, so.fileSize())
+		if so.LockedCollateral.Cmp(r.LockedCollateral) > 0 {
+			r.LockedCollateral = types.ZeroCurrency
+		} else {
+			r.LockedCollateral = r.LockedCollateral.Sub(so.LockedCollateral)
+		}
+	})
+}
+
+// releaseRenterStorage subtracts the provided number of bytes from the
+// storage used by the renter, stopping at zero.
+func releaseRenterStorage(r *modules.HostRenter, size uint64) {
+	if size > r.StorageUsed {
+		size = r.StorageUsed
+	}
+	r.StorageUsed -= size
+}
+
+// rebuildRenterUsage recomputes the contract count, storage used and locked
+// collateral of every renter from the unresolved storage obligations in the
+// database. This keeps the registry correct for obligations that were formed
+// before the registry existed.
+func (h *Host) rebuildRenterUsage() error {
+	return h.db.Update(func(tx *bolt.Tx) error {
+		// Reset the usage of all known renters.
+		var renters []modules.HostRenter
+		err := tx.Bucket(bucketRenters).ForEach(func(_, v []byte) error {
+			var r modules.HostRenter
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			r.ContractCount = 0
+			r.StorageUsed = 0
+			r.LockedCollateral = types.ZeroCurrency
+			renters = append(renters, r)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, r := range renters {
+			if err := putRenter(tx, r); err != nil {
+				return err
+			}
+		}
+
+		// Add the usage of every unresolved obligation.
+		var sos []storageObligation
+		err = tx.Bucket(bucketStorageObligations).ForEach(func(_, v []byte) error {
+			var so storageObligation
+			if err := json.Unmarshal(v, &so); err != nil {
+				return err
+			}
+			if so.ObligationStatus == obligationUnresolved {
+				sos = append(sos, so)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, so := range sos {
+			if err := addRenterObligation(tx, so); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// managedCheckRenterStorage returns an error if storing the provided number of
+// additional bytes would exceed the storage quota of the renter.
+func (h *Host) managedCheckRenterStorage(spk types.SiaPublicKey, added uint64) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.db.View(func(tx *bolt.Tx) error {
+		r, err := getRenter(tx, spk)
+		if err != nil {
+			return err
+		}
+		if r.Quota.MaxStorage != 0 && r.StorageUsed+added > r.Quota.MaxStorage {
+			return errRenterStorageQuota
+		}
+		return nil
+	})
+}
+
+// managedCheckRenterBandwidth returns an error if transferring the provided
+// number of bytes would exceed the bandwidth quota of the renter for the
+// current period.
+func (h *Host) managedCheckRenterBandwidth(spk types.SiaPublicKey, bytes uint64) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.db.View(func(tx *bolt.Tx) error {
+		r, err := getRenter(tx, spk)
+		if err != nil {
+			return err
+		}
+		rolloverRenterPeriod(&r, h.blockHeight)
+		if r.Quota.MaxBandwidth != 0 && r.UploadBandwidth+r.DownloadBandwidth+bytes > r.Quota.MaxBandwidth {
+			return errRenterBandwidthQuota
+		}
+		return nil
+	})
+}
+
+// managedRecordRenterBandwidth adds the provided upload and download bytes to
+// the bandwidth used by the renter in the current period.
+func (h *Host) managedRecordRenterBandwidth(spk types.SiaPublicKey, upload, download uint64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.db.Update(func(tx *bolt.Tx) error {
+		return updateRenter(tx, spk, func(r *modules.HostRenter) {
+			rolloverRenterPeriod(r, h.blockHeight)
+			r.UploadBandwidth += upload
+			r.DownloadBandwidth += download
+		})
+	})
+}
+
+// managedConnectRenter registers a new connection from the renter, returning
+// an error if the renter already has as many connections open as its quota
+// allows. Every successful call must be followed by a call to
+// managedDisconnectRenter once the connection is finished.
+func (h *Host) managedConnectRenter(spk types.SiaPublicKey) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var r modules.HostRenter
+	err := h.db.View(func(tx *bolt.Tx) error {
+		var err error
+		r, err = getRenter(tx, spk)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	key := spk.String()
+	if r.Quota.MaxConnections != 0 && h.renterConnections[key] >= r.Quota.MaxConnections {
+		return errRenterConnectionQuota
+	}
+	h.renterConnections[key]++
+	return nil
+}
+
+// managedDisconnectRenter unregisters a connection that was registered by
+// managedConnectRenter.
+func (h *Host) managedDisconnectRenter(spk types.SiaPublicKey) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := spk.String()
+	if h.renterConnections[key] <= 1 {
+		delete(h.renterConnections, key)
+		return
+	}
+	h.renterConnections[key]--
+}
+
+// Renters returns the quota and resource usage of every renter known to the
+// host.
+func (h *Host) Renters() (renters []modules.HostRenter, err error) {
+	if err = h.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer h.tg.Done()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	err = h.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketRenters).ForEach(func(_, v []byte) error {
+			var r modules.HostRenter
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			rolloverRenterPeriod(&r, h.blockHeight)
+			r.Connections = h.renterConnections[r.PublicKey.String()]
+			renters = append(renters, r)
+			return nil
+		})
+	})
+	return renters, err
+}
+
+// SetRenterQuota sets the quota of the renter with the provided public key.
+func (h *Host) SetRenterQuota(spk types.SiaPublicKey, quota modules.HostRenterQuota) error {
+	if spk.Algorithm != types.SignatureEd25519 || len(spk.Key) == 0 {
+		return errInvalidRenterKey
+	}
+	if err := h.tg.Add(); err != nil {
+		return err
+	}
+	defer h.tg.Done()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.db.Update(func(tx *bolt.Tx) error {
+		return updateRenter(tx, spk, func(r *modules.HostRenter) {
+			r.Quota = quota
+			rolloverRenterPeriod(r, h.blockHeight)
+		})
+	})
+}
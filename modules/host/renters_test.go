@@ -0,0 +1,215 @@
+package host
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// testRenterRevision returns a revision transaction set for the storage
+// obligation that is signed by the provided renter key and protects the
+// provided number of bytes.
+func testRenterRevision(so storageObligation, renterKey types.SiaPublicKey, fileSize uint64) []types.Transaction {
+	validPayouts, missedPayouts := so.payouts()
+	return []types.Transaction{{
+		FileContractRevisions: []types.FileContractRevision{{
+			ParentID: so.id(),
+			UnlockConditions: types.UnlockConditions{
+				PublicKeys:         []types.SiaPublicKey{renterKey, {}},
+				SignaturesRequired: 2,
+			},
+			NewRevisionNumber: 1,
+
+			NewFileSize:           fileSize,
+			NewWindowStart:        so.expiration(),
+			NewWindowEnd:          so.proofDeadline(),
+			NewValidProofOutputs:  validPayouts,
+			NewMissedProofOutputs: missedPayouts,
+		}},
+	}}
+}
+
+// findRenter returns the renter with the provided public key from the host's
+// registry.
+func findRenter(t *testing.T, h *Host, spk types.SiaPublicKey) modules.HostRenter {
+	renters, err := h.Renters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range renters {
+		if r.PublicKey.String() == spk.String() {
+			return r
+		}
+	}
+	t.Fatal("renter is not in the registry:", spk.String())
+	return modules.HostRenter{}
+}
+
+// TestRenterQuotas checks that the host tracks the usage of a renter across
+// the lifecycle of a storage obligation, and that the renter's quotas are
+// enforced.
+func TestRenterQuotas(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+	h := ht.host
+
+	// Add a storage obligation that belongs to the renter.
+	_, pk := crypto.GenerateKeyPair()
+	renterKey := types.Ed25519PublicKey(pk)
+	so, err := ht.newTesterStorageObligation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	so.RevisionTransactionSet = testRenterRevision(so, renterKey, 0)
+	h.managedLockStorageObligation(so.id())
+	err = h.managedAddStorageObligation(so)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.managedUnlockStorageObligation(so.id())
+	if r := findRenter(t, h, renterKey); r.ContractCount != 1 || r.StorageUsed != 0 {
+		t.Fatal("renter usage was not recorded when the obligation was added:", r)
+	}
+
+	// Give the renter a quota of one sector and add a sector.
+	err = h.SetRenterQuota(renterKey, modules.HostRenterQuota{
+		MaxStorage:     modules.SectorSize,
+		MaxBandwidth:   100,
+		MaxConnections: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.managedCheckRenterStorage(renterKey, 2*modules.SectorSize); err != errRenterStorageQuota {
+		t.Fatal("expected storage quota error, got", err)
+	}
+	sectorRoot, sectorData := randSector()
+	so.SectorRoots = []crypto.Hash{sectorRoot}
+	so.RevisionTransactionSet = testRenterRevision(so, renterKey, modules.SectorSize)
+	h.managedLockStorageObligation(so.id())
+	h.mu.Lock()
+	err = h.modifyStorageObligation(so, nil, []crypto.Hash{sectorRoot}, [][]byte{sectorData})
+	h.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.managedUnlockStorageObligation(so.id())
+	if r := findRenter(t, h, renterKey); r.StorageUsed != modules.SectorSize || r.Quota.MaxStorage != modules.SectorSize {
+		t.Fatal("renter usage was not updated when the obligation was modified:", r)
+	}
+	if err := h.managedCheckRenterStorage(renterKey, 1); err != errRenterStorageQuota {
+		t.Fatal("expected storage quota error, got", err)
+	}
+
+	// Use up most of the bandwidth quota.
+	err = h.managedRecordRenterBandwidth(renterKey, 40, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.managedCheckRenterBandwidth(renterKey, 40); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.managedCheckRenterBandwidth(renterKey, 41); err != errRenterBandwidthQuota {
+		t.Fatal("expected bandwidth quota error, got", err)
+	}
+	if r := findRenter(t, h, renterKey); r.UploadBandwidth != 40 || r.DownloadBandwidth != 20 {
+		t.Fatal("renter bandwidth was not recorded:", r)
+	}
+
+	// Only one connection should be allowed at a time.
+	if err := h.managedConnectRenter(renterKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.managedConnectRenter(renterKey); err != errRenterConnectionQuota {
+		t.Fatal("expected connection quota error, got", err)
+	}
+	if r := findRenter(t, h, renterKey); r.Connections != 1 {
+		t.Fatal("renter connection was not counted:", r)
+	}
+	h.managedDisconnectRenter(renterKey)
+	if err := h.managedConnectRenter(renterKey); err != nil {
+		t.Fatal(err)
+	}
+	h.managedDisconnectRenter(renterKey)
+
+	// Losing the usage of the renter should be fixed by a rebuild.
+	err = h.db.Update(func(tx *bolt.Tx) error {
+		return updateRenter(tx, renterKey, func(r *modules.HostRenter) {
+			r.ContractCount = 0
+			r.StorageUsed = 0
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = h.rebuildRenterUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := findRenter(t, h, renterKey); r.ContractCount != 1 || r.StorageUsed != modules.SectorSize {
+		t.Fatal("renter usage was not rebuilt:", r)
+	}
+
+	// Removing the obligation should release the storage of the renter but
+	// keep its quota.
+	h.mu.Lock()
+	err = h.removeStorageObligation(so, obligationSucceeded)
+	h.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := findRenter(t, h, renterKey); r.ContractCount != 0 || r.StorageUsed != 0 || r.Quota.MaxStorage != modules.SectorSize {
+		t.Fatal("renter usage was not updated when the obligation was removed:", r)
+	}
+}
+
+// TestRenterBandwidthPeriod checks that the bandwidth used by a renter is
+// reset at the start of every bandwidth period.
+func TestRenterBandwidthPeriod(t *testing.T) {
+	t.Parallel()
+	r := modules.HostRenter{
+		Quota:           modules.HostRenterQuota{BandwidthPeriod: 10},
+		PeriodStart:     20,
+		UploadBandwidth: 5,
+	}
+	rolloverRenterPeriod(&r, 29)
+	if r.PeriodStart != 20 || r.UploadBandwidth != 5 {
+		t.Fatal("bandwidth was reset within the period:", r)
+	}
+	rolloverRenterPeriod(&r, 30)
+	if r.PeriodStart != 30 || r.UploadBandwidth != 0 {
+		t.Fatal("bandwidth was not reset at the start of a new period:", r)
+	}
+	if start := renterPeriodStart(modules.HostRenterQuota{}, defaultRenterBandwidthPeriod+1); start != defaultRenterBandwidthPeriod {
+		t.Fatal("default period was not used:", start)
+	}
+}
+
+// TestSetRenterQuotaInvalidKey checks that quotas cannot be set for keys that
+// cannot belong to a renter.
+func TestSetRenterQuotaInvalidKey(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := blankHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	if err := ht.host.SetRenterQuota(types.SiaPublicKey{}, modules.HostRenterQuota{}); err != errInvalidRenterKey {
+		t.Fatal("expected invalid key error, got", err)
+	}
+}
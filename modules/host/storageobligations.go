@@ -295,6 +295,20 @@ func (so storageObligation) proofDeadline() types.BlockHeight {
 	return so.OriginTransactionSet[len(so.OriginTransactionSet)-1].FileContracts[0].WindowEnd
 }
 
+// renterKey returns the public key of the renter that formed the storage
+// obligation. The key is taken from the unlock conditions of the most recent
+// revision, false is returned if the obligation has no revision.
+func (so storageObligation) renterKey() (types.SiaPublicKey, bool) {
+	if len(so.RevisionTransactionSet) == 0 {
+		return types.SiaPublicKey{}, false
+	}
+	revisionTxn := so.RevisionTransactionSet[len(so.RevisionTransactionSet)-1]
+	if len(revisionTxn.FileContractRevisions) == 0 || len(revisionTxn.FileContractRevisions[0].UnlockConditions.PublicKeys) != 2 {
+		return types.SiaPublicKey{}, false
+	}
+	return revisionTxn.FileContractRevisions[0].UnlockConditions.PublicKeys[0], true
+}
+
 // value returns the value of fulfilling the storage obligation to the host.
 func (so storageObligation) value() types.Currency {
 	return so.ContractCost.Add(so.PotentialDownloadRevenue).Add(so.PotentialStorageRevenue).Add(so.PotentialUploadRevenue).Add(so.RiskedCollateral)
@@ -383,7 +397,33 @@ func (h *Host) managedAddStorageObligation(so storageObligation) error {
 			if err != nil {
 				return err
 			}
-			return bso.Put(soid[:], soBytes)
+			err = bso.Put(soid[:], soBytes)
+			if err != nil {
+				return err
+			}
+
+			// Add the obligation to the usage of the renter.
+			err = addRenterObligation(tx, so)
+			if err != nil {
+				return err
+			}
+
+			// Record the formation of the contract in the ledger.
+			err = recordLedgerEntry(tx, modules.HostLedgerEntry{
+				Type:         modules.HostLedgerContractFormed,
+				ObligationID: soid,
+				BlockHeight:  h.blockHeight,
+				Amount:       so.ContractCost,
+			})
+			if err != nil {
+				return err
+			}
+			return recordLedgerEntry(tx, modules.HostLedgerEntry{
+				Type:         modules.HostLedgerTransactionFees,
+				ObligationID: soid,
+				BlockHeight:  h.blockHeight,
+				Amount:       so.TransactionFeesAdded,
+			})
 		})
 		if err != nil {
 			return err
@@ -506,7 +546,29 @@ func (h *Host) modifyStorageObligation(so storageObligation, sectorsRemoved []cr
 		}
 
 		// Store the new storage obligation to replace the old one.
-		return putStorageObligation(tx, so)
+		err = putStorageObligation(tx, so)
+		if err != nil {
+			return err
+		}
+
+		// Update the storage used by the renter.
+		err = modifyRenterObligation(tx, oldSO, so)
+		if err != nil {
+			return err
+		}
+
+		// Record any increase in potential revenue in the ledger.
+		oldRevenue := oldSO.PotentialStorageRevenue.Add(oldSO.PotentialUploadRevenue).Add(oldSO.PotentialDownloadRevenue)
+		newRevenue := so.PotentialStorageRevenue.Add(so.PotentialUploadRevenue).Add(so.PotentialDownloadRevenue)
+		if newRevenue.Cmp(oldRevenue) <= 0 {
+			return nil
+		}
+		return recordLedgerEntry(tx, modules.HostLedgerEntry{
+			Type:         modules.HostLedgerRevisionIncome,
+			ObligationID: soid,
+			BlockHeight:  h.blockHeight,
+			Amount:       newRevenue.Sub(oldRevenue),
+		})
 	})
 	if err != nil {
 		// Because there was an error, all of the sectors that got added need
@@ -620,7 +682,33 @@ func (h *Host) removeStorageObligation(so storageObligation, sos storageObligati
 	so.ObligationStatus = sos
 	so.SectorRoots = nil
 	return h.db.Update(func(tx *bolt.Tx) error {
-		return putStorageObligation(tx, so)
+		err := putStorageObligation(tx, so)
+		if err != nil {
+			return err
+		}
+		err = removeRenterObligation(tx, so)
+		if err != nil {
+			return err
+		}
+
+		// Record the outcome of the obligation in the ledger.
+		switch sos {
+		case obligationSucceeded:
+			return recordLedgerEntry(tx, modules.HostLedgerEntry{
+				Type:         modules.HostLedgerPayoutReceived,
+				ObligationID: so.id(),
+				BlockHeight:  h.blockHeight,
+				Amount:       so.ContractCost.Add(so.PotentialStorageRevenue).Add(so.PotentialDownloadRevenue).Add(so.PotentialUploadRevenue),
+			})
+		case obligationFailed:
+			return recordLedgerEntry(tx, modules.HostLedgerEntry{
+				Type:         modules.HostLedgerCollateralLost,
+				ObligationID: so.id(),
+				BlockHeight:  h.blockHeight,
+				Amount:       so.RiskedCollateral,
+			})
+		}
+		return nil
 	})
 }
 
@@ -659,6 +747,10 @@ func (h *Host) threadedHandleActionItem(soid types.FileContractID) {
 		return
 	}
 
+	// Keep track of the transaction fees that get added while handling the
+	// action item so that they can be recorded in the ledger.
+	feesAdded := types.ZeroCurrency
+
 	// Check whether the file contract has been seen. If not, resubmit and
 	// queue another action item. Check for death. (signature should have a
 	// kill height)
@@ -772,6 +864,7 @@ func (h *Host) threadedHandleActionItem(soid types.FileContractID) {
 			builder.Drop()
 		}
 		so.TransactionFeesAdded = so.TransactionFeesAdded.Add(requiredFee)
+		feesAdded = feesAdded.Add(requiredFee)
 		// return
 	}
 
@@ -879,6 +972,7 @@ func (h *Host) threadedHandleActionItem(soid types.FileContractID) {
 			return
 		}
 		so.TransactionFeesAdded = so.TransactionFeesAdded.Add(requiredFee)
+		feesAdded = feesAdded.Add(requiredFee)
 
 		// Queue another action item to check whether the storage proof
 		// got confirmed.
@@ -896,7 +990,16 @@ func (h *Host) threadedHandleActionItem(soid types.FileContractID) {
 		if err != nil {
 			return err
 		}
-		return tx.Bucket(bucketStorageObligations).Put(soid[:], soBytes)
+		err = tx.Bucket(bucketStorageObligations).Put(soid[:], soBytes)
+		if err != nil {
+			return err
+		}
+		return recordLedgerEntry(tx, modules.HostLedgerEntry{
+			Type:         modules.HostLedgerTransactionFees,
+			ObligationID: soid,
+			BlockHeight:  blockHeight,
+			Amount:       feesAdded,
+		})
 	})
 	if err != nil {
 		h.log.Println("Error updating the storage obligations", err)
@@ -349,6 +349,30 @@ func TestSingleSectorStorageObligationStack(t *testing.T) {
 	if !ht.host.financialMetrics.StorageRevenue.Equals(sectorCost) {
 		t.Fatal("the host should be reporting revenue after a successful storage proof")
 	}
+
+	// The ledger should contain the confirmed storage proof and the payout.
+	entries, err := ht.host.Ledger(0, ht.host.blockHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var proofs, payouts int
+	for _, e := range entries {
+		if e.ObligationID != so.id() {
+			continue
+		}
+		if e.Type == modules.HostLedgerProofSubmitted {
+			proofs++
+		}
+		if e.Type == modules.HostLedgerPayoutReceived {
+			payouts++
+			if !e.Amount.Equals(sectorCost) {
+				t.Error("payout in the ledger does not match the revenue:", e.Amount)
+			}
+		}
+	}
+	if proofs != 1 || payouts != 1 {
+		t.Fatal("ledger is missing the storage proof or the payout:", proofs, payouts)
+	}
 }
 
 // TestMultiSectorObligationStack checks that the host correctly manages a
@@ -62,7 +62,9 @@ func (h *Host) initRescan() error {
 	// at this time, none of the host external functions are exposed, so it is
 	// save to make the exported call.
 	err = h.cs.ConsensusSetSubscribe(h, modules.ConsensusChangeBeginning, h.tg.StopChan())
-	if err != nil {
+	if err == modules.ErrBlockPruned {
+		return errPrunedSubscription
+	} else if err != nil {
 		return err
 	}
 	h.tg.OnStop(func() {
@@ -107,6 +109,11 @@ func (h *Host) initConsensusSubscription() error {
 		// structure.
 		return h.initRescan()
 	}
+	if err == modules.ErrBlockPruned {
+		// The consensus set has pruned blocks that the host has missed while
+		// it was offline, so the host cannot catch up.
+		return errPrunedSubscription
+	}
 	if err != nil {
 		return err
 	}
@@ -189,6 +196,13 @@ func (h *Host) ProcessConsensusChange(cc modules.ConsensusChange) {
 						if err != nil {
 							continue
 						}
+
+						// The proof is no longer on the blockchain, remove it
+						// from the ledger.
+						err = removeLedgerEntries(tx, h.blockHeight, modules.HostLedgerProofSubmitted, sp.ParentID)
+						if err != nil {
+							h.log.Println("Unable to remove reverted storage proof from the ledger:", err)
+						}
 					}
 				}
 			}
@@ -259,6 +273,23 @@ func (h *Host) ProcessConsensusChange(cc modules.ConsensusChange) {
 						if err != nil {
 							continue
 						}
+
+						// Record the confirmed proof in the ledger at the
+						// height of the block that contains it.
+						proofHeight := h.blockHeight
+						if block.ID() != types.GenesisID {
+							proofHeight++
+						}
+						validPayouts, _ := so.payouts()
+						err = recordLedgerEntry(tx, modules.HostLedgerEntry{
+							Type:         modules.HostLedgerProofSubmitted,
+							ObligationID: sp.ParentID,
+							BlockHeight:  proofHeight,
+							Amount:       validPayouts[1].Value,
+						})
+						if err != nil {
+							h.log.Println("Unable to record storage proof in the ledger:", err)
+						}
 					}
 				}
 			}
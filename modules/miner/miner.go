@@ -29,6 +29,10 @@ var (
 	errNilTpool  = errors.New("miner cannot use a nil transaction pool")
 	errNilWallet = errors.New("miner cannot use a nil wallet")
 
+	// errPrunedSubscription is returned if the consensus set has pruned blocks
+	// that the miner has not processed yet.
+	errPrunedSubscription = errors.New("miner cannot subscribe to the consensus set because it has pruned blocks that the miner has not processed")
+
 	// HeaderMemory is the number of previous calls to 'header'
 	// that are remembered. Additionally, 'header' will only poll for a
 	// new block every 'headerMemory / blockMemory' times it is
@@ -136,7 +140,9 @@ func (m *Miner) startupRescan() error {
 	// Subscribe to the consensus set. This is a blocking call that will not
 	// return until the miner has fully caught up to the current block.
 	err = m.cs.ConsensusSetSubscribe(m, modules.ConsensusChangeBeginning, m.tg.StopChan())
-	if err != nil {
+	if err == modules.ErrBlockPruned {
+		return errPrunedSubscription
+	} else if err != nil {
 		return err
 	}
 	m.tg.OnStop(func() {
@@ -203,6 +209,10 @@ func New(cs modules.ConsensusSet, tpool modules.TransactionPool, w modules.Walle
 		if err != nil {
 			return nil, errors.New("miner startup failed - rescanning failed: " + err.Error())
 		}
+	} else if err == modules.ErrBlockPruned {
+		// The consensus set has pruned blocks that the miner has missed while
+		// it was offline, so the miner cannot catch up.
+		return nil, errPrunedSubscription
 	} else if err != nil {
 		return nil, errors.New("miner subscription failed: " + err.Error())
 	}
@@ -1,5 +1,9 @@
 package miner
 
+import (
+	"github.com/NebulousLabs/Sia/modules"
+)
+
 // mapElements are stored in a mapHeap. The index refers to the location of the
 // splitSet in the underlying slice used to represent the heap.
 type mapElement struct {
@@ -8,7 +12,7 @@ type mapElement struct {
 	index int
 }
 
-// mapHeap is a heap of splitSets (compared by averageFee). The minHeap bool
+// mapHeap is a heap of splitSets (compared by fee rate). The minHeap bool
 // specifies whether it is a min-heap or max-heap.
 type mapHeap struct {
 	selectID map[splitSetID]*mapElement
@@ -80,12 +84,16 @@ func (mh mapHeap) len() int {
 
 // less returns true if the mapElement at index i is less than the element at
 // index j if the mapHeap is a min-heap. If the mapHeap is a max-heap, it
-// returns true if the element at index i is greater.
+// returns true if the element at index i is greater. Elements are ranked the
+// same way the transaction pool ranks transaction sets, see
+// modules.CmpFeeRate.
 func (mh mapHeap) less(i, j int) bool {
+	seti, setj := mh.data[i].set, mh.data[j].set
+	c := modules.CmpFeeRate(seti.averageFee, seti.size, setj.averageFee, setj.size)
 	if mh.minHeap {
-		return mh.data[i].set.averageFee.Cmp(mh.data[j].set.averageFee) == -1
+		return c == -1
 	}
-	return mh.data[i].set.averageFee.Cmp(mh.data[j].set.averageFee) == 1
+	return c == 1
 }
 
 // swap swaps the elements at indices i and j. It also mutates the mapElements
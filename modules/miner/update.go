@@ -26,7 +26,7 @@ func (m *Miner) addMapElementTxns(elem *mapElement) {
 	// blockMapHeap
 	bottomSets := make([]*mapElement, 0)
 	var sizeOfBottomSets uint64
-	var averageFeeOfBottomSets types.Currency
+	var feesOfBottomSets types.Currency
 	for {
 		// Check if the candidateSet can fit in the block.
 		if m.blockMapHeap.size-sizeOfBottomSets+candidateSet.size < types.BlockSizeLimit-5e3 {
@@ -54,21 +54,16 @@ func (m *Miner) addMapElementTxns(elem *mapElement) {
 			// Finished with this candidate set.
 			break
 		}
-		// Add the set to the bottomSets slice. Note that we don't increase
-		// sizeOfBottomSets until after calculating the average.
+		// Add the set to the bottomSets slice.
 		nextSet := m.popFromBlock()
 		bottomSets = append(bottomSets, nextSet)
-
-		// Calculating fees to compare total fee from those sets removed and the current set s.
-		totalFeeFromNextSet := nextSet.set.averageFee.Mul64(nextSet.set.size)
-		totalBottomFees := averageFeeOfBottomSets.Mul64(sizeOfBottomSets).Add(totalFeeFromNextSet)
+		feesOfBottomSets = feesOfBottomSets.Add(nextSet.set.averageFee.Mul64(nextSet.set.size))
 		sizeOfBottomSets += nextSet.set.size
-		averageFeeOfBottomSets := totalBottomFees.Div64(sizeOfBottomSets)
 
-		// If the average fee of the bottom sets from the block is higher than
-		// the fee from this candidate set, put the candidate into the overflow
-		// MapHeap.
-		if averageFeeOfBottomSets.Cmp(candidateSet.averageFee) == 1 {
+		// If the bottom sets from the block rank higher than this candidate
+		// set, put the candidate into the overflow MapHeap.
+		averageFeeOfBottomSets := feesOfBottomSets.Div64(sizeOfBottomSets)
+		if modules.CmpFeeRate(averageFeeOfBottomSets, sizeOfBottomSets, candidateSet.averageFee, candidateSet.size) == 1 {
 			// CandidateSet goes into the overflow.
 			m.pushToOverflow(elem)
 			// Put transaction sets from bottom back into the blockMapHeap.
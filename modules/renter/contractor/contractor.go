@@ -26,6 +26,8 @@ var (
 	errNilTpool  = errors.New("cannot create contractor with nil transaction pool")
 	errNilWallet = errors.New("cannot create contractor with nil wallet")
 
+	errPrunedSubscription = errors.New("contractor cannot subscribe to the consensus set because it has pruned blocks that the contractor has not processed")
+
 	// COMPATv1.0.4-lts
 	// metricsContractID identifies a special contract that contains aggregate
 	// financial metrics from older contractors
@@ -305,6 +307,11 @@ func NewCustomContractor(cs consensusSet, w wallet, tp transactionPool, hdb host
 		c.lastChange = modules.ConsensusChangeBeginning
 		err = cs.ConsensusSetSubscribe(c, c.lastChange, c.tg.StopChan())
 	}
+	if err == modules.ErrBlockPruned {
+		// The consensus set has pruned blocks that the contractor has missed
+		// while it was offline, so the contractor cannot catch up.
+		return nil, errPrunedSubscription
+	}
 	if err != nil {
 		return nil, errors.New("contractor subscription failed: " + err.Error())
 	}
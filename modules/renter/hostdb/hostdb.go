@@ -25,6 +25,7 @@ var (
 	ErrInitialScanIncomplete = errors.New("initial hostdb scan is not yet completed")
 	errNilCS                 = errors.New("cannot create hostdb with nil consensus set")
 	errNilGateway            = errors.New("cannot create hostdb with nil gateway")
+	errPrunedSubscription    = errors.New("hostdb cannot subscribe to the consensus set because it has pruned blocks that the hostdb has not processed")
 )
 
 // The HostDB is a database of potential hosts. It assigns a weight to each
@@ -165,6 +166,11 @@ func NewCustomHostDB(g modules.Gateway, cs modules.ConsensusSet, persistDir stri
 		hdb.mu.Unlock()
 		err = cs.ConsensusSetSubscribe(hdb, hdb.lastChange, hdb.tg.StopChan())
 	}
+	if err == modules.ErrBlockPruned {
+		// The consensus set has pruned blocks that the hostdb has missed while
+		// it was offline, so the hostdb cannot catch up.
+		return nil, errPrunedSubscription
+	}
 	if err != nil {
 		return nil, errors.New("hostdb subscription failed: " + err.Error())
 	}
@@ -117,6 +117,11 @@ type (
 		// within 10 blocks.
 		FeeEstimation() (minimumRecommended, maximumRecommended types.Currency)
 
+		// FeeEstimationTarget returns the fee per byte that a transaction set
+		// should pay to be confirmed within target blocks, based on the fees
+		// paid and the confirmation times observed in recent blocks.
+		FeeEstimationTarget(target types.BlockHeight) (types.Currency, error)
+
 		// PurgeTransactionPool is a temporary function available to the miner. In
 		// the event that a miner mines an unacceptable block, the transaction pool
 		// will be purged to clear out the transaction pool and get rid of the
@@ -176,3 +181,24 @@ func CalculateFee(ts []types.Transaction) types.Currency {
 	size := len(encoding.Marshal(ts))
 	return sum.Div64(uint64(size))
 }
+
+// CmpFeeRate compares two transaction sets that pay rate1 and rate2 in miner
+// fees per byte, and are size1 and size2 bytes large. It returns 1 if the
+// first set pays a higher fee rate, and -1 if it pays a lower fee rate. If
+// both sets pay the same fee rate, the smaller set ranks higher, because it
+// leaves more room for other transactions. 0 is only returned if both the fee
+// rates and the sizes are equal.
+//
+// The transaction pool and the miner both use CmpFeeRate to rank transaction
+// sets, so that the pool keeps the sets that the miner would put into a block.
+func CmpFeeRate(rate1 types.Currency, size1 uint64, rate2 types.Currency, size2 uint64) int {
+	if c := rate1.Cmp(rate2); c != 0 {
+		return c
+	}
+	if size1 < size2 {
+		return 1
+	} else if size1 > size2 {
+		return -1
+	}
+	return 0
+}
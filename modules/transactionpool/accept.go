@@ -17,9 +17,12 @@ import (
 )
 
 var (
+	errDeepChain           = errors.New("transaction set extends a chain of unconfirmed transaction sets that is too deep")
 	errEmptySet            = errors.New("transaction set is empty")
 	errFullTransactionPool = errors.New("transaction pool cannot accept more transactions")
+	errLargeChain          = errors.New("transaction set extends a chain of unconfirmed transaction sets that is too large")
 	errLowMinerFees        = errors.New("transaction set needs more miner fees to be accepted")
+	errLowReplacementFees  = errors.New("transaction set double-spends an existing transaction set, but does not pay enough additional fees")
 	errObjectConflict      = errors.New("transaction set conflicts with an existing transaction set")
 )
 
@@ -57,6 +60,38 @@ func relatedObjectIDs(ts []types.Transaction) []ObjectID {
 	return oids
 }
 
+// spentObjectIDs determines the object ids that are spent by a transaction
+// set. Two sets that spend the same object cannot both be confirmed.
+func spentObjectIDs(ts []types.Transaction) map[ObjectID]struct{} {
+	spent := make(map[ObjectID]struct{})
+	for _, t := range ts {
+		for _, sci := range t.SiacoinInputs {
+			spent[ObjectID(sci.ParentID)] = struct{}{}
+		}
+		for _, fcr := range t.FileContractRevisions {
+			spent[ObjectID(fcr.ParentID)] = struct{}{}
+		}
+		for _, sp := range t.StorageProofs {
+			spent[ObjectID(sp.ParentID)] = struct{}{}
+		}
+		for _, sfi := range t.SiafundInputs {
+			spent[ObjectID(sfi.ParentID)] = struct{}{}
+		}
+	}
+	return spent
+}
+
+// transactionSetFees returns the sum of the miner fees of a transaction set.
+func transactionSetFees(ts []types.Transaction) types.Currency {
+	var fees types.Currency
+	for _, t := range ts {
+		for _, fee := range t.MinerFees {
+			fees = fees.Add(fee)
+		}
+	}
+	return fees
+}
+
 // requiredFeesToExtendTpool returns the amount of fees required to extend the
 // transaction pool to fit another transaction set. The amount returned has the
 // unit 'currency per byte'.
@@ -73,6 +108,56 @@ func (tp *TransactionPool) requiredFeesToExtendTpool() types.Currency {
 	return types.SiacoinPrecision.MulFloat(feeFactor).Div64(1000) // Divide by 1000 to get SC / kb
 }
 
+// evictionCandidates returns the transaction sets that have to be evicted
+// from the pool to make room for a new set that pays fees and is size bytes
+// large. The sets in replaced are removed from the pool when the new set is
+// added, and are not considered for eviction. errFullTransactionPool is
+// returned if the new set does not pay a higher fee rate than each of the
+// sets that would have to be evicted.
+func (tp *TransactionPool) evictionCandidates(fees types.Currency, size uint64, replaced map[TransactionSetID]struct{}) ([]TransactionSetID, error) {
+	newSize := tp.transactionListSize + int(size)
+	for id := range replaced {
+		newSize -= len(encoding.Marshal(tp.transactionSets[id]))
+	}
+	if newSize <= tp.sizeLimit {
+		return nil, nil
+	}
+
+	excess := newSize - tp.sizeLimit
+	var evictions []TransactionSetID
+	var freed int
+	for _, e := range tp.feeIndex.lowest(uint64(excess), replaced) {
+		if modules.CmpFeeRate(fees.Div64(size), size, e.rate(), e.size) <= 0 {
+			return nil, errFullTransactionPool
+		}
+		evictions = append(evictions, e.id)
+		freed += int(e.size)
+	}
+	if freed < excess {
+		return nil, errFullTransactionPool
+	}
+	return evictions, nil
+}
+
+// evictTransactionSet removes a transaction set from the pool to make room
+// for a set that pays a higher fee rate.
+func (tp *TransactionPool) evictTransactionSet(setID TransactionSetID) {
+	set := tp.transactionSets[setID]
+	for _, oid := range relatedObjectIDs(set) {
+		if tp.knownObjects[oid] == setID {
+			delete(tp.knownObjects, oid)
+		}
+	}
+	for _, txn := range set {
+		delete(tp.transactionHeights, txn.ID())
+	}
+	tp.transactionListSize -= len(encoding.Marshal(set))
+	delete(tp.transactionSets, setID)
+	delete(tp.transactionSetDiffs, setID)
+	tp.feeIndex.remove(setID)
+	tp.log.Debugf("transaction set %v was evicted by a transaction set with a higher fee rate", setID)
+}
+
 // checkTransactionSetComposition checks if the transaction set is valid given
 // the state of the pool. It does not check that each individual transaction
 // would be legal in the next block, but does check things like miner fees and
@@ -100,6 +185,56 @@ func (tp *TransactionPool) checkTransactionSetComposition(ts []types.Transaction
 	return setSize, nil
 }
 
+// splitDoubleSpends splits a transaction set into the transactions that
+// spend none of the objects in spent, and the transactions that do, either
+// directly or by depending on another such transaction.
+func splitDoubleSpends(ts []types.Transaction, spent map[ObjectID]struct{}) (kept, evicted []types.Transaction) {
+	evictedObjects := make(map[ObjectID]struct{})
+	for _, t := range ts {
+		doubleSpend := false
+		for oid := range spentObjectIDs([]types.Transaction{t}) {
+			_, spentByNew := spent[oid]
+			_, spentByEvicted := evictedObjects[oid]
+			doubleSpend = doubleSpend || spentByNew || spentByEvicted
+		}
+		if !doubleSpend {
+			kept = append(kept, t)
+			continue
+		}
+		evicted = append(evicted, t)
+		for _, oid := range relatedObjectIDs([]types.Transaction{t}) {
+			evictedObjects[oid] = struct{}{}
+		}
+	}
+	return kept, evicted
+}
+
+// checkReplacement checks that the transaction set ts pays enough fees to
+// replace the evicted transactions that it double-spends. ts has to pay the
+// fees of the evicted transactions plus the fees required to add a set of its
+// size to the pool, and a higher fee per byte, so that replacing transactions
+// never makes the pool less valuable to miners. The required increment keeps
+// peers from making the pool validate and relay a set over and over for a
+// negligible fee increase.
+func (tp *TransactionPool) checkReplacement(ts, evicted []types.Transaction) error {
+	fees := transactionSetFees(ts)
+	evictedFees := transactionSetFees(evicted)
+	size := uint64(len(encoding.Marshal(ts)))
+	incrementRate := tp.requiredFeesToExtendTpool()
+	if incrementRate.Cmp(minReplacementFeeIncrement) < 0 {
+		incrementRate = minReplacementFeeIncrement
+	}
+	if fees.Cmp(evictedFees.Add(incrementRate.Mul64(size))) < 0 {
+		return errLowReplacementFees
+	}
+	// Compare fees/size > evictedFees/evictedSize without dividing.
+	evictedSize := uint64(len(encoding.Marshal(evicted)))
+	if fees.Mul64(evictedSize).Cmp(evictedFees.Mul64(size)) <= 0 {
+		return errLowReplacementFees
+	}
+	return nil
+}
+
 // handleConflicts detects whether the conflicts in the transaction pool are
 // legal children of the new transaction pool set or not.
 func (tp *TransactionPool) handleConflicts(ts []types.Transaction, conflicts []TransactionSetID, txnFn func([]types.Transaction) (modules.ConsensusChange, error)) error {
@@ -156,11 +291,41 @@ func (tp *TransactionPool) handleConflicts(ts []types.Transaction, conflicts []T
 	for _, conflict := range conflictMap {
 		supersetMap[conflict] = struct{}{}
 	}
+	// Transactions of the conflicts that spend the same objects as the new
+	// set are double-spends rather than parents. The new set replaces them
+	// if it pays higher fees, in which case they and their children are left
+	// out of the superset. The conflicts that remain in the superset are the
+	// parents of the new set, which extends their chains by one.
+	spent := spentObjectIDs(dedupSet)
+	var evicted []types.Transaction
+	depth := 1
 	for conflict := range supersetMap {
-		superset = append(superset, tp.transactionSets[conflict]...)
+		kept, dropped := splitDoubleSpends(tp.transactionSets[conflict], spent)
+		superset = append(superset, kept...)
+		evicted = append(evicted, dropped...)
+		if parentDepth := tp.feeIndex.depth(conflict); len(kept) > 0 && parentDepth >= depth {
+			depth = parentDepth + 1
+		}
+	}
+	if len(evicted) > 0 {
+		if err := tp.checkReplacement(dedupSet, evicted); err != nil {
+			return err
+		}
 	}
 	superset = append(superset, dedupSet...)
 
+	// Check that the chain of dependent sets does not grow too deep or too
+	// large. These limits depend on the state of the pool, so they are
+	// checked before the IsStandard rules, which reject a set regardless of
+	// the pool.
+	if depth > maxChainDepth {
+		return errDeepChain
+	}
+	supersetSize := len(encoding.Marshal(superset))
+	if depth > 1 && supersetSize > maxChainSize {
+		return errLargeChain
+	}
+
 	// Check the composition of the transaction set, including fees and
 	// IsStandard rules (this is a new set, the rules must be rechecked).
 	setSize, err := tp.checkTransactionSetComposition(superset)
@@ -181,11 +346,16 @@ func (tp *TransactionPool) handleConflicts(ts []types.Transaction, conflicts []T
 		}
 	}
 	if requiredFees.Cmp(setFees) > 0 {
-		// TODO: check if there is an existing set with lower fees that we can
-		// kick out.
 		return errLowMinerFees
 	}
 
+	// Check that the transaction set fits into the pool, possibly by evicting
+	// sets that pay a lower fee rate.
+	evictions, err := tp.evictionCandidates(setFees, uint64(supersetSize), supersetMap)
+	if err != nil {
+		return err
+	}
+
 	// Check that the transaction set is valid.
 	cc, err := txnFn(superset)
 	if err != nil {
@@ -198,6 +368,18 @@ func (tp *TransactionPool) handleConflicts(ts []types.Transaction, conflicts []T
 		tp.transactionListSize -= len(encoding.Marshal(conflictSet))
 		delete(tp.transactionSets, conflict)
 		delete(tp.transactionSetDiffs, conflict)
+		tp.feeIndex.remove(conflict)
+	}
+	for _, id := range evictions {
+		tp.evictTransactionSet(id)
+	}
+	// Forget the objects of the evicted transactions. Objects that are still
+	// part of the new set are added back below.
+	for _, oid := range relatedObjectIDs(evicted) {
+		delete(tp.knownObjects, oid)
+	}
+	for _, t := range evicted {
+		tp.log.Debugf("transaction %v was replaced by a transaction set with higher fees", t.ID())
 	}
 
 	// Add the transaction set to the pool.
@@ -213,8 +395,8 @@ func (tp *TransactionPool) handleConflicts(ts []types.Transaction, conflicts []T
 		tp.knownObjects[ObjectID(diff.ID)] = setID
 	}
 	tp.transactionSetDiffs[setID] = &cc
-	tsetSize := len(encoding.Marshal(superset))
-	tp.transactionListSize += tsetSize
+	tp.transactionListSize += supersetSize
+	tp.feeIndex.add(setID, setFees, uint64(supersetSize), depth)
 
 	// debug logging
 	if build.DEBUG {
@@ -222,7 +404,7 @@ func (tp *TransactionPool) handleConflicts(ts []types.Transaction, conflicts []T
 		for i, t := range superset {
 			txLogs += fmt.Sprintf("superset transaction %v size: %vB\n", i, len(encoding.Marshal(t)))
 		}
-		tp.log.Debugf("accepted transaction superset %v, size: %vB\ntpool size is %vB after accpeting transaction superset\ntransactions: \n%v\n", setID, tsetSize, tp.transactionListSize, txLogs)
+		tp.log.Debugf("accepted transaction superset %v, size: %vB\ntpool size is %vB after accpeting transaction superset\ntransactions: \n%v\n", setID, supersetSize, tp.transactionListSize, txLogs)
 	}
 
 	return nil
@@ -267,8 +449,6 @@ func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction, txnFn fu
 		}
 	}
 	if requiredFees.Cmp(setFees) > 0 {
-		// TODO: check if there is an existing set with lower fees that we can
-		// kick out.
 		return errLowMinerFees
 	}
 
@@ -286,10 +466,22 @@ func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction, txnFn fu
 	if len(conflicts) > 0 {
 		return tp.handleConflicts(ts, conflicts, txnFn)
 	}
+
+	// Check that the transaction set fits into the pool, possibly by evicting
+	// sets that pay a lower fee rate.
+	tsetSize := len(encoding.Marshal(ts))
+	evictions, err := tp.evictionCandidates(setFees, uint64(tsetSize), nil)
+	if err != nil {
+		return err
+	}
+
 	cc, err := txnFn(ts)
 	if err != nil {
 		return modules.NewConsensusConflict("provided transaction set is standalone and invalid: " + err.Error())
 	}
+	for _, id := range evictions {
+		tp.evictTransactionSet(id)
+	}
 
 	// Add the transaction set to the pool.
 	setID := TransactionSetID(crypto.HashObject(ts))
@@ -298,8 +490,8 @@ func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction, txnFn fu
 		tp.knownObjects[oid] = setID
 	}
 	tp.transactionSetDiffs[setID] = &cc
-	tsetSize := len(encoding.Marshal(ts))
 	tp.transactionListSize += tsetSize
+	tp.feeIndex.add(setID, setFees, uint64(tsetSize), 1)
 	for _, txn := range ts {
 		if _, exists := tp.transactionHeights[txn.ID()]; !exists {
 			tp.transactionHeights[txn.ID()] = tp.blockHeight
@@ -377,5 +569,28 @@ func (tp *TransactionPool) relayTransactionSet(conn modules.PeerConn) error {
 		return err
 	}
 
-	return tp.AcceptTransactionSet(ts)
+	err = tp.AcceptTransactionSet(ts)
+	if err != nil && tp.isInvalidTransactionSet(ts, err) {
+		tp.gateway.ReportMisbehavior(conn.RPCAddr(), modules.MisbehaviorInvalidTransactionSet, err)
+	}
+	return err
+}
+
+// isInvalidTransactionSet returns true if a transaction set that was rejected
+// with err is invalid regardless of the state of the transaction pool and the
+// consensus set. A set that conflicts with the pool, pays too little or
+// spends outputs that do not exist (yet) may still be relayed by an honest
+// peer.
+func (tp *TransactionPool) isInvalidTransactionSet(ts []types.Transaction, err error) bool {
+	switch err {
+	case errEmptySet, modules.ErrInvalidArbPrefix, modules.ErrLargeTransaction, modules.ErrLargeTransactionSet:
+		return true
+	}
+	height := tp.consensusSet.Height()
+	for _, t := range ts {
+		if t.StandaloneValid(height) != nil {
+			return true
+		}
+	}
+	return false
 }
@@ -3,6 +3,7 @@ package transactionpool
 import (
 	"testing"
 
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 	"github.com/NebulousLabs/fastrand"
@@ -72,8 +73,9 @@ func TestConflictingTransactionSets(t *testing.T) {
 	}
 	defer tpt.Close()
 
-	// Fund a partial transaction.
-	fund := types.NewCurrency64(30e6)
+	// Fund a partial transaction. The fund is large enough that paying it as
+	// a miner fee covers the fee increment required to replace a set.
+	fund := types.SiacoinPrecision
 	txnBuilder, err := tpt.wallet.StartTransaction()
 	if err != nil {
 		t.Fatal(err)
@@ -108,15 +110,111 @@ func TestConflictingTransactionSets(t *testing.T) {
 		t.Error("transaction should not have passed inspection")
 	}
 
-	// Purge and try the sets in the reverse order.
+	// Purge and try the sets in the reverse order. The set with the miner fee
+	// pays more fees, so it replaces the set without fees.
 	tpt.tpool.PurgeTransactionPool()
 	err = tpt.tpool.AcceptTransactionSet(txnSetDoubleSpend)
 	if err != nil {
 		t.Error(err)
 	}
 	err = tpt.tpool.AcceptTransactionSet(txnSet)
-	if err == nil {
-		t.Error("transaction should not have passed inspection")
+	if err != nil {
+		t.Error("set with higher fees should replace the set without fees:", err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(txnSetDoubleSpend[txnIndex].ID()); exists {
+		t.Error("replaced transaction is still in the transaction pool")
+	}
+	if _, _, exists := tpt.tpool.Transaction(txnSet[txnIndex].ID()); !exists {
+		t.Error("replacement transaction is not in the transaction pool")
+	}
+}
+
+// TestReplaceByFee checks that a transaction in the transaction pool is only
+// replaced by a double-spend that pays sufficiently higher fees, and that the
+// parents of the replaced transaction are kept.
+func TestReplaceByFee(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Create transactions that spend the same output of a parent, with
+	// increasing fees.
+	fund := types.SiacoinPrecision
+	txnBuilder, err := tpt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = txnBuilder.FundSiacoins(fund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// wholeTransaction is set to false so that the same signature can be
+	// used for all of the transactions.
+	txnSet, err := txnBuilder.Sign(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txnSet) != 2 {
+		t.Fatal("expected a parent and a child transaction, got", len(txnSet))
+	}
+	parent, child := txnSet[0], txnSet[1]
+	withFee := func(fee types.Currency) types.Transaction {
+		txn := child
+		txn.MinerFees = []types.Currency{fee}
+		txn.SiacoinOutputs = []types.SiacoinOutput{{Value: fund.Sub(fee)}}
+		return txn
+	}
+	// The increment from mid to high covers the minimum fee per byte of the
+	// child, slightlyHigher pays only a single hasting more than mid.
+	increment := minReplacementFeeIncrement.Mul64(uint64(len(encoding.Marshal(child))) * 2)
+	low := withFee(minReplacementFeeIncrement.Mul64(1e3))
+	mid := withFee(minReplacementFeeIncrement.Mul64(2e3))
+	slightlyHigher := withFee(minReplacementFeeIncrement.Mul64(2e3).Add(types.NewCurrency64(1)))
+	high := withFee(minReplacementFeeIncrement.Mul64(2e3).Add(increment))
+
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{parent, mid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{parent, low})
+	if err != errLowReplacementFees {
+		t.Fatal("expected errLowReplacementFees, got", err)
+	}
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{parent, slightlyHigher})
+	if err != errLowReplacementFees {
+		t.Fatal("expected errLowReplacementFees, got", err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(mid.ID()); !exists {
+		t.Fatal("transaction was replaced by a transaction that pays a negligible fee increase")
+	}
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{parent, high})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(mid.ID()); exists {
+		t.Fatal("replaced transaction is still in the transaction pool")
+	}
+	for _, txn := range []types.Transaction{parent, high} {
+		if _, _, exists := tpt.tpool.Transaction(txn.ID()); !exists {
+			t.Fatal("transaction is missing from the transaction pool:", txn.ID())
+		}
+	}
+	if len(tpt.tpool.TransactionList()) != 2 {
+		t.Fatal("expected 2 transactions in the pool, got", len(tpt.tpool.TransactionList()))
+	}
+
+	// The replacement is mined.
+	_, err = tpt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tpt.tpool.TransactionList()) != 0 {
+		t.Fatal("transaction pool should be empty after mining a block")
 	}
 }
 
@@ -232,6 +330,178 @@ func TestCheckMinerFees(t *testing.T) {
 	}
 }
 
+// TestFeeRateEviction checks that a full transaction pool evicts the sets
+// with the lowest fee rates to make room for a set that pays more, and rejects
+// sets that do not pay more.
+func TestFeeRateEviction(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Fill the transaction pool with arbitrary data transactions that pay no
+	// fees, and limit the pool to its current size.
+	arbTxn := func() types.Transaction {
+		arbData := make([]byte, 10e3)
+		copy(arbData, modules.PrefixNonSia[:])
+		fastrand.Read(arbData[100:116]) // prevents collisions with other transacitons.
+		return types.Transaction{ArbitraryData: [][]byte{arbData}}
+	}
+	numArbTxns := 5
+	for i := 0; i < numArbTxns; i++ {
+		err := tpt.tpool.AcceptTransactionSet([]types.Transaction{arbTxn()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tpt.tpool.mu.Lock()
+	tpt.tpool.sizeLimit = tpt.tpool.transactionListSize
+	tpt.tpool.mu.Unlock()
+
+	// Another set that pays no fees does not fit into the pool.
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{arbTxn()})
+	if err != errFullTransactionPool {
+		t.Fatal("expected errFullTransactionPool, got", err)
+	}
+
+	// A set that pays fees evicts one of the arbitrary data transactions.
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(txns[len(txns)-1].ID()); !exists {
+		t.Fatal("transaction set with fees was not added to the pool")
+	}
+	tpt.tpool.mu.Lock()
+	defer tpt.tpool.mu.Unlock()
+	if tpt.tpool.transactionListSize > tpt.tpool.sizeLimit {
+		t.Error("transaction pool exceeds its size limit")
+	}
+	if len(tpt.tpool.transactionSets) != numArbTxns {
+		t.Error("expected one set to be evicted, pool has", len(tpt.tpool.transactionSets), "sets")
+	}
+	if len(tpt.tpool.feeIndex.sets) != len(tpt.tpool.transactionSets) || tpt.tpool.feeIndex.heap.Len() != len(tpt.tpool.transactionSets) {
+		t.Error("fee index is out of sync with the transaction sets")
+	}
+}
+
+// TestChainDepthLimit checks that the transaction pool limits the number of
+// dependent unconfirmed transaction sets that can be chained.
+func TestChainDepthLimit(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Create an unconfirmed output that TransactionGraph can spend, and a
+	// chain of transactions that each spend the output of the previous one.
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(1000), types.UnlockConditions{}.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var edges []types.TransactionGraphEdge
+	for i := 0; i < maxChainDepth; i++ {
+		edges = append(edges, types.TransactionGraphEdge{
+			Dest:   i + 1,
+			Fee:    types.SiacoinPrecision,
+			Source: i,
+			Value:  types.SiacoinPrecision.Mul64(uint64(999 - i)),
+		})
+	}
+	chain, err := types.TransactionGraph(txns[len(txns)-1].SiacoinOutputID(0), edges)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each transaction of the chain extends the chain of the wallet's set by
+	// one, until the chain is too deep.
+	for i := 0; i < maxChainDepth-1; i++ {
+		err := tpt.tpool.AcceptTransactionSet([]types.Transaction{chain[i]})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	last := []types.Transaction{chain[maxChainDepth-1]}
+	if err := tpt.tpool.AcceptTransactionSet(last); err != errDeepChain {
+		t.Fatal("expected errDeepChain, got", err)
+	}
+
+	// Once the chain is confirmed, the last transaction is accepted.
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tpt.tpool.AcceptTransactionSet(last); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestChainSizeLimit checks that the transaction pool limits the size of a
+// chain of dependent unconfirmed transaction sets.
+func TestChainSizeLimit(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Create an unconfirmed output that anyone can spend, and a chain of
+	// large transactions that each spend the output of the previous one.
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(1000), types.UnlockConditions{}.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parentID := txns[len(txns)-1].SiacoinOutputID(0)
+	value := types.SiacoinPrecision.Mul64(1000)
+	padding := append(modules.PrefixNonSia[:], make([]byte, modules.TransactionSizeLimit-2e3)...)
+	var chain []types.Transaction
+	for size := 0; size <= maxChainSize; {
+		value = value.Sub(types.SiacoinPrecision)
+		txn := types.Transaction{
+			SiacoinInputs:  []types.SiacoinInput{{ParentID: parentID}},
+			SiacoinOutputs: []types.SiacoinOutput{{Value: value, UnlockHash: types.UnlockConditions{}.UnlockHash()}},
+			MinerFees:      []types.Currency{types.SiacoinPrecision},
+			ArbitraryData:  [][]byte{padding},
+		}
+		chain = append(chain, txn)
+		parentID = txn.SiacoinOutputID(0)
+		size += len(encoding.Marshal(txn))
+	}
+	if len(chain) >= maxChainDepth {
+		t.Fatal("chain is too deep to hit the size limit")
+	}
+
+	// Each transaction of the chain extends the chain of the wallet's set,
+	// until the chain is too large.
+	for _, txn := range chain[:len(chain)-1] {
+		if err := tpt.tpool.AcceptTransactionSet([]types.Transaction{txn}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	last := []types.Transaction{chain[len(chain)-1]}
+	if err := tpt.tpool.AcceptTransactionSet(last); err != errLargeChain {
+		t.Fatal("expected errLargeChain, got", err)
+	}
+
+	// Once the chain is confirmed, the last transaction is accepted.
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tpt.tpool.AcceptTransactionSet(last); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestTransactionGraph checks that the TransactionGraph method of the types
 // package is able to create transasctions that actually validate and can get
 // inserted into the tpool.
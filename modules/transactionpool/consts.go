@@ -37,6 +37,24 @@ const (
 	// TransactionPoolSizeTarget defines the target size of the pool when the
 	// transactions are paying 1 SC / kb in fees.
 	TransactionPoolSizeTarget = 3e6
+
+	// TransactionPoolSizeLimit defines the maximum size of the transaction
+	// pool. A transaction set that does not fit into a full pool is only
+	// accepted if it pays a higher fee per byte than the sets that have to be
+	// evicted to make room for it.
+	TransactionPoolSizeLimit = 2 * TransactionPoolSizeTarget
+
+	// maxChainSize defines the maximum size of a chain of dependent
+	// unconfirmed transaction sets. Dependent sets are merged in the pool, and
+	// the merged set is validated again whenever the chain is extended, so a
+	// chain is limited to less than the largest standard transaction set.
+	maxChainSize = 100e3
+
+	// maxChainDepth defines the maximum number of dependent unconfirmed
+	// transaction sets that can be chained in the transaction pool. Every set
+	// that extends a chain causes the whole chain to be validated again, so
+	// long chains are expensive for the pool.
+	maxChainDepth = 25
 )
 
 // Constants related to fee estimation.
@@ -54,6 +72,16 @@ const (
 	// amount required to extend the fee pool when coming up with a min fee
 	// recommendation.
 	minExtendMultiplier = 1.2
+
+	// feeEstimationConfidence is the fraction of the observed transaction
+	// sets paying a fee rate that need to have been confirmed within the
+	// target number of blocks for the rate to be recommended.
+	feeEstimationConfidence = 0.85
+
+	// feeEstimationMinSamples is the minimum number of observed transaction
+	// sets that the fee history needs to contain before it is used to
+	// estimate fees for a confirmation target.
+	feeEstimationMinSamples = 10
 )
 
 // Variables related to the persisting structures of the transaction pool.
@@ -69,6 +97,22 @@ var (
 	// minEstimation defines a sane minimum fee per byte for transactions.  This
 	// will typically be only suggested as a fee in the absence of congestion.
 	minEstimation = types.SiacoinPrecision.Div64(100).Div64(1e3)
+
+	// minReplacementFeeIncrement defines the minimum fee per byte that a
+	// transaction set has to pay on top of the fees of the sets it replaces
+	// while the pool is not congested enough to require fees.
+	minReplacementFeeIncrement = minEstimation
+)
+
+// Variables related to fee estimation.
+var (
+	// feeHistoryDepth defines how many of the most recent blocks are kept in
+	// the fee history.
+	feeHistoryDepth = build.Select(build.Var{
+		Standard: types.BlockHeight(1008),
+		Dev:      types.BlockHeight(100),
+		Testing:  types.BlockHeight(20),
+	}).(types.BlockHeight)
 )
 
 // Variables related to propagating transactions through the network.
@@ -80,4 +124,13 @@ var (
 		Dev:      20 * time.Second,
 		Testing:  3 * time.Second,
 	}).(time.Duration)
+
+	// restoredSetsBroadcastDelay is the time the transaction pool waits after
+	// startup before it rebroadcasts the transaction sets that were restored
+	// from the database, to give the gateway time to connect to peers.
+	restoredSetsBroadcastDelay = build.Select(build.Var{
+		Standard: 2 * time.Minute,
+		Dev:      20 * time.Second,
+		Testing:  time.Second,
+	}).(time.Duration)
 )
@@ -2,6 +2,7 @@ package transactionpool
 
 import (
 	"encoding/json"
+	"sort"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/encoding"
@@ -26,6 +27,10 @@ var (
 	// been confirmed on the blockchain.
 	bucketConfirmedTransactions = []byte("ConfirmedTransactions")
 
+	// bucketFeeHistory holds the fee history of the recent confirmed blocks,
+	// keyed by block height.
+	bucketFeeHistory = []byte("FeeHistory")
+
 	// bucketFeeMedian stores all of the persist data relating to the fee
 	// median.
 	bucketFeeMedian = []byte("FeeMedian")
@@ -33,6 +38,10 @@ var (
 	// bucketRecentConsensusChange holds the most recent consensus change seen
 	// by the transaction pool.
 	bucketRecentConsensusChange = []byte("RecentConsensusChange")
+
+	// bucketTransactionSets holds the unconfirmed transaction sets of the
+	// pool, keyed by transaction set id, so that they survive restarts.
+	bucketTransactionSets = []byte("TransactionSets")
 )
 
 // Explicitly named fields in the database.
@@ -77,8 +86,22 @@ type (
 		RecentMedians   []types.Currency
 		RecentMedianFee types.Currency
 	}
+
+	// storedTransactionSet is an unconfirmed transaction set as it is stored
+	// in bucketTransactionSets. SeenHeights holds the height at which the
+	// pool first saw each transaction of the set, so that the age of the
+	// transactions survives restarts.
+	storedTransactionSet struct {
+		Transactions []types.Transaction
+		SeenHeights  []types.BlockHeight
+	}
 )
 
+// deleteFeeHistory deletes the fee history of the block at the given height.
+func (tp *TransactionPool) deleteFeeHistory(tx *bolt.Tx, height types.BlockHeight) error {
+	return tx.Bucket(bucketFeeHistory).Delete(encoding.Marshal(height))
+}
+
 // deleteTransaction deletes a transaction from the list of confirmed
 // transactions.
 func (tp *TransactionPool) deleteTransaction(tx *bolt.Tx, id types.TransactionID) error {
@@ -91,6 +114,36 @@ func (tp *TransactionPool) getBlockHeight(tx *bolt.Tx) (bh types.BlockHeight, er
 	return
 }
 
+// getFeeHistory returns the fee history of all blocks stored in the database,
+// sorted by height.
+func (tp *TransactionPool) getFeeHistory(tx *bolt.Tx) (history []blockFeeHistory, err error) {
+	err = tx.Bucket(bucketFeeHistory).ForEach(func(_, v []byte) error {
+		var bfh blockFeeHistory
+		if err := encoding.Unmarshal(v, &bfh); err != nil {
+			return build.ExtendErr("unable to unmarshal fee history:", err)
+		}
+		history = append(history, bfh)
+		return nil
+	})
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Height < history[j].Height
+	})
+	return history, err
+}
+
+// getTransactionSets returns the transaction sets stored in the database.
+func (tp *TransactionPool) getTransactionSets(tx *bolt.Tx) (sets []storedTransactionSet, err error) {
+	err = tx.Bucket(bucketTransactionSets).ForEach(func(_, v []byte) error {
+		var set storedTransactionSet
+		if err := encoding.Unmarshal(v, &set); err != nil {
+			return build.ExtendErr("unable to unmarshal transaction set:", err)
+		}
+		sets = append(sets, set)
+		return nil
+	})
+	return sets, err
+}
+
 // getFeeMedian will get the fee median struct stored in the database.
 func (tp *TransactionPool) getFeeMedian(tx *bolt.Tx) (medianPersist, error) {
 	medianBytes := tp.dbTx.Bucket(bucketFeeMedian).Get(fieldFeeMedian)
@@ -137,6 +190,11 @@ func (tp *TransactionPool) putBlockHeight(tx *bolt.Tx, height types.BlockHeight)
 	return tx.Bucket(bucketBlockHeight).Put(fieldBlockHeight, encoding.Marshal(height))
 }
 
+// putFeeHistory stores the fee history of a block.
+func (tp *TransactionPool) putFeeHistory(tx *bolt.Tx, bfh blockFeeHistory) error {
+	return tx.Bucket(bucketFeeHistory).Put(encoding.Marshal(bfh.Height), encoding.Marshal(bfh))
+}
+
 // putFeeMedian puts a median fees object into the database.
 func (tp *TransactionPool) putFeeMedian(tx *bolt.Tx, mp medianPersist) error {
 	objBytes, err := json.Marshal(mp)
@@ -158,6 +216,31 @@ func (tp *TransactionPool) putRecentConsensusChange(tx *bolt.Tx, cc modules.Cons
 	return tx.Bucket(bucketRecentConsensusChange).Put(fieldRecentConsensusChange, cc[:])
 }
 
+// putTransactionSets replaces the transaction sets stored in the database with
+// the current unconfirmed transaction sets of the pool.
+func (tp *TransactionPool) putTransactionSets(tx *bolt.Tx) error {
+	if err := tx.DeleteBucket(bucketTransactionSets); err != nil {
+		return err
+	}
+	b, err := tx.CreateBucket(bucketTransactionSets)
+	if err != nil {
+		return err
+	}
+	for id, set := range tp.transactionSets {
+		stored := storedTransactionSet{
+			Transactions: set,
+			SeenHeights:  make([]types.BlockHeight, len(set)),
+		}
+		for i, txn := range set {
+			stored.SeenHeights[i] = tp.transactionHeights[txn.ID()]
+		}
+		if err := b.Put(id[:], encoding.Marshal(stored)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // putTransaction adds a transaction to the list of confirmed transactions.
 func (tp *TransactionPool) putTransaction(tx *bolt.Tx, id types.TransactionID) error {
 	return tx.Bucket(bucketConfirmedTransactions).Put(id[:], []byte{})
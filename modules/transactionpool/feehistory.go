@@ -0,0 +1,115 @@
+package transactionpool
+
+// feehistory.go keeps a history of the fee rates that were paid by the
+// transaction sets in the recent confirmed blocks, and of the number of blocks
+// that these sets waited in the transaction pool before they were confirmed.
+// The history is used to estimate the fee rate that a transaction set needs
+// to pay to be confirmed within a target number of blocks.
+
+import (
+	"sort"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+type (
+	// confirmedSet describes a transaction set that was confirmed in a block.
+	// Wait is the number of blocks that the set waited in the transaction pool
+	// before it was confirmed, and is zero if the set was never seen by the
+	// transaction pool.
+	confirmedSet struct {
+		FeeRate types.Currency // SC per byte
+		Size    uint64
+		Wait    types.BlockHeight
+	}
+
+	// blockFeeHistory is the fee history of a single confirmed block.
+	blockFeeHistory struct {
+		Height types.BlockHeight
+		Sets   []confirmedSet
+	}
+)
+
+// confirmationWait returns the number of blocks that the transactions of ts
+// waited in the transaction pool before being confirmed at the current
+// height, measured from the first transaction that was seen. Zero is returned
+// if none of the transactions were seen.
+func (tp *TransactionPool) confirmationWait(ts []types.Transaction) (wait types.BlockHeight) {
+	for _, txn := range ts {
+		seenHeight, seen := tp.transactionHeights[txn.ID()]
+		if seen && seenHeight < tp.blockHeight && tp.blockHeight-seenHeight > wait {
+			wait = tp.blockHeight - seenHeight
+		}
+	}
+	return wait
+}
+
+// applyFeeHistory adds the fee history of the block at the current height,
+// and drops history that is older than feeHistoryDepth.
+func (tp *TransactionPool) applyFeeHistory(bfh blockFeeHistory) {
+	err := tp.putFeeHistory(tp.dbTx, bfh)
+	if err != nil {
+		tp.log.Println("ERROR: could not store the fee history of a block:", err)
+	}
+	tp.feeHistory = append(tp.feeHistory, bfh)
+	for len(tp.feeHistory) > 0 && tp.feeHistory[0].Height+feeHistoryDepth <= bfh.Height {
+		err := tp.deleteFeeHistory(tp.dbTx, tp.feeHistory[0].Height)
+		if err != nil {
+			tp.log.Println("ERROR: could not delete the fee history of a block:", err)
+		}
+		tp.feeHistory = tp.feeHistory[1:]
+	}
+}
+
+// revertFeeHistory removes the fee history of the block at the given height.
+func (tp *TransactionPool) revertFeeHistory(height types.BlockHeight) {
+	err := tp.deleteFeeHistory(tp.dbTx, height)
+	if err != nil {
+		tp.log.Println("ERROR: could not delete the fee history of a block:", err)
+	}
+	if n := len(tp.feeHistory); n > 0 && tp.feeHistory[n-1].Height == height {
+		tp.feeHistory = tp.feeHistory[:n-1]
+	}
+}
+
+// estimateFeeRate uses the fee history to find the lowest fee rate at which
+// at least feeEstimationConfidence of the observed transaction sets were
+// confirmed within target blocks. Only sets paying that fee rate or more are
+// considered for each candidate rate. false is returned if the history does
+// not contain enough observations, or if even the highest paying sets were
+// not confirmed quickly enough.
+func (tp *TransactionPool) estimateFeeRate(target types.BlockHeight) (types.Currency, bool) {
+	var observed []confirmedSet
+	for _, bfh := range tp.feeHistory {
+		for _, cs := range bfh.Sets {
+			if cs.Wait > 0 {
+				observed = append(observed, cs)
+			}
+		}
+	}
+	if len(observed) < feeEstimationMinSamples {
+		return types.ZeroCurrency, false
+	}
+	sort.Slice(observed, func(i, j int) bool {
+		return observed[i].FeeRate.Cmp(observed[j].FeeRate) > 0
+	})
+
+	var rate types.Currency
+	var found bool
+	var confirmed int
+	for i, cs := range observed {
+		if cs.Wait <= target {
+			confirmed++
+		}
+		// Only consider a rate after all of the sets paying that rate have
+		// been counted.
+		if i+1 < len(observed) && observed[i+1].FeeRate.Equals(cs.FeeRate) {
+			continue
+		}
+		if i+1 >= feeEstimationMinSamples && float64(confirmed)/float64(i+1) >= feeEstimationConfidence {
+			rate = cs.FeeRate
+			found = true
+		}
+	}
+	return rate, found
+}
@@ -0,0 +1,125 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestEstimateFeeRate checks that the fee rate estimated for a confirmation
+// target is the lowest rate at which enough of the observed transaction sets
+// were confirmed within the target.
+func TestEstimateFeeRate(t *testing.T) {
+	tp := &TransactionPool{}
+	if _, ok := tp.estimateFeeRate(1); ok {
+		t.Fatal("fee rate was estimated without any history")
+	}
+
+	// Sets paying 100 are confirmed after 1 block, sets paying 50 after 3
+	// blocks and sets paying 10 after 10 blocks. Sets that were never seen
+	// in the pool are ignored.
+	bfh := blockFeeHistory{Height: 10}
+	for i := 0; i < feeEstimationMinSamples; i++ {
+		bfh.Sets = append(bfh.Sets,
+			confirmedSet{FeeRate: types.NewCurrency64(100), Size: 1e3, Wait: 1},
+			confirmedSet{FeeRate: types.NewCurrency64(50), Size: 1e3, Wait: 3},
+			confirmedSet{FeeRate: types.NewCurrency64(10), Size: 1e3, Wait: 10},
+			confirmedSet{FeeRate: types.NewCurrency64(1), Size: 1e3, Wait: 0},
+		)
+	}
+	tp.feeHistory = []blockFeeHistory{bfh}
+
+	tests := []struct {
+		target types.BlockHeight
+		rate   uint64
+	}{
+		{1, 100},
+		{2, 100},
+		{3, 50},
+		{10, 10},
+		{20, 10},
+	}
+	for _, test := range tests {
+		rate, ok := tp.estimateFeeRate(test.target)
+		if !ok {
+			t.Fatal("no fee rate was estimated for target", test.target)
+		}
+		if !rate.Equals64(test.rate) {
+			t.Errorf("expected rate %v for target %v, got %v", test.rate, test.target, rate)
+		}
+	}
+}
+
+// TestFeeHistory checks that the transaction pool records the fee rates and
+// confirmation waits of confirmed blocks, and persists them.
+func TestFeeHistory(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	if _, err := tpt.tpool.FeeEstimationTarget(0); err != errZeroTarget {
+		t.Fatal("expected errZeroTarget, got", err)
+	}
+
+	// Confirm a transaction set that waited one block in the pool.
+	if _, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	tpt.tpool.mu.Lock()
+	history := tpt.tpool.feeHistory
+	tpt.tpool.mu.Unlock()
+	if len(history) == 0 || history[len(history)-1].Height != tpt.cs.Height() {
+		t.Fatal("fee history does not contain the most recent block")
+	}
+	recorded := false
+	for _, cs := range history[len(history)-1].Sets {
+		recorded = recorded || (cs.Wait == 1 && !cs.FeeRate.IsZero())
+	}
+	if !recorded {
+		t.Fatal("fee history did not record the confirmed set:", history[len(history)-1].Sets)
+	}
+
+	// Mine enough blocks to drop the oldest history.
+	for i := types.BlockHeight(0); i < feeHistoryDepth; i++ {
+		if _, err := tpt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tpt.tpool.mu.Lock()
+	historyLen := len(tpt.tpool.feeHistory)
+	tpt.tpool.mu.Unlock()
+	if historyLen != int(feeHistoryDepth) {
+		t.Fatalf("expected %v blocks of fee history, got %v", feeHistoryDepth, historyLen)
+	}
+
+	// The estimate for a target is always within the range of FeeEstimation.
+	min, max := tpt.tpool.FeeEstimation()
+	fee, err := tpt.tpool.FeeEstimationTarget(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee.Cmp(min) < 0 || fee.Cmp(max) > 0 {
+		t.Error("fee estimate for a target is outside of the estimated range")
+	}
+
+	// The fee history is persisted.
+	persistDir := tpt.tpool.persistDir
+	if err := tpt.tpool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tpt.tpool, err = New(tpt.cs, tpt.gateway, persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tpt.tpool.feeHistory) != historyLen {
+		t.Fatalf("expected %v blocks of fee history after reloading, got %v", historyLen, len(tpt.tpool.feeHistory))
+	}
+}
@@ -0,0 +1,129 @@
+package transactionpool
+
+import (
+	"container/heap"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+type (
+	// setFeeEntry tracks the fees, the size and the chain depth of a
+	// transaction set in the pool. The index is the position of the entry in
+	// the setFeeHeap.
+	setFeeEntry struct {
+		id    TransactionSetID
+		fees  types.Currency
+		size  uint64
+		depth int
+		index int
+	}
+
+	// setFeeHeap is a min-heap of transaction sets, ordered by fee rate
+	// according to modules.CmpFeeRate. It implements heap.Interface.
+	setFeeHeap []*setFeeEntry
+
+	// feeIndex indexes the transaction sets of the pool by fee rate, so that
+	// the sets paying the lowest fee per byte can be evicted when the pool is
+	// full.
+	feeIndex struct {
+		heap setFeeHeap
+		sets map[TransactionSetID]*setFeeEntry
+	}
+)
+
+// rate returns the fee per byte paid by the set.
+func (e *setFeeEntry) rate() types.Currency {
+	return e.fees.Div64(e.size)
+}
+
+// cmp compares the fee rate of e to the fee rate of other.
+func (e *setFeeEntry) cmp(other *setFeeEntry) int {
+	return modules.CmpFeeRate(e.rate(), e.size, other.rate(), other.size)
+}
+
+func (h setFeeHeap) Len() int           { return len(h) }
+func (h setFeeHeap) Less(i, j int) bool { return h[i].cmp(h[j]) < 0 }
+func (h setFeeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+// Push adds an entry to the heap. Use heap.Push instead of calling Push
+// directly.
+func (h *setFeeHeap) Push(x interface{}) {
+	e := x.(*setFeeEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+// Pop removes the last entry of the heap. Use heap.Pop instead of calling Pop
+// directly.
+func (h *setFeeHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+// newFeeIndex returns an empty feeIndex.
+func newFeeIndex() *feeIndex {
+	return &feeIndex{
+		sets: make(map[TransactionSetID]*setFeeEntry),
+	}
+}
+
+// add adds a transaction set to the index.
+func (fi *feeIndex) add(id TransactionSetID, fees types.Currency, size uint64, depth int) {
+	e := &setFeeEntry{
+		id:    id,
+		fees:  fees,
+		size:  size,
+		depth: depth,
+	}
+	fi.sets[id] = e
+	heap.Push(&fi.heap, e)
+}
+
+// remove removes a transaction set from the index.
+func (fi *feeIndex) remove(id TransactionSetID) {
+	e, exists := fi.sets[id]
+	if !exists {
+		return
+	}
+	heap.Remove(&fi.heap, e.index)
+	delete(fi.sets, id)
+}
+
+// depth returns the chain depth of a transaction set in the index.
+func (fi *feeIndex) depth(id TransactionSetID) int {
+	e, exists := fi.sets[id]
+	if !exists {
+		return 0
+	}
+	return e.depth
+}
+
+// lowest returns the sets with the lowest fee rates, skipping the sets in
+// skip, until their combined size is at least size. Fewer sets are returned
+// if the index does not contain enough sets. The sets are popped from the
+// heap to visit them in order, and pushed back before lowest returns.
+func (fi *feeIndex) lowest(size uint64, skip map[TransactionSetID]struct{}) (entries []*setFeeEntry) {
+	var popped []*setFeeEntry
+	var total uint64
+	for total < size && fi.heap.Len() > 0 {
+		e := heap.Pop(&fi.heap).(*setFeeEntry)
+		popped = append(popped, e)
+		if _, exists := skip[e.id]; exists {
+			continue
+		}
+		entries = append(entries, e)
+		total += e.size
+	}
+	for _, e := range popped {
+		heap.Push(&fi.heap, e)
+	}
+	return entries
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
 	"github.com/NebulousLabs/Sia/types"
@@ -38,10 +39,14 @@ func (tp *TransactionPool) threadedRegularSync() {
 }
 
 // syncDB commits the current global transaction and immediately begins a new
-// one.
+// one. The unconfirmed transaction sets are stored before committing.
 func (tp *TransactionPool) syncDB() {
+	err := tp.putTransactionSets(tp.dbTx)
+	if err != nil {
+		tp.log.Println("ERROR: could not store the transaction sets:", err)
+	}
 	// Commit the existing tx.
-	err := tp.dbTx.Commit()
+	err = tp.dbTx.Commit()
 	if err != nil {
 		tp.log.Severe("ERROR: failed to apply database update:", err)
 		tp.dbTx.Rollback()
@@ -59,6 +64,15 @@ func (tp *TransactionPool) resetDB(tx *bolt.Tx) error {
 	if err != nil {
 		return err
 	}
+	err = tx.DeleteBucket(bucketFeeHistory)
+	if err != nil {
+		return err
+	}
+	tp.feeHistory = nil
+	_, err = tx.CreateBucket(bucketFeeHistory)
+	if err != nil {
+		return err
+	}
 	err = tp.putRecentBlockID(tx, types.BlockID{})
 	if err != nil {
 		return err
@@ -113,7 +127,11 @@ func (tp *TransactionPool) initPersist() error {
 	}
 	tp.tg.AfterStop(func() {
 		tp.mu.Lock()
-		err := tp.dbTx.Commit()
+		err := tp.putTransactionSets(tp.dbTx)
+		if err != nil {
+			tp.log.Println("Unable to store the transaction sets during shutdown:", err)
+		}
+		err = tp.dbTx.Commit()
 		tp.mu.Unlock()
 		if err != nil {
 			tp.log.Println("Unable to close transaction properly during shutdown:", err)
@@ -129,7 +147,9 @@ func (tp *TransactionPool) initPersist() error {
 		bucketBlockHeight,
 		bucketRecentConsensusChange,
 		bucketConfirmedTransactions,
+		bucketFeeHistory,
 		bucketFeeMedian,
+		bucketTransactionSets,
 	}
 	for _, bucket := range buckets {
 		_, err := tp.dbTx.CreateBucketIfNotExists(bucket)
@@ -176,6 +196,12 @@ func (tp *TransactionPool) initPersist() error {
 		tp.recentMedianFee = mp.RecentMedianFee
 	}
 
+	// Get the fee history.
+	tp.feeHistory, err = tp.getFeeHistory(tp.dbTx)
+	if err != nil {
+		return build.ExtendErr("unable to load the fee history", err)
+	}
+
 	// Subscribe to the consensus set using the most recent consensus change.
 	err = tp.consensusSet.ConsensusSetSubscribe(tp, cc, tp.tg.StopChan())
 	if err == modules.ErrInvalidConsensusChangeID {
@@ -187,7 +213,9 @@ func (tp *TransactionPool) initPersist() error {
 			return resetErr
 		}
 		freshScanErr := tp.consensusSet.ConsensusSetSubscribe(tp, modules.ConsensusChangeBeginning, tp.tg.StopChan())
-		if freshScanErr != nil {
+		if freshScanErr == modules.ErrBlockPruned {
+			return errPrunedSubscription
+		} else if freshScanErr != nil {
 			return freshScanErr
 		}
 		tp.tg.OnStop(func() {
@@ -195,6 +223,12 @@ func (tp *TransactionPool) initPersist() error {
 		})
 		return nil
 	}
+	if err == modules.ErrBlockPruned {
+		// The consensus set has pruned blocks that the transaction pool has
+		// missed while it was offline, so the transaction pool cannot catch
+		// up.
+		return errPrunedSubscription
+	}
 	if err != nil {
 		return err
 	}
@@ -220,3 +254,91 @@ func (tp *TransactionPool) TransactionConfirmed(id types.TransactionID) (bool, e
 func (tp *TransactionPool) transactionConfirmed(tx *bolt.Tx, id types.TransactionID) bool {
 	return tx.Bucket(bucketConfirmedTransactions).Get(id[:]) != nil
 }
+
+// restoreTransactionSets adds the transaction sets that were stored in the
+// database back to the transaction pool. Each set is validated against the
+// current consensus set, and sets that are no longer valid are dropped. The
+// restored sets are rebroadcast after restoredSetsBroadcastDelay.
+func (tp *TransactionPool) restoreTransactionSets() error {
+	tp.mu.Lock()
+	sets, err := tp.getTransactionSets(tp.dbTx)
+	tp.mu.Unlock()
+	if err != nil {
+		return build.ExtendErr("unable to load the transaction sets", err)
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	cs, ok := tp.consensusSet.(interface {
+		LockedTryTransactionSet(fn func(func(txns []types.Transaction) (modules.ConsensusChange, error)) error) error
+	})
+	if !ok {
+		tp.log.Println("WARN: consensus set does not support LockedTryTransactionSet, dropping the stored transaction sets")
+		return nil
+	}
+
+	var restored []TransactionSetID
+	err = cs.LockedTryTransactionSet(func(txnFn func(txns []types.Transaction) (modules.ConsensusChange, error)) error {
+		tp.mu.Lock()
+		defer tp.mu.Unlock()
+		for _, stored := range sets {
+			set := stored.Transactions
+			err := tp.acceptTransactionSet(set, txnFn)
+			if err != nil {
+				tp.log.Debugln("Dropping stored transaction set:", err)
+				continue
+			}
+			// Restore the heights at which the transactions were first seen,
+			// so that a restart does not reset their age. Heights above the
+			// current height can be left by a reorg and are ignored.
+			for i, txn := range set {
+				if _, exists := tp.transactionHeights[txn.ID()]; exists && i < len(stored.SeenHeights) && stored.SeenHeights[i] <= tp.blockHeight {
+					tp.transactionHeights[txn.ID()] = stored.SeenHeights[i]
+				}
+			}
+			// The set may have been merged with other sets or stripped of
+			// confirmed transactions, so look up the id it is stored under.
+			setID := TransactionSetID(crypto.HashObject(set))
+			if oids := relatedObjectIDs(set[len(set)-1:]); len(oids) > 0 {
+				if id, exists := tp.knownObjects[oids[0]]; exists {
+					setID = id
+				}
+			}
+			restored = append(restored, setID)
+		}
+		tp.updateSubscribersTransactions()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	tp.log.Printf("Restored %v of %v stored transaction sets", len(restored), len(sets))
+	go tp.threadedBroadcastRestoredSets(restored)
+	return nil
+}
+
+// threadedBroadcastRestoredSets waits for restoredSetsBroadcastDelay and then
+// broadcasts the restored transaction sets that are still in the pool.
+func (tp *TransactionPool) threadedBroadcastRestoredSets(ids []TransactionSetID) {
+	if err := tp.tg.Add(); err != nil {
+		return
+	}
+	defer tp.tg.Done()
+	select {
+	case <-tp.tg.StopChan():
+		return
+	case <-time.After(restoredSetsBroadcastDelay):
+	}
+
+	tp.mu.Lock()
+	var sets [][]types.Transaction
+	for _, id := range ids {
+		if set, exists := tp.transactionSets[id]; exists {
+			sets = append(sets, set)
+		}
+	}
+	tp.mu.Unlock()
+	for _, set := range sets {
+		tp.Broadcast(set)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
 	"github.com/NebulousLabs/Sia/types"
@@ -93,3 +94,112 @@ func TestRescan(t *testing.T) {
 		t.Fatal("expecting modules.ErrDuplicateTransactionSet, got:", err)
 	}
 }
+
+// TestPersistTransactionSets checks that the unconfirmed transaction sets of
+// the pool are restored after a restart together with the heights at which
+// they were first seen, and that stored sets which are no longer valid are
+// dropped.
+func TestPersistTransactionSets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	txns, err := tpt.wallet.SendSiacoins(types.NewCurrency64(100), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := txns[len(txns)-1].ID()
+	seenHeight := tpt.tpool.transactionHeights[id]
+
+	// Mine blocks that do not confirm the set.
+	for i := 0; i < 2; i++ {
+		b, target, err := tpt.miner.BlockForWork()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Transactions = nil
+		b.MinerPayouts = []types.SiacoinOutput{{Value: types.CalculateCoinbase(tpt.cs.Height() + 1)}}
+		solved, ok := tpt.miner.SolveBlock(b, target)
+		if !ok {
+			t.Fatal("failed to solve block")
+		}
+		if err := tpt.cs.AcceptBlock(solved); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Restart the tpool. The transaction set should be restored, and its age
+	// should not be reset.
+	persistDir := tpt.tpool.persistDir
+	if err := tpt.tpool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tpt.tpool, err = New(tpt.cs, tpt.gateway, persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tpt.tpool.transactionSets) != 1 {
+		t.Fatal("expected 1 restored transaction set, got", len(tpt.tpool.transactionSets))
+	}
+	if _, _, exists := tpt.tpool.Transaction(id); !exists {
+		t.Fatal("restored transaction is not in the pool")
+	}
+	if height := tpt.tpool.transactionHeights[id]; height != seenHeight || height == tpt.cs.Height() {
+		t.Fatalf("restored transaction was seen at height %v, expected %v", height, seenHeight)
+	}
+
+	// Store an invalid transaction set, then restart the tpool. Only the
+	// valid set should be restored.
+	if err := tpt.tpool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, err := persist.OpenDatabase(dbMetadata, filepath.Join(persistDir, dbFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := storedTransactionSet{
+		Transactions: []types.Transaction{{
+			SiacoinInputs: []types.SiacoinInput{{}},
+		}},
+		SeenHeights: []types.BlockHeight{0},
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketTransactionSets).Put([]byte("invalid"), encoding.Marshal(invalid))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tpt.tpool, err = New(tpt.cs, tpt.gateway, persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tpt.tpool.transactionSets) != 1 {
+		t.Fatal("expected 1 restored transaction set, got", len(tpt.tpool.transactionSets))
+	}
+	if _, _, exists := tpt.tpool.Transaction(id); !exists {
+		t.Fatal("restored transaction is not in the pool")
+	}
+
+	// Once the set is confirmed, it should not be restored again.
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tpt.tpool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tpt.tpool, err = New(tpt.cs, tpt.gateway, persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tpt.tpool.transactionSets) != 0 {
+		t.Fatal("confirmed transaction set was restored")
+	}
+}
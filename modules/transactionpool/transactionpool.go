@@ -14,8 +14,10 @@ import (
 )
 
 var (
-	errNilCS      = errors.New("transaction pool cannot initialize with a nil consensus set")
-	errNilGateway = errors.New("transaction pool cannot initialize with a nil gateway")
+	errNilCS              = errors.New("transaction pool cannot initialize with a nil consensus set")
+	errNilGateway         = errors.New("transaction pool cannot initialize with a nil gateway")
+	errPrunedSubscription = errors.New("transaction pool cannot subscribe to the consensus set because it has pruned blocks that the transaction pool has not processed")
+	errZeroTarget         = errors.New("confirmation target must be at least one block")
 )
 
 type (
@@ -52,10 +54,17 @@ type (
 		transactionSetDiffs map[TransactionSetID]*modules.ConsensusChange
 		transactionListSize int
 
+		// The fee index orders the transaction sets by fee rate. When the size
+		// of the pool would exceed sizeLimit, the sets with the lowest fee
+		// rates are evicted to make room for sets that pay more.
+		feeIndex  *feeIndex
+		sizeLimit int
+
 		// Variables related to the blockchain.
 		blockHeight     types.BlockHeight
 		recentMedians   []types.Currency
 		recentMedianFee types.Currency // SC per byte
+		feeHistory      []blockFeeHistory
 
 		// The consensus change index tracks how many consensus changes have
 		// been sent to the transaction pool. When a new subscriber joins the
@@ -94,6 +103,9 @@ func New(cs modules.ConsensusSet, g modules.Gateway, persistDir string) (*Transa
 		transactionSets:     make(map[TransactionSetID][]types.Transaction),
 		transactionSetDiffs: make(map[TransactionSetID]*modules.ConsensusChange),
 
+		feeIndex:  newFeeIndex(),
+		sizeLimit: TransactionPoolSizeLimit,
+
 		persistDir: persistDir,
 	}
 
@@ -103,6 +115,13 @@ func New(cs modules.ConsensusSet, g modules.Gateway, persistDir string) (*Transa
 		return nil, err
 	}
 
+	// Add the transaction sets that were in the pool before the last
+	// shutdown.
+	err = tp.restoreTransactionSets()
+	if err != nil {
+		return nil, err
+	}
+
 	// Register RPCs
 	g.RegisterRPC("RelayTransactionSet", tp.relayTransactionSet)
 	tp.tg.OnStop(func() {
@@ -128,7 +147,46 @@ func (tp *TransactionPool) FeeEstimation() (min, max types.Currency) {
 	defer tp.tg.Done()
 	tp.mu.Lock()
 	defer tp.mu.Unlock()
+	return tp.feeEstimation()
+}
 
+// FeeEstimationTarget returns the fee per transaction byte that a transaction
+// set should pay to be confirmed within target blocks. The estimate is based
+// on the fees paid by the transaction sets in recent blocks, and on how long
+// these sets waited to be confirmed. It never falls outside of the range
+// returned by FeeEstimation, and the maximum is returned if there is not
+// enough history for an estimate.
+func (tp *TransactionPool) FeeEstimationTarget(target types.BlockHeight) (types.Currency, error) {
+	if target == 0 {
+		return types.ZeroCurrency, errZeroTarget
+	}
+	err := tp.tg.Add()
+	if err != nil {
+		return types.ZeroCurrency, err
+	}
+	defer tp.tg.Done()
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	// Sets are dropped from the pool after maxTxnAge blocks, so the history
+	// does not contain longer waits.
+	if target > maxTxnAge {
+		target = maxTxnAge
+	}
+	min, max := tp.feeEstimation()
+	rate, ok := tp.estimateFeeRate(target)
+	if !ok || rate.Cmp(max) > 0 {
+		return max, nil
+	}
+	if rate.Cmp(min) < 0 {
+		return min, nil
+	}
+	return rate, nil
+}
+
+// feeEstimation returns the minimum and maximum estimated fee per transaction
+// byte.
+func (tp *TransactionPool) feeEstimation() (min, max types.Currency) {
 	// Use three methods to determine an acceptable fee, and then take the
 	// largest result of the two methods. The first method checks the historic
 	// blocks, to make sure that we don't under-estimate the number of fees
@@ -166,6 +166,7 @@ func (tp *TransactionPool) purge() {
 	tp.transactionSets = make(map[TransactionSetID][]types.Transaction)
 	tp.transactionSetDiffs = make(map[TransactionSetID]*modules.ConsensusChange)
 	tp.transactionListSize = 0
+	tp.feeIndex = newFeeIndex()
 }
 
 // ProcessConsensusChange gets called to inform the transaction pool of changes
@@ -179,6 +180,11 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 	// being provided to us correctly.
 	resetSanityCheck := false
 	recentID, err := tp.getRecentBlockID(tp.dbTx)
+	if err == errNilRecentBlock && len(cc.AppliedBlockIDs) > 0 && cc.AppliedBlockIDs[0] == types.GenesisID {
+		// A new database starts with the genesis block, whose parent id is
+		// the empty block id.
+		err = nil
+	}
 	if err == errNilRecentBlock {
 		// This almost certainly means that the database hasn't been initialized
 		// yet with a recent block, meaning the user was previously running
@@ -198,6 +204,7 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 		}
 		recentID = block.ParentID
 
+		tp.revertFeeHistory(tp.blockHeight)
 		if tp.blockHeight > 0 || block.ID() != types.GenesisID {
 			tp.blockHeight--
 		}
@@ -217,15 +224,15 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 			tp.recentMedians = tp.recentMedians[:len(tp.recentMedians)-1]
 		}
 	}
-	for _, block := range cc.AppliedBlocks {
+	for i, block := range cc.AppliedBlocks {
 		// Sanity check - the parent id of each block should match the current
 		// block id.
 		if block.ParentID != recentID && !resetSanityCheck {
 			panic(fmt.Sprintf("Consensus change series appears to be inconsistent - we are applying the wrong block. pid: %v recent: %v", block.ParentID, recentID))
 		}
-		recentID = block.ID()
+		recentID = cc.AppliedBlockIDs[i]
 
-		if tp.blockHeight > 0 || block.ID() != types.GenesisID {
+		if tp.blockHeight > 0 || recentID != types.GenesisID {
 			tp.blockHeight++
 		}
 		for _, txn := range block.Transactions {
@@ -235,13 +242,15 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 			}
 		}
 
-		// Find the median transaction fee for this block.
+		// Find the median transaction fee for this block, and record the fee
+		// rates of the block in the fee history.
 		type feeSummary struct {
 			fee  types.Currency
 			size int
 		}
 		var fees []feeSummary
 		var totalSize int
+		bfh := blockFeeHistory{Height: tp.blockHeight}
 		txnSets := findSets(block.Transactions)
 		for _, set := range txnSets {
 			// Compile the fees for this set.
@@ -262,7 +271,13 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 				size: sizeSum,
 			})
 			totalSize += sizeSum
+			bfh.Sets = append(bfh.Sets, confirmedSet{
+				FeeRate: feeAvg,
+				Size:    uint64(sizeSum),
+				Wait:    tp.confirmationWait(set),
+			})
 		}
+		tp.applyFeeHistory(bfh)
 		// Add an extra zero-fee tranasction for any unused block space.
 		remaining := int(types.BlockSizeLimit) - totalSize
 		fees = append(fees, feeSummary{
@@ -106,3 +106,28 @@ func TestCalculateFee(t *testing.T) {
 		t.Error("got the wrong fee for a multi transaction set")
 	}
 }
+
+// TestCmpFeeRate checks that transaction sets are ranked by fee rate, and that
+// smaller sets rank higher if the fee rates are equal.
+func TestCmpFeeRate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		rate1, rate2 uint64
+		size1, size2 uint64
+		cmp          int
+	}{
+		{rate1: 2, size1: 10, rate2: 1, size2: 10, cmp: 1},
+		{rate1: 1, size1: 10, rate2: 2, size2: 10, cmp: -1},
+		{rate1: 2, size1: 20, rate2: 1, size2: 10, cmp: 1},
+		{rate1: 1, size1: 10, rate2: 1, size2: 20, cmp: 1},
+		{rate1: 1, size1: 20, rate2: 1, size2: 10, cmp: -1},
+		{rate1: 1, size1: 10, rate2: 1, size2: 10, cmp: 0},
+	}
+	for i, test := range tests {
+		cmp := CmpFeeRate(types.NewCurrency64(test.rate1), test.size1, types.NewCurrency64(test.rate2), test.size2)
+		if cmp != test.cmp {
+			t.Errorf("test %v: expected %v, got %v", i, test.cmp, cmp)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package modules
 import (
 	"bytes"
 	"errors"
+	"time"
 
 	"github.com/NebulousLabs/entropy-mnemonics"
 
@@ -21,6 +22,87 @@ const (
 
 	// WalletDir is the directory that contains the wallet persistence.
 	WalletDir = "wallet"
+
+	// WalletEventMinConfirmations is the number of confirmations from which
+	// on the wallet reports WalletEventConfirmations events for a
+	// transaction. The first confirmation is reported as a
+	// WalletEventConfirmed event.
+	WalletEventMinConfirmations = 2
+
+	// WalletEventMaxConfirmations is the number of confirmations up to which
+	// the wallet reports WalletEventConfirmations events for a transaction.
+	WalletEventMaxConfirmations = 144
+)
+
+const (
+	// CoinSelectionLargestFirst spends the largest outputs of the wallet
+	// first. It is the default strategy and keeps the number of inputs low.
+	CoinSelectionLargestFirst CoinSelectionStrategy = "largest-first"
+
+	// CoinSelectionSmallestFirst spends the smallest outputs of the wallet
+	// first, which consolidates small outputs over time.
+	CoinSelectionSmallestFirst CoinSelectionStrategy = "smallest-first"
+
+	// CoinSelectionBranchAndBound searches for a set of outputs that funds
+	// the transaction without a change output. If no such set exists, the
+	// largest outputs are spent first.
+	CoinSelectionBranchAndBound CoinSelectionStrategy = "branch-and-bound"
+)
+
+const (
+	// BumpFeeReplace replaces a stuck transaction with a transaction that
+	// spends the same inputs and pays a higher miner fee.
+	BumpFeeReplace BumpFeeMethod = "rbf"
+
+	// BumpFeeChild creates a child transaction that spends a change output
+	// of a stuck transaction and pays a high miner fee, so that miners
+	// include both transactions.
+	BumpFeeChild BumpFeeMethod = "cpfp"
+)
+
+const (
+	// CategoryContractFunding is the category of transactions that fund a
+	// file contract.
+	CategoryContractFunding TransactionCategory = "contractfunding"
+
+	// CategoryHostPayout is the category of transactions that submit a
+	// storage proof which pays out to the wallet.
+	CategoryHostPayout TransactionCategory = "hostpayout"
+
+	// CategoryMinerPayout is the category of the block subsidies and miner
+	// fees that the wallet received for mining a block.
+	CategoryMinerPayout TransactionCategory = "minerpayout"
+
+	// CategorySiafundClaim is the category of transactions that pay out the
+	// siacoin claim of siafunds to the wallet.
+	CategorySiafundClaim TransactionCategory = "siafundclaim"
+
+	// CategorySend is the category of transactions that send more coins
+	// from the wallet than they send to it.
+	CategorySend TransactionCategory = "send"
+
+	// CategoryReceive is the category of transactions that send more coins
+	// to the wallet than they send from it.
+	CategoryReceive TransactionCategory = "receive"
+)
+
+const (
+	// WalletEventUnconfirmed is reported when a transaction of the wallet
+	// enters the transaction pool.
+	WalletEventUnconfirmed WalletEventType = "unconfirmed"
+
+	// WalletEventConfirmed is reported when a transaction of the wallet is
+	// included in a block.
+	WalletEventConfirmed WalletEventType = "confirmed"
+
+	// WalletEventReverted is reported when a block that contained a
+	// transaction of the wallet is reverted by a reorg.
+	WalletEventReverted WalletEventType = "reverted"
+
+	// WalletEventConfirmations is reported for every block that is added on
+	// top of a confirmed transaction of the wallet, until the transaction
+	// has WalletEventMaxConfirmations confirmations.
+	WalletEventConfirmations WalletEventType = "confirmations"
 )
 
 var (
@@ -28,6 +110,14 @@ var (
 	// file is provided.
 	ErrBadEncryptionKey = errors.New("provided encryption key is incorrect")
 
+	// ErrBadSpendingKey is returned if the spending key of the wallet is
+	// required and an incorrect key is provided.
+	ErrBadSpendingKey = errors.New("provided spending password is incorrect")
+
+	// ErrSpendingKeyRequired is returned if the wallet has a spending key
+	// and the keys of the wallet are used or exported without it.
+	ErrSpendingKeyRequired = errors.New("wallet requires the spending password to sign transactions and export keys")
+
 	// ErrIncompleteTransactions is returned if the wallet has incomplete
 	// transactions being built that are using all of the current outputs, and
 	// therefore the wallet is unable to spend money despite it not technically
@@ -42,12 +132,64 @@ var (
 	// complete the desired action.
 	ErrLowBalance = errors.New("insufficient balance")
 
+	// ErrUnknownBumpFeeMethod is returned if the method used to bump the fee
+	// of a transaction is not known.
+	ErrUnknownBumpFeeMethod = errors.New("unknown fee bump method")
+
+	// ErrUnknownCoinSelection is returned if the coin selection strategy of
+	// the wallet settings is not known.
+	ErrUnknownCoinSelection = errors.New("unknown coin selection strategy")
+
 	// ErrWalletShutdown is returned when a method can't continue execution due
 	// to the wallet shutting down.
 	ErrWalletShutdown = errors.New("wallet is shutting down")
 )
 
 type (
+	// BumpFeeMethod determines how the wallet raises the fee of an
+	// unconfirmed transaction.
+	BumpFeeMethod string
+
+	// CoinSelectionStrategy determines which outputs the wallet spends to
+	// fund a transaction.
+	CoinSelectionStrategy string
+
+	// TransactionCategory describes the purpose of a transaction of the
+	// wallet. It is determined from the transaction itself.
+	TransactionCategory string
+
+	// WalletEventType is the type of a WalletEvent.
+	WalletEventType string
+
+	// A WalletEvent reports a change of the state of a transaction of the
+	// wallet. Transaction is only set for unconfirmed and confirmed events.
+	WalletEvent struct {
+		Type          WalletEventType       `json:"type"`
+		TransactionID types.TransactionID   `json:"transactionid"`
+		Height        types.BlockHeight     `json:"height"`
+		Confirmations uint64                `json:"confirmations"`
+		Transaction   *ProcessedTransaction `json:"transaction,omitempty"`
+	}
+
+	// A WalletEventSubscriber receives the events of the wallet. The events
+	// are delivered in order, and ReceiveWalletEvents must not block or call
+	// the wallet.
+	WalletEventSubscriber interface {
+		ReceiveWalletEvents([]WalletEvent)
+	}
+
+	// A WalletWebhook is a URL that the wallet posts its events to. Each
+	// request carries an HMAC-SHA256 signature of the body, keyed with the
+	// secret, in the Sia-Signature header. If Events is empty, all events
+	// are delivered. Confirmations events are only delivered when the
+	// transaction reaches exactly Confirmations confirmations.
+	WalletWebhook struct {
+		URL           string            `json:"url"`
+		Secret        string            `json:"secret,omitempty"`
+		Events        []WalletEventType `json:"events"`
+		Confirmations uint64            `json:"confirmations"`
+	}
+
 	// Seed is cryptographic entropy that is used to derive spendable wallet
 	// addresses.
 	Seed [crypto.EntropySize]byte
@@ -104,6 +246,30 @@ type (
 		Outputs []ProcessedOutput `json:"outputs"`
 	}
 
+	// A WalletLabel is a label and a note that the user attached to a
+	// transaction or an address.
+	WalletLabel struct {
+		Label string `json:"label"`
+		Note  string `json:"note"`
+	}
+
+	// A PreparedTransaction is a funded but unsigned transaction, together
+	// with the information needed to sign it on another machine. The
+	// transaction contains a TransactionSignature without a Signature for
+	// every signature that the unlock conditions of its inputs require.
+	PreparedTransaction struct {
+		Transaction types.Transaction `json:"transaction"`
+
+		// ParentOutputs contains the output spent by each siacoin input of
+		// the transaction, in the same order as the inputs.
+		ParentOutputs []types.SiacoinOutput `json:"parentoutputs"`
+
+		// SigHashes contains the hash that has to be signed for each
+		// TransactionSignature of the transaction, in the same order as the
+		// signatures.
+		SigHashes []crypto.Hash `json:"sighashes"`
+	}
+
 	// TransactionBuilder is used to construct custom transactions. A transaction
 	// builder is initialized via 'RegisterTransaction' and then can be modified by
 	// adding funds or other fields. The transaction is completed by calling
@@ -124,6 +290,11 @@ type (
 		// transaction failed.
 		FundSiacoins(amount types.Currency) error
 
+		// FundSiacoinsFromOutputs works like FundSiacoins, but spends exactly
+		// the confirmed wallet outputs with the given IDs instead of letting
+		// the wallet choose. Locked outputs can be spent this way.
+		FundSiacoinsFromOutputs(amount types.Currency, ids []types.SiacoinOutputID) error
+
 		// FundSiafunds will add a siafund input of exactly 'amount' to the
 		// transaction. A parent transaction may be needed to achieve an input
 		// with the correct value. The siafund input will not be signed until
@@ -345,7 +516,8 @@ type (
 
 		// ConfirmedBalance returns the confirmed balance of the wallet, minus
 		// any outgoing transactions. ConfirmedBalance will include unconfirmed
-		// refund transactions.
+		// refund transactions. Siacoins that are still timelocked are not
+		// included, see TimelockedBalance.
 		ConfirmedBalance() (siacoinBalance types.Currency, siafundBalance types.Currency, siacoinClaimBalance types.Currency, err error)
 
 		// UnconfirmedBalance returns the unconfirmed balance of the wallet.
@@ -404,6 +576,15 @@ type (
 		// are also returned to the caller.
 		SendSiacoins(amount types.Currency, dest types.UnlockHash) ([]types.Transaction, error)
 
+		// SendSiacoinsWithTarget works like SendSiacoins, but pays the fee
+		// that the transaction pool recommends for being confirmed within
+		// target blocks instead of the maximum recommended fee.
+		SendSiacoinsWithTarget(amount types.Currency, dest types.UnlockHash, target types.BlockHeight) ([]types.Transaction, error)
+
+		// SendSiacoinsFromOutputs works like SendSiacoins, but the
+		// transaction is funded from the wallet outputs with the given IDs.
+		SendSiacoinsFromOutputs(amount types.Currency, dest types.UnlockHash, ids []types.SiacoinOutputID) ([]types.Transaction, error)
+
 		// SendSiacoinsMulti sends coins to multiple addresses.
 		SendSiacoinsMulti(outputs []types.SiacoinOutput) ([]types.Transaction, error)
 
@@ -416,14 +597,267 @@ type (
 		// DustThreshold returns the quantity per byte below which a Currency is
 		// considered to be Dust.
 		DustThreshold() (types.Currency, error)
+
+		// AddWatchAddresses adds watch-only addresses to the wallet. The
+		// wallet rescans the blockchain for the addresses unless unused is
+		// set.
+		AddWatchAddresses(addrs []types.UnlockHash, unused bool) error
+
+		// RemoveWatchAddresses removes watch-only addresses from the wallet.
+		RemoveWatchAddresses(addrs []types.UnlockHash) error
+
+		// WatchAddresses returns the watch-only addresses of the wallet.
+		WatchAddresses() ([]types.UnlockHash, error)
+
+		// WatchBalance returns the confirmed balance of the watch-only
+		// addresses. It is not included in the ConfirmedBalance.
+		WatchBalance() (siacoinBalance types.Currency, siafundBalance types.Currency, err error)
+
+		// PrepareTransaction builds and funds an unsigned transaction that
+		// sends the outputs. If watchOnly is set, the transaction is funded
+		// from the watch-only addresses, whose unlock conditions must be
+		// provided in ucs. Otherwise the wallet must be unlocked.
+		PrepareTransaction(outputs []types.SiacoinOutput, watchOnly bool, ucs []types.UnlockConditions) (PreparedTransaction, error)
+
+		// AddMultisigAddress creates an M-of-N multisig address from the
+		// public keys and tracks it as a watch-only address. Unless unused
+		// is set, the blockchain is rescanned for the address.
+		AddMultisigAddress(publicKeys []types.SiaPublicKey, required uint64, unused bool) (types.UnlockConditions, error)
+
+		// MultisigAddresses returns the unlock conditions of the multisig
+		// addresses of the wallet.
+		MultisigAddresses() ([]types.UnlockConditions, error)
+
+		// PrepareMultisigTransaction builds a transaction that sends the
+		// outputs from a multisig address, and signs it with the keys of
+		// the wallet. The other signers add the remaining signatures.
+		PrepareMultisigTransaction(addr types.UnlockHash, outputs []types.SiacoinOutput) (types.Transaction, error)
+
+		// SignTransaction adds the missing signatures of the transaction
+		// that belong to keys of the wallet, and returns the number of
+		// added signatures.
+		SignTransaction(txn *types.Transaction) (int, error)
+
+		// SpendableOutputs returns the confirmed siacoin outputs that the
+		// wallet can spend, including locked outputs.
+		SpendableOutputs() ([]SpendableOutput, error)
+
+		// LockOutputs prevents the wallet from choosing the outputs to fund
+		// transactions.
+		LockOutputs(ids []types.SiacoinOutputID) error
+
+		// UnlockOutputs releases outputs that were locked with LockOutputs.
+		UnlockOutputs(ids []types.SiacoinOutputID) error
+
+		// BumpFee raises the miner fee of an unconfirmed transaction of the
+		// wallet, either by replacing it or by spending its change in a
+		// child transaction. The new transactions are given to the
+		// transaction pool and returned. A zero fee lets the wallet choose
+		// the fee.
+		BumpFee(txid types.TransactionID, fee types.Currency, method BumpFeeMethod) ([]types.Transaction, error)
+
+		// TransactionReplacements returns the transactions of the wallet
+		// that were replaced by transactions with a higher fee.
+		TransactionReplacements() ([]TransactionReplacement, error)
+
+		// SetTransactionLabel attaches a label and a note to a transaction
+		// of the wallet. An empty label and note remove them.
+		SetTransactionLabel(txid types.TransactionID, label WalletLabel) error
+
+		// SetAddressLabel attaches a label and a note to an address. An
+		// empty label and note remove them.
+		SetAddressLabel(addr types.UnlockHash, label WalletLabel) error
+
+		// TransactionLabels returns the labels of all labeled transactions.
+		TransactionLabels() (map[types.TransactionID]WalletLabel, error)
+
+		// AddressLabels returns the labels of all labeled addresses.
+		AddressLabels() (map[types.UnlockHash]WalletLabel, error)
+
+		// NewTimelockedAddress returns a new address of the wallet whose
+		// outputs cannot be spent before the unlock height.
+		NewTimelockedAddress(unlockHeight types.BlockHeight) (types.UnlockConditions, error)
+
+		// TimelockedAddresses returns the timelocked addresses of the
+		// wallet together with their unlock height and balance.
+		TimelockedAddresses() ([]TimelockedAddress, error)
+
+		// TimelockedBalance returns the confirmed siacoins of the wallet
+		// that are still timelocked. They are not included in the siacoin
+		// balance of ConfirmedBalance until they are unlocked.
+		TimelockedBalance() (types.Currency, error)
+
+		// SubscribeEvents adds a subscriber that receives the events of the
+		// wallet.
+		SubscribeEvents(WalletEventSubscriber)
+
+		// UnsubscribeEvents removes a subscriber of the wallet events.
+		UnsubscribeEvents(WalletEventSubscriber)
+
+		// AddWebhook registers a webhook that the wallet posts its events
+		// to. A webhook with the same URL is replaced.
+		AddWebhook(WalletWebhook) error
+
+		// RemoveWebhook removes the webhook with the given URL.
+		RemoveWebhook(url string) error
+
+		// Webhooks returns the webhooks of the wallet without their secrets.
+		Webhooks() ([]WalletWebhook, error)
+
+		// AddressBalance returns the confirmed and unconfirmed balance of an
+		// address of the wallet or a watch-only address.
+		AddressBalance(addr types.UnlockHash) (AddressBalance, error)
+
+		// AddressBalances returns the balances of all addresses that the
+		// wallet is able to spend from, ordered like AllAddresses.
+		AddressBalances() ([]AddressBalance, error)
+
+		// SetSpendingKey sets a second key that must be provided to sign
+		// transactions with the keys of the wallet or to export them.
+		// masterKey must be the encryption key of the wallet. A blank
+		// spendingKey removes the spending key.
+		SetSpendingKey(masterKey, spendingKey crypto.TwofishKey) error
+
+		// SpendingKeyRequired reports whether the wallet has a spending key.
+		SpendingKeyRequired() (bool, error)
+
+		// AuthorizeSpending returns a view of the wallet that is authorized
+		// by spendingKey to sign transactions with the keys of the wallet
+		// and to export them. If the wallet has a spending key, these
+		// methods return ErrSpendingKeyRequired unless they are called on
+		// such a view. ErrBadSpendingKey is returned if spendingKey is not
+		// the spending key of the wallet.
+		AuthorizeSpending(spendingKey crypto.TwofishKey) (Wallet, error)
+
+		// AutoLockTimeout returns the time after which an unlocked wallet
+		// locks itself. Zero means the wallet stays unlocked.
+		AutoLockTimeout() (time.Duration, error)
+
+		// SetAutoLockTimeout sets the time after which an unlocked wallet
+		// locks itself. If the wallet is unlocked, the timeout starts
+		// anew.
+		SetAutoLockTimeout(time.Duration) error
+	}
+
+	// An AddressBalance contains the confirmed and unconfirmed balance of an
+	// address. Unconfirmed siacoins are those of the transactions in the
+	// transaction pool.
+	AddressBalance struct {
+		Address                     types.UnlockHash `json:"address"`
+		ConfirmedSiacoinBalance     types.Currency   `json:"confirmedsiacoinbalance"`
+		ConfirmedSiacoinOutputs     uint64           `json:"confirmedsiacoinoutputs"`
+		UnconfirmedIncomingSiacoins types.Currency   `json:"unconfirmedincomingsiacoins"`
+		UnconfirmedOutgoingSiacoins types.Currency   `json:"unconfirmedoutgoingsiacoins"`
+		UnconfirmedSiacoinOutputs   uint64           `json:"unconfirmedsiacoinoutputs"`
+		SiafundBalance              types.Currency   `json:"siafundbalance"`
+		SiafundOutputs              uint64           `json:"siafundoutputs"`
+	}
+
+	// A TimelockedAddress is an address of the wallet whose outputs cannot
+	// be spent before the unlock height.
+	TimelockedAddress struct {
+		Address          types.UnlockHash       `json:"address"`
+		UnlockConditions types.UnlockConditions `json:"unlockconditions"`
+		UnlockHeight     types.BlockHeight      `json:"unlockheight"`
+		Balance          types.Currency         `json:"balance"`
+	}
+
+	// SpendableOutput is a confirmed siacoin output that the wallet can
+	// spend. Locked outputs are not used when the wallet chooses the outputs
+	// of a transaction.
+	SpendableOutput struct {
+		ID         types.SiacoinOutputID `json:"id"`
+		Value      types.Currency        `json:"value"`
+		UnlockHash types.UnlockHash      `json:"unlockhash"`
+		Locked     bool                  `json:"locked"`
+	}
+
+	// A TransactionReplacement records that an unconfirmed transaction of
+	// the wallet was replaced by a transaction with a higher fee that spends
+	// the same inputs.
+	TransactionReplacement struct {
+		ReplacedID    types.TransactionID `json:"replacedid"`
+		ReplacementID types.TransactionID `json:"replacementid"`
+		Height        types.BlockHeight   `json:"height"`
 	}
 
 	// WalletSettings control the behavior of the Wallet.
 	WalletSettings struct {
-		NoDefrag bool `json:"noDefrag"`
+		NoDefrag      bool                  `json:"noDefrag"`
+		CoinSelection CoinSelectionStrategy `json:"coinSelection"`
 	}
 )
 
+// Matches reports whether the event passes a filter of event types and a
+// number of confirmations. An empty filter matches all event types, and a
+// confirmations event only matches if the transaction has exactly the given
+// number of confirmations.
+func (we WalletEvent) Matches(filter []WalletEventType, confirmations uint64) bool {
+	if we.Type == WalletEventConfirmations && we.Confirmations != confirmations {
+		return false
+	}
+	if len(filter) == 0 {
+		return true
+	}
+	for _, t := range filter {
+		if t == we.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Category determines the category of the transaction from its inputs and
+// outputs.
+func (pt ProcessedTransaction) Category() TransactionCategory {
+	var siacoinsIn, siacoinsOut, siafundsIn, siafundsOut types.Currency
+	claim, payout := false, false
+	for _, output := range pt.Outputs {
+		switch output.FundType {
+		case types.SpecifierMinerPayout:
+			return CategoryMinerPayout
+		case types.SpecifierClaimOutput:
+			claim = claim || output.WalletAddress
+		case types.SpecifierStorageProofOutput:
+			payout = payout || output.WalletAddress
+		case types.SpecifierSiacoinOutput:
+			if output.WalletAddress {
+				siacoinsIn = siacoinsIn.Add(output.Value)
+			}
+		case types.SpecifierSiafundOutput:
+			if output.WalletAddress {
+				siafundsIn = siafundsIn.Add(output.Value)
+			}
+		}
+	}
+	for _, input := range pt.Inputs {
+		if !input.WalletAddress {
+			continue
+		}
+		switch input.FundType {
+		case types.SpecifierSiacoinInput:
+			siacoinsOut = siacoinsOut.Add(input.Value)
+		case types.SpecifierSiafundInput:
+			siafundsOut = siafundsOut.Add(input.Value)
+		}
+	}
+
+	switch {
+	case payout:
+		return CategoryHostPayout
+	case len(pt.Transaction.FileContracts) != 0:
+		return CategoryContractFunding
+	case claim:
+		return CategorySiafundClaim
+	case siacoinsIn.Cmp(siacoinsOut) > 0:
+		return CategoryReceive
+	case siacoinsIn.Equals(siacoinsOut) && siafundsIn.Cmp(siafundsOut) > 0:
+		return CategoryReceive
+	default:
+		return CategorySend
+	}
+}
+
 // CalculateWalletTransactionID is a helper function for determining the id of
 // a wallet transaction.
 func CalculateWalletTransactionID(tid types.TransactionID, oid types.OutputID) WalletTransactionID {
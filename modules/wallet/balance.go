@@ -0,0 +1,228 @@
+package wallet
+
+// balance.go implements the per-address balances of the wallet. The confirmed
+// balance of every address is kept in bucketAddrBalances and is updated
+// whenever an output is stored in or deleted from the output buckets, so that
+// a query does not need to walk the outputs or the history of the wallet.
+// Unconfirmed amounts are computed from the unconfirmed transactions, which
+// the wallet keeps in memory.
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// errUnknownAddress is returned if the balance of an address is requested
+// that the wallet does not track.
+var errUnknownAddress = errors.New("address is not an address of the wallet or a watch-only address")
+
+// addrBalance is the confirmed balance of an address as stored in
+// bucketAddrBalances.
+type addrBalance struct {
+	Siacoins       types.Currency
+	SiacoinOutputs uint64
+	Siafunds       types.Currency
+	SiafundOutputs uint64
+}
+
+func dbGetAddrBalance(tx *bolt.Tx, addr types.UnlockHash) (ab addrBalance, err error) {
+	err = dbGet(tx.Bucket(bucketAddrBalances), addr, &ab)
+	if err == errNoKey {
+		err = nil
+	}
+	return
+}
+
+// dbPutAddrBalance stores the balance of an address. Addresses without
+// outputs are removed from the bucket.
+func dbPutAddrBalance(tx *bolt.Tx, addr types.UnlockHash, ab addrBalance) error {
+	if ab.SiacoinOutputs == 0 && ab.SiafundOutputs == 0 {
+		return dbDelete(tx.Bucket(bucketAddrBalances), addr)
+	}
+	return dbPut(tx.Bucket(bucketAddrBalances), addr, ab)
+}
+
+// dbAddSiacoinOutputBalance adds a siacoin output that is about to be stored
+// in the bucket to the balance of its address. Outputs that are stored
+// already, e.g. because the wallet rescans the blockchain, are not counted
+// twice.
+func dbAddSiacoinOutputBalance(tx *bolt.Tx, bucket []byte, id types.SiacoinOutputID, sco types.SiacoinOutput) error {
+	if tx.Bucket(bucket).Get(encoding.Marshal(id)) != nil {
+		return nil
+	}
+	ab, err := dbGetAddrBalance(tx, sco.UnlockHash)
+	if err != nil {
+		return err
+	}
+	ab.Siacoins = ab.Siacoins.Add(sco.Value)
+	ab.SiacoinOutputs++
+	return dbPutAddrBalance(tx, sco.UnlockHash, ab)
+}
+
+// dbRemoveSiacoinOutputBalance removes a siacoin output that is about to be
+// deleted from the bucket from the balance of its address.
+func dbRemoveSiacoinOutputBalance(tx *bolt.Tx, bucket []byte, id types.SiacoinOutputID) error {
+	var sco types.SiacoinOutput
+	if err := dbGet(tx.Bucket(bucket), id, &sco); err == errNoKey {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	ab, err := dbGetAddrBalance(tx, sco.UnlockHash)
+	if err != nil {
+		return err
+	}
+	ab.Siacoins = ab.Siacoins.Sub(sco.Value)
+	ab.SiacoinOutputs--
+	return dbPutAddrBalance(tx, sco.UnlockHash, ab)
+}
+
+// dbAddSiafundOutputBalance adds a siafund output that is about to be stored
+// in the bucket to the balance of its address.
+func dbAddSiafundOutputBalance(tx *bolt.Tx, bucket []byte, id types.SiafundOutputID, sfo types.SiafundOutput) error {
+	if tx.Bucket(bucket).Get(encoding.Marshal(id)) != nil {
+		return nil
+	}
+	ab, err := dbGetAddrBalance(tx, sfo.UnlockHash)
+	if err != nil {
+		return err
+	}
+	ab.Siafunds = ab.Siafunds.Add(sfo.Value)
+	ab.SiafundOutputs++
+	return dbPutAddrBalance(tx, sfo.UnlockHash, ab)
+}
+
+// dbRemoveSiafundOutputBalance removes a siafund output that is about to be
+// deleted from the bucket from the balance of its address.
+func dbRemoveSiafundOutputBalance(tx *bolt.Tx, bucket []byte, id types.SiafundOutputID) error {
+	var sfo types.SiafundOutput
+	if err := dbGet(tx.Bucket(bucket), id, &sfo); err == errNoKey {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	ab, err := dbGetAddrBalance(tx, sfo.UnlockHash)
+	if err != nil {
+		return err
+	}
+	ab.Siafunds = ab.Siafunds.Sub(sfo.Value)
+	ab.SiafundOutputs--
+	return dbPutAddrBalance(tx, sfo.UnlockHash, ab)
+}
+
+// dbBuildAddrBalances builds bucketAddrBalances from the output buckets. It
+// is used to initialize the bucket for wallets that were created before the
+// balances were tracked.
+func dbBuildAddrBalances(tx *bolt.Tx) error {
+	balances := make(map[types.UnlockHash]addrBalance)
+	addSiacoins := func(_ types.SiacoinOutputID, sco types.SiacoinOutput) {
+		ab := balances[sco.UnlockHash]
+		ab.Siacoins = ab.Siacoins.Add(sco.Value)
+		ab.SiacoinOutputs++
+		balances[sco.UnlockHash] = ab
+	}
+	addSiafunds := func(_ types.SiafundOutputID, sfo types.SiafundOutput) {
+		ab := balances[sfo.UnlockHash]
+		ab.Siafunds = ab.Siafunds.Add(sfo.Value)
+		ab.SiafundOutputs++
+		balances[sfo.UnlockHash] = ab
+	}
+	if err := dbForEachSiacoinOutput(tx, addSiacoins); err != nil {
+		return err
+	}
+	if err := dbForEachWatchedSiacoinOutput(tx, addSiacoins); err != nil {
+		return err
+	}
+	if err := dbForEachSiafundOutput(tx, addSiafunds); err != nil {
+		return err
+	}
+	if err := dbForEachWatchedSiafundOutput(tx, addSiafunds); err != nil {
+		return err
+	}
+	for addr, ab := range balances {
+		if err := dbPutAddrBalance(tx, addr, ab); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addressBalance returns the balance of an address.
+func (w *Wallet) addressBalance(addr types.UnlockHash) (modules.AddressBalance, error) {
+	ab, err := dbGetAddrBalance(w.dbTx, addr)
+	if err != nil {
+		return modules.AddressBalance{}, err
+	}
+	balance := modules.AddressBalance{
+		Address:                 addr,
+		ConfirmedSiacoinBalance: ab.Siacoins,
+		ConfirmedSiacoinOutputs: ab.SiacoinOutputs,
+		SiafundBalance:          ab.Siafunds,
+		SiafundOutputs:          ab.SiafundOutputs,
+	}
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		for _, input := range upt.Inputs {
+			if input.FundType == types.SpecifierSiacoinInput && input.RelatedAddress == addr {
+				balance.UnconfirmedOutgoingSiacoins = balance.UnconfirmedOutgoingSiacoins.Add(input.Value)
+			}
+		}
+		for _, output := range upt.Outputs {
+			if output.FundType == types.SpecifierSiacoinOutput && output.RelatedAddress == addr {
+				balance.UnconfirmedIncomingSiacoins = balance.UnconfirmedIncomingSiacoins.Add(output.Value)
+				balance.UnconfirmedSiacoinOutputs++
+			}
+		}
+	}
+	return balance, nil
+}
+
+// AddressBalance returns the confirmed and unconfirmed balance of an address
+// of the wallet or a watch-only address.
+func (w *Wallet) AddressBalance(addr types.UnlockHash) (modules.AddressBalance, error) {
+	if err := w.tg.Add(); err != nil {
+		return modules.AddressBalance{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.isRelevantAddress(addr) {
+		return modules.AddressBalance{}, errUnknownAddress
+	}
+	return w.addressBalance(addr)
+}
+
+// AddressBalances returns the balances of all addresses that the wallet is
+// able to spend from, ordered by address.
+func (w *Wallet) AddressBalances() ([]modules.AddressBalance, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	addrs := make([]types.UnlockHash, 0, len(w.keys))
+	for addr := range w.keys {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	balances := make([]modules.AddressBalance, 0, len(addrs))
+	for _, addr := range addrs {
+		balance, err := w.addressBalance(addr)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	return balances, nil
+}
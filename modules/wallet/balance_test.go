@@ -0,0 +1,100 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestAddressBalance checks that the wallet tracks the confirmed and
+// unconfirmed balance of each of its addresses.
+func TestAddressBalance(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	if _, err := wt.wallet.AddressBalance(types.UnlockHash{}); err != errUnknownAddress {
+		t.Fatal("expected errUnknownAddress, got", err)
+	}
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := uc.UnlockHash()
+
+	// Coins sent to the address are unconfirmed until they are mined.
+	amount := types.SiacoinPrecision.Mul64(10)
+	if _, err := wt.wallet.SendSiacoins(amount, addr); err != nil {
+		t.Fatal(err)
+	}
+	ab, err := wt.wallet.AddressBalance(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ab.ConfirmedSiacoinBalance.IsZero() || !ab.UnconfirmedIncomingSiacoins.Equals(amount) || ab.UnconfirmedSiacoinOutputs != 1 {
+		t.Fatal("unexpected unconfirmed balance:", ab)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.SendSiacoins(amount, addr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	ab, err = wt.wallet.AddressBalance(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ab.ConfirmedSiacoinBalance.Equals(amount.Mul64(2)) || ab.ConfirmedSiacoinOutputs != 2 || !ab.UnconfirmedIncomingSiacoins.IsZero() {
+		t.Fatal("unexpected confirmed balance:", ab)
+	}
+
+	// The balances of all addresses add up to the balance of the wallet.
+	balances, err := wt.wallet.AddressBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total types.Currency
+	var found bool
+	for _, b := range balances {
+		total = total.Add(b.ConfirmedSiacoinBalance)
+		found = found || b.Address == addr
+	}
+	siacoins, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || !total.Equals(siacoins) {
+		t.Fatalf("address balances add up to %v, expected %v", total, siacoins)
+	}
+
+	// Rebuilding the index yields the same balances.
+	wt.wallet.mu.Lock()
+	if err := wt.wallet.dbTx.DeleteBucket(bucketAddrBalances); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.dbTx.CreateBucket(bucketAddrBalances); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbBuildAddrBalances(wt.wallet.dbTx); err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.Unlock()
+	rebuilt, err := wt.wallet.AddressBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range balances {
+		if balances[i].Address != rebuilt[i].Address || !balances[i].ConfirmedSiacoinBalance.Equals(rebuilt[i].ConfirmedSiacoinBalance) || balances[i].ConfirmedSiacoinOutputs != rebuilt[i].ConfirmedSiacoinOutputs {
+			t.Fatal("rebuilt balance differs:", balances[i], rebuilt[i])
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package wallet
+
+// bumpfee.go raises the fee of unconfirmed transactions that are stuck in the
+// transaction pool. A transaction can be replaced by a transaction that
+// spends the same inputs with a higher fee (replace-by-fee), or the wallet
+// can spend its change output in a child transaction that pays a high fee
+// (child-pays-for-parent). Miners consider the fees of a transaction set as a
+// whole, so the child makes the parent attractive as well.
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// errBumpFeeNoChange is returned if a transaction has no output of the
+	// wallet that can pay for the higher fee.
+	errBumpFeeNoChange = errors.New("transaction has no unspent output of the wallet that can pay the higher fee")
+
+	// errBumpFeeNotOwned is returned if a transaction cannot be replaced
+	// because the wallet cannot sign all of its inputs.
+	errBumpFeeNotOwned = errors.New("transaction spends inputs that the wallet cannot sign")
+
+	// errBumpFeeTooLow is returned if the requested fee of a replacement is
+	// not higher than the fee of the transactions it replaces.
+	errBumpFeeTooLow = errors.New("new fee must be higher than the current fee")
+
+	// errBumpFeeUnsupported is returned if a transaction that is not a plain
+	// siacoin transfer is replaced.
+	errBumpFeeUnsupported = errors.New("only siacoin transfers can be replaced")
+
+	// errUnknownUnconfirmedTxn is returned if the fee of a transaction is
+	// bumped that is not an unconfirmed transaction of the wallet.
+	errUnknownUnconfirmedTxn = errors.New("transaction is not an unconfirmed transaction of the wallet")
+)
+
+// BumpFee raises the miner fee of the unconfirmed transaction txid. With
+// BumpFeeReplace, the transaction set that contains txid is replaced by a
+// single transaction that spends the same inputs and pays fee in total; the
+// difference to the old fee is taken from the largest output of the wallet.
+// With BumpFeeChild, a child transaction that spends the largest output of
+// the wallet in txid and pays fee is created. If fee is zero, the wallet
+// chooses the fee from the fee estimation of the transaction pool.
+func (w *Wallet) BumpFee(txid types.TransactionID, fee types.Currency, method modules.BumpFeeMethod) ([]types.Transaction, error) {
+	return w.managedBumpFee(nil, txid, fee, method)
+}
+
+// managedBumpFee implements BumpFee. Both methods sign a transaction with the
+// keys of the wallet, so spendingKey must be the spending key of the wallet
+// if it has one.
+func (w *Wallet) managedBumpFee(spendingKey *crypto.TwofishKey, txid types.TransactionID, fee types.Currency, method modules.BumpFeeMethod) ([]types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if err := w.managedCheckSpendingKey(spendingKey); err != nil {
+		return nil, err
+	}
+	if method != modules.BumpFeeReplace && method != modules.BumpFeeChild {
+		return nil, modules.ErrUnknownBumpFeeMethod
+	}
+	_, maxFee := w.tpool.FeeEstimation()
+	defaultFee := maxFee.Mul64(750) // Estimated transaction size in bytes
+
+	w.mu.Lock()
+	if !w.unlocked {
+		w.mu.Unlock()
+		return nil, modules.ErrLockedWallet
+	}
+	var txn types.Transaction
+	var replaced []types.TransactionID
+	var spentOutput types.OutputID
+	var err error
+	if method == modules.BumpFeeReplace {
+		txn, replaced, err = w.replaceTransaction(txid, fee, defaultFee)
+	} else {
+		txn, spentOutput, err = w.childPaysForParent(txid, fee, defaultFee)
+	}
+	w.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// The transaction pool notifies the wallet about the new set, so the
+	// lock must not be held.
+	err = w.tpool.AcceptTransactionSet([]types.Transaction{txn})
+	if err != nil {
+		w.log.Println("Attempt to bump fee has failed - transaction pool rejected transaction:", err)
+		if method == modules.BumpFeeChild {
+			w.mu.Lock()
+			dbDeleteSpentOutput(w.dbTx, spentOutput)
+			w.mu.Unlock()
+		}
+		return nil, build.ExtendErr("unable to get transaction accepted", err)
+	}
+	w.log.Printf("Bumped the fee of transaction %v with %v transaction %v", txid, method, txn.ID())
+
+	if len(replaced) == 0 {
+		return []types.Transaction{txn}, nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	height, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range replaced {
+		err := dbPutReplacedTxn(w.dbTx, modules.TransactionReplacement{
+			ReplacedID:    id,
+			ReplacementID: txn.ID(),
+			Height:        height,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []types.Transaction{txn}, w.syncDB()
+}
+
+// TransactionReplacements returns the unconfirmed transactions of the wallet
+// that were replaced by BumpFee, ordered by the height of the replacement.
+func (w *Wallet) TransactionReplacements() ([]modules.TransactionReplacement, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var rs []modules.TransactionReplacement
+	err := dbForEachReplacedTxn(w.dbTx, func(_ types.TransactionID, r modules.TransactionReplacement) {
+		rs = append(rs, r)
+	})
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rs[i].Height < rs[j].Height
+	})
+	return rs, err
+}
+
+// unconfirmedSet returns the transactions of the wallet in the transaction
+// pool set that contains txid, in the order of the set. nil is returned if
+// txid is not an unconfirmed transaction of the wallet.
+func (w *Wallet) unconfirmedSet(txid types.TransactionID) []types.Transaction {
+	var setIDs []types.TransactionID
+	for _, ids := range w.unconfirmedSets {
+		for _, id := range ids {
+			if id == txid {
+				setIDs = ids
+				break
+			}
+		}
+	}
+	upts := make(map[types.TransactionID]types.Transaction)
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		upts[upt.TransactionID] = upt.Transaction
+	}
+	if _, exists := upts[txid]; !exists {
+		return nil
+	}
+	var set []types.Transaction
+	for _, id := range setIDs {
+		if txn, exists := upts[id]; exists {
+			set = append(set, txn)
+		}
+	}
+	return set
+}
+
+// replaceTransaction creates and signs a transaction that replaces the
+// transactions of the wallet in the transaction pool set that contains txid.
+// The replacement spends the inputs of the set that are not created within
+// the set, and creates the outputs of the set that are not spent within the
+// set. The IDs of the replaced transactions are returned as well.
+func (w *Wallet) replaceTransaction(txid types.TransactionID, fee, defaultFee types.Currency) (types.Transaction, []types.TransactionID, error) {
+	set := w.unconfirmedSet(txid)
+	if set == nil {
+		return types.Transaction{}, nil, errUnknownUnconfirmedTxn
+	}
+
+	created := make(map[types.SiacoinOutputID]struct{})
+	spent := make(map[types.SiacoinOutputID]struct{})
+	var oldFee types.Currency
+	var replaced []types.TransactionID
+	for _, txn := range set {
+		if len(txn.FileContracts) != 0 || len(txn.FileContractRevisions) != 0 || len(txn.StorageProofs) != 0 ||
+			len(txn.SiafundInputs) != 0 || len(txn.SiafundOutputs) != 0 {
+			return types.Transaction{}, nil, errBumpFeeUnsupported
+		}
+		for i := range txn.SiacoinOutputs {
+			created[txn.SiacoinOutputID(uint64(i))] = struct{}{}
+		}
+		for _, sci := range txn.SiacoinInputs {
+			spent[sci.ParentID] = struct{}{}
+		}
+		for _, mf := range txn.MinerFees {
+			oldFee = oldFee.Add(mf)
+		}
+		replaced = append(replaced, txn.ID())
+	}
+	if fee.IsZero() {
+		fee = oldFee.Add(defaultFee)
+	} else if fee.Cmp(oldFee) <= 0 {
+		return types.Transaction{}, nil, errBumpFeeTooLow
+	}
+
+	var txn types.Transaction
+	for _, parent := range set {
+		for _, sci := range parent.SiacoinInputs {
+			if _, exists := created[sci.ParentID]; exists {
+				continue
+			}
+			if _, exists := w.keys[sci.UnlockConditions.UnlockHash()]; !exists {
+				return types.Transaction{}, nil, errBumpFeeNotOwned
+			}
+			txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{
+				ParentID:         sci.ParentID,
+				UnlockConditions: sci.UnlockConditions,
+			})
+		}
+		for i, sco := range parent.SiacoinOutputs {
+			if _, exists := spent[parent.SiacoinOutputID(uint64(i))]; exists {
+				continue
+			}
+			txn.SiacoinOutputs = append(txn.SiacoinOutputs, sco)
+		}
+	}
+
+	// Pay the higher fee from the largest output of the wallet, which is
+	// usually the change output.
+	increase := fee.Sub(oldFee)
+	change := -1
+	for i, sco := range txn.SiacoinOutputs {
+		if _, exists := w.keys[sco.UnlockHash]; !exists {
+			continue
+		}
+		if change == -1 || sco.Value.Cmp(txn.SiacoinOutputs[change].Value) > 0 {
+			change = i
+		}
+	}
+	if change == -1 || txn.SiacoinOutputs[change].Value.Cmp(increase) < 0 {
+		return types.Transaction{}, nil, errBumpFeeNoChange
+	}
+	if txn.SiacoinOutputs[change].Value.Equals(increase) {
+		txn.SiacoinOutputs = append(txn.SiacoinOutputs[:change], txn.SiacoinOutputs[change+1:]...)
+	} else {
+		txn.SiacoinOutputs[change].Value = txn.SiacoinOutputs[change].Value.Sub(increase)
+	}
+	txn.MinerFees = []types.Currency{fee}
+
+	for _, sci := range txn.SiacoinInputs {
+		addSignatures(&txn, types.FullCoveredFields, sci.UnlockConditions, crypto.Hash(sci.ParentID), w.keys[sci.UnlockConditions.UnlockHash()])
+	}
+	return txn, replaced, nil
+}
+
+// childPaysForParent creates and signs a transaction that spends the largest
+// unspent output of the wallet in the unconfirmed transaction txid, and pays
+// fee. The rest of the output is sent back to the wallet. The spent output is
+// marked as spent and returned.
+func (w *Wallet) childPaysForParent(txid types.TransactionID, fee, defaultFee types.Currency) (types.Transaction, types.OutputID, error) {
+	var parent types.Transaction
+	found := false
+	spent := make(map[types.SiacoinOutputID]struct{})
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		if upt.TransactionID == txid {
+			parent = upt.Transaction
+			found = true
+		}
+		for _, sci := range upt.Transaction.SiacoinInputs {
+			spent[sci.ParentID] = struct{}{}
+		}
+	}
+	if !found {
+		return types.Transaction{}, types.OutputID{}, errUnknownUnconfirmedTxn
+	}
+	if fee.IsZero() {
+		// The child pays for its own size and for the size of the parent.
+		fee = defaultFee.Mul64(2)
+	}
+
+	// Choose the largest output of the wallet that is not spent yet.
+	change := -1
+	for i, sco := range parent.SiacoinOutputs {
+		id := parent.SiacoinOutputID(uint64(i))
+		if _, exists := w.keys[sco.UnlockHash]; !exists {
+			continue
+		} else if _, exists := spent[id]; exists {
+			continue
+		} else if _, err := dbGetSpentOutput(w.dbTx, types.OutputID(id)); err == nil {
+			continue
+		}
+		if change == -1 || sco.Value.Cmp(parent.SiacoinOutputs[change].Value) > 0 {
+			change = i
+		}
+	}
+	if change == -1 || parent.SiacoinOutputs[change].Value.Cmp(fee) <= 0 {
+		return types.Transaction{}, types.OutputID{}, errBumpFeeNoChange
+	}
+	changeOutput := parent.SiacoinOutputs[change]
+	changeID := parent.SiacoinOutputID(uint64(change))
+
+	refundUnlockConditions, err := w.nextPrimarySeedAddress(w.dbTx)
+	if err != nil {
+		return types.Transaction{}, types.OutputID{}, err
+	}
+	key := w.keys[changeOutput.UnlockHash]
+	txn := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{
+			ParentID:         changeID,
+			UnlockConditions: key.UnlockConditions,
+		}},
+		SiacoinOutputs: []types.SiacoinOutput{{
+			Value:      changeOutput.Value.Sub(fee),
+			UnlockHash: refundUnlockConditions.UnlockHash(),
+		}},
+		MinerFees: []types.Currency{fee},
+	}
+	addSignatures(&txn, types.FullCoveredFields, key.UnlockConditions, crypto.Hash(changeID), key)
+
+	height, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return types.Transaction{}, types.OutputID{}, err
+	}
+	if err := dbPutSpentOutput(w.dbTx, types.OutputID(changeID), height); err != nil {
+		return types.Transaction{}, types.OutputID{}, err
+	}
+	return txn, types.OutputID(changeID), nil
+}
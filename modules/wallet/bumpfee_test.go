@@ -0,0 +1,155 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestBumpFeeReplace checks that the wallet can replace an unconfirmed
+// transaction set with a transaction that pays a higher fee, and that the
+// replacement is recorded.
+func TestBumpFeeReplace(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	amount := types.SiacoinPrecision.Mul64(100)
+	txns, err := wt.wallet.SendSiacoins(amount, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := txns[len(txns)-1]
+	var oldFee types.Currency
+	for _, setTxn := range txns {
+		for _, fee := range setTxn.MinerFees {
+			oldFee = oldFee.Add(fee)
+		}
+	}
+
+	if _, err := wt.wallet.BumpFee(types.TransactionID{}, types.ZeroCurrency, modules.BumpFeeReplace); err != errUnknownUnconfirmedTxn {
+		t.Fatal("expected errUnknownUnconfirmedTxn, got", err)
+	}
+	if _, err := wt.wallet.BumpFee(txn.ID(), oldFee, modules.BumpFeeReplace); err != errBumpFeeTooLow {
+		t.Fatal("expected errBumpFeeTooLow, got", err)
+	}
+	if _, err := wt.wallet.BumpFee(txn.ID(), types.ZeroCurrency, "foo"); err != modules.ErrUnknownBumpFeeMethod {
+		t.Fatal("expected ErrUnknownBumpFeeMethod, got", err)
+	}
+
+	newFee := oldFee.Mul64(2)
+	replacement, err := wt.wallet.BumpFee(txn.ID(), newFee, modules.BumpFeeReplace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replacement) != 1 || !replacement[0].MinerFees[0].Equals(newFee) {
+		t.Fatal("replacement does not pay the new fee:", replacement)
+	}
+	newID := replacement[0].ID()
+
+	// The old transactions are gone from the transaction pool and from the
+	// unconfirmed transactions of the wallet.
+	for _, old := range txns {
+		if _, _, exists := wt.tpool.Transaction(old.ID()); exists {
+			t.Fatal("replaced transaction is still in the transaction pool")
+		}
+	}
+	upts, err := wt.wallet.UnconfirmedTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upts) != 1 || upts[0].TransactionID != newID {
+		t.Fatal("expected the replacement to be the only unconfirmed transaction:", upts)
+	}
+
+	// The replacement is recorded for all of the replaced transactions.
+	rs, err := wt.wallet.TransactionReplacements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != len(txns) {
+		t.Fatalf("expected %v replacements, got %v", len(txns), len(rs))
+	}
+	for _, r := range rs {
+		if r.ReplacementID != newID {
+			t.Fatal("wrong replacement recorded:", r)
+		}
+	}
+
+	// The replacement is mined and still sends the full amount.
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	pt, exists, err := wt.wallet.Transaction(newID)
+	if err != nil {
+		t.Fatal(err)
+	} else if !exists {
+		t.Fatal("replacement was not confirmed")
+	}
+	var sent bool
+	for _, sco := range pt.Transaction.SiacoinOutputs {
+		sent = sent || (sco.UnlockHash == types.UnlockHash{} && sco.Value.Equals(amount))
+	}
+	if !sent {
+		t.Fatal("replacement does not send the amount")
+	}
+}
+
+// TestBumpFeeChild checks that the wallet can spend the change output of an
+// unconfirmed transaction in a child transaction with a higher fee.
+func TestBumpFeeChild(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns) != 2 {
+		t.Fatal("expected a parent and a child transaction, got", len(txns))
+	}
+
+	// The transaction that sends the coins has no change output, but its
+	// parent does.
+	if _, err := wt.wallet.BumpFee(txns[1].ID(), types.ZeroCurrency, modules.BumpFeeChild); err != errBumpFeeNoChange {
+		t.Fatal("expected errBumpFeeNoChange, got", err)
+	}
+	fee := types.SiacoinPrecision
+	child, err := wt.wallet.BumpFee(txns[0].ID(), fee, modules.BumpFeeChild)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(child) != 1 || !child[0].MinerFees[0].Equals(fee) {
+		t.Fatal("child does not pay the fee:", child)
+	}
+	_, parents, exists := wt.tpool.Transaction(child[0].ID())
+	if !exists {
+		t.Fatal("child is not in the transaction pool")
+	} else if len(parents) != 1 {
+		t.Fatal("child should depend on one parent, got", len(parents))
+	}
+
+	// The change output is spent now, so it cannot be spent by another child.
+	if _, err := wt.wallet.BumpFee(txns[0].ID(), fee, modules.BumpFeeChild); err != errBumpFeeNoChange {
+		t.Fatal("expected errBumpFeeNoChange, got", err)
+	}
+
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists, err := wt.wallet.Transaction(child[0].ID()); err != nil || !exists {
+		t.Fatal("child was not confirmed:", err)
+	}
+}
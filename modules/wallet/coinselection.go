@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+const (
+	// changeOutputCost is the estimated number of bytes that a change output
+	// adds to the blockchain, including the input that later spends it. The
+	// branch-and-bound coin selection spends up to changeOutputCost times the
+	// fee per byte more than necessary to avoid a change output.
+	changeOutputCost = 250
+
+	// maxBranchAndBoundTries is the maximum number of combinations of
+	// outputs that the branch-and-bound coin selection tries before falling
+	// back to spending the largest outputs first.
+	maxBranchAndBoundTries = 100e3
+)
+
+// branchAndBound searches for a subset of values whose sum lies between
+// target and target+tolerance. values must be sorted in descending order. The
+// indices of the subset are returned, or nil if no subset was found within
+// maxBranchAndBoundTries tries.
+func branchAndBound(values []types.Currency, target, tolerance types.Currency) []int {
+	// remaining[i] is the sum of values[i:], which is used to skip branches
+	// that cannot reach the target.
+	remaining := make([]types.Currency, len(values)+1)
+	for i := len(values) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1].Add(values[i])
+	}
+	upper := target.Add(tolerance)
+
+	var selected []int
+	tries := 0
+	var search func(i int, sum types.Currency) bool
+	search = func(i int, sum types.Currency) bool {
+		tries++
+		if tries > maxBranchAndBoundTries {
+			return false
+		}
+		if sum.Cmp(target) >= 0 {
+			return sum.Cmp(upper) <= 0
+		}
+		if i == len(values) || sum.Add(remaining[i]).Cmp(target) < 0 {
+			return false
+		}
+		// Try to include values[i] before excluding it.
+		selected = append(selected, i)
+		if search(i+1, sum.Add(values[i])) {
+			return true
+		}
+		selected = selected[:len(selected)-1]
+		return search(i+1, sum)
+	}
+	if !search(0, types.ZeroCurrency) {
+		return nil
+	}
+	return selected
+}
+
+// selectOutputs chooses outputs of so that fund amount according to the coin
+// selection strategy. so is sorted in place. The chosen outputs and their
+// total value are returned; the value is less than amount if so does not
+// contain enough outputs. If changeless is true, the outputs were chosen to
+// exceed amount by at most tolerance, so that the excess can be added to the
+// miner fees instead of creating a change output.
+func selectOutputs(so sortedOutputs, amount, tolerance types.Currency, strategy modules.CoinSelectionStrategy) (selected sortedOutputs, fund types.Currency, changeless bool) {
+	if strategy == modules.CoinSelectionSmallestFirst {
+		sort.Sort(so)
+	} else {
+		sort.Sort(sort.Reverse(so))
+	}
+
+	if strategy == modules.CoinSelectionBranchAndBound {
+		values := make([]types.Currency, len(so.outputs))
+		for i, sco := range so.outputs {
+			values[i] = sco.Value
+		}
+		if indices := branchAndBound(values, amount, tolerance); indices != nil {
+			for _, i := range indices {
+				selected.ids = append(selected.ids, so.ids[i])
+				selected.outputs = append(selected.outputs, so.outputs[i])
+				fund = fund.Add(so.outputs[i].Value)
+			}
+			return selected, fund, true
+		}
+	}
+
+	for i := range so.ids {
+		if fund.Cmp(amount) >= 0 {
+			break
+		}
+		selected.ids = append(selected.ids, so.ids[i])
+		selected.outputs = append(selected.outputs, so.outputs[i])
+		fund = fund.Add(so.outputs[i].Value)
+	}
+	return selected, fund, false
+}
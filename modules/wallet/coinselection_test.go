@@ -0,0 +1,91 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// testSortedOutputs returns a sortedOutputs object with outputs of the given
+// values, whose IDs are derived from the values.
+func testSortedOutputs(values ...uint64) sortedOutputs {
+	var so sortedOutputs
+	for _, v := range values {
+		so.ids = append(so.ids, types.SiacoinOutputID{byte(v)})
+		so.outputs = append(so.outputs, types.SiacoinOutput{Value: types.NewCurrency64(v)})
+	}
+	return so
+}
+
+// TestBranchAndBound probes the branchAndBound function.
+func TestBranchAndBound(t *testing.T) {
+	values := []types.Currency{
+		types.NewCurrency64(50),
+		types.NewCurrency64(30),
+		types.NewCurrency64(20),
+		types.NewCurrency64(7),
+	}
+	tests := []struct {
+		target, tolerance uint64
+		found             bool
+	}{
+		{57, 0, true},
+		{27, 0, true},
+		{45, 0, false},
+		{45, 5, true},
+		{108, 0, false},
+	}
+	for _, test := range tests {
+		indices := branchAndBound(values, types.NewCurrency64(test.target), types.NewCurrency64(test.tolerance))
+		if (indices != nil) != test.found {
+			t.Fatalf("target %v: expected found = %v, got %v", test.target, test.found, indices)
+		}
+		var sum types.Currency
+		for _, i := range indices {
+			sum = sum.Add(values[i])
+		}
+		if test.found && (sum.Cmp64(test.target) < 0 || sum.Cmp64(test.target+test.tolerance) > 0) {
+			t.Fatalf("target %v: selected sum %v is out of range", test.target, sum)
+		}
+	}
+}
+
+// TestSelectOutputs checks that selectOutputs chooses outputs according to
+// the coin selection strategy.
+func TestSelectOutputs(t *testing.T) {
+	tests := []struct {
+		strategy   modules.CoinSelectionStrategy
+		amount     uint64
+		selected   []uint64
+		changeless bool
+	}{
+		{modules.CoinSelectionLargestFirst, 25, []uint64{50}, false},
+		{modules.CoinSelectionSmallestFirst, 25, []uint64{7, 20}, false},
+		{modules.CoinSelectionBranchAndBound, 27, []uint64{20, 7}, true},
+		// Without an exact match, branch-and-bound spends the largest outputs
+		// first.
+		{modules.CoinSelectionBranchAndBound, 24, []uint64{50}, false},
+		{modules.CoinSelectionLargestFirst, 200, []uint64{50, 30, 20, 7}, false},
+	}
+	for _, test := range tests {
+		so := testSortedOutputs(20, 50, 7, 30)
+		selected, fund, changeless := selectOutputs(so, types.NewCurrency64(test.amount), types.NewCurrency64(1), test.strategy)
+		if changeless != test.changeless {
+			t.Fatalf("%v %v: expected changeless = %v", test.strategy, test.amount, test.changeless)
+		}
+		if len(selected.outputs) != len(test.selected) {
+			t.Fatalf("%v %v: expected %v outputs, got %v", test.strategy, test.amount, len(test.selected), len(selected.outputs))
+		}
+		var sum types.Currency
+		for i, v := range test.selected {
+			if selected.outputs[i].Value.Cmp64(v) != 0 || selected.ids[i] != (types.SiacoinOutputID{byte(v)}) {
+				t.Fatalf("%v %v: expected output %v at position %v, got %v", test.strategy, test.amount, v, i, selected.outputs[i].Value)
+			}
+			sum = sum.Add(types.NewCurrency64(v))
+		}
+		if !fund.Equals(sum) {
+			t.Fatalf("%v %v: expected fund %v, got %v", test.strategy, test.amount, sum, fund)
+		}
+	}
+}
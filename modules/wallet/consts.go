@@ -1,6 +1,8 @@
 package wallet
 
 import (
+	"time"
+
 	"github.com/NebulousLabs/Sia/build"
 )
 
@@ -33,6 +35,14 @@ var (
 		Standard: uint64(1000),
 		Testing:  uint64(10),
 	}).(uint64)
+
+	// rebroadcastInterval is the interval at which the wallet rebroadcasts
+	// its unconfirmed outgoing transactions.
+	rebroadcastInterval = build.Select(build.Var{
+		Dev:      5 * time.Minute,
+		Standard: 30 * time.Minute,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
 )
 
 func init() {
@@ -42,6 +42,47 @@ var (
 	// bucketWallet contains various fields needed by the wallet, such as its
 	// UID, EncryptionVerification, and PrimarySeedFile.
 	bucketWallet = []byte("bucketWallet")
+	// bucketMultisigAddrs maps the UnlockHash of a multisig address to its
+	// UnlockConditions. Multisig addresses are also watch-only addresses, so
+	// their outputs are stored in the watched buckets.
+	bucketMultisigAddrs = []byte("bucketMultisigAddrs")
+	// bucketLockedOutputs stores the IDs of the siacoin outputs that the
+	// user has locked. The wallet does not choose locked outputs to fund
+	// transactions. The values are unused.
+	bucketLockedOutputs = []byte("bucketLockedOutputs")
+	// bucketReplacedTxns maps the TransactionID of an unconfirmed
+	// transaction that was replaced by a transaction with a higher fee to
+	// the TransactionReplacement that records the replacement.
+	bucketReplacedTxns = []byte("bucketReplacedTxns")
+	// bucketTxnLabels maps a TransactionID to the WalletLabel that the user
+	// attached to the transaction.
+	bucketTxnLabels = []byte("bucketTxnLabels")
+	// bucketAddrLabels maps an UnlockHash to the WalletLabel that the user
+	// attached to the address. The address does not have to belong to the
+	// wallet.
+	bucketAddrLabels = []byte("bucketAddrLabels")
+	// bucketTimelockedAddrs maps the UnlockHash of a timelocked address to
+	// the timelockedAddr that is used to regenerate its key when the wallet
+	// is unlocked.
+	bucketTimelockedAddrs = []byte("bucketTimelockedAddrs")
+	// bucketWebhooks maps the URL of a webhook to the WalletWebhook.
+	bucketWebhooks = []byte("bucketWebhooks")
+	// bucketAddrBalances maps an UnlockHash to the confirmed addrBalance of
+	// the address. Only addresses with outputs in bucketSiacoinOutputs,
+	// bucketSiafundOutputs or the watched output buckets are stored.
+	bucketAddrBalances = []byte("bucketAddrBalances")
+	// bucketWatchedAddrs stores the watch-only addresses of the wallet. The
+	// wallet tracks the outputs and transactions of these addresses, but it
+	// does not have the keys to spend them. The values are unused.
+	bucketWatchedAddrs = []byte("bucketWatchedAddrs")
+	// bucketWatchedSiacoinOutputs maps a SiacoinOutputID to its
+	// SiacoinOutput for outputs that belong to a watch-only address. These
+	// outputs are never used to fund transactions.
+	bucketWatchedSiacoinOutputs = []byte("bucketWatchedSiacoinOutputs")
+	// bucketWatchedSiafundOutputs maps a SiafundOutputID to its SiafundOutput
+	// for outputs that belong to a watch-only address. These outputs are
+	// never used to fund transactions.
+	bucketWatchedSiafundOutputs = []byte("bucketWatchedSiafundOutputs")
 
 	dbBuckets = [][]byte{
 		bucketProcessedTransactions,
@@ -51,12 +92,24 @@ var (
 		bucketSiafundOutputs,
 		bucketSpentOutputs,
 		bucketWallet,
+		bucketWatchedAddrs,
+		bucketWatchedSiacoinOutputs,
+		bucketWatchedSiafundOutputs,
+		bucketMultisigAddrs,
+		bucketLockedOutputs,
+		bucketReplacedTxns,
+		bucketTxnLabels,
+		bucketAddrLabels,
+		bucketTimelockedAddrs,
+		bucketWebhooks,
+		bucketAddrBalances,
 	}
 
 	errNoKey = errors.New("key does not exist")
 
 	// these keys are used in bucketWallet
 	keyAuxiliarySeedFiles     = []byte("keyAuxiliarySeedFiles")
+	keyAutoLockTimeout        = []byte("keyAutoLockTimeout")
 	keyConsensusChange        = []byte("keyConsensusChange")
 	keyConsensusHeight        = []byte("keyConsensusHeight")
 	keyEncryptionVerification = []byte("keyEncryptionVerification")
@@ -64,7 +117,9 @@ var (
 	keyPrimarySeedProgress    = []byte("keyPrimarySeedProgress")
 	keySiafundPool            = []byte("keySiafundPool")
 	keySpendableKeyFiles      = []byte("keySpendableKeyFiles")
+	keySpendingVerification   = []byte("keySpendingVerification")
 	keyUID                    = []byte("keyUID")
+	keyWalletSettings         = []byte("keyWalletSettings")
 )
 
 // threadedDBUpdate commits the active database transaction and starts a new
@@ -194,6 +249,9 @@ func dbForEach(b *bolt.Bucket, fn interface{}) error {
 // Type-safe wrappers around the db helpers
 
 func dbPutSiacoinOutput(tx *bolt.Tx, id types.SiacoinOutputID, output types.SiacoinOutput) error {
+	if err := dbAddSiacoinOutputBalance(tx, bucketSiacoinOutputs, id, output); err != nil {
+		return err
+	}
 	return dbPut(tx.Bucket(bucketSiacoinOutputs), id, output)
 }
 func dbGetSiacoinOutput(tx *bolt.Tx, id types.SiacoinOutputID) (output types.SiacoinOutput, err error) {
@@ -201,6 +259,9 @@ func dbGetSiacoinOutput(tx *bolt.Tx, id types.SiacoinOutputID) (output types.Sia
 	return
 }
 func dbDeleteSiacoinOutput(tx *bolt.Tx, id types.SiacoinOutputID) error {
+	if err := dbRemoveSiacoinOutputBalance(tx, bucketSiacoinOutputs, id); err != nil {
+		return err
+	}
 	return dbDelete(tx.Bucket(bucketSiacoinOutputs), id)
 }
 func dbForEachSiacoinOutput(tx *bolt.Tx, fn func(types.SiacoinOutputID, types.SiacoinOutput)) error {
@@ -208,6 +269,9 @@ func dbForEachSiacoinOutput(tx *bolt.Tx, fn func(types.SiacoinOutputID, types.Si
 }
 
 func dbPutSiafundOutput(tx *bolt.Tx, id types.SiafundOutputID, output types.SiafundOutput) error {
+	if err := dbAddSiafundOutputBalance(tx, bucketSiafundOutputs, id, output); err != nil {
+		return err
+	}
 	return dbPut(tx.Bucket(bucketSiafundOutputs), id, output)
 }
 func dbGetSiafundOutput(tx *bolt.Tx, id types.SiafundOutputID) (output types.SiafundOutput, err error) {
@@ -215,12 +279,88 @@ func dbGetSiafundOutput(tx *bolt.Tx, id types.SiafundOutputID) (output types.Sia
 	return
 }
 func dbDeleteSiafundOutput(tx *bolt.Tx, id types.SiafundOutputID) error {
+	if err := dbRemoveSiafundOutputBalance(tx, bucketSiafundOutputs, id); err != nil {
+		return err
+	}
 	return dbDelete(tx.Bucket(bucketSiafundOutputs), id)
 }
 func dbForEachSiafundOutput(tx *bolt.Tx, fn func(types.SiafundOutputID, types.SiafundOutput)) error {
 	return dbForEach(tx.Bucket(bucketSiafundOutputs), fn)
 }
 
+func dbPutWatchedSiacoinOutput(tx *bolt.Tx, id types.SiacoinOutputID, output types.SiacoinOutput) error {
+	if err := dbAddSiacoinOutputBalance(tx, bucketWatchedSiacoinOutputs, id, output); err != nil {
+		return err
+	}
+	return dbPut(tx.Bucket(bucketWatchedSiacoinOutputs), id, output)
+}
+func dbDeleteWatchedSiacoinOutput(tx *bolt.Tx, id types.SiacoinOutputID) error {
+	if err := dbRemoveSiacoinOutputBalance(tx, bucketWatchedSiacoinOutputs, id); err != nil {
+		return err
+	}
+	return dbDelete(tx.Bucket(bucketWatchedSiacoinOutputs), id)
+}
+func dbForEachWatchedSiacoinOutput(tx *bolt.Tx, fn func(types.SiacoinOutputID, types.SiacoinOutput)) error {
+	return dbForEach(tx.Bucket(bucketWatchedSiacoinOutputs), fn)
+}
+
+func dbPutWatchedSiafundOutput(tx *bolt.Tx, id types.SiafundOutputID, output types.SiafundOutput) error {
+	if err := dbAddSiafundOutputBalance(tx, bucketWatchedSiafundOutputs, id, output); err != nil {
+		return err
+	}
+	return dbPut(tx.Bucket(bucketWatchedSiafundOutputs), id, output)
+}
+func dbDeleteWatchedSiafundOutput(tx *bolt.Tx, id types.SiafundOutputID) error {
+	if err := dbRemoveSiafundOutputBalance(tx, bucketWatchedSiafundOutputs, id); err != nil {
+		return err
+	}
+	return dbDelete(tx.Bucket(bucketWatchedSiafundOutputs), id)
+}
+func dbForEachWatchedSiafundOutput(tx *bolt.Tx, fn func(types.SiafundOutputID, types.SiafundOutput)) error {
+	return dbForEach(tx.Bucket(bucketWatchedSiafundOutputs), fn)
+}
+
+func dbPutWatchedAddr(tx *bolt.Tx, addr types.UnlockHash) error {
+	return dbPut(tx.Bucket(bucketWatchedAddrs), addr, struct{}{})
+}
+func dbDeleteWatchedAddr(tx *bolt.Tx, addr types.UnlockHash) error {
+	return dbDelete(tx.Bucket(bucketWatchedAddrs), addr)
+}
+func dbForEachWatchedAddr(tx *bolt.Tx, fn func(types.UnlockHash, struct{})) error {
+	return dbForEach(tx.Bucket(bucketWatchedAddrs), fn)
+}
+
+func dbPutMultisigAddr(tx *bolt.Tx, uc types.UnlockConditions) error {
+	return dbPut(tx.Bucket(bucketMultisigAddrs), uc.UnlockHash(), uc)
+}
+func dbGetMultisigAddr(tx *bolt.Tx, addr types.UnlockHash) (uc types.UnlockConditions, err error) {
+	err = dbGet(tx.Bucket(bucketMultisigAddrs), addr, &uc)
+	return
+}
+func dbDeleteMultisigAddr(tx *bolt.Tx, addr types.UnlockHash) error {
+	return dbDelete(tx.Bucket(bucketMultisigAddrs), addr)
+}
+func dbForEachMultisigAddr(tx *bolt.Tx, fn func(types.UnlockHash, types.UnlockConditions)) error {
+	return dbForEach(tx.Bucket(bucketMultisigAddrs), fn)
+}
+
+func dbPutTimelockedAddr(tx *bolt.Tx, ta timelockedAddr) error {
+	return dbPut(tx.Bucket(bucketTimelockedAddrs), ta.UnlockConditions.UnlockHash(), ta)
+}
+func dbForEachTimelockedAddr(tx *bolt.Tx, fn func(types.UnlockHash, timelockedAddr)) error {
+	return dbForEach(tx.Bucket(bucketTimelockedAddrs), fn)
+}
+
+func dbPutWebhook(tx *bolt.Tx, hook modules.WalletWebhook) error {
+	return dbPut(tx.Bucket(bucketWebhooks), hook.URL, hook)
+}
+func dbDeleteWebhook(tx *bolt.Tx, url string) error {
+	return dbDelete(tx.Bucket(bucketWebhooks), url)
+}
+func dbForEachWebhook(tx *bolt.Tx, fn func(string, modules.WalletWebhook)) error {
+	return dbForEach(tx.Bucket(bucketWebhooks), fn)
+}
+
 func dbPutSpentOutput(tx *bolt.Tx, id types.OutputID, height types.BlockHeight) error {
 	return dbPut(tx.Bucket(bucketSpentOutputs), id, height)
 }
@@ -232,6 +372,43 @@ func dbDeleteSpentOutput(tx *bolt.Tx, id types.OutputID) error {
 	return dbDelete(tx.Bucket(bucketSpentOutputs), id)
 }
 
+func dbPutLockedOutput(tx *bolt.Tx, id types.SiacoinOutputID) error {
+	return dbPut(tx.Bucket(bucketLockedOutputs), id, struct{}{})
+}
+func dbDeleteLockedOutput(tx *bolt.Tx, id types.SiacoinOutputID) error {
+	return dbDelete(tx.Bucket(bucketLockedOutputs), id)
+}
+func dbIsLockedOutput(tx *bolt.Tx, id types.SiacoinOutputID) bool {
+	return tx.Bucket(bucketLockedOutputs).Get(encoding.Marshal(id)) != nil
+}
+
+func dbPutReplacedTxn(tx *bolt.Tx, r modules.TransactionReplacement) error {
+	return dbPut(tx.Bucket(bucketReplacedTxns), r.ReplacedID, r)
+}
+func dbForEachReplacedTxn(tx *bolt.Tx, fn func(types.TransactionID, modules.TransactionReplacement)) error {
+	return dbForEach(tx.Bucket(bucketReplacedTxns), fn)
+}
+
+func dbPutTxnLabel(tx *bolt.Tx, txid types.TransactionID, l modules.WalletLabel) error {
+	return dbPut(tx.Bucket(bucketTxnLabels), txid, l)
+}
+func dbDeleteTxnLabel(tx *bolt.Tx, txid types.TransactionID) error {
+	return dbDelete(tx.Bucket(bucketTxnLabels), txid)
+}
+func dbForEachTxnLabel(tx *bolt.Tx, fn func(types.TransactionID, modules.WalletLabel)) error {
+	return dbForEach(tx.Bucket(bucketTxnLabels), fn)
+}
+
+func dbPutAddrLabel(tx *bolt.Tx, addr types.UnlockHash, l modules.WalletLabel) error {
+	return dbPut(tx.Bucket(bucketAddrLabels), addr, l)
+}
+func dbDeleteAddrLabel(tx *bolt.Tx, addr types.UnlockHash) error {
+	return dbDelete(tx.Bucket(bucketAddrLabels), addr)
+}
+func dbForEachAddrLabel(tx *bolt.Tx, fn func(types.UnlockHash, modules.WalletLabel)) error {
+	return dbForEach(tx.Bucket(bucketAddrLabels), fn)
+}
+
 func dbPutAddrTransactions(tx *bolt.Tx, addr types.UnlockHash, txns []uint64) error {
 	return dbPut(tx.Bucket(bucketAddrTransactions), addr, txns)
 }
@@ -466,6 +643,36 @@ func dbPutConsensusHeight(tx *bolt.Tx, height types.BlockHeight) error {
 	return tx.Bucket(bucketWallet).Put(keyConsensusHeight, encoding.Marshal(height))
 }
 
+// dbGetWalletSettings returns the persisted settings of the wallet.
+func dbGetWalletSettings(tx *bolt.Tx) (settings modules.WalletSettings, err error) {
+	settingsBytes := tx.Bucket(bucketWallet).Get(keyWalletSettings)
+	if settingsBytes == nil {
+		return modules.WalletSettings{}, errNoKey
+	}
+	err = encoding.Unmarshal(settingsBytes, &settings)
+	return
+}
+
+// dbPutWalletSettings stores the settings of the wallet.
+func dbPutWalletSettings(tx *bolt.Tx, settings modules.WalletSettings) error {
+	return tx.Bucket(bucketWallet).Put(keyWalletSettings, encoding.Marshal(settings))
+}
+
+// dbGetAutoLockTimeout returns the auto-lock timeout of the wallet.
+func dbGetAutoLockTimeout(tx *bolt.Tx) (timeout time.Duration, err error) {
+	timeoutBytes := tx.Bucket(bucketWallet).Get(keyAutoLockTimeout)
+	if timeoutBytes == nil {
+		return 0, nil
+	}
+	err = encoding.Unmarshal(timeoutBytes, &timeout)
+	return
+}
+
+// dbPutAutoLockTimeout stores the auto-lock timeout of the wallet.
+func dbPutAutoLockTimeout(tx *bolt.Tx, timeout time.Duration) error {
+	return tx.Bucket(bucketWallet).Put(keyAutoLockTimeout, encoding.Marshal(timeout))
+}
+
 // dbGetSiafundPool returns the value of the siafund pool.
 func dbGetSiafundPool(tx *bolt.Tx) (pool types.Currency, err error) {
 	err = encoding.Unmarshal(tx.Bucket(bucketWallet).Get(keySiafundPool), &pool)
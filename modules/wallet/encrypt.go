@@ -16,10 +16,12 @@ import (
 )
 
 var (
-	errAlreadyUnlocked   = errors.New("wallet has already been unlocked")
-	errReencrypt         = errors.New("wallet is already encrypted, cannot encrypt again")
-	errScanInProgress    = errors.New("another wallet rescan is already underway")
-	errUnencryptedWallet = errors.New("wallet has not been encrypted yet")
+	errAlreadyUnlocked         = errors.New("wallet has already been unlocked")
+	errNegativeAutoLockTimeout = errors.New("auto-lock timeout must not be negative")
+	errPrunedRescan            = errors.New("cannot rescan the blockchain because the consensus set has pruned old blocks")
+	errReencrypt               = errors.New("wallet is already encrypted, cannot encrypt again")
+	errScanInProgress          = errors.New("another wallet rescan is already underway")
+	errUnencryptedWallet       = errors.New("wallet has not been encrypted yet")
 
 	// verificationPlaintext is the plaintext used to verify encryption keys.
 	// By storing the corresponding ciphertext for a given key, we can later
@@ -183,7 +185,9 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 			}
 			w.integrateSpendableKey(masterKey, sk)
 		}
-		return nil
+
+		// timelocked addresses
+		return w.integrateTimelockedAddrs()
 	}()
 	if err != nil {
 		return err
@@ -203,7 +207,9 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 		defer close(done)
 
 		err = w.cs.ConsensusSetSubscribe(w, lastChange, w.tg.StopChan())
-		if err == modules.ErrInvalidConsensusChangeID {
+		if err == modules.ErrInvalidConsensusChangeID && w.cs.Pruned() {
+			err = errPrunedRescan
+		} else if err == modules.ErrInvalidConsensusChangeID {
 			// something went wrong; resubscribe from the beginning
 			err = dbPutConsensusChangeID(w.dbTx, modules.ConsensusChangeBeginning)
 			if err != nil {
@@ -215,6 +221,11 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 			}
 			err = w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan())
 		}
+		if err == modules.ErrBlockPruned {
+			// The consensus set has pruned blocks that the wallet has not
+			// processed since it was last unlocked, so it cannot catch up.
+			err = errPrunedRescan
+		}
 		if err != nil {
 			return fmt.Errorf("wallet subscription failed: %v", err)
 		}
@@ -224,6 +235,7 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 	w.mu.Lock()
 	w.unlocked = true
 	w.subscribed = true
+	w.armAutoLock()
 	w.mu.Unlock()
 	return nil
 }
@@ -341,11 +353,21 @@ func (w *Wallet) Reset() error {
 	w.wipeSecrets()
 	w.keys = make(map[types.UnlockHash]spendableKey)
 	w.lookahead = make(map[types.UnlockHash]uint64)
+	w.watchedAddrs = make(map[types.UnlockHash]struct{})
 	w.seeds = []modules.Seed{}
 	w.unconfirmedProcessedTransactions = []modules.ProcessedTransaction{}
+	// The webhooks were deleted with the database, and the events of the old
+	// seed must not be delivered to anyone.
+	w.webhooks = make(map[string]modules.WalletWebhook)
+	w.webhookQueues = make(map[string]*webhookQueue)
+	w.pendingEvents = nil
+	w.eventSubscribers = nil
 	w.unlocked = false
 	w.encrypted = false
 	w.subscribed = false
+	w.spendingKeyRequired = false
+	w.autoLockTimeout = 0
+	w.disarmAutoLock()
 
 	return nil
 }
@@ -363,6 +385,9 @@ func (w *Wallet) InitFromSeed(masterKey crypto.TwofishKey, seed modules.Seed) er
 	if !w.cs.Synced() {
 		return errors.New("cannot init from seed until blockchain is synced")
 	}
+	if w.cs.Pruned() {
+		return errPrunedRescan
+	}
 
 	// If masterKey is blank, use the hash of the seed.
 	if masterKey == (crypto.TwofishKey{}) {
@@ -596,6 +621,7 @@ func (w *Wallet) managedLock() error {
 	// we can continue processing blocks.
 	w.wipeSecrets()
 	w.unlocked = false
+	w.disarmAutoLock()
 	return nil
 }
 
@@ -605,3 +631,151 @@ func (w *Wallet) managedUnlocked() bool {
 	defer w.mu.RUnlock()
 	return w.unlocked
 }
+
+// armAutoLock starts the auto-lock timer of an unlocked wallet. Every timer
+// gets its own generation, so that a timer that fires after the wallet was
+// locked and unlocked again does not lock the wallet early.
+func (w *Wallet) armAutoLock() {
+	w.disarmAutoLock()
+	if w.autoLockTimeout == 0 || !w.unlocked {
+		return
+	}
+	gen := w.autoLockGen
+	w.autoLockTimer = time.AfterFunc(w.autoLockTimeout, func() {
+		w.threadedAutoLock(gen)
+	})
+}
+
+// disarmAutoLock stops the auto-lock timer.
+func (w *Wallet) disarmAutoLock() {
+	w.autoLockGen++
+	if w.autoLockTimer != nil {
+		w.autoLockTimer.Stop()
+		w.autoLockTimer = nil
+	}
+}
+
+// threadedAutoLock locks the wallet when the auto-lock timer of generation
+// gen fires.
+func (w *Wallet) threadedAutoLock(gen uint64) {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	current := w.autoLockGen == gen && w.unlocked
+	w.mu.Unlock()
+	if !current {
+		return
+	}
+	w.log.Println("INFO: Auto-lock timeout expired.")
+	if err := w.managedLock(); err != nil && err != modules.ErrLockedWallet {
+		w.log.Println("WARN: could not auto-lock wallet:", err)
+	}
+}
+
+// AutoLockTimeout returns the time after which an unlocked wallet locks
+// itself. Zero means the wallet stays unlocked until it is locked manually.
+func (w *Wallet) AutoLockTimeout() (time.Duration, error) {
+	if err := w.tg.Add(); err != nil {
+		return 0, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.autoLockTimeout, nil
+}
+
+// SetAutoLockTimeout sets the time after which an unlocked wallet locks
+// itself. If the wallet is unlocked, the timeout starts anew. The timeout is
+// persisted.
+func (w *Wallet) SetAutoLockTimeout(timeout time.Duration) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if timeout < 0 {
+		return errNegativeAutoLockTimeout
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := dbPutAutoLockTimeout(w.dbTx, timeout); err != nil {
+		return err
+	}
+	w.autoLockTimeout = timeout
+	w.armAutoLock()
+	return w.syncDB()
+}
+
+// SetSpendingKey sets the spending key of the wallet. If the wallet has a
+// spending key, it is required to sign transactions with the keys of the
+// wallet and to export them, so that a wallet that is unlocked for read-only
+// use cannot be drained by a client that only knows the API password. See
+// AuthorizeSpending. masterKey must be the encryption key of the wallet. A
+// blank spendingKey removes the spending key.
+//
+// Like the encryption key, the spending key is never stored. Only a
+// ciphertext that verifies it is.
+func (w *Wallet) SetSpendingKey(masterKey, spendingKey crypto.TwofishKey) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.encrypted {
+		return errUnencryptedWallet
+	}
+	if err := checkMasterKey(w.dbTx, masterKey); err != nil {
+		return err
+	}
+	wb := w.dbTx.Bucket(bucketWallet)
+	if spendingKey == (crypto.TwofishKey{}) {
+		if err := wb.Delete(keySpendingVerification); err != nil {
+			return err
+		}
+		w.spendingKeyRequired = false
+		return w.syncDB()
+	}
+	uk := uidEncryptionKey(spendingKey, dbGetWalletUID(w.dbTx))
+	if err := wb.Put(keySpendingVerification, uk.EncryptBytes(verificationPlaintext)); err != nil {
+		return err
+	}
+	w.spendingKeyRequired = true
+	return w.syncDB()
+}
+
+// SpendingKeyRequired reports whether the wallet has a spending key.
+func (w *Wallet) SpendingKeyRequired() (bool, error) {
+	if err := w.tg.Add(); err != nil {
+		return false, err
+	}
+	defer w.tg.Done()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.spendingKeyRequired, nil
+}
+
+// managedCheckSpendingKey returns modules.ErrSpendingKeyRequired if the
+// wallet has a spending key and key is nil, and modules.ErrBadSpendingKey if
+// key is not the spending key. Like the master key, the spending key is
+// verified by decrypting a ciphertext that was encrypted with it.
+func (w *Wallet) managedCheckSpendingKey(key *crypto.TwofishKey) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.spendingKeyRequired {
+		return nil
+	} else if key == nil {
+		return modules.ErrSpendingKeyRequired
+	}
+	uk := uidEncryptionKey(*key, dbGetWalletUID(w.dbTx))
+	encryptedVerification := w.dbTx.Bucket(bucketWallet).Get(keySpendingVerification)
+	if verifyEncryption(uk, encryptedVerification) != nil {
+		return modules.ErrBadSpendingKey
+	}
+	return nil
+}
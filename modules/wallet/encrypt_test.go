@@ -2,15 +2,20 @@ package wallet
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/consensus"
+	"github.com/NebulousLabs/Sia/modules/gateway"
 	"github.com/NebulousLabs/Sia/modules/miner"
+	"github.com/NebulousLabs/Sia/modules/transactionpool"
 	"github.com/NebulousLabs/Sia/types"
 	"github.com/NebulousLabs/fastrand"
 )
@@ -385,6 +390,90 @@ func TestUnlockConcurrent(t *testing.T) {
 	}
 }
 
+// TestUnlockPruned checks that unlocking a wallet fails with errPrunedRescan
+// if the consensus set has pruned blocks that the wallet has not processed.
+func TestUnlockPruned(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	testdir := build.TempDir(modules.WalletDir, t.Name())
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	keepBlocks := types.BlockHeight(12)
+	cs, err := consensus.NewPruned(g, false, filepath.Join(testdir, modules.ConsensusDir), nil, keepBlocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	tp, err := transactionpool.New(cs, g, filepath.Join(testdir, modules.TransactionPoolDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tp.Close()
+	w, err := New(cs, tp, filepath.Join(testdir, modules.WalletDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	var masterKey crypto.TwofishKey
+	fastrand.Read(masterKey[:])
+	if _, err := w.Encrypt(masterKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Unlock(masterKey); err != nil {
+		t.Fatal(err)
+	}
+	m, err := miner.New(cs, tp, w, filepath.Join(testdir, modules.MinerDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	// Create a second wallet that processes a few blocks and is closed.
+	dir := filepath.Join(testdir, "pruned"+modules.WalletDir)
+	pw, err := New(cs, tp, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pw.Encrypt(masterKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.Unlock(masterKey); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := m.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mine until the blocks that the second wallet has not seen are pruned.
+	for cs.Height() < types.OakHardforkBlock+2*keepBlocks {
+		if _, err := m.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !cs.Pruned() {
+		t.Fatal("consensus set did not prune any blocks")
+	}
+
+	// The second wallet cannot catch up with the consensus set.
+	pw, err = New(cs, tp, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pw.Close()
+	if err := pw.Unlock(masterKey); err == nil || !strings.Contains(err.Error(), errPrunedRescan.Error()) {
+		t.Fatal("expected errPrunedRescan, got", err)
+	}
+}
+
 // TestInitFromSeed tests creating a wallet from a preexisting seed.
 func TestInitFromSeed(t *testing.T) {
 	if testing.Short() {
@@ -450,10 +539,32 @@ func TestReset(t *testing.T) {
 	}
 	postEncryptionTesting(wt.miner, wt.wallet, originalKey)
 
+	// Register a subscriber and a webhook, neither should receive events
+	// after the reset.
+	er := new(eventRecorder)
+	wt.wallet.SubscribeEvents(er)
+	err = wt.wallet.AddWebhook(modules.WalletWebhook{URL: "http://localhost:9980/events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	err = wt.wallet.Reset()
 	if err != nil {
 		t.Fatal(err)
 	}
+	hooks, err := wt.wallet.Webhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 0 {
+		t.Fatal("webhooks should be removed by a reset, got", hooks)
+	}
+	wt.wallet.mu.RLock()
+	wantsEvents, pending := wt.wallet.wantsEvents(), len(wt.wallet.pendingEvents)
+	wt.wallet.mu.RUnlock()
+	if wantsEvents || pending != 0 {
+		t.Fatal("wallet still delivers events after a reset")
+	}
 
 	// reinitialize the miner so it mines into the new seed
 	err = wt.miner.Close()
@@ -478,6 +589,11 @@ func TestReset(t *testing.T) {
 		t.Fatal(err)
 	}
 	postEncryptionTesting(wt.miner, wt.wallet, newKey)
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	if len(er.events) != 0 {
+		t.Fatal("subscriber received events of the new seed after the reset:", len(er.events))
+	}
 }
 
 // TestChangeKey tests that a wallet can only be unlocked with the new key
@@ -533,3 +649,166 @@ func TestChangeKey(t *testing.T) {
 	}
 	postEncryptionTesting(wt.miner, wt.wallet, newKey)
 }
+
+// TestSpendingKey checks that the wallet verifies its spending key, persists
+// it and refuses to sign transactions or export keys without it.
+func TestSpendingKey(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Without a spending key, any key is accepted.
+	var spendingKey, wrongKey crypto.TwofishKey
+	fastrand.Read(spendingKey[:])
+	fastrand.Read(wrongKey[:])
+	if required, err := wt.wallet.SpendingKeyRequired(); err != nil || required {
+		t.Fatal("new wallet requires a spending key:", required, err)
+	}
+	if _, err := wt.wallet.AuthorizeSpending(wrongKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wt.wallet.SetSpendingKey(wrongKey, spendingKey); err != modules.ErrBadEncryptionKey {
+		t.Fatal("expected ErrBadEncryptionKey, got", err)
+	}
+	if err := wt.wallet.SetSpendingKey(wt.walletMasterKey, spendingKey); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.AuthorizeSpending(wrongKey); err != modules.ErrBadSpendingKey {
+		t.Fatal("expected ErrBadSpendingKey, got", err)
+	}
+
+	// Every method that signs transactions or exports keys is refused without
+	// the spending key.
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := uc.UnlockHash()
+	refused := map[string]error{}
+	_, refused["AllSeeds"] = wt.wallet.AllSeeds()
+	_, refused["BumpFee"] = wt.wallet.BumpFee(types.TransactionID{}, types.ZeroCurrency, modules.BumpFeeReplace)
+	refused["CreateBackup"] = wt.wallet.CreateBackup(filepath.Join(wt.wallet.persistDir, "refused.backup"))
+	_, refused["PrepareMultisigTransaction"] = wt.wallet.PrepareMultisigTransaction(dest, []types.SiacoinOutput{{Value: types.SiacoinPrecision, UnlockHash: dest}})
+	_, _, refused["PrimarySeed"] = wt.wallet.PrimarySeed()
+	_, refused["SendSiacoins"] = wt.wallet.SendSiacoins(types.SiacoinPrecision, dest)
+	_, refused["SendSiacoinsFromOutputs"] = wt.wallet.SendSiacoinsFromOutputs(types.SiacoinPrecision, dest, []types.SiacoinOutputID{{}})
+	_, refused["SendSiacoinsMulti"] = wt.wallet.SendSiacoinsMulti([]types.SiacoinOutput{{Value: types.SiacoinPrecision, UnlockHash: dest}})
+	_, refused["SendSiacoinsWithTarget"] = wt.wallet.SendSiacoinsWithTarget(types.SiacoinPrecision, dest, 1)
+	_, refused["SendSiafunds"] = wt.wallet.SendSiafunds(types.NewCurrency64(1), dest)
+	_, refused["SignTransaction"] = wt.wallet.SignTransaction(&types.Transaction{})
+	_, _, refused["SweepSeed"] = wt.wallet.SweepSeed(modules.Seed{})
+	for method, err := range refused {
+		if err != modules.ErrSpendingKeyRequired {
+			t.Errorf("expected %v to return ErrSpendingKeyRequired, got %v", method, err)
+		}
+	}
+
+	// The authorized view can spend and export keys.
+	sw, err := wt.wallet.AuthorizeSpending(spendingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sw.PrimarySeed(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sw.SendSiacoins(types.SiacoinPrecision, dest); err != nil {
+		t.Fatal(err)
+	}
+
+	// The spending key survives a restart.
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet, err = New(wt.cs, wt.tpool, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if required, err := wt.wallet.SpendingKeyRequired(); err != nil || !required {
+		t.Fatal("spending key was not persisted:", required, err)
+	}
+	if _, err := wt.wallet.AuthorizeSpending(wrongKey); err != modules.ErrBadSpendingKey {
+		t.Fatal("expected ErrBadSpendingKey, got", err)
+	}
+
+	// A blank spending key removes the requirement.
+	if err := wt.wallet.SetSpendingKey(wt.walletMasterKey, crypto.TwofishKey{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.AuthorizeSpending(wrongKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := wt.wallet.PrimarySeed(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestAutoLock checks that an unlocked wallet locks itself after the
+// auto-lock timeout.
+func TestAutoLock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	if err := wt.wallet.SetAutoLockTimeout(-time.Second); err != errNegativeAutoLockTimeout {
+		t.Fatal("expected errNegativeAutoLockTimeout, got", err)
+	}
+	// Setting the timeout on an unlocked wallet starts the timer.
+	timeout := 200 * time.Millisecond
+	if err := wt.wallet.SetAutoLockTimeout(timeout); err != nil {
+		t.Fatal(err)
+	}
+	if unlocked, _ := wt.wallet.Unlocked(); !unlocked {
+		t.Fatal("wallet locked before the timeout")
+	}
+	err = build.Retry(50, 20*time.Millisecond, func() error {
+		if unlocked, _ := wt.wallet.Unlocked(); unlocked {
+			return errors.New("wallet is still unlocked")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A timer of an earlier unlock does not lock the wallet early.
+	if err := wt.wallet.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(timeout / 2)
+	if err := wt.wallet.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(timeout * 3 / 4)
+	if unlocked, _ := wt.wallet.Unlocked(); !unlocked {
+		t.Fatal("wallet was locked by the timer of an earlier unlock")
+	}
+
+	// The wallet stays unlocked once the timeout is removed.
+	if err := wt.wallet.SetAutoLockTimeout(0); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := wt.wallet.AutoLockTimeout(); err != nil || d != 0 {
+		t.Fatal("unexpected timeout:", d, err)
+	}
+	time.Sleep(timeout * 2)
+	if unlocked, _ := wt.wallet.Unlocked(); !unlocked {
+		t.Fatal("wallet was locked without a timeout")
+	}
+}
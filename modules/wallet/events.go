@@ -0,0 +1,371 @@
+package wallet
+
+// events.go implements the events of the wallet. Events are created while
+// the wallet processes consensus changes and transaction pool updates, are
+// queued while the wallet holds its lock, and are delivered to the
+// subscribers and webhooks after the lock is released. Consensus events are
+// only created for synced consensus changes, so that a rescan of the
+// blockchain does not report the whole history of the wallet again.
+//
+// Every webhook has a bounded queue of events and at most one worker that
+// posts them, so that the events of a webhook arrive in order even if some
+// of them need to be retried.
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// errInvalidWebhookURL is returned if a webhook is added with a URL that
+	// is not an absolute http or https URL.
+	errInvalidWebhookURL = errors.New("webhook URL must be an absolute http or https URL")
+
+	// errTooFewConfirmations is returned if a webhook asks for
+	// confirmations events with fewer confirmations than the wallet
+	// reports.
+	errTooFewConfirmations = fmt.Errorf("confirmations must be at least %v", modules.WalletEventMinConfirmations)
+
+	// errTooManyConfirmations is returned if a webhook asks for more
+	// confirmations than the wallet reports.
+	errTooManyConfirmations = fmt.Errorf("confirmations must be at most %v", modules.WalletEventMaxConfirmations)
+
+	// errUnknownEventType is returned if a webhook filters for an event type
+	// that does not exist.
+	errUnknownEventType = errors.New("unknown wallet event type")
+
+	// errUnknownWebhook is returned if a webhook that does not exist is
+	// removed.
+	errUnknownWebhook = errors.New("no webhook with that URL")
+)
+
+var (
+	// webhookQueueSize is the maximum number of events that wait to be
+	// delivered to a webhook. If the queue is full, the oldest event is
+	// dropped.
+	webhookQueueSize = build.Select(build.Var{
+		Dev:      1000,
+		Standard: 1000,
+		Testing:  10,
+	}).(int)
+
+	// webhookMaxAttempts is the number of times the wallet tries to deliver
+	// an event to a webhook before it gives up.
+	webhookMaxAttempts = build.Select(build.Var{
+		Dev:      5,
+		Standard: 8,
+		Testing:  3,
+	}).(int)
+
+	// webhookRetryInterval is the time the wallet waits before the first
+	// retry of a failed delivery. The interval doubles with every retry.
+	webhookRetryInterval = build.Select(build.Var{
+		Dev:      time.Second,
+		Standard: 10 * time.Second,
+		Testing:  50 * time.Millisecond,
+	}).(time.Duration)
+
+	// webhookTimeout is the timeout of a single webhook request.
+	webhookTimeout = build.Select(build.Var{
+		Dev:      10 * time.Second,
+		Standard: 30 * time.Second,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+)
+
+// webhookQueue holds the events that wait to be delivered to a webhook.
+type webhookQueue struct {
+	events  []modules.WalletEvent
+	running bool
+}
+
+// webhookSignature returns the hex encoded HMAC-SHA256 signature of the body
+// of a webhook request.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// queueEvent queues an event for delivery. Events are only queued if there is
+// someone to deliver them to.
+func (w *Wallet) queueEvent(e modules.WalletEvent) {
+	if w.wantsEvents() {
+		w.pendingEvents = append(w.pendingEvents, e)
+	}
+}
+
+// wantsEvents reports whether any events are delivered.
+func (w *Wallet) wantsEvents() bool {
+	return len(w.eventSubscribers) != 0 || len(w.webhooks) != 0
+}
+
+// queueConfirmationEvents queues a confirmations event for every processed
+// transaction that reached between WalletEventMinConfirmations and
+// WalletEventMaxConfirmations confirmations at the given height.
+func (w *Wallet) queueConfirmationEvents(tx *bolt.Tx, height types.BlockHeight) {
+	var events []modules.WalletEvent
+	c := tx.Bucket(bucketProcessedTransactions).Cursor()
+	for _, ptBytes := c.Last(); ptBytes != nil; _, ptBytes = c.Prev() {
+		var pt modules.ProcessedTransaction
+		if err := decodeProcessedTransaction(ptBytes, &pt); err != nil {
+			w.log.Println("WARN: could not decode processed transaction:", err)
+			break
+		}
+		if pt.ConfirmationHeight >= height {
+			continue
+		}
+		confirmations := uint64(height-pt.ConfirmationHeight) + 1
+		if confirmations > modules.WalletEventMaxConfirmations {
+			break
+		}
+		events = append(events, modules.WalletEvent{
+			Type:          modules.WalletEventConfirmations,
+			TransactionID: pt.TransactionID,
+			Height:        pt.ConfirmationHeight,
+			Confirmations: confirmations,
+		})
+	}
+	// The cursor walked backwards, queue the events in chronological order.
+	for i := len(events) - 1; i >= 0; i-- {
+		w.queueEvent(events[i])
+	}
+}
+
+// managedFlushEvents delivers the queued events to the subscribers and adds
+// them to the queues of the webhooks.
+func (w *Wallet) managedFlushEvents() {
+	w.mu.Lock()
+	events := w.pendingEvents
+	w.pendingEvents = nil
+	subscribers := append([]modules.WalletEventSubscriber(nil), w.eventSubscribers...)
+	for _, hook := range w.webhooks {
+		for _, e := range events {
+			if e.Matches(hook.Events, hook.Confirmations) {
+				w.queueWebhookEvent(hook.URL, e)
+			}
+		}
+	}
+	w.mu.Unlock()
+	if len(events) == 0 {
+		return
+	}
+
+	for _, s := range subscribers {
+		s.ReceiveWalletEvents(events)
+	}
+}
+
+// queueWebhookEvent adds an event to the queue of a webhook and starts the
+// worker of the queue if it is not running.
+func (w *Wallet) queueWebhookEvent(url string, e modules.WalletEvent) {
+	q, exists := w.webhookQueues[url]
+	if !exists {
+		q = new(webhookQueue)
+		w.webhookQueues[url] = q
+	}
+	if len(q.events) >= webhookQueueSize {
+		w.log.Printf("WARN: webhook queue of %v is full, dropping %v event for %v", url, q.events[0].Type, q.events[0].TransactionID)
+		q.events = q.events[1:]
+	}
+	q.events = append(q.events, e)
+	if !q.running {
+		q.running = true
+		go w.threadedDeliverWebhook(url, q)
+	}
+}
+
+// threadedDeliverWebhook posts the events of a webhook queue, one request per
+// event, until the queue is empty. Failed requests are retried with
+// exponential backoff before the next event is posted.
+func (w *Wallet) threadedDeliverWebhook(url string, q *webhookQueue) {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	// Cancel outstanding requests when the wallet shuts down.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-w.tg.StopChan():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	client := &http.Client{Timeout: webhookTimeout}
+	for {
+		// Stop once the queue is empty, or if the webhook was removed.
+		w.mu.Lock()
+		hook, exists := w.webhooks[url]
+		if !exists || w.webhookQueues[url] != q || len(q.events) == 0 {
+			q.running = false
+			w.mu.Unlock()
+			return
+		}
+		e := q.events[0]
+		q.events = q.events[1:]
+		w.mu.Unlock()
+
+		body, err := json.Marshal(e)
+		if err != nil {
+			w.log.Println("WARN: could not encode wallet event:", err)
+			continue
+		}
+		for attempt := 0; ; attempt++ {
+			err = postWebhook(ctx, client, hook, e.Type, body)
+			if err == nil {
+				break
+			}
+			if attempt+1 >= webhookMaxAttempts {
+				w.log.Printf("WARN: giving up on delivering %v event for %v to %v: %v", e.Type, e.TransactionID, hook.URL, err)
+				break
+			}
+			select {
+			case <-w.tg.StopChan():
+				return
+			case <-time.After(webhookRetryInterval << uint(attempt)):
+			}
+		}
+	}
+}
+
+// postWebhook sends a single signed event to a webhook.
+func postWebhook(ctx context.Context, client *http.Client, hook modules.WalletWebhook, t modules.WalletEventType, body []byte) error {
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Sia-Agent")
+	req.Header.Set("Sia-Event", string(t))
+	req.Header.Set("Sia-Signature", webhookSignature(hook.Secret, body))
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %v", resp.Status)
+	}
+	return nil
+}
+
+// SubscribeEvents adds a subscriber that receives the events of the wallet.
+func (w *Wallet) SubscribeEvents(s modules.WalletEventSubscriber) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.eventSubscribers = append(w.eventSubscribers, s)
+}
+
+// UnsubscribeEvents removes a subscriber of the wallet events.
+func (w *Wallet) UnsubscribeEvents(s modules.WalletEventSubscriber) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i := range w.eventSubscribers {
+		if w.eventSubscribers[i] == s {
+			w.eventSubscribers = append(w.eventSubscribers[:i], w.eventSubscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// AddWebhook registers a webhook that the wallet posts its events to. A
+// webhook with the same URL is replaced.
+func (w *Wallet) AddWebhook(hook modules.WalletWebhook) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	u, err := url.Parse(hook.URL)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return errInvalidWebhookURL
+	}
+	if hook.Confirmations > modules.WalletEventMaxConfirmations {
+		return errTooManyConfirmations
+	}
+	for _, t := range hook.Events {
+		switch t {
+		case modules.WalletEventConfirmations:
+			if hook.Confirmations == 0 {
+				return errTooFewConfirmations
+			}
+		case modules.WalletEventUnconfirmed, modules.WalletEventConfirmed,
+			modules.WalletEventReverted:
+		default:
+			return errUnknownEventType
+		}
+	}
+	// Confirmations events are only reported from the second confirmation
+	// on, so a webhook that asks for fewer would never receive them.
+	if hook.Confirmations != 0 && hook.Confirmations < modules.WalletEventMinConfirmations {
+		return errTooFewConfirmations
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := dbPutWebhook(w.dbTx, hook); err != nil {
+		return err
+	}
+	w.webhooks[hook.URL] = hook
+	return w.syncDB()
+}
+
+// RemoveWebhook removes the webhook with the given URL.
+func (w *Wallet) RemoveWebhook(url string) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, exists := w.webhooks[url]; !exists {
+		return errUnknownWebhook
+	}
+	if err := dbDeleteWebhook(w.dbTx, url); err != nil {
+		return err
+	}
+	delete(w.webhooks, url)
+	delete(w.webhookQueues, url)
+	return w.syncDB()
+}
+
+// Webhooks returns the webhooks of the wallet without their secrets.
+func (w *Wallet) Webhooks() ([]modules.WalletWebhook, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	hooks := make([]modules.WalletWebhook, 0, len(w.webhooks))
+	for _, hook := range w.webhooks {
+		hook.Secret = ""
+		hooks = append(hooks, hook)
+	}
+	sort.Slice(hooks, func(i, j int) bool {
+		return hooks[i].URL < hooks[j].URL
+	})
+	return hooks, nil
+}
@@ -0,0 +1,338 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// eventRecorder is a WalletEventSubscriber that records the events it
+// receives.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []modules.WalletEvent
+}
+
+func (er *eventRecorder) ReceiveWalletEvents(events []modules.WalletEvent) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	er.events = append(er.events, events...)
+}
+
+// find returns the recorded event of the given type for a transaction.
+func (er *eventRecorder) find(t modules.WalletEventType, txid types.TransactionID, confirmations uint64) (modules.WalletEvent, bool) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	for _, e := range er.events {
+		if e.Type == t && e.TransactionID == txid && (t != modules.WalletEventConfirmations || e.Confirmations == confirmations) {
+			return e, true
+		}
+	}
+	return modules.WalletEvent{}, false
+}
+
+// TestWalletEvents checks that the wallet reports the events of its
+// transactions to its subscribers.
+func TestWalletEvents(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Consensus events are only reported once the consensus set is synced.
+	err = build.Retry(100, 50*time.Millisecond, func() error {
+		if !wt.cs.Synced() {
+			return errors.New("consensus set is not synced")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	er := new(eventRecorder)
+	wt.wallet.SubscribeEvents(er)
+
+	txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(10), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := txns[len(txns)-1].ID()
+	if e, ok := er.find(modules.WalletEventUnconfirmed, txid, 0); !ok {
+		t.Fatal("no unconfirmed event for the sent transaction")
+	} else if e.Transaction == nil || e.Transaction.TransactionID != txid {
+		t.Fatal("unconfirmed event does not contain the transaction:", e)
+	}
+
+	b1, err := wt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := er.find(modules.WalletEventConfirmed, txid, 0)
+	if !ok {
+		t.Fatal("no confirmed event for the sent transaction")
+	} else if e.Height != wt.cs.Height() || e.Confirmations != 1 {
+		t.Fatal("wrong height or confirmations:", e.Height, e.Confirmations)
+	}
+	b2, err := wt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := er.find(modules.WalletEventConfirmations, txid, 2); !ok {
+		t.Fatal("no confirmations event for the sent transaction")
+	}
+
+	// Unsubscribed subscribers receive no more events.
+	wt.wallet.UnsubscribeEvents(er)
+	b3, err := wt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := er.find(modules.WalletEventConfirmations, txid, 3); ok {
+		t.Fatal("unsubscribed subscriber received an event")
+	}
+
+	// Reverting the transaction is reported as well. The last two blocks do
+	// not contain wallet transactions other than their miner payouts, so
+	// they are reverted first.
+	wt.wallet.SubscribeEvents(er)
+	wt.wallet.mu.Lock()
+	if err := wt.wallet.revertHistory(wt.wallet.dbTx, []types.Block{b3, b2, b1}); err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet.mu.Unlock()
+	wt.wallet.managedFlushEvents()
+	if _, ok := er.find(modules.WalletEventReverted, txid, 0); !ok {
+		t.Fatal("no reverted event for the sent transaction")
+	}
+}
+
+// TestWalletWebhook checks that the wallet posts signed events to webhooks
+// and retries failed deliveries.
+func TestWalletWebhook(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Consensus events are only reported once the consensus set is synced.
+	err = build.Retry(100, 50*time.Millisecond, func() error {
+		if !wt.cs.Synced() {
+			return errors.New("consensus set is not synced")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The webhook fails the first request.
+	secret := "foo"
+	var mu sync.Mutex
+	var requests int
+	var events []modules.WalletEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		if req.Header.Get("Sia-Signature") != webhookSignature(secret, body) {
+			t.Error("webhook request has an invalid signature")
+		}
+		var e modules.WalletEvent
+		if err := json.Unmarshal(body, &e); err != nil {
+			t.Error(err)
+		}
+		if req.Header.Get("Sia-Event") != string(e.Type) {
+			t.Error("wrong event header:", req.Header.Get("Sia-Event"))
+		}
+		events = append(events, e)
+	}))
+	defer srv.Close()
+
+	if err := wt.wallet.AddWebhook(modules.WalletWebhook{URL: "foo"}); err != errInvalidWebhookURL {
+		t.Fatal("expected errInvalidWebhookURL, got", err)
+	}
+	if err := wt.wallet.AddWebhook(modules.WalletWebhook{URL: srv.URL, Events: []modules.WalletEventType{"foo"}}); err != errUnknownEventType {
+		t.Fatal("expected errUnknownEventType, got", err)
+	}
+	// Confirmations events are only reported from the second confirmation
+	// on.
+	for _, confirmations := range []uint64{0, 1} {
+		err := wt.wallet.AddWebhook(modules.WalletWebhook{URL: srv.URL, Events: []modules.WalletEventType{modules.WalletEventConfirmations}, Confirmations: confirmations})
+		if err != errTooFewConfirmations {
+			t.Fatal("expected errTooFewConfirmations, got", err)
+		}
+	}
+	if err := wt.wallet.AddWebhook(modules.WalletWebhook{URL: srv.URL, Confirmations: 1}); err != errTooFewConfirmations {
+		t.Fatal("expected errTooFewConfirmations, got", err)
+	}
+	hook := modules.WalletWebhook{
+		URL:    srv.URL,
+		Secret: secret,
+		Events: []modules.WalletEventType{modules.WalletEventConfirmed},
+	}
+	if err := wt.wallet.AddWebhook(hook); err != nil {
+		t.Fatal(err)
+	}
+	hooks, err := wt.wallet.Webhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 1 || hooks[0].URL != srv.URL || hooks[0].Secret != "" {
+		t.Fatal("unexpected webhooks:", hooks)
+	}
+
+	txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(10), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := txns[len(txns)-1].ID()
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only confirmed events are delivered, and the failed delivery is
+	// retried.
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, e := range events {
+			if e.Type != modules.WalletEventConfirmed {
+				t.Fatal("received an event that was filtered:", e.Type)
+			}
+			if e.TransactionID == txid {
+				return nil
+			}
+		}
+		return errUnknownTransaction
+	})
+	if err != nil {
+		t.Fatal("the webhook did not receive the confirmed event")
+	}
+
+	if err := wt.wallet.RemoveWebhook(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.RemoveWebhook(srv.URL); err != errUnknownWebhook {
+		t.Fatal("expected errUnknownWebhook, got", err)
+	}
+}
+
+// TestWalletWebhookQueue checks that the events of a webhook are posted one at
+// a time and in order, and that the queue of a slow webhook is bounded.
+func TestWalletWebhookQueue(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// The webhook blocks the first request until it is released.
+	var mu sync.Mutex
+	var inFlight, maxInFlight int
+	var heights []types.BlockHeight
+	first := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		var e modules.WalletEvent
+		if err := json.NewDecoder(req.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		heights = append(heights, e.Height)
+		blocked := len(heights) == 1
+		mu.Unlock()
+		if blocked {
+			close(first)
+			<-release
+		}
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	hook := modules.WalletWebhook{
+		URL:    srv.URL,
+		Events: []modules.WalletEventType{modules.WalletEventReverted},
+	}
+	if err := wt.wallet.AddWebhook(hook); err != nil {
+		t.Fatal(err)
+	}
+	queue := func(height types.BlockHeight) {
+		wt.wallet.mu.Lock()
+		wt.wallet.pendingEvents = append(wt.wallet.pendingEvents, modules.WalletEvent{
+			Type:   modules.WalletEventReverted,
+			Height: height,
+		})
+		wt.wallet.mu.Unlock()
+		wt.wallet.managedFlushEvents()
+	}
+
+	// Queue more events than fit into the queue while the first event is
+	// being posted. The oldest of the waiting events are dropped.
+	queue(0)
+	select {
+	case <-first:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the webhook did not receive the first event")
+	}
+	total := types.BlockHeight(webhookQueueSize + 5)
+	for h := types.BlockHeight(1); h <= total; h++ {
+		queue(h)
+	}
+	close(release)
+
+	expected := []types.BlockHeight{0}
+	for h := total - types.BlockHeight(webhookQueueSize) + 1; h <= total; h++ {
+		expected = append(expected, h)
+	}
+	err = build.Retry(100, 50*time.Millisecond, func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(heights) < len(expected) {
+			return errors.New("not all events were posted")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if maxInFlight != 1 {
+		t.Fatal("events were posted concurrently:", maxInFlight)
+	}
+	if fmt.Sprint(heights) != fmt.Sprint(expected) {
+		t.Fatal("events were posted out of order or not dropped:", heights)
+	}
+}
@@ -0,0 +1,116 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// maxLabelLen is the maximum combined length of the label and the note of a
+// transaction or an address in bytes.
+const maxLabelLen = 4096
+
+var (
+	// errLabelTooLong is returned if a label and note exceed maxLabelLen.
+	errLabelTooLong = errors.New("label and note are too long")
+
+	// errUnknownTransaction is returned if a label is attached to a
+	// transaction that is not related to the wallet.
+	errUnknownTransaction = errors.New("transaction is not a transaction of the wallet")
+)
+
+// SetTransactionLabel attaches a label and a note to a confirmed or
+// unconfirmed transaction of the wallet. An empty label and note remove the
+// label of the transaction.
+func (w *Wallet) SetTransactionLabel(txid types.TransactionID, label modules.WalletLabel) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if len(label.Label)+len(label.Note) > maxLabelLen {
+		return errLabelTooLong
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if label == (modules.WalletLabel{}) {
+		if err := dbDeleteTxnLabel(w.dbTx, txid); err != nil {
+			return err
+		}
+		return w.syncDB()
+	}
+	known := false
+	if _, err := dbGetTransactionIndex(w.dbTx, txid); err == nil {
+		known = true
+	}
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		known = known || upt.TransactionID == txid
+	}
+	if !known {
+		return errUnknownTransaction
+	}
+	if err := dbPutTxnLabel(w.dbTx, txid, label); err != nil {
+		return err
+	}
+	return w.syncDB()
+}
+
+// SetAddressLabel attaches a label and a note to an address. The address
+// does not have to belong to the wallet, so that the addresses of
+// counterparties can be labeled as well. An empty label and note remove the
+// label of the address.
+func (w *Wallet) SetAddressLabel(addr types.UnlockHash, label modules.WalletLabel) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if len(label.Label)+len(label.Note) > maxLabelLen {
+		return errLabelTooLong
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var err error
+	if label == (modules.WalletLabel{}) {
+		err = dbDeleteAddrLabel(w.dbTx, addr)
+	} else {
+		err = dbPutAddrLabel(w.dbTx, addr, label)
+	}
+	if err != nil {
+		return err
+	}
+	return w.syncDB()
+}
+
+// TransactionLabels returns the labels of all labeled transactions.
+func (w *Wallet) TransactionLabels() (map[types.TransactionID]modules.WalletLabel, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	labels := make(map[types.TransactionID]modules.WalletLabel)
+	err := dbForEachTxnLabel(w.dbTx, func(txid types.TransactionID, l modules.WalletLabel) {
+		labels[txid] = l
+	})
+	return labels, err
+}
+
+// AddressLabels returns the labels of all labeled addresses.
+func (w *Wallet) AddressLabels() (map[types.UnlockHash]modules.WalletLabel, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	labels := make(map[types.UnlockHash]modules.WalletLabel)
+	err := dbForEachAddrLabel(w.dbTx, func(addr types.UnlockHash, l modules.WalletLabel) {
+		labels[addr] = l
+	})
+	return labels, err
+}
@@ -0,0 +1,143 @@
+package wallet
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestTransactionLabels checks that labels can be attached to transactions
+// and addresses, that they are persisted, and that they can be removed.
+func TestTransactionLabels(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(10), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := txns[len(txns)-1].ID()
+
+	// Unconfirmed and confirmed transactions of the wallet can be labeled,
+	// other transactions cannot.
+	label := modules.WalletLabel{Label: "rent", Note: "October"}
+	if err := wt.wallet.SetTransactionLabel(types.TransactionID{}, label); err != errUnknownTransaction {
+		t.Fatal("expected errUnknownTransaction, got", err)
+	}
+	if err := wt.wallet.SetTransactionLabel(txid, modules.WalletLabel{Note: strings.Repeat("a", maxLabelLen+1)}); err != errLabelTooLong {
+		t.Fatal("expected errLabelTooLong, got", err)
+	}
+	if err := wt.wallet.SetTransactionLabel(txid, label); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetTransactionLabel(txns[0].ID(), modules.WalletLabel{Label: "split"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetAddressLabel(types.UnlockHash{}, modules.WalletLabel{Label: "landlord"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The labels are persisted.
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, filepath.Join(wt.persistDir, modules.WalletDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	txnLabels, err := wt.wallet.TransactionLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txnLabels) != 2 || txnLabels[txid] != label {
+		t.Fatal("transaction labels were not persisted:", txnLabels)
+	}
+	addrLabels, err := wt.wallet.AddressLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrLabels) != 1 || addrLabels[types.UnlockHash{}].Label != "landlord" {
+		t.Fatal("address labels were not persisted:", addrLabels)
+	}
+
+	// An empty label removes the label.
+	if err := wt.wallet.SetTransactionLabel(txid, modules.WalletLabel{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetAddressLabel(types.UnlockHash{}, modules.WalletLabel{}); err != nil {
+		t.Fatal(err)
+	}
+	if txnLabels, err := wt.wallet.TransactionLabels(); err != nil || len(txnLabels) != 1 {
+		t.Fatal("transaction label was not removed:", txnLabels, err)
+	}
+	if addrLabels, err := wt.wallet.AddressLabels(); err != nil || len(addrLabels) != 0 {
+		t.Fatal("address label was not removed:", addrLabels, err)
+	}
+}
+
+// TestTransactionCategory checks that the transactions of the wallet are
+// categorized.
+func TestTransactionCategory(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Send coins to another address.
+	sent, err := wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(10), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	pts, err := wt.wallet.Transactions(0, ^types.BlockHeight(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	categories := make(map[modules.TransactionCategory]int)
+	for _, pt := range pts {
+		categories[pt.Category()]++
+		if pt.TransactionID == sent[len(sent)-1].ID() && pt.Category() != modules.CategorySend {
+			t.Fatal("expected the sent transaction to be categorized as send, got", pt.Category())
+		}
+	}
+	if categories[modules.CategoryMinerPayout] == 0 {
+		t.Fatal("expected miner payouts in the history:", categories)
+	}
+
+	// Processed transactions with contracts, storage proofs and claims.
+	tests := []struct {
+		pt       modules.ProcessedTransaction
+		category modules.TransactionCategory
+	}{
+		{modules.ProcessedTransaction{Transaction: types.Transaction{FileContracts: []types.FileContract{{}}}}, modules.CategoryContractFunding},
+		{modules.ProcessedTransaction{Transaction: types.Transaction{StorageProofs: []types.StorageProof{{}}}, Outputs: []modules.ProcessedOutput{{FundType: types.SpecifierStorageProofOutput, WalletAddress: true, Value: types.NewCurrency64(1)}}}, modules.CategoryHostPayout},
+		{modules.ProcessedTransaction{Transaction: types.Transaction{StorageProofs: []types.StorageProof{{}}}, Outputs: []modules.ProcessedOutput{{FundType: types.SpecifierStorageProofOutput, Value: types.NewCurrency64(1)}}}, modules.CategorySend},
+		{modules.ProcessedTransaction{Outputs: []modules.ProcessedOutput{{FundType: types.SpecifierClaimOutput, WalletAddress: true}}}, modules.CategorySiafundClaim},
+		{modules.ProcessedTransaction{Outputs: []modules.ProcessedOutput{{FundType: types.SpecifierSiacoinOutput, WalletAddress: true, Value: types.NewCurrency64(1)}}}, modules.CategoryReceive},
+	}
+	for _, test := range tests {
+		if c := test.pt.Category(); c != test.category {
+			t.Errorf("expected %v, got %v", test.category, c)
+		}
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"errors"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 )
 
+var (
+	errZeroTarget = errors.New("confirmation target must be at least one block")
+)
+
 // sortedOutputs is a struct containing a slice of siacoin outputs and their
 // corresponding ids. sortedOutputs can be sorted using the sort package.
 type sortedOutputs struct {
@@ -28,7 +33,8 @@ func (w *Wallet) DustThreshold() (types.Currency, error) {
 }
 
 // ConfirmedBalance returns the balance of the wallet according to all of the
-// confirmed transactions.
+// confirmed transactions. Outputs of timelocked addresses are only included
+// once their timelock has expired.
 func (w *Wallet) ConfirmedBalance() (siacoinBalance types.Currency, siafundBalance types.Currency, siafundClaimBalance types.Currency, err error) {
 	if err := w.tg.Add(); err != nil {
 		return types.ZeroCurrency, types.ZeroCurrency, types.ZeroCurrency, modules.ErrWalletShutdown
@@ -49,8 +55,17 @@ func (w *Wallet) ConfirmedBalance() (siacoinBalance types.Currency, siafundBalan
 		return
 	}
 
+	// Timelocked outputs are not part of the balance until they unlock.
+	height, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return
+	}
+	timelocks, err := w.timelocks()
+	if err != nil {
+		return
+	}
 	dbForEachSiacoinOutput(w.dbTx, func(_ types.SiacoinOutputID, sco types.SiacoinOutput) {
-		if sco.Value.Cmp(dustThreshold) > 0 {
+		if sco.Value.Cmp(dustThreshold) > 0 && height >= timelocks[sco.UnlockHash] {
 			siacoinBalance = siacoinBalance.Add(sco.Value)
 		}
 	})
@@ -108,11 +123,57 @@ func (w *Wallet) UnconfirmedBalance() (outgoingSiacoins types.Currency, incoming
 // SendSiacoins creates a transaction sending 'amount' to 'dest'. The transaction
 // is submitted to the transaction pool and is also returned.
 func (w *Wallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) (txns []types.Transaction, err error) {
+	return w.managedSendSiacoins(nil, amount, dest, nil, 0)
+}
+
+// SendSiacoinsWithTarget creates a transaction sending 'amount' to 'dest'
+// that pays the fee recommended by the transaction pool for being confirmed
+// within 'target' blocks. The transaction is submitted to the transaction
+// pool and is also returned.
+func (w *Wallet) SendSiacoinsWithTarget(amount types.Currency, dest types.UnlockHash, target types.BlockHeight) (txns []types.Transaction, err error) {
+	return w.managedSendSiacoinsWithTarget(nil, amount, dest, target)
+}
+
+// SendSiacoinsFromOutputs creates a transaction sending 'amount' to 'dest'
+// that is funded from the wallet outputs with the given IDs. Any value of the
+// outputs that is not sent or paid as fee is returned to the wallet. The
+// transaction is submitted to the transaction pool and is also returned.
+func (w *Wallet) SendSiacoinsFromOutputs(amount types.Currency, dest types.UnlockHash, ids []types.SiacoinOutputID) (txns []types.Transaction, err error) {
+	return w.managedSendSiacoinsFromOutputs(nil, amount, dest, ids)
+}
+
+// managedSendSiacoinsWithTarget implements SendSiacoinsWithTarget, see
+// managedSendSiacoins for spendingKey.
+func (w *Wallet) managedSendSiacoinsWithTarget(spendingKey *crypto.TwofishKey, amount types.Currency, dest types.UnlockHash, target types.BlockHeight) (txns []types.Transaction, err error) {
+	if target == 0 {
+		return nil, errZeroTarget
+	}
+	return w.managedSendSiacoins(spendingKey, amount, dest, nil, target)
+}
+
+// managedSendSiacoinsFromOutputs implements SendSiacoinsFromOutputs, see
+// managedSendSiacoins for spendingKey.
+func (w *Wallet) managedSendSiacoinsFromOutputs(spendingKey *crypto.TwofishKey, amount types.Currency, dest types.UnlockHash, ids []types.SiacoinOutputID) (txns []types.Transaction, err error) {
+	if len(ids) == 0 {
+		return nil, errNoOutputIDs
+	}
+	return w.managedSendSiacoins(spendingKey, amount, dest, ids, 0)
+}
+
+// managedSendSiacoins sends 'amount' to 'dest'. If ids is not empty, the
+// transaction is funded from the outputs with these IDs. If target is not
+// zero, the transaction pays the fee for being confirmed within target
+// blocks, otherwise it pays the maximum recommended fee. spendingKey must be
+// the spending key of the wallet if it has one.
+func (w *Wallet) managedSendSiacoins(spendingKey *crypto.TwofishKey, amount types.Currency, dest types.UnlockHash, ids []types.SiacoinOutputID, target types.BlockHeight) (txns []types.Transaction, err error) {
 	if err := w.tg.Add(); err != nil {
 		err = modules.ErrWalletShutdown
 		return nil, err
 	}
 	defer w.tg.Done()
+	if err := w.managedCheckSpendingKey(spendingKey); err != nil {
+		return nil, err
+	}
 
 	w.mu.RLock()
 	unlocked := w.unlocked
@@ -123,6 +184,12 @@ func (w *Wallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) (txn
 	}
 
 	_, tpoolFee := w.tpool.FeeEstimation()
+	if target > 0 {
+		tpoolFee, err = w.tpool.FeeEstimationTarget(target)
+		if err != nil {
+			return nil, build.ExtendErr("unable to estimate fee", err)
+		}
+	}
 	tpoolFee = tpoolFee.Mul64(750) // Estimated transaction size in bytes
 	output := types.SiacoinOutput{
 		Value:      amount,
@@ -138,7 +205,11 @@ func (w *Wallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) (txn
 			txnBuilder.Drop()
 		}
 	}()
-	err = txnBuilder.FundSiacoins(amount.Add(tpoolFee))
+	if len(ids) != 0 {
+		err = txnBuilder.FundSiacoinsFromOutputs(amount.Add(tpoolFee), ids)
+	} else {
+		err = txnBuilder.FundSiacoins(amount.Add(tpoolFee))
+	}
 	if err != nil {
 		w.log.Println("Attempt to send coins has failed - failed to fund transaction:", err)
 		return nil, build.ExtendErr("unable to fund transaction", err)
@@ -169,12 +240,21 @@ func (w *Wallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) (txn
 // outputs. The transaction is submitted to the transaction pool and is also
 // returned.
 func (w *Wallet) SendSiacoinsMulti(outputs []types.SiacoinOutput) (txns []types.Transaction, err error) {
+	return w.managedSendSiacoinsMulti(nil, outputs)
+}
+
+// managedSendSiacoinsMulti implements SendSiacoinsMulti. spendingKey must be
+// the spending key of the wallet if it has one.
+func (w *Wallet) managedSendSiacoinsMulti(spendingKey *crypto.TwofishKey, outputs []types.SiacoinOutput) (txns []types.Transaction, err error) {
 	w.log.Println("Beginning call to SendSiacoinsMulti")
 	if err := w.tg.Add(); err != nil {
 		err = modules.ErrWalletShutdown
 		return nil, err
 	}
 	defer w.tg.Done()
+	if err := w.managedCheckSpendingKey(spendingKey); err != nil {
+		return nil, err
+	}
 	w.mu.RLock()
 	unlocked := w.unlocked
 	w.mu.RUnlock()
@@ -244,11 +324,20 @@ func (w *Wallet) SendSiacoinsMulti(outputs []types.SiacoinOutput) (txns []types.
 // SendSiafunds creates a transaction sending 'amount' to 'dest'. The transaction
 // is submitted to the transaction pool and is also returned.
 func (w *Wallet) SendSiafunds(amount types.Currency, dest types.UnlockHash) (txns []types.Transaction, err error) {
+	return w.managedSendSiafunds(nil, amount, dest)
+}
+
+// managedSendSiafunds implements SendSiafunds. spendingKey must be the
+// spending key of the wallet if it has one.
+func (w *Wallet) managedSendSiafunds(spendingKey *crypto.TwofishKey, amount types.Currency, dest types.UnlockHash) (txns []types.Transaction, err error) {
 	if err := w.tg.Add(); err != nil {
 		err = modules.ErrWalletShutdown
 		return nil, err
 	}
 	defer w.tg.Done()
+	if err := w.managedCheckSpendingKey(spendingKey); err != nil {
+		return nil, err
+	}
 	w.mu.RLock()
 	unlocked := w.unlocked
 	w.mu.RUnlock()
@@ -275,3 +275,38 @@ func TestSendSiacoinsAcceptTxnSetFailed(t *testing.T) {
 		t.Fatalf("SendSiacoins failed: %v", err)
 	}
 }
+
+// TestSendSiacoinsWithTarget checks that SendSiacoinsWithTarget pays the fee
+// that the transaction pool recommends for the confirmation target.
+func TestSendSiacoinsWithTarget(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	if _, err := wt.wallet.SendSiacoinsWithTarget(types.SiacoinPrecision, types.UnlockHash{}, 0); err != errZeroTarget {
+		t.Fatal("expected errZeroTarget, got", err)
+	}
+
+	fee, err := wt.wallet.tpool.FeeEstimationTarget(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txns, err := wt.wallet.SendSiacoinsWithTarget(types.SiacoinPrecision, types.UnlockHash{}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paid types.Currency
+	for _, txn := range txns {
+		for _, mf := range txn.MinerFees {
+			paid = paid.Add(mf)
+		}
+	}
+	if !paid.Equals(fee.Mul64(750)) {
+		t.Errorf("expected the transaction to pay %v in fees, paid %v", fee.Mul64(750), paid)
+	}
+}
@@ -0,0 +1,131 @@
+package wallet
+
+// multisig.go implements M-of-N multisig addresses. The wallet stores the
+// unlock conditions of a multisig address and tracks it as a watch-only
+// address. A spend from the address is built like a watch-only transaction
+// and signed with the keys of the wallet that belong to the address. The
+// transaction is then passed to the other signers, which add their
+// signatures with SignTransaction until it can be broadcast.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// errMultisigNoKeys is returned if a multisig address is created without
+	// public keys.
+	errMultisigNoKeys = errors.New("a multisig address needs at least one public key")
+
+	// errMultisigRequired is returned if the number of required signatures
+	// of a multisig address is invalid.
+	errMultisigRequired = errors.New("the number of required signatures must be between 1 and the number of public keys")
+
+	// errUnknownMultisigAddress is returned if a transaction is prepared for
+	// an address that is not a multisig address of the wallet.
+	errUnknownMultisigAddress = errors.New("address is not a multisig address of the wallet")
+)
+
+// AddMultisigAddress creates an M-of-N multisig address from the public keys,
+// where M is the number of required signatures, and adds it to the wallet as
+// a watch-only address. Unless unused is set, the wallet rescans the
+// blockchain to find the existing outputs of the address.
+func (w *Wallet) AddMultisigAddress(publicKeys []types.SiaPublicKey, required uint64, unused bool) (types.UnlockConditions, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.UnlockConditions{}, err
+	}
+	defer w.tg.Done()
+	if len(publicKeys) == 0 {
+		return types.UnlockConditions{}, errMultisigNoKeys
+	}
+	if required == 0 || required > uint64(len(publicKeys)) {
+		return types.UnlockConditions{}, errMultisigRequired
+	}
+	uc := types.UnlockConditions{
+		PublicKeys:         publicKeys,
+		SignaturesRequired: required,
+	}
+
+	w.mu.Lock()
+	err := dbPutMultisigAddr(w.dbTx, uc)
+	w.mu.Unlock()
+	if err != nil {
+		return types.UnlockConditions{}, err
+	}
+	if err := w.AddWatchAddresses([]types.UnlockHash{uc.UnlockHash()}, unused); err != nil {
+		return types.UnlockConditions{}, err
+	}
+	return uc, nil
+}
+
+// MultisigAddresses returns the unlock conditions of the multisig addresses
+// of the wallet.
+func (w *Wallet) MultisigAddresses() ([]types.UnlockConditions, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var ucs []types.UnlockConditions
+	err := dbForEachMultisigAddr(w.dbTx, func(_ types.UnlockHash, uc types.UnlockConditions) {
+		ucs = append(ucs, uc)
+	})
+	return ucs, err
+}
+
+// PrepareMultisigTransaction builds a transaction that sends the outputs
+// from the multisig address addr, with the change going back to addr. The
+// transaction is signed with the keys of the wallet that belong to the
+// address, and the remaining signatures are left empty for the other signers.
+func (w *Wallet) PrepareMultisigTransaction(addr types.UnlockHash, outputs []types.SiacoinOutput) (types.Transaction, error) {
+	return w.managedPrepareMultisigTransaction(nil, addr, outputs)
+}
+
+// managedPrepareMultisigTransaction implements PrepareMultisigTransaction.
+// spendingKey must be the spending key of the wallet if it has one.
+func (w *Wallet) managedPrepareMultisigTransaction(spendingKey *crypto.TwofishKey, addr types.UnlockHash, outputs []types.SiacoinOutput) (types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if err := w.managedCheckSpendingKey(spendingKey); err != nil {
+		return types.Transaction{}, err
+	}
+
+	w.mu.Lock()
+	unlocked := w.unlocked
+	uc, err := dbGetMultisigAddr(w.dbTx, addr)
+	w.mu.Unlock()
+	if !unlocked {
+		return types.Transaction{}, modules.ErrLockedWallet
+	} else if err == errNoKey {
+		return types.Transaction{}, errUnknownMultisigAddress
+	} else if err != nil {
+		return types.Transaction{}, err
+	}
+
+	// Only the unlock conditions of addr are provided, so only the outputs
+	// of addr are used to fund the transaction.
+	pt, err := w.PrepareTransaction(outputs, true, []types.UnlockConditions{uc})
+	if err == errMissingUnlockConditions {
+		return types.Transaction{}, modules.ErrLowBalance
+	} else if err != nil {
+		return types.Transaction{}, err
+	}
+	txn := pt.Transaction
+
+	// The wallet might not own any of the keys of the address, in which case
+	// the transaction is returned unsigned.
+	w.mu.RLock()
+	_, err = signMissing(&txn, w.findSecretKey)
+	w.mu.RUnlock()
+	if err != nil && err != errNoSignableKeys {
+		return types.Transaction{}, err
+	}
+	return txn, nil
+}
@@ -0,0 +1,116 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestMultisigAddress checks that the wallet can create a 2-of-3 multisig
+// address, fund a spend from it that it partially signs, and that the spend
+// is valid once another signer adds the second signature.
+func TestMultisigAddress(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Create a 2-of-3 address from two keys of another signer and one key of
+	// the wallet. The key of the wallet is last, so that the wallet has to
+	// move its signature to a public key that was not reserved for it.
+	var coSignerSeed modules.Seed
+	fastrand.Read(coSignerSeed[:])
+	walletUC, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pks := []types.SiaPublicKey{
+		generateSpendableKey(coSignerSeed, 0).UnlockConditions.PublicKeys[0],
+		generateSpendableKey(coSignerSeed, 1).UnlockConditions.PublicKeys[0],
+		walletUC.PublicKeys[0],
+	}
+	if _, err := wt.wallet.AddMultisigAddress(pks, 4, true); err != errMultisigRequired {
+		t.Fatal("expected errMultisigRequired, got", err)
+	}
+	uc, err := wt.wallet.AddMultisigAddress(pks, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := uc.UnlockHash()
+	ucs, err := wt.wallet.MultisigAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ucs) != 1 || ucs[0].UnlockHash() != addr {
+		t.Fatal("multisig address was not stored:", ucs)
+	}
+
+	// Fund the address.
+	if _, err := wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), addr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Prepare a spend. It should only have the signature of the wallet.
+	output := types.SiacoinOutput{Value: types.SiacoinPrecision.Mul64(10)}
+	if _, err := wt.wallet.PrepareMultisigTransaction(types.UnlockHash{}, []types.SiacoinOutput{output}); err != errUnknownMultisigAddress {
+		t.Fatal("expected errUnknownMultisigAddress, got", err)
+	}
+	txn, err := wt.wallet.PrepareMultisigTransaction(addr, []types.SiacoinOutput{output})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.TransactionSignatures) != 2 {
+		t.Fatal("expected 2 signatures, got", len(txn.TransactionSignatures))
+	}
+	if len(txn.TransactionSignatures[0].Signature) == 0 || txn.TransactionSignatures[0].PublicKeyIndex != 2 {
+		t.Fatal("wallet did not sign with its own key:", txn.TransactionSignatures[0])
+	}
+	if len(txn.TransactionSignatures[1].Signature) != 0 {
+		t.Fatal("wallet should not be able to make the second signature")
+	}
+	if err := txn.StandaloneValid(wt.cs.Height()); err == nil {
+		t.Fatal("partially signed transaction should not be valid")
+	}
+	if _, err := wt.wallet.SignTransaction(&txn); err != errNoSignableKeys {
+		t.Fatal("expected errNoSignableKeys, got", err)
+	}
+
+	// The other signer adds the second signature.
+	signed, err := SignTransaction(&txn, coSignerSeed, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed != 1 {
+		t.Fatal("expected 1 signature, got", signed)
+	}
+	if err := wt.tpool.AcceptTransactionSet([]types.Transaction{txn}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	sc, _, err := wt.wallet.WatchBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Equals(txn.SiacoinOutputs[1].Value) {
+		t.Fatalf("expected multisig balance of %v after spending, got %v", txn.SiacoinOutputs[1].Value, sc)
+	}
+
+	// Removing the address forgets its unlock conditions.
+	if err := wt.wallet.RemoveWatchAddresses([]types.UnlockHash{addr}); err != nil {
+		t.Fatal(err)
+	}
+	if ucs, err := wt.wallet.MultisigAddresses(); err != nil || len(ucs) != 0 {
+		t.Fatal("multisig address was not removed:", ucs, err)
+	}
+}
@@ -0,0 +1,96 @@
+package wallet
+
+import (
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// SpendableOutputs returns the confirmed siacoin outputs of the wallet that
+// can be spent, sorted by value from largest to smallest. Locked outputs are
+// included, while dust, timelocked outputs and outputs that were recently
+// spent by the wallet are not.
+func (w *Wallet) SpendableOutputs() ([]modules.SpendableOutput, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	// dustThreshold has to be obtained separate from the lock
+	dustThreshold, err := w.DustThreshold()
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs []modules.SpendableOutput
+	err = dbForEachSiacoinOutput(w.dbTx, func(scoid types.SiacoinOutputID, sco types.SiacoinOutput) {
+		err := w.checkOutput(w.dbTx, consensusHeight, scoid, sco, dustThreshold)
+		if err != nil && err != errOutputLocked {
+			return
+		}
+		outputs = append(outputs, modules.SpendableOutput{
+			ID:         scoid,
+			Value:      sco.Value,
+			UnlockHash: sco.UnlockHash,
+			Locked:     err == errOutputLocked,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(outputs, func(i, j int) bool {
+		return outputs[i].Value.Cmp(outputs[j].Value) > 0
+	})
+	return outputs, nil
+}
+
+// LockOutputs locks siacoin outputs of the wallet, so that the wallet does
+// not choose them to fund transactions. Locked outputs can still be spent
+// explicitly with SendSiacoinsFromOutputs. The locks are persisted.
+func (w *Wallet) LockOutputs(ids []types.SiacoinOutputID) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, id := range ids {
+		if _, err := dbGetSiacoinOutput(w.dbTx, id); err == errNoKey {
+			return errUnknownOutput
+		} else if err != nil {
+			return err
+		}
+	}
+	for _, id := range ids {
+		if err := dbPutLockedOutput(w.dbTx, id); err != nil {
+			return err
+		}
+	}
+	return w.syncDB()
+}
+
+// UnlockOutputs unlocks siacoin outputs that were locked with LockOutputs.
+func (w *Wallet) UnlockOutputs(ids []types.SiacoinOutputID) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, id := range ids {
+		if err := dbDeleteLockedOutput(w.dbTx, id); err != nil {
+			return err
+		}
+	}
+	return w.syncDB()
+}
@@ -0,0 +1,139 @@
+package wallet
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestLockOutputs checks that locked outputs are not chosen to fund
+// transactions, that they can still be spent explicitly, and that the locks
+// and the coin selection strategy are persisted.
+func TestLockOutputs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Create outputs of 10 SC and 20 SC.
+	ids := make(map[uint64]types.SiacoinOutputID)
+	addrs := make(map[types.UnlockHash]uint64)
+	for _, sc := range []uint64{10, 20} {
+		uc, err := wt.wallet.NextAddress()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(sc), uc.UnlockHash()); err != nil {
+			t.Fatal(err)
+		}
+		addrs[uc.UnlockHash()] = sc
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	outputs, err := wt.wallet.SpendableOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, so := range outputs {
+		if i > 0 && outputs[i-1].Value.Cmp(so.Value) < 0 {
+			t.Fatal("outputs are not sorted by value")
+		}
+		if sc, ok := addrs[so.UnlockHash]; ok {
+			ids[sc] = so.ID
+		}
+	}
+	if len(ids) != 2 {
+		t.Fatal("new outputs are not spendable:", outputs)
+	}
+
+	// Lock the 10 SC output and spend the smallest outputs first. The 20 SC
+	// output should be chosen.
+	if err := wt.wallet.LockOutputs([]types.SiacoinOutputID{ids[10]}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.LockOutputs([]types.SiacoinOutputID{{1}}); err != errUnknownOutput {
+		t.Fatal("expected errUnknownOutput, got", err)
+	}
+	if err := wt.wallet.SetSettings(modules.WalletSettings{CoinSelection: "random"}); err != modules.ErrUnknownCoinSelection {
+		t.Fatal("expected ErrUnknownCoinSelection, got", err)
+	}
+	if err := wt.wallet.SetSettings(modules.WalletSettings{CoinSelection: modules.CoinSelectionSmallestFirst}); err != nil {
+		t.Fatal(err)
+	}
+	tb, err := wt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.FundSiacoins(types.SiacoinPrecision.Mul64(5)); err != nil {
+		t.Fatal(err)
+	}
+	_, parents := tb.View()
+	if len(parents) != 1 || len(parents[0].SiacoinInputs) != 1 || parents[0].SiacoinInputs[0].ParentID != ids[20] {
+		t.Fatal("expected the 20 SC output to fund the transaction:", parents)
+	}
+	tb.Drop()
+
+	// The locked output can be spent explicitly.
+	txns, err := wt.wallet.SendSiacoinsFromOutputs(types.SiacoinPrecision.Mul64(5), types.UnlockHash{}, []types.SiacoinOutputID{ids[10]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns) != 2 || len(txns[0].SiacoinInputs) != 1 || txns[0].SiacoinInputs[0].ParentID != ids[10] {
+		t.Fatal("expected the 10 SC output to fund the transaction:", txns)
+	}
+	if _, err := wt.wallet.SendSiacoinsFromOutputs(types.SiacoinPrecision, types.UnlockHash{}, []types.SiacoinOutputID{ids[10]}); err == nil || !strings.Contains(err.Error(), errSpendHeightTooHigh.Error()) {
+		t.Fatal("expected errSpendHeightTooHigh, got", err)
+	}
+
+	// Lock the 20 SC output and restart the wallet.
+	if err := wt.wallet.LockOutputs([]types.SiacoinOutputID{ids[20]}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, filepath.Join(wt.persistDir, modules.WalletDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	if settings, err := wt.wallet.Settings(); err != nil || settings.CoinSelection != modules.CoinSelectionSmallestFirst {
+		t.Fatal("coin selection strategy was not persisted:", settings, err)
+	}
+	outputs, err = wt.wallet.SpendableOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, so := range outputs {
+		if so.ID == ids[20] {
+			found = true
+			if !so.Locked {
+				t.Fatal("lock was not persisted")
+			}
+		}
+	}
+	if !found {
+		t.Fatal("locked output is missing from the spendable outputs")
+	}
+	if err := wt.wallet.UnlockOutputs([]types.SiacoinOutputID{ids[20]}); err != nil {
+		t.Fatal(err)
+	}
+	outputs, err = wt.wallet.SpendableOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, so := range outputs {
+		if so.Locked {
+			t.Fatal("output is still locked:", so.ID)
+		}
+	}
+}
@@ -47,6 +47,8 @@ func (w *Wallet) openDB(filename string) (err error) {
 	err = w.db.Update(func(tx *bolt.Tx) error {
 		// check whether we need to init bucketAddrTransactions
 		buildAddrTxns := tx.Bucket(bucketAddrTransactions) == nil
+		// check whether we need to init bucketAddrBalances
+		buildAddrBalances := tx.Bucket(bucketAddrBalances) == nil
 		// ensure that all buckets exist
 		for _, b := range dbBuckets {
 			_, err := tx.CreateBucketIfNotExists(b)
@@ -86,8 +88,47 @@ func (w *Wallet) openDB(filename string) (err error) {
 			}
 		}
 
-		// check whether wallet is encrypted
+		// build the bucketAddrBalances bucket if necessary
+		if buildAddrBalances {
+			if err := dbBuildAddrBalances(tx); err != nil {
+				return err
+			}
+		}
+
+		// load the watch-only addresses
+		err := dbForEachWatchedAddr(tx, func(addr types.UnlockHash, _ struct{}) {
+			w.watchedAddrs[addr] = struct{}{}
+		})
+		if err != nil {
+			return err
+		}
+
+		// load the webhooks
+		err = dbForEachWebhook(tx, func(url string, hook modules.WalletWebhook) {
+			w.webhooks[url] = hook
+		})
+		if err != nil {
+			return err
+		}
+
+		// load the settings
+		settings, err := dbGetWalletSettings(tx)
+		if err == nil {
+			w.defragDisabled = settings.NoDefrag
+			w.coinSelection = settings.CoinSelection
+		} else if err != errNoKey {
+			return err
+		}
+
+		// load the auto-lock timeout
+		w.autoLockTimeout, err = dbGetAutoLockTimeout(tx)
+		if err != nil {
+			return err
+		}
+
+		// check whether wallet is encrypted and has a spending key
 		w.encrypted = tx.Bucket(bucketWallet).Get(keyEncryptionVerification) != nil
+		w.spendingKeyRequired = tx.Bucket(bucketWallet).Get(keySpendingVerification) != nil
 		return nil
 	})
 	return err
@@ -144,6 +185,7 @@ func (w *Wallet) initPersist() error {
 		return err
 	}
 	go w.threadedDBUpdate()
+	go w.threadedRebroadcastTransactions()
 	return nil
 }
 
@@ -155,10 +197,20 @@ func (w *Wallet) createBackup(dst io.Writer) error {
 
 // CreateBackup creates a backup file at the desired filepath.
 func (w *Wallet) CreateBackup(backupFilepath string) error {
+	return w.managedCreateBackup(nil, backupFilepath)
+}
+
+// managedCreateBackup implements CreateBackup. The backup contains the keys
+// of the wallet, so spendingKey must be the spending key of the wallet if it
+// has one.
+func (w *Wallet) managedCreateBackup(spendingKey *crypto.TwofishKey, backupFilepath string) error {
 	if err := w.tg.Add(); err != nil {
 		return err
 	}
 	defer w.tg.Done()
+	if err := w.managedCheckSpendingKey(spendingKey); err != nil {
+		return err
+	}
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	f, err := os.Create(backupFilepath)
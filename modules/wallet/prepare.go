@@ -0,0 +1,206 @@
+package wallet
+
+// prepare.go builds unsigned transactions that are signed on another machine.
+// The wallet selects the inputs, adds the change output and the miner fee, and
+// reserves an empty TransactionSignature for every signature that the unlock
+// conditions of the inputs require. The transaction can then be signed
+// offline with SignTransaction and broadcast through the transaction pool.
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// errMissingUnlockConditions is returned if a watch-only transaction
+	// cannot be funded because the unlock conditions of some watch-only
+	// addresses were not provided.
+	errMissingUnlockConditions = errors.New("insufficient balance with the provided unlock conditions, the unlock conditions of more watch-only addresses are needed")
+
+	// errNoPreparedOutputs is returned if a transaction without outputs is
+	// prepared.
+	errNoPreparedOutputs = errors.New("cannot prepare a transaction without outputs")
+)
+
+// estimatedSignedSize returns the size of the transaction once it has a change
+// output, a miner fee and all of its signatures.
+func estimatedSignedSize(txn types.Transaction, change types.SiacoinOutput) uint64 {
+	txn.SiacoinOutputs = append(txn.SiacoinOutputs[:len(txn.SiacoinOutputs):len(txn.SiacoinOutputs)], change)
+	txn.MinerFees = []types.Currency{change.Value}
+	for _, sci := range txn.SiacoinInputs {
+		for i := uint64(0); i < sci.UnlockConditions.SignaturesRequired; i++ {
+			txn.TransactionSignatures = append(txn.TransactionSignatures, types.TransactionSignature{
+				ParentID:      crypto.Hash(sci.ParentID),
+				CoveredFields: types.FullCoveredFields,
+				Signature:     make([]byte, crypto.SignatureSize),
+			})
+		}
+	}
+	return uint64(len(encoding.Marshal(txn)))
+}
+
+// PrepareTransaction builds and funds an unsigned transaction that sends the
+// provided outputs. If watchOnly is set, the transaction is funded from the
+// confirmed outputs of the watch-only addresses, and the change is sent back
+// to the address of the first input. The unlock conditions of the watch-only
+// addresses must be provided, since the wallet only knows their hashes.
+// Otherwise the transaction is funded from the confirmed outputs of the
+// wallet, which has to be unlocked. The spent outputs are marked as spent, so
+// that they are not used to fund other transactions.
+func (w *Wallet) PrepareTransaction(outputs []types.SiacoinOutput, watchOnly bool, ucs []types.UnlockConditions) (modules.PreparedTransaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return modules.PreparedTransaction{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if len(outputs) == 0 {
+		return modules.PreparedTransaction{}, errNoPreparedOutputs
+	}
+
+	// dustThreshold has to be obtained separate from the lock
+	dustThreshold, err := w.DustThreshold()
+	if err != nil {
+		return modules.PreparedTransaction{}, err
+	}
+	_, tpoolFee := w.tpool.FeeEstimation()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !watchOnly && !w.unlocked {
+		return modules.PreparedTransaction{}, modules.ErrLockedWallet
+	}
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return modules.PreparedTransaction{}, err
+	}
+	knownConditions := make(map[types.UnlockHash]types.UnlockConditions)
+	for _, uc := range ucs {
+		knownConditions[uc.UnlockHash()] = uc
+	}
+
+	// Collect a value-sorted set of confirmed siacoin outputs.
+	var so sortedOutputs
+	collect := func(scoid types.SiacoinOutputID, sco types.SiacoinOutput) {
+		so.ids = append(so.ids, scoid)
+		so.outputs = append(so.outputs, sco)
+	}
+	if watchOnly {
+		err = dbForEachWatchedSiacoinOutput(w.dbTx, collect)
+	} else {
+		err = dbForEachSiacoinOutput(w.dbTx, collect)
+	}
+	if err != nil {
+		return modules.PreparedTransaction{}, err
+	}
+	sort.Sort(sort.Reverse(so))
+
+	var amount types.Currency
+	for _, sco := range outputs {
+		amount = amount.Add(sco.Value)
+	}
+	txn := types.Transaction{
+		SiacoinOutputs: outputs,
+	}
+
+	// Add inputs until the outputs and the fee of the signed transaction are
+	// covered.
+	var parents []types.SiacoinOutput
+	var fund, potentialFund, fee types.Currency
+	var funded, missingConditions bool
+	for i, scoid := range so.ids {
+		sco := so.outputs[i]
+		if sco.Value.Cmp(dustThreshold) < 0 {
+			continue
+		}
+		if spendHeight, err := dbGetSpentOutput(w.dbTx, types.OutputID(scoid)); err == nil && spendHeight+RespendTimeout > consensusHeight {
+			potentialFund = potentialFund.Add(sco.Value)
+			continue
+		}
+		if dbIsLockedOutput(w.dbTx, scoid) {
+			continue
+		}
+		uc, known := w.keys[sco.UnlockHash].UnlockConditions, true
+		if watchOnly {
+			uc, known = knownConditions[sco.UnlockHash]
+		}
+		if !known {
+			missingConditions = true
+			continue
+		}
+		if consensusHeight < uc.Timelock {
+			continue
+		}
+
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{
+			ParentID:         scoid,
+			UnlockConditions: uc,
+		})
+		parents = append(parents, sco)
+		fund = fund.Add(sco.Value)
+		potentialFund = potentialFund.Add(sco.Value)
+		fee = tpoolFee.Mul64(estimatedSignedSize(txn, types.SiacoinOutput{Value: fund, UnlockHash: sco.UnlockHash}))
+		if fund.Cmp(amount.Add(fee)) >= 0 {
+			funded = true
+			break
+		}
+	}
+	if !funded {
+		if potentialFund.Cmp(amount.Add(fee)) >= 0 {
+			return modules.PreparedTransaction{}, modules.ErrIncompleteTransactions
+		} else if missingConditions {
+			return modules.PreparedTransaction{}, errMissingUnlockConditions
+		}
+		return modules.PreparedTransaction{}, modules.ErrLowBalance
+	}
+
+	// Send the change back to the wallet, or add it to the fee if it is dust.
+	change := fund.Sub(amount).Sub(fee)
+	if change.Cmp(dustThreshold) > 0 {
+		changeAddr := parents[0].UnlockHash
+		if !watchOnly {
+			uc, err := w.nextPrimarySeedAddress(w.dbTx)
+			if err != nil {
+				return modules.PreparedTransaction{}, err
+			}
+			changeAddr = uc.UnlockHash()
+		}
+		txn.SiacoinOutputs = append(txn.SiacoinOutputs, types.SiacoinOutput{
+			Value:      change,
+			UnlockHash: changeAddr,
+		})
+	} else {
+		fee = fee.Add(change)
+	}
+	txn.MinerFees = []types.Currency{fee}
+
+	// Reserve the signatures required by each input.
+	for _, sci := range txn.SiacoinInputs {
+		for i := uint64(0); i < sci.UnlockConditions.SignaturesRequired; i++ {
+			txn.TransactionSignatures = append(txn.TransactionSignatures, types.TransactionSignature{
+				ParentID:       crypto.Hash(sci.ParentID),
+				PublicKeyIndex: i,
+				CoveredFields:  types.FullCoveredFields,
+			})
+		}
+	}
+	pt := modules.PreparedTransaction{
+		Transaction:   txn,
+		ParentOutputs: parents,
+	}
+	for i := range txn.TransactionSignatures {
+		pt.SigHashes = append(pt.SigHashes, txn.SigHash(i))
+	}
+
+	// Mark the outputs as spent.
+	for _, sci := range txn.SiacoinInputs {
+		if err := dbPutSpentOutput(w.dbTx, types.OutputID(sci.ParentID), consensusHeight); err != nil {
+			return modules.PreparedTransaction{}, err
+		}
+	}
+	w.log.Println("Prepared an unsigned transaction sending", amount.HumanString(), "with fees", fee.HumanString(), "ID:", txn.ID())
+	return pt, nil
+}
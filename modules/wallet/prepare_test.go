@@ -0,0 +1,174 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestPrepareTransaction checks that a transaction prepared by the wallet can
+// be signed offline with the primary seed and accepted by the transaction
+// pool.
+func TestPrepareTransaction(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	output := types.SiacoinOutput{Value: types.SiacoinPrecision.Mul64(5)}
+	pt, err := wt.wallet.PrepareTransaction([]types.SiacoinOutput{output}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := pt.Transaction
+	if len(pt.ParentOutputs) != len(txn.SiacoinInputs) || len(pt.SigHashes) != len(txn.TransactionSignatures) {
+		t.Fatal("prepared transaction is missing parents or sighashes:", pt)
+	}
+	for i := range txn.TransactionSignatures {
+		if pt.SigHashes[i] != txn.SigHash(i) {
+			t.Fatal("wrong sighash for signature", i)
+		}
+	}
+	if err := txn.StandaloneValid(wt.cs.Height()); err == nil {
+		t.Fatal("unsigned transaction should not be valid")
+	}
+
+	// The wallet only has one mature output, which should be reserved for the
+	// prepared transaction.
+	if _, err := wt.wallet.PrepareTransaction([]types.SiacoinOutput{output}, false, nil); err != modules.ErrIncompleteTransactions {
+		t.Fatal("expected ErrIncompleteTransactions, got", err)
+	}
+
+	// Sign the transaction with the primary seed.
+	seed, _, err := wt.wallet.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := SignTransaction(&txn, seed, modules.PublicKeysPerSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed != len(txn.TransactionSignatures) {
+		t.Fatalf("expected %v signatures, got %v", len(txn.TransactionSignatures), signed)
+	}
+	if err := wt.tpool.AcceptTransactionSet([]types.Transaction{txn}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A seed without the keys should not be able to sign.
+	var otherSeed modules.Seed
+	fastrand.Read(otherSeed[:])
+	txn.TransactionSignatures = []types.TransactionSignature{txn.TransactionSignatures[0]}
+	txn.TransactionSignatures[0].Signature = nil
+	if _, err := SignTransaction(&txn, otherSeed, 10); err != errNoSignableKeys {
+		t.Fatal("expected errNoSignableKeys, got", err)
+	}
+}
+
+// TestPrepareWatchOnlyTransaction checks that a transaction can be funded
+// from a watch-only address and signed with the seed of that address.
+func TestPrepareWatchOnlyTransaction(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Fund an address of a cold seed and watch it.
+	var coldSeed modules.Seed
+	fastrand.Read(coldSeed[:])
+	coldKey := generateSpendableKey(coldSeed, 3)
+	coldAddr := coldKey.UnlockConditions.UnlockHash()
+	if err := wt.wallet.AddWatchAddresses([]types.UnlockHash{coldAddr}, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), coldAddr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The unlock conditions of the address are required.
+	output := types.SiacoinOutput{Value: types.SiacoinPrecision.Mul64(10)}
+	if _, err := wt.wallet.PrepareTransaction([]types.SiacoinOutput{output}, true, nil); err != errMissingUnlockConditions {
+		t.Fatal("expected errMissingUnlockConditions, got", err)
+	}
+	pt, err := wt.wallet.PrepareTransaction([]types.SiacoinOutput{output}, true, []types.UnlockConditions{coldKey.UnlockConditions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := pt.Transaction
+	if len(txn.SiacoinOutputs) != 2 || txn.SiacoinOutputs[1].UnlockHash != coldAddr {
+		t.Fatal("change was not sent back to the watch-only address:", txn.SiacoinOutputs)
+	}
+	if _, err := SignTransaction(&txn, coldSeed, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.tpool.AcceptTransactionSet([]types.Transaction{txn}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	sc, _, err := wt.wallet.WatchBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Equals(txn.SiacoinOutputs[1].Value) {
+		t.Fatalf("expected watched balance of %v after spending, got %v", txn.SiacoinOutputs[1].Value, sc)
+	}
+}
+
+// TestSignTransactionCoveredFields checks that SignTransaction signs
+// signatures that only cover parts of the transaction.
+func TestSignTransactionCoveredFields(t *testing.T) {
+	var seed modules.Seed
+	fastrand.Read(seed[:])
+	sk := generateSpendableKey(seed, 0)
+	txn := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{
+			UnlockConditions: sk.UnlockConditions,
+		}},
+		SiacoinOutputs: []types.SiacoinOutput{{Value: types.NewCurrency64(1)}},
+		MinerFees:      []types.Currency{types.NewCurrency64(1)},
+	}
+	txn.TransactionSignatures = []types.TransactionSignature{{
+		ParentID: crypto.Hash(txn.SiacoinInputs[0].ParentID),
+		CoveredFields: types.CoveredFields{
+			SiacoinInputs: []uint64{0},
+			MinerFees:     []uint64{0},
+		},
+	}}
+	signed, err := SignTransaction(&txn, seed, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed != 1 {
+		t.Fatal("expected 1 signature, got", signed)
+	}
+	if err := txn.StandaloneValid(0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The output is not covered, so it can be changed without invalidating
+	// the signature.
+	txn.SiacoinOutputs[0].UnlockHash = types.UnlockHash{1}
+	if err := txn.StandaloneValid(0); err != nil {
+		t.Fatal(err)
+	}
+	txn.MinerFees[0] = types.NewCurrency64(2)
+	if err := txn.StandaloneValid(0); err == nil {
+		t.Fatal("changing a covered field should invalidate the signature")
+	}
+}
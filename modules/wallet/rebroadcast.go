@@ -0,0 +1,71 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// managedRebroadcastSets returns the transaction sets that contain the
+// wallet's unconfirmed outgoing transactions, together with their unconfirmed
+// parents. Transactions that are no longer in the transaction pool are
+// skipped.
+func (w *Wallet) managedRebroadcastSets() [][]types.Transaction {
+	// Collect the outgoing transactions, children first, so that a parent
+	// that is already part of a child's set is not broadcast again.
+	w.mu.RLock()
+	var ids []types.TransactionID
+	for i := len(w.unconfirmedProcessedTransactions) - 1; i >= 0; i-- {
+		upt := w.unconfirmedProcessedTransactions[i]
+		for _, input := range upt.Inputs {
+			if input.WalletAddress {
+				ids = append(ids, upt.TransactionID)
+				break
+			}
+		}
+	}
+	w.mu.RUnlock()
+
+	included := make(map[types.TransactionID]struct{})
+	var sets [][]types.Transaction
+	for _, id := range ids {
+		if _, exists := included[id]; exists {
+			continue
+		}
+		txn, parents, exists := w.tpool.Transaction(id)
+		if !exists {
+			continue
+		}
+		set := append(parents, txn)
+		for _, t := range set {
+			included[t.ID()] = struct{}{}
+		}
+		sets = append(sets, set)
+	}
+	return sets
+}
+
+// threadedRebroadcastTransactions periodically rebroadcasts the wallet's
+// unconfirmed outgoing transactions, in case they were not relayed by the
+// peers of the transaction pool.
+func (w *Wallet) threadedRebroadcastTransactions() {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	for {
+		select {
+		case <-time.After(rebroadcastInterval):
+		case <-w.tg.StopChan():
+			return
+		}
+		sets := w.managedRebroadcastSets()
+		for _, set := range sets {
+			w.tpool.Broadcast(set)
+		}
+		if len(sets) > 0 {
+			w.log.Debugln("Rebroadcast", len(sets), "unconfirmed transaction sets")
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestRebroadcastSets checks that the wallet rebroadcasts its unconfirmed
+// outgoing transactions until they are confirmed.
+func TestRebroadcastSets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	if sets := wt.wallet.managedRebroadcastSets(); len(sets) != 0 {
+		t.Fatal("expected no sets to rebroadcast, got", len(sets))
+	}
+	txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sets := wt.wallet.managedRebroadcastSets()
+	if len(sets) != 1 {
+		t.Fatal("expected 1 set to rebroadcast, got", len(sets))
+	}
+	set := sets[0]
+	if set[len(set)-1].ID() != txns[len(txns)-1].ID() {
+		t.Fatal("rebroadcast set does not end with the sent transaction")
+	}
+
+	// Confirmed transactions are not rebroadcast.
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if sets := wt.wallet.managedRebroadcastSets(); len(sets) != 0 {
+		t.Fatal("expected no sets to rebroadcast, got", len(sets))
+	}
+}
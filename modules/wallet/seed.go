@@ -153,6 +153,15 @@ func (w *Wallet) nextPrimarySeedAddress(tx *bolt.Tx) (types.UnlockConditions, er
 
 // AllSeeds returns a list of all seeds known to and used by the wallet.
 func (w *Wallet) AllSeeds() ([]modules.Seed, error) {
+	return w.managedAllSeeds(nil)
+}
+
+// managedAllSeeds implements AllSeeds. spendingKey must be the spending key
+// of the wallet if it has one.
+func (w *Wallet) managedAllSeeds(spendingKey *crypto.TwofishKey) ([]modules.Seed, error) {
+	if err := w.managedCheckSpendingKey(spendingKey); err != nil {
+		return nil, err
+	}
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	if !w.unlocked {
@@ -164,6 +173,15 @@ func (w *Wallet) AllSeeds() ([]modules.Seed, error) {
 // PrimarySeed returns the decrypted primary seed of the wallet, as well as
 // the number of addresses that the seed can be safely used to generate.
 func (w *Wallet) PrimarySeed() (modules.Seed, uint64, error) {
+	return w.managedPrimarySeed(nil)
+}
+
+// managedPrimarySeed implements PrimarySeed. spendingKey must be the spending
+// key of the wallet if it has one.
+func (w *Wallet) managedPrimarySeed(spendingKey *crypto.TwofishKey) (modules.Seed, uint64, error) {
+	if err := w.managedCheckSpendingKey(spendingKey); err != nil {
+		return modules.Seed{}, 0, err
+	}
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	if !w.unlocked {
@@ -232,6 +250,9 @@ func (w *Wallet) LoadSeed(masterKey crypto.TwofishKey, seed modules.Seed) error
 	if !w.cs.Synced() {
 		return errors.New("cannot load seed until blockchain is synced")
 	}
+	if w.cs.Pruned() {
+		return errPrunedRescan
+	}
 
 	if !w.scanLock.TryLock() {
 		return errScanInProgress
@@ -334,10 +355,20 @@ func (w *Wallet) LoadSeed(masterKey crypto.TwofishKey, seed modules.Seed) error
 // transaction fee. It returns the total value of the outputs, minus the fee.
 // If only siafunds were found, the fee is deducted from the wallet.
 func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err error) {
+	return w.managedSweepSeed(nil, seed)
+}
+
+// managedSweepSeed implements SweepSeed. The fee of the sweep can be paid
+// from the outputs of the wallet, so spendingKey must be the spending key of
+// the wallet if it has one.
+func (w *Wallet) managedSweepSeed(spendingKey *crypto.TwofishKey, seed modules.Seed) (coins, funds types.Currency, err error) {
 	if err = w.tg.Add(); err != nil {
 		return
 	}
 	defer w.tg.Done()
+	if err = w.managedCheckSpendingKey(spendingKey); err != nil {
+		return
+	}
 
 	if !w.scanLock.TryLock() {
 		return types.Currency{}, types.Currency{}, errScanInProgress
@@ -354,6 +385,9 @@ func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err
 	if !w.cs.Synced() {
 		return types.Currency{}, types.Currency{}, errors.New("cannot sweep until blockchain is synced")
 	}
+	if w.cs.Pruned() {
+		return types.Currency{}, types.Currency{}, errPrunedRescan
+	}
 
 	// get an address to spend into
 	w.mu.Lock()
@@ -0,0 +1,179 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// errNoSignableKeys is returned if none of the missing signatures of a
+	// transaction can be made with the available keys.
+	errNoSignableKeys = errors.New("none of the missing signatures can be made with the available keys")
+)
+
+// parentUnlockConditions returns the unlock conditions of every input and
+// file contract revision of the transaction, keyed by the parent ID that the
+// transaction signatures refer to.
+func parentUnlockConditions(txn types.Transaction) map[crypto.Hash]types.UnlockConditions {
+	ucs := make(map[crypto.Hash]types.UnlockConditions)
+	for _, sci := range txn.SiacoinInputs {
+		ucs[crypto.Hash(sci.ParentID)] = sci.UnlockConditions
+	}
+	for _, sfi := range txn.SiafundInputs {
+		ucs[crypto.Hash(sfi.ParentID)] = sfi.UnlockConditions
+	}
+	for _, fcr := range txn.FileContractRevisions {
+		ucs[crypto.Hash(fcr.ParentID)] = fcr.UnlockConditions
+	}
+	return ucs
+}
+
+// signMissing adds the missing signatures of txn using the secret keys
+// returned by findKey. A signature is missing if its TransactionSignature has
+// an empty Signature field. If the key of a missing signature is not
+// available, the signature is moved to another public key of its unlock
+// conditions that is available and not used by any other signature of the
+// same parent. This lets each signer of a multisig input use its own keys,
+// regardless of which public keys the creator of the transaction reserved.
+// Signatures are made in the order of the signatures, so that signatures
+// covering earlier signatures sign their final value.
+func signMissing(txn *types.Transaction, findKey func(types.SiaPublicKey) (crypto.SecretKey, bool)) (int, error) {
+	ucs := parentUnlockConditions(*txn)
+	used := make(map[crypto.Hash]map[uint64]bool)
+	for i, sig := range txn.TransactionSignatures {
+		uc, ok := ucs[sig.ParentID]
+		if !ok {
+			return 0, fmt.Errorf("signature %v does not belong to an input of the transaction", i)
+		}
+		if sig.PublicKeyIndex >= uint64(len(uc.PublicKeys)) {
+			return 0, fmt.Errorf("signature %v refers to a public key that is not in its unlock conditions", i)
+		}
+		if used[sig.ParentID] == nil {
+			used[sig.ParentID] = make(map[uint64]bool)
+		}
+		used[sig.ParentID][sig.PublicKeyIndex] = true
+	}
+
+	signed := 0
+	for i, sig := range txn.TransactionSignatures {
+		if len(sig.Signature) != 0 {
+			continue
+		}
+		uc := ucs[sig.ParentID]
+		sk, ok := findKey(uc.PublicKeys[sig.PublicKeyIndex])
+		for j := uint64(0); !ok && j < uint64(len(uc.PublicKeys)); j++ {
+			if used[sig.ParentID][j] {
+				continue
+			}
+			if sk, ok = findKey(uc.PublicKeys[j]); ok {
+				delete(used[sig.ParentID], sig.PublicKeyIndex)
+				used[sig.ParentID][j] = true
+				txn.TransactionSignatures[i].PublicKeyIndex = j
+			}
+		}
+		if !ok {
+			continue
+		}
+		encodedSig := crypto.SignHash(txn.SigHash(i), sk)
+		txn.TransactionSignatures[i].Signature = encodedSig[:]
+		signed++
+	}
+	if signed == 0 {
+		return 0, errNoSignableKeys
+	}
+	return signed, nil
+}
+
+// SignTransaction adds the missing signatures of txn that belong to keys
+// derived from seed. Each signature signs txn.SigHash, so the CoveredFields
+// of the TransactionSignature determine which parts of the transaction it
+// covers. SignTransaction does not need a wallet, so it can be used to sign
+// prepared transactions on an offline machine. Since the keys are derived
+// from scratch, at most maxKeys keys are searched. The number of added
+// signatures is returned.
+func SignTransaction(txn *types.Transaction, seed modules.Seed, maxKeys uint64) (int, error) {
+	// Determine the public keys that could sign a missing signature.
+	ucs := parentUnlockConditions(*txn)
+	wanted := make(map[string]struct{})
+	for _, sig := range txn.TransactionSignatures {
+		if len(sig.Signature) != 0 {
+			continue
+		}
+		for _, pk := range ucs[sig.ParentID].PublicKeys {
+			if pk.Algorithm == types.SignatureEd25519 {
+				wanted[string(pk.Key)] = struct{}{}
+			}
+		}
+	}
+	if len(wanted) == 0 {
+		return 0, nil
+	}
+
+	// Derive keys from the seed until all public keys are found.
+	secretKeys := make(map[string]crypto.SecretKey)
+	for start := uint64(0); start < maxKeys && len(wanted) > 0; start += modules.PublicKeysPerSeed {
+		n := uint64(modules.PublicKeysPerSeed)
+		if start+n > maxKeys {
+			n = maxKeys - start
+		}
+		for _, sk := range generateKeys(seed, start, n) {
+			pk := sk.SecretKeys[0].PublicKey()
+			if _, ok := wanted[string(pk[:])]; ok {
+				secretKeys[string(pk[:])] = sk.SecretKeys[0]
+				delete(wanted, string(pk[:]))
+			}
+		}
+	}
+	return signMissing(txn, func(pk types.SiaPublicKey) (crypto.SecretKey, bool) {
+		if pk.Algorithm != types.SignatureEd25519 {
+			return crypto.SecretKey{}, false
+		}
+		sk, ok := secretKeys[string(pk.Key)]
+		return sk, ok
+	})
+}
+
+// SignTransaction adds the missing signatures of txn that belong to keys of
+// the wallet, and returns the number of added signatures. It is used to
+// co-sign transactions that spend from multisig addresses.
+func (w *Wallet) SignTransaction(txn *types.Transaction) (int, error) {
+	return w.managedSignTransaction(nil, txn)
+}
+
+// managedSignTransaction implements SignTransaction. spendingKey must be the
+// spending key of the wallet if it has one.
+func (w *Wallet) managedSignTransaction(spendingKey *crypto.TwofishKey, txn *types.Transaction) (int, error) {
+	if err := w.tg.Add(); err != nil {
+		return 0, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if err := w.managedCheckSpendingKey(spendingKey); err != nil {
+		return 0, err
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.unlocked {
+		return 0, modules.ErrLockedWallet
+	}
+	return signMissing(txn, w.findSecretKey)
+}
+
+// findSecretKey returns the secret key of the wallet that belongs to pk.
+func (w *Wallet) findSecretKey(pk types.SiaPublicKey) (crypto.SecretKey, bool) {
+	if pk.Algorithm != types.SignatureEd25519 {
+		return crypto.SecretKey{}, false
+	}
+	uc := types.UnlockConditions{
+		PublicKeys:         []types.SiaPublicKey{pk},
+		SignaturesRequired: 1,
+	}
+	key, ok := w.keys[uc.UnlockHash()]
+	if !ok || len(key.SecretKeys) != 1 {
+		return crypto.SecretKey{}, false
+	}
+	return key.SecretKeys[0], true
+}
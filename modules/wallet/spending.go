@@ -0,0 +1,106 @@
+package wallet
+
+// spending.go implements the view of the wallet that is authorized by the
+// spending key. If the wallet has a spending key, the methods that sign
+// transactions with the keys of the wallet or export them refuse to run unless
+// they are called on such a view. Every authorized call verifies the spending
+// key again, so a view stops working once the spending key is changed.
+
+import (
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// spendingWallet is a view of the wallet that is authorized by a spending key
+// to sign transactions with the keys of the wallet and to export them.
+type spendingWallet struct {
+	*Wallet
+	spendingKey crypto.TwofishKey
+}
+
+// AuthorizeSpending returns a view of the wallet that is authorized by
+// spendingKey to sign transactions with the keys of the wallet and to export
+// them. modules.ErrBadSpendingKey is returned if the wallet has a spending key
+// and spendingKey is not that key.
+func (w *Wallet) AuthorizeSpending(spendingKey crypto.TwofishKey) (modules.Wallet, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if err := w.managedCheckSpendingKey(&spendingKey); err != nil {
+		return nil, err
+	}
+	return &spendingWallet{
+		Wallet:      w,
+		spendingKey: spendingKey,
+	}, nil
+}
+
+// AllSeeds returns a list of all seeds known to and used by the wallet.
+func (sw *spendingWallet) AllSeeds() ([]modules.Seed, error) {
+	return sw.managedAllSeeds(&sw.spendingKey)
+}
+
+// BumpFee raises the miner fee of the unconfirmed transaction txid, see
+// Wallet.BumpFee.
+func (sw *spendingWallet) BumpFee(txid types.TransactionID, fee types.Currency, method modules.BumpFeeMethod) ([]types.Transaction, error) {
+	return sw.managedBumpFee(&sw.spendingKey, txid, fee, method)
+}
+
+// CreateBackup creates a backup file at the desired filepath.
+func (sw *spendingWallet) CreateBackup(backupFilepath string) error {
+	return sw.managedCreateBackup(&sw.spendingKey, backupFilepath)
+}
+
+// PrepareMultisigTransaction builds a transaction that sends the outputs from
+// the multisig address addr, see Wallet.PrepareMultisigTransaction.
+func (sw *spendingWallet) PrepareMultisigTransaction(addr types.UnlockHash, outputs []types.SiacoinOutput) (types.Transaction, error) {
+	return sw.managedPrepareMultisigTransaction(&sw.spendingKey, addr, outputs)
+}
+
+// PrimarySeed returns the decrypted primary seed of the wallet, as well as
+// the number of addresses that the seed can be safely used to generate.
+func (sw *spendingWallet) PrimarySeed() (modules.Seed, uint64, error) {
+	return sw.managedPrimarySeed(&sw.spendingKey)
+}
+
+// SendSiacoins creates a transaction sending 'amount' to 'dest'.
+func (sw *spendingWallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) ([]types.Transaction, error) {
+	return sw.managedSendSiacoins(&sw.spendingKey, amount, dest, nil, 0)
+}
+
+// SendSiacoinsFromOutputs creates a transaction sending 'amount' to 'dest'
+// that is funded from the wallet outputs with the given IDs.
+func (sw *spendingWallet) SendSiacoinsFromOutputs(amount types.Currency, dest types.UnlockHash, ids []types.SiacoinOutputID) ([]types.Transaction, error) {
+	return sw.managedSendSiacoinsFromOutputs(&sw.spendingKey, amount, dest, ids)
+}
+
+// SendSiacoinsMulti creates a transaction that includes the specified
+// outputs.
+func (sw *spendingWallet) SendSiacoinsMulti(outputs []types.SiacoinOutput) ([]types.Transaction, error) {
+	return sw.managedSendSiacoinsMulti(&sw.spendingKey, outputs)
+}
+
+// SendSiacoinsWithTarget creates a transaction sending 'amount' to 'dest'
+// that pays the fee recommended for being confirmed within 'target' blocks.
+func (sw *spendingWallet) SendSiacoinsWithTarget(amount types.Currency, dest types.UnlockHash, target types.BlockHeight) ([]types.Transaction, error) {
+	return sw.managedSendSiacoinsWithTarget(&sw.spendingKey, amount, dest, target)
+}
+
+// SendSiafunds creates a transaction sending 'amount' to 'dest'.
+func (sw *spendingWallet) SendSiafunds(amount types.Currency, dest types.UnlockHash) ([]types.Transaction, error) {
+	return sw.managedSendSiafunds(&sw.spendingKey, amount, dest)
+}
+
+// SignTransaction adds the missing signatures of txn that belong to keys of
+// the wallet, and returns the number of added signatures.
+func (sw *spendingWallet) SignTransaction(txn *types.Transaction) (int, error) {
+	return sw.managedSignTransaction(&sw.spendingKey, txn)
+}
+
+// SweepSeed scans the blockchain for outputs generated from seed and creates
+// a transaction that transfers them to the wallet.
+func (sw *spendingWallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err error) {
+	return sw.managedSweepSeed(&sw.spendingKey, seed)
+}
@@ -0,0 +1,151 @@
+package wallet
+
+// timelock.go implements timelocked addresses. A timelocked address uses a
+// key of the primary seed, but its unlock conditions carry a timelock, so
+// consensus rejects any transaction that spends its outputs before the
+// unlock height. The wallet stores the seed index and the timelock of each
+// address, and regenerates the key when it is unlocked. Outputs of a
+// timelocked address are tracked like any other output of the wallet, and
+// checkOutput keeps them from funding transactions until they unlock.
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// errUnlockHeightPassed is returned if a timelocked address is created with
+// an unlock height that is not in the future.
+var errUnlockHeightPassed = errors.New("unlock height must be greater than the current height")
+
+// timelockedAddr is the persisted form of a timelocked address.
+type timelockedAddr struct {
+	Index            uint64
+	UnlockConditions types.UnlockConditions
+}
+
+// integrateTimelockedAddrs loads the keys of the timelocked addresses into
+// the wallet. The primary seed must be loaded already.
+func (w *Wallet) integrateTimelockedAddrs() error {
+	return dbForEachTimelockedAddr(w.dbTx, func(_ types.UnlockHash, ta timelockedAddr) {
+		sk := generateSpendableKey(w.primarySeed, ta.Index)
+		sk.UnlockConditions.Timelock = ta.UnlockConditions.Timelock
+		w.keys[sk.UnlockConditions.UnlockHash()] = sk
+	})
+}
+
+// timelocks returns the unlock heights of the timelocked addresses of the
+// wallet. It does not need the wallet to be unlocked.
+func (w *Wallet) timelocks() (map[types.UnlockHash]types.BlockHeight, error) {
+	timelocks := make(map[types.UnlockHash]types.BlockHeight)
+	err := dbForEachTimelockedAddr(w.dbTx, func(addr types.UnlockHash, ta timelockedAddr) {
+		timelocks[addr] = ta.UnlockConditions.Timelock
+	})
+	return timelocks, err
+}
+
+// NewTimelockedAddress returns a new address of the wallet whose outputs
+// cannot be spent before unlockHeight.
+func (w *Wallet) NewTimelockedAddress(unlockHeight types.BlockHeight) (types.UnlockConditions, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.UnlockConditions{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.unlocked {
+		return types.UnlockConditions{}, modules.ErrLockedWallet
+	}
+	height, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return types.UnlockConditions{}, err
+	}
+	if unlockHeight <= height {
+		return types.UnlockConditions{}, errUnlockHeightPassed
+	}
+
+	// Use the next key of the primary seed, so that the key is never reused
+	// for a regular address.
+	index, err := dbGetPrimarySeedProgress(w.dbTx)
+	if err != nil {
+		return types.UnlockConditions{}, err
+	}
+	if _, err := w.nextPrimarySeedAddress(w.dbTx); err != nil {
+		return types.UnlockConditions{}, err
+	}
+	sk := generateSpendableKey(w.primarySeed, index)
+	sk.UnlockConditions.Timelock = unlockHeight
+	if err := dbPutTimelockedAddr(w.dbTx, timelockedAddr{Index: index, UnlockConditions: sk.UnlockConditions}); err != nil {
+		return types.UnlockConditions{}, err
+	}
+	w.keys[sk.UnlockConditions.UnlockHash()] = sk
+	if err := w.syncDB(); err != nil {
+		return types.UnlockConditions{}, err
+	}
+	return sk.UnlockConditions, nil
+}
+
+// TimelockedAddresses returns the timelocked addresses of the wallet,
+// ordered by unlock height, together with their confirmed balance.
+func (w *Wallet) TimelockedAddresses() ([]modules.TimelockedAddress, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	balances := make(map[types.UnlockHash]types.Currency)
+	err := dbForEachSiacoinOutput(w.dbTx, func(_ types.SiacoinOutputID, sco types.SiacoinOutput) {
+		balances[sco.UnlockHash] = balances[sco.UnlockHash].Add(sco.Value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var addrs []modules.TimelockedAddress
+	err = dbForEachTimelockedAddr(w.dbTx, func(addr types.UnlockHash, ta timelockedAddr) {
+		addrs = append(addrs, modules.TimelockedAddress{
+			Address:          addr,
+			UnlockConditions: ta.UnlockConditions,
+			UnlockHeight:     ta.UnlockConditions.Timelock,
+			Balance:          balances[addr],
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].UnlockHeight < addrs[j].UnlockHeight
+	})
+	return addrs, nil
+}
+
+// TimelockedBalance returns the confirmed siacoins of the wallet that cannot
+// be spent yet because their timelock has not expired.
+func (w *Wallet) TimelockedBalance() (types.Currency, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.ZeroCurrency, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	height, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return types.ZeroCurrency, err
+	}
+	timelocks, err := w.timelocks()
+	if err != nil {
+		return types.ZeroCurrency, err
+	}
+	var locked types.Currency
+	err = dbForEachSiacoinOutput(w.dbTx, func(_ types.SiacoinOutputID, sco types.SiacoinOutput) {
+		if height < timelocks[sco.UnlockHash] {
+			locked = locked.Add(sco.Value)
+		}
+	})
+	return locked, err
+}
@@ -0,0 +1,103 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestTimelockedAddress checks that the wallet can receive coins on a
+// timelocked address, reports them as locked until the unlock height, and
+// spends them afterwards.
+func TestTimelockedAddress(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	height := wt.cs.Height()
+	if _, err := wt.wallet.NewTimelockedAddress(height); err != errUnlockHeightPassed {
+		t.Fatal("expected errUnlockHeightPassed, got", err)
+	}
+	unlockHeight := height + 5
+	uc, err := wt.wallet.NewTimelockedAddress(unlockHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uc.Timelock != unlockHeight {
+		t.Fatal("address has the wrong timelock:", uc.Timelock)
+	}
+	addr := uc.UnlockHash()
+
+	// Send coins to the address.
+	amount := types.SiacoinPrecision.Mul64(100)
+	txns, err := wt.wallet.SendSiacoins(amount, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var id types.SiacoinOutputID
+	for i, sco := range txns[len(txns)-1].SiacoinOutputs {
+		if sco.UnlockHash == addr {
+			id = txns[len(txns)-1].SiacoinOutputID(uint64(i))
+		}
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The key of the address is regenerated when the wallet is unlocked.
+	if err := wt.wallet.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+
+	locked, err := wt.wallet.TimelockedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !locked.Equals(amount) {
+		t.Fatalf("expected %v locked siacoins, got %v", amount, locked)
+	}
+	addrs, err := wt.wallet.TimelockedAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0].Address != addr || addrs[0].UnlockHeight != unlockHeight || !addrs[0].Balance.Equals(amount) {
+		t.Fatal("unexpected timelocked addresses:", addrs)
+	}
+
+	// The coins cannot be spent before the unlock height.
+	if _, err := wt.wallet.SendSiacoinsFromOutputs(types.SiacoinPrecision, types.UnlockHash{}, []types.SiacoinOutputID{id}); err == nil {
+		t.Fatal("spent a timelocked output before the unlock height")
+	}
+	for wt.cs.Height() < unlockHeight {
+		if _, err := wt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if locked, err := wt.wallet.TimelockedBalance(); err != nil || !locked.IsZero() {
+		t.Fatal("expected no locked siacoins after the unlock height:", locked, err)
+	}
+
+	// Once unlocked, the coins can be spent.
+	if _, err := wt.wallet.SendSiacoinsFromOutputs(types.SiacoinPrecision, types.UnlockHash{}, []types.SiacoinOutputID{id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	addrs, err = wt.wallet.TimelockedAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || !addrs[0].Balance.IsZero() {
+		t.Fatal("timelocked output was not spent:", addrs)
+	}
+}
@@ -3,7 +3,6 @@ package wallet
 import (
 	"bytes"
 	"errors"
-	"sort"
 
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
@@ -22,12 +21,27 @@ var (
 	// errDustOutput indicates an output is not spendable because it is dust.
 	errDustOutput = errors.New("output is too small")
 
+	// errDuplicateOutputID indicates that an output was listed more than once
+	// to fund a transaction.
+	errDuplicateOutputID = errors.New("output is listed more than once")
+
+	// errNoOutputIDs indicates that a transaction should be funded from an
+	// empty list of outputs.
+	errNoOutputIDs = errors.New("no outputs were provided to fund the transaction")
+
+	// errOutputLocked indicates an output was locked by the user.
+	errOutputLocked = errors.New("output is locked")
+
 	// errOutputTimelock indicates an output's timelock is still active.
 	errOutputTimelock = errors.New("wallet consensus set height is lower than the output timelock")
 
 	// errSpendHeightTooHigh indicates an output's spend height is greater than
 	// the allowed height.
 	errSpendHeightTooHigh = errors.New("output spend height exceeds the allowed height")
+
+	// errUnknownOutput indicates that an output is not a confirmed siacoin
+	// output of the wallet.
+	errUnknownOutput = errors.New("output is not a confirmed siacoin output of the wallet")
 )
 
 // transactionBuilder allows transactions to be manually constructed, including
@@ -108,6 +122,10 @@ func (w *Wallet) checkOutput(tx *bolt.Tx, currentHeight types.BlockHeight, id ty
 	if currentHeight < outputUnlockConditions.Timelock {
 		return errOutputTimelock
 	}
+	// Check that the output is not locked by the user.
+	if dbIsLockedOutput(tx, id) {
+		return errOutputLocked
+	}
 
 	return nil
 }
@@ -115,13 +133,15 @@ func (w *Wallet) checkOutput(tx *bolt.Tx, currentHeight types.BlockHeight, id ty
 // FundSiacoins will add a siacoin input of exactly 'amount' to the
 // transaction. A parent transaction may be needed to achieve an input with the
 // correct value. The siacoin input will not be signed until 'Sign' is called
-// on the transaction builder.
+// on the transaction builder. The outputs that fund the parent transaction are
+// chosen according to the coin selection strategy of the wallet.
 func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 	// dustThreshold has to be obtained separate from the lock
 	dustThreshold, err := tb.wallet.DustThreshold()
 	if err != nil {
 		return err
 	}
+	_, tpoolFee := tb.wallet.tpool.FeeEstimation()
 
 	tb.wallet.mu.Lock()
 	defer tb.wallet.mu.Unlock()
@@ -131,7 +151,7 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 		return err
 	}
 
-	// Collect a value-sorted set of siacoin outputs.
+	// Collect the spendable siacoin outputs.
 	var so sortedOutputs
 	err = dbForEachSiacoinOutput(tb.wallet.dbTx, func(scoid types.SiacoinOutputID, sco types.SiacoinOutput) {
 		so.ids = append(so.ids, scoid)
@@ -152,20 +172,13 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 			so.outputs = append(so.outputs, sco)
 		}
 	}
-	sort.Sort(sort.Reverse(so))
-
-	// Create and fund a parent transaction that will add the correct amount of
-	// siacoins to the transaction.
-	var fund types.Currency
 	// potentialFund tracks the balance of the wallet including outputs that
 	// have been spent in other unconfirmed transactions recently. This is to
 	// provide the user with a more useful error message in the event that they
 	// are overspending.
 	var potentialFund types.Currency
-	parentTxn := types.Transaction{}
-	var spentScoids []types.SiacoinOutputID
-	for i := range so.ids {
-		scoid := so.ids[i]
+	var spendable sortedOutputs
+	for i, scoid := range so.ids {
 		sco := so.outputs[i]
 		// Check that the output can be spent.
 		if err := tb.wallet.checkOutput(tb.wallet.dbTx, consensusHeight, scoid, sco, dustThreshold); err != nil {
@@ -174,28 +187,81 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 			}
 			continue
 		}
-
-		// Add a siacoin input for this output.
-		sci := types.SiacoinInput{
-			ParentID:         scoid,
-			UnlockConditions: tb.wallet.keys[sco.UnlockHash].UnlockConditions,
-		}
-		parentTxn.SiacoinInputs = append(parentTxn.SiacoinInputs, sci)
-		spentScoids = append(spentScoids, scoid)
-
-		// Add the output to the total fund
-		fund = fund.Add(sco.Value)
+		spendable.ids = append(spendable.ids, scoid)
+		spendable.outputs = append(spendable.outputs, sco)
 		potentialFund = potentialFund.Add(sco.Value)
-		if fund.Cmp(amount) >= 0 {
-			break
-		}
 	}
+
+	// Choose the outputs that fund the parent transaction.
+	tolerance := tpoolFee.Mul64(changeOutputCost)
+	selected, fund, changeless := selectOutputs(spendable, amount, tolerance, tb.wallet.coinSelection)
 	if potentialFund.Cmp(amount) >= 0 && fund.Cmp(amount) < 0 {
 		return modules.ErrIncompleteTransactions
 	}
 	if fund.Cmp(amount) < 0 {
 		return modules.ErrLowBalance
 	}
+	return tb.addParentTransaction(selected, fund, amount, changeless, consensusHeight)
+}
+
+// FundSiacoinsFromOutputs will add a siacoin input of exactly 'amount' to the
+// transaction, funded by a parent transaction that spends exactly the
+// confirmed wallet outputs with the given IDs. Locked outputs can be spent
+// this way, but outputs that were recently spent by the wallet cannot.
+func (tb *transactionBuilder) FundSiacoinsFromOutputs(amount types.Currency, ids []types.SiacoinOutputID) error {
+	if len(ids) == 0 {
+		return errNoOutputIDs
+	}
+	tb.wallet.mu.Lock()
+	defer tb.wallet.mu.Unlock()
+
+	consensusHeight, err := dbGetConsensusHeight(tb.wallet.dbTx)
+	if err != nil {
+		return err
+	}
+
+	var so sortedOutputs
+	var fund types.Currency
+	seen := make(map[types.SiacoinOutputID]struct{})
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			return errDuplicateOutputID
+		}
+		seen[id] = struct{}{}
+		sco, err := dbGetSiacoinOutput(tb.wallet.dbTx, id)
+		if err == errNoKey {
+			return errUnknownOutput
+		} else if err != nil {
+			return err
+		}
+		// Dust and locked outputs can be spent explicitly.
+		err = tb.wallet.checkOutput(tb.wallet.dbTx, consensusHeight, id, sco, types.ZeroCurrency)
+		if err != nil && err != errOutputLocked {
+			return err
+		}
+		so.ids = append(so.ids, id)
+		so.outputs = append(so.outputs, sco)
+		fund = fund.Add(sco.Value)
+	}
+	if fund.Cmp(amount) < 0 {
+		return modules.ErrLowBalance
+	}
+	return tb.addParentTransaction(so, fund, amount, false, consensusHeight)
+}
+
+// addParentTransaction creates and signs a parent transaction that spends the
+// outputs of so, whose total value is fund, into an output of exactly amount.
+// The output is added as a siacoin input to the transaction. If changeless is
+// set, the excess value is added to the miner fees of the parent transaction;
+// otherwise it is sent back to the wallet.
+func (tb *transactionBuilder) addParentTransaction(so sortedOutputs, fund, amount types.Currency, changeless bool, consensusHeight types.BlockHeight) error {
+	parentTxn := types.Transaction{}
+	for i, scoid := range so.ids {
+		parentTxn.SiacoinInputs = append(parentTxn.SiacoinInputs, types.SiacoinInput{
+			ParentID:         scoid,
+			UnlockConditions: tb.wallet.keys[so.outputs[i].UnlockHash].UnlockConditions,
+		})
+	}
 
 	// Create and add the output that will be used to fund the standard
 	// transaction.
@@ -211,7 +277,9 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 	parentTxn.SiacoinOutputs = append(parentTxn.SiacoinOutputs, exactOutput)
 
 	// Create a refund output if needed.
-	if !amount.Equals(fund) {
+	if changeless && !amount.Equals(fund) {
+		parentTxn.MinerFees = append(parentTxn.MinerFees, fund.Sub(amount))
+	} else if !amount.Equals(fund) {
 		refundUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
 		if err != nil {
 			return err
@@ -245,7 +313,7 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 	tb.transaction.SiacoinInputs = append(tb.transaction.SiacoinInputs, newInput)
 
 	// Mark all outputs that were spent as spent.
-	for _, scoid := range spentScoids {
+	for _, scoid := range so.ids {
 		err = dbPutSpentOutput(tb.wallet.dbTx, types.OutputID(scoid), consensusHeight)
 		if err != nil {
 			return err
@@ -179,6 +179,10 @@ func (w *Wallet) LoadSiagKeys(masterKey crypto.TwofishKey, keyfiles []string) er
 	}
 	defer w.tg.Done()
 
+	if w.cs.Pruned() {
+		return errPrunedRescan
+	}
+
 	// load the keys and reset the consensus change ID and height in preparation for rescan
 	err := func() error {
 		w.mu.Lock()
@@ -229,6 +233,10 @@ func (w *Wallet) Load033xWallet(masterKey crypto.TwofishKey, filepath033x string
 	}
 	defer w.tg.Done()
 
+	if w.cs.Pruned() {
+		return errPrunedRescan
+	}
+
 	// load the keys and reset the consensus change ID and height in preparation for rescan
 	err := func() error {
 		w.mu.Lock()
@@ -11,8 +11,9 @@ import (
 )
 
 type (
-	spentSiacoinOutputSet map[types.SiacoinOutputID]types.SiacoinOutput
-	spentSiafundOutputSet map[types.SiafundOutputID]types.SiafundOutput
+	spentSiacoinOutputSet   map[types.SiacoinOutputID]types.SiacoinOutput
+	spentSiafundOutputSet   map[types.SiafundOutputID]types.SiafundOutput
+	delayedSiacoinOutputSet map[types.SiacoinOutputID]types.SiacoinOutput
 )
 
 // threadedResetSubscriptions unsubscribes the wallet from the consensus set and transaction pool
@@ -23,6 +24,11 @@ func (w *Wallet) threadedResetSubscriptions() error {
 	}
 	defer w.scanLock.Unlock()
 
+	if w.cs.Pruned() {
+		w.log.Println("WARN: unable to rescan for the outputs of new addresses:", errPrunedRescan)
+		return errPrunedRescan
+	}
+
 	w.cs.Unsubscribe(w)
 	w.tpool.Unsubscribe(w)
 
@@ -77,6 +83,20 @@ func (w *Wallet) isWalletAddress(uh types.UnlockHash) bool {
 	return exists
 }
 
+// isWatchedAddress is a helper function that checks if an UnlockHash is one
+// of the wallet's watch-only addresses.
+func (w *Wallet) isWatchedAddress(uh types.UnlockHash) bool {
+	_, exists := w.watchedAddrs[uh]
+	return exists
+}
+
+// isRelevantAddress is a helper function that checks if the wallet tracks the
+// history of an UnlockHash, either because it is a wallet address or because
+// it is watched.
+func (w *Wallet) isRelevantAddress(uh types.UnlockHash) bool {
+	return w.isWalletAddress(uh) || w.isWatchedAddress(uh)
+}
+
 // updateLookahead uses a consensus change to update the seed progress if one of the outputs
 // contains an unlock hash of the lookahead set. Returns true if a blockchain rescan is required
 func (w *Wallet) updateLookahead(tx *bolt.Tx, cc modules.ConsensusChange) (bool, error) {
@@ -106,6 +126,20 @@ func (w *Wallet) updateLookahead(tx *bolt.Tx, cc modules.ConsensusChange) (bool,
 // outputs as understood by the wallet.
 func (w *Wallet) updateConfirmedSet(tx *bolt.Tx, cc modules.ConsensusChange) error {
 	for _, diff := range cc.SiacoinOutputDiffs {
+		// Outputs of watch-only addresses are tracked separately.
+		if w.isWatchedAddress(diff.SiacoinOutput.UnlockHash) && !w.isWalletAddress(diff.SiacoinOutput.UnlockHash) {
+			var err error
+			if diff.Direction == modules.DiffApply {
+				err = dbPutWatchedSiacoinOutput(tx, diff.ID, diff.SiacoinOutput)
+			} else {
+				err = dbDeleteWatchedSiacoinOutput(tx, diff.ID)
+			}
+			if err != nil {
+				w.log.Severe("Could not update watched siacoin output:", err)
+				return err
+			}
+			continue
+		}
 		// Verify that the diff is relevant to the wallet.
 		if !w.isWalletAddress(diff.SiacoinOutput.UnlockHash) {
 			continue
@@ -125,6 +159,20 @@ func (w *Wallet) updateConfirmedSet(tx *bolt.Tx, cc modules.ConsensusChange) err
 		}
 	}
 	for _, diff := range cc.SiafundOutputDiffs {
+		// Outputs of watch-only addresses are tracked separately.
+		if w.isWatchedAddress(diff.SiafundOutput.UnlockHash) && !w.isWalletAddress(diff.SiafundOutput.UnlockHash) {
+			var err error
+			if diff.Direction == modules.DiffApply {
+				err = dbPutWatchedSiafundOutput(tx, diff.ID, diff.SiafundOutput)
+			} else {
+				err = dbDeleteWatchedSiafundOutput(tx, diff.ID)
+			}
+			if err != nil {
+				w.log.Severe("Could not update watched siafund output:", err)
+				return err
+			}
+			continue
+		}
 		// Verify that the diff is relevant to the wallet.
 		if !w.isWalletAddress(diff.SiafundOutput.UnlockHash) {
 			continue
@@ -177,6 +225,11 @@ func (w *Wallet) revertHistory(tx *bolt.Tx, reverted []types.Block) error {
 					w.log.Severe("Could not revert transaction:", err)
 					return err
 				}
+				w.queueEvent(modules.WalletEvent{
+					Type:          modules.WalletEventReverted,
+					TransactionID: txid,
+					Height:        pt.ConfirmationHeight,
+				})
 			}
 		}
 
@@ -194,6 +247,11 @@ func (w *Wallet) revertHistory(tx *bolt.Tx, reverted []types.Block) error {
 					w.log.Severe("Could not revert transaction:", err)
 					return err
 				}
+				w.queueEvent(modules.WalletEvent{
+					Type:          modules.WalletEventReverted,
+					TransactionID: pt.TransactionID,
+					Height:        pt.ConfirmationHeight,
+				})
 				break // there will only ever be one miner transaction
 			}
 		}
@@ -238,16 +296,43 @@ func computeSpentSiafundOutputSet(diffs []modules.SiafundOutputDiff) spentSiafun
 	return outputs
 }
 
+// computeDelayedSiacoinOutputSet scans a slice of delayed Siacoin output diffs
+// for created outputs and collects them in a map of SiacoinOutputID ->
+// SiacoinOutput.
+func computeDelayedSiacoinOutputSet(diffs []modules.DelayedSiacoinOutputDiff) delayedSiacoinOutputSet {
+	outputs := make(delayedSiacoinOutputSet)
+	for _, diff := range diffs {
+		if diff.Direction == modules.DiffApply {
+			// DiffApply means created.
+			outputs[diff.ID] = diff.SiacoinOutput
+		}
+	}
+	return outputs
+}
+
+// storageProofOutputs returns the valid proof outputs that a storage proof for
+// the file contract created, in order.
+func (outputs delayedSiacoinOutputSet) storageProofOutputs(fcid types.FileContractID) []types.SiacoinOutput {
+	var scos []types.SiacoinOutput
+	for i := uint64(0); ; i++ {
+		sco, exists := outputs[fcid.StorageProofOutputID(types.ProofValid, i)]
+		if !exists {
+			return scos
+		}
+		scos = append(scos, sco)
+	}
+}
+
 // computeProcessedTransactionsFromBlock searches all the miner payouts and
 // transactions in a block and computes a ProcessedTransaction slice containing
 // all of the transactions processed for the given block.
-func (w *Wallet) computeProcessedTransactionsFromBlock(tx *bolt.Tx, block types.Block, spentSiacoinOutputs spentSiacoinOutputSet, spentSiafundOutputs spentSiafundOutputSet, consensusHeight types.BlockHeight) []modules.ProcessedTransaction {
+func (w *Wallet) computeProcessedTransactionsFromBlock(tx *bolt.Tx, block types.Block, spentSiacoinOutputs spentSiacoinOutputSet, spentSiafundOutputs spentSiafundOutputSet, delayedSiacoinOutputs delayedSiacoinOutputSet, consensusHeight types.BlockHeight) []modules.ProcessedTransaction {
 	var pts []modules.ProcessedTransaction
 
 	// Find ProcessedTransactions from miner payouts.
 	relevant := false
 	for _, mp := range block.MinerPayouts {
-		relevant = relevant || w.isWalletAddress(mp.UnlockHash)
+		relevant = relevant || w.isRelevantAddress(mp.UnlockHash)
 	}
 	if relevant {
 		w.log.Println("Wallet has received new miner payouts:", block.ID())
@@ -277,16 +362,21 @@ func (w *Wallet) computeProcessedTransactionsFromBlock(tx *bolt.Tx, block types.
 		// Determine if transaction is relevant.
 		relevant := false
 		for _, sci := range txn.SiacoinInputs {
-			relevant = relevant || w.isWalletAddress(sci.UnlockConditions.UnlockHash())
+			relevant = relevant || w.isRelevantAddress(sci.UnlockConditions.UnlockHash())
 		}
 		for _, sco := range txn.SiacoinOutputs {
-			relevant = relevant || w.isWalletAddress(sco.UnlockHash)
+			relevant = relevant || w.isRelevantAddress(sco.UnlockHash)
 		}
 		for _, sfi := range txn.SiafundInputs {
-			relevant = relevant || w.isWalletAddress(sfi.UnlockConditions.UnlockHash())
+			relevant = relevant || w.isRelevantAddress(sfi.UnlockConditions.UnlockHash())
 		}
 		for _, sfo := range txn.SiafundOutputs {
-			relevant = relevant || w.isWalletAddress(sfo.UnlockHash)
+			relevant = relevant || w.isRelevantAddress(sfo.UnlockHash)
+		}
+		for _, sp := range txn.StorageProofs {
+			for _, sco := range delayedSiacoinOutputs.storageProofOutputs(sp.ParentID) {
+				relevant = relevant || w.isRelevantAddress(sco.UnlockHash)
+			}
 		}
 
 		// Only create a ProcessedTransaction if transaction is relevant.
@@ -387,6 +477,26 @@ func (w *Wallet) computeProcessedTransactionsFromBlock(tx *bolt.Tx, block types.
 			}
 		}
 
+		// The payouts of a storage proof are not part of the transaction, so
+		// they are taken from the delayed outputs that it created.
+		for _, sp := range txn.StorageProofs {
+			for i, sco := range delayedSiacoinOutputs.storageProofOutputs(sp.ParentID) {
+				po := modules.ProcessedOutput{
+					ID:             types.OutputID(sp.ParentID.StorageProofOutputID(types.ProofValid, uint64(i))),
+					FundType:       types.SpecifierStorageProofOutput,
+					MaturityHeight: consensusHeight + types.MaturityDelay,
+					WalletAddress:  w.isWalletAddress(sco.UnlockHash),
+					RelatedAddress: sco.UnlockHash,
+					Value:          sco.Value,
+				}
+				pt.Outputs = append(pt.Outputs, po)
+				// Log any wallet-relevant outputs.
+				if po.WalletAddress {
+					w.log.Println("\tStorage Proof Output:", po.ID, "::", po.Value.HumanString())
+				}
+			}
+		}
+
 		for _, fee := range txn.MinerFees {
 			pt.Outputs = append(pt.Outputs, modules.ProcessedOutput{
 				FundType:       types.SpecifierMinerFee,
@@ -404,6 +514,7 @@ func (w *Wallet) computeProcessedTransactionsFromBlock(tx *bolt.Tx, block types.
 func (w *Wallet) applyHistory(tx *bolt.Tx, cc modules.ConsensusChange) error {
 	spentSiacoinOutputs := computeSpentSiacoinOutputSet(cc.SiacoinOutputDiffs)
 	spentSiafundOutputs := computeSpentSiafundOutputSet(cc.SiafundOutputDiffs)
+	delayedSiacoinOutputs := computeDelayedSiacoinOutputSet(cc.DelayedSiacoinOutputDiffs)
 
 	for _, block := range cc.AppliedBlocks {
 		consensusHeight, err := dbGetConsensusHeight(tx)
@@ -419,12 +530,22 @@ func (w *Wallet) applyHistory(tx *bolt.Tx, cc modules.ConsensusChange) error {
 			}
 		}
 
-		pts := w.computeProcessedTransactionsFromBlock(tx, block, spentSiacoinOutputs, spentSiafundOutputs, consensusHeight)
-		for _, pt := range pts {
+		pts := w.computeProcessedTransactionsFromBlock(tx, block, spentSiacoinOutputs, spentSiafundOutputs, delayedSiacoinOutputs, consensusHeight)
+		for i, pt := range pts {
 			err := dbAppendProcessedTransaction(tx, pt)
 			if err != nil {
 				return errors.AddContext(err, "could not put processed transaction")
 			}
+			w.queueEvent(modules.WalletEvent{
+				Type:          modules.WalletEventConfirmed,
+				TransactionID: pt.TransactionID,
+				Height:        consensusHeight,
+				Confirmations: 1,
+				Transaction:   &pts[i],
+			})
+		}
+		if cc.Synced && w.wantsEvents() {
+			w.queueConfirmationEvents(tx, consensusHeight)
 		}
 	}
 
@@ -439,9 +560,19 @@ func (w *Wallet) ProcessConsensusChange(cc modules.ConsensusChange) {
 	}
 	defer w.tg.Done()
 
+	defer w.managedFlushEvents()
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	// Only report events for synced consensus changes, so that rescans and
+	// the initial sync do not report the whole history of the wallet.
+	numEvents := len(w.pendingEvents)
+	defer func() {
+		if !cc.Synced {
+			w.pendingEvents = w.pendingEvents[:numEvents]
+		}
+	}()
+
 	if needRescan, err := w.updateLookahead(w.dbTx, cc); err != nil {
 		w.log.Severe("ERROR: failed to update lookahead:", err)
 		w.dbRollback = true
@@ -478,9 +609,19 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.Transaction
 	}
 	defer w.tg.Done()
 
+	defer w.managedFlushEvents()
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	// The transaction pool resubmits its remaining sets after every block,
+	// remember the known transactions to report each of them only once.
+	known := make(map[types.TransactionID]struct{})
+	if w.wantsEvents() {
+		for _, upt := range w.unconfirmedProcessedTransactions {
+			known[upt.TransactionID] = struct{}{}
+		}
+	}
+
 	// Do the pruning first. If there are any pruned transactions, we will need
 	// to re-allocate the whole processed transactions array.
 	droppedTransactions := make(map[types.TransactionID]struct{})
@@ -537,10 +678,10 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.Transaction
 			// determine whether transaction is relevant to the wallet
 			relevant := false
 			for _, sci := range txn.SiacoinInputs {
-				relevant = relevant || w.isWalletAddress(sci.UnlockConditions.UnlockHash())
+				relevant = relevant || w.isRelevantAddress(sci.UnlockConditions.UnlockHash())
 			}
 			for _, sco := range txn.SiacoinOutputs {
-				relevant = relevant || w.isWalletAddress(sco.UnlockHash)
+				relevant = relevant || w.isRelevantAddress(sco.UnlockHash)
 			}
 
 			// only create a ProcessedTransaction if txn is relevant
@@ -580,6 +721,13 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.Transaction
 				})
 			}
 			w.unconfirmedProcessedTransactions = append(w.unconfirmedProcessedTransactions, pt)
+			if _, exists := known[pt.TransactionID]; !exists {
+				w.queueEvent(modules.WalletEvent{
+					Type:          modules.WalletEventUnconfirmed,
+					TransactionID: pt.TransactionID,
+					Transaction:   &pt,
+				})
+			}
 		}
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/coreos/bbolt"
 
@@ -75,6 +76,11 @@ type Wallet struct {
 	keys      map[types.UnlockHash]spendableKey
 	lookahead map[types.UnlockHash]uint64
 
+	// watchedAddrs are the watch-only addresses of the wallet. Their outputs
+	// and transactions are tracked like those of the wallet's own keys, but
+	// their outputs are stored separately and are never spent.
+	watchedAddrs map[types.UnlockHash]struct{}
+
 	// unconfirmedProcessedTransactions tracks unconfirmed transactions.
 	//
 	// TODO: Replace this field with a linked list. Currently when a new
@@ -111,6 +117,29 @@ type Wallet struct {
 	// defragDisabled determines if the wallet is set to defrag outputs once it
 	// reaches a certain threshold
 	defragDisabled bool
+
+	// coinSelection is the strategy that the wallet uses to choose the
+	// outputs that fund a transaction.
+	coinSelection modules.CoinSelectionStrategy
+
+	// spendingKeyRequired is set if the wallet has a spending key, see
+	// SetSpendingKey.
+	spendingKeyRequired bool
+
+	// autoLockTimeout is the time after which an unlocked wallet locks
+	// itself. autoLockTimer is the timer of the current unlock, and
+	// autoLockGen identifies it.
+	autoLockTimeout time.Duration
+	autoLockTimer   *time.Timer
+	autoLockGen     uint64
+
+	// Events are queued while the wallet processes an update and are
+	// delivered to the subscribers and webhooks once mu is released. The
+	// events of each webhook wait in its queue until they are posted.
+	eventSubscribers []modules.WalletEventSubscriber
+	pendingEvents    []modules.WalletEvent
+	webhooks         map[string]modules.WalletWebhook
+	webhookQueues    map[string]*webhookQueue
 }
 
 // Height return the internal processed consensus height of the wallet
@@ -156,8 +185,13 @@ func NewCustomWallet(cs modules.ConsensusSet, tpool modules.TransactionPool, per
 		cs:    cs,
 		tpool: tpool,
 
-		keys:      make(map[types.UnlockHash]spendableKey),
-		lookahead: make(map[types.UnlockHash]uint64),
+		keys:          make(map[types.UnlockHash]spendableKey),
+		lookahead:     make(map[types.UnlockHash]uint64),
+		watchedAddrs:  make(map[types.UnlockHash]struct{}),
+		webhooks:      make(map[string]modules.WalletWebhook),
+		webhookQueues: make(map[string]*webhookQueue),
+
+		coinSelection: modules.CoinSelectionLargestFirst,
 
 		unconfirmedSets: make(map[modules.TransactionSetID][]types.TransactionID),
 
@@ -259,20 +293,36 @@ func (w *Wallet) Settings() (modules.WalletSettings, error) {
 		return modules.WalletSettings{}, modules.ErrWalletShutdown
 	}
 	defer w.tg.Done()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 	return modules.WalletSettings{
-		NoDefrag: w.defragDisabled,
+		NoDefrag:      w.defragDisabled,
+		CoinSelection: w.coinSelection,
 	}, nil
 }
 
-// SetSettings will update the settings for the wallet.
+// SetSettings will update the settings for the wallet. The settings are
+// persisted. An empty coin selection strategy selects the default strategy.
 func (w *Wallet) SetSettings(s modules.WalletSettings) error {
 	if err := w.tg.Add(); err != nil {
 		return modules.ErrWalletShutdown
 	}
 	defer w.tg.Done()
 
+	switch s.CoinSelection {
+	case "":
+		s.CoinSelection = modules.CoinSelectionLargestFirst
+	case modules.CoinSelectionLargestFirst, modules.CoinSelectionSmallestFirst, modules.CoinSelectionBranchAndBound:
+	default:
+		return modules.ErrUnknownCoinSelection
+	}
+
 	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.defragDisabled = s.NoDefrag
-	w.mu.Unlock()
-	return nil
+	w.coinSelection = s.CoinSelection
+	if err := dbPutWalletSettings(w.dbTx, s); err != nil {
+		return err
+	}
+	return w.syncDB()
 }
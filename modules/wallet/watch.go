@@ -0,0 +1,192 @@
+package wallet
+
+// watch.go implements watch-only addresses. The wallet tracks the outputs and
+// transactions of a watch-only address like those of its own addresses, but
+// because it does not know the secret key of the address, the outputs are
+// stored in separate buckets and are never used to fund transactions.
+
+import (
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/errors"
+
+	"github.com/coreos/bbolt"
+)
+
+// resetHistory clears the transaction history and consensus progress of the
+// wallet, so that the next subscription to the consensus set rebuilds it from
+// the beginning of the blockchain.
+func (w *Wallet) resetHistory(tx *bolt.Tx) error {
+	for _, bucket := range [][]byte{bucketProcessedTransactions, bucketProcessedTxnIndex, bucketAddrTransactions} {
+		if err := tx.DeleteBucket(bucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket(bucket); err != nil {
+			return err
+		}
+	}
+	w.unconfirmedProcessedTransactions = nil
+	if err := dbPutConsensusChangeID(tx, modules.ConsensusChangeBeginning); err != nil {
+		return err
+	}
+	return dbPutConsensusHeight(tx, 0)
+}
+
+// AddWatchAddresses adds watch-only addresses to the wallet. Unless unused is
+// set, the wallet rescans the blockchain to find the existing outputs and
+// transactions of the addresses. If the wallet has not been unlocked yet, the
+// rescan happens when it is unlocked for the first time. A rescan is not
+// possible if the consensus set is pruned.
+func (w *Wallet) AddWatchAddresses(addrs []types.UnlockHash, unused bool) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+
+	if !unused && w.cs.Pruned() {
+		return errPrunedRescan
+	}
+
+	if !w.scanLock.TryLock() {
+		return errScanInProgress
+	}
+	defer w.scanLock.Unlock()
+
+	var subscribed bool
+	err := func() error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		for _, addr := range addrs {
+			if err := dbPutWatchedAddr(w.dbTx, addr); err != nil {
+				return err
+			}
+			w.watchedAddrs[addr] = struct{}{}
+		}
+		subscribed = w.subscribed
+		if unused {
+			return w.syncDB()
+		}
+		if err := w.resetHistory(w.dbTx); err != nil {
+			return err
+		}
+		return w.syncDB()
+	}()
+	if err != nil {
+		return errors.AddContext(err, "unable to add watch-only addresses")
+	}
+	if unused || !subscribed {
+		return nil
+	}
+
+	// rescan the blockchain
+	w.cs.Unsubscribe(w)
+	w.tpool.Unsubscribe(w)
+
+	done := make(chan struct{})
+	go w.rescanMessage(done)
+	defer close(done)
+
+	err = w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning, w.tg.StopChan())
+	if err != nil {
+		return err
+	}
+	w.tpool.TransactionPoolSubscribe(w)
+	return nil
+}
+
+// RemoveWatchAddresses removes watch-only addresses from the wallet, together
+// with their outputs. Removed multisig addresses are forgotten. Transactions
+// that were recorded for the addresses stay in the history of the wallet.
+func (w *Wallet) RemoveWatchAddresses(addrs []types.UnlockHash) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	removed := make(map[types.UnlockHash]struct{})
+	for _, addr := range addrs {
+		if err := dbDeleteWatchedAddr(w.dbTx, addr); err != nil {
+			return err
+		}
+		if err := dbDeleteMultisigAddr(w.dbTx, addr); err != nil {
+			return err
+		}
+		delete(w.watchedAddrs, addr)
+		removed[addr] = struct{}{}
+	}
+
+	var scoids []types.SiacoinOutputID
+	err := dbForEachWatchedSiacoinOutput(w.dbTx, func(id types.SiacoinOutputID, sco types.SiacoinOutput) {
+		if _, ok := removed[sco.UnlockHash]; ok {
+			scoids = append(scoids, id)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range scoids {
+		if err := dbDeleteWatchedSiacoinOutput(w.dbTx, id); err != nil {
+			return err
+		}
+	}
+	var sfoids []types.SiafundOutputID
+	err = dbForEachWatchedSiafundOutput(w.dbTx, func(id types.SiafundOutputID, sfo types.SiafundOutput) {
+		if _, ok := removed[sfo.UnlockHash]; ok {
+			sfoids = append(sfoids, id)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range sfoids {
+		if err := dbDeleteWatchedSiafundOutput(w.dbTx, id); err != nil {
+			return err
+		}
+	}
+	return w.syncDB()
+}
+
+// WatchAddresses returns the watch-only addresses of the wallet.
+func (w *Wallet) WatchAddresses() ([]types.UnlockHash, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	addrs := make([]types.UnlockHash, 0, len(w.watchedAddrs))
+	for addr := range w.watchedAddrs {
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// WatchBalance returns the confirmed balance of the watch-only addresses of
+// the wallet. The balance is not part of the ConfirmedBalance of the wallet,
+// since the wallet cannot spend it.
+func (w *Wallet) WatchBalance() (siacoinBalance types.Currency, siafundBalance types.Currency, err error) {
+	if err := w.tg.Add(); err != nil {
+		return types.ZeroCurrency, types.ZeroCurrency, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// ensure durability of reported balance
+	if err = w.syncDB(); err != nil {
+		return
+	}
+	err = dbForEachWatchedSiacoinOutput(w.dbTx, func(_ types.SiacoinOutputID, sco types.SiacoinOutput) {
+		siacoinBalance = siacoinBalance.Add(sco.Value)
+	})
+	if err != nil {
+		return
+	}
+	err = dbForEachWatchedSiafundOutput(w.dbTx, func(_ types.SiafundOutputID, sfo types.SiafundOutput) {
+		siafundBalance = siafundBalance.Add(sfo.Value)
+	})
+	return
+}
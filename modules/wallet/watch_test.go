@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestWatchAddresses checks that the wallet tracks the outputs and
+// transactions of watch-only addresses separately from its spendable funds,
+// and that adding an address rescans the blockchain for it.
+func TestWatchAddresses(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Watch a new address and send coins to it.
+	var addr types.UnlockHash
+	fastrand.Read(addr[:])
+	if err := wt.wallet.AddWatchAddresses([]types.UnlockHash{addr}, true); err != nil {
+		t.Fatal(err)
+	}
+	sendValue := types.SiacoinPrecision.Mul64(3)
+	if _, err := wt.wallet.SendSiacoins(sendValue, addr); err != nil {
+		t.Fatal(err)
+	}
+	upts, err := wt.wallet.AddressUnconfirmedTransactions(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upts) != 1 {
+		t.Fatal("expected 1 unconfirmed transaction for the watched address, got", len(upts))
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The watched balance should contain the coins, and the spendable
+	// outputs of the wallet should not.
+	sc, sf, err := wt.wallet.WatchBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Equals(sendValue) || !sf.IsZero() {
+		t.Fatalf("expected watched balance of %v, got %v and %v SF", sendValue, sc, sf)
+	}
+	wt.wallet.mu.Lock()
+	dbForEachSiacoinOutput(wt.wallet.dbTx, func(_ types.SiacoinOutputID, sco types.SiacoinOutput) {
+		if sco.UnlockHash == addr {
+			t.Error("watched output was added to the spendable outputs")
+		}
+	})
+	wt.wallet.mu.Unlock()
+	confirmedBal, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pts, err := wt.wallet.AddressTransactions(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 1 {
+		t.Fatal("expected 1 transaction for the watched address, got", len(pts))
+	}
+
+	// Removing the address should remove its balance.
+	if err := wt.wallet.RemoveWatchAddresses([]types.UnlockHash{addr}); err != nil {
+		t.Fatal(err)
+	}
+	if addrs, err := wt.wallet.WatchAddresses(); err != nil || len(addrs) != 0 {
+		t.Fatal("address was not removed:", addrs, err)
+	}
+	if sc, _, err := wt.wallet.WatchBalance(); err != nil || !sc.IsZero() {
+		t.Fatal("balance of removed address was not removed:", sc, err)
+	}
+
+	// Adding the address again should find its outputs through a rescan.
+	if err := wt.wallet.AddWatchAddresses([]types.UnlockHash{addr}, false); err != nil {
+		t.Fatal(err)
+	}
+	if addrs, err := wt.wallet.WatchAddresses(); err != nil || len(addrs) != 1 || addrs[0] != addr {
+		t.Fatal("address was not added:", addrs, err)
+	}
+	sc, _, err = wt.wallet.WatchBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Equals(sendValue) {
+		t.Fatalf("rescan did not find the watched balance: expected %v, got %v", sendValue, sc)
+	}
+	pts, err = wt.wallet.AddressTransactions(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 1 {
+		t.Fatal("expected 1 transaction for the watched address after the rescan, got", len(pts))
+	}
+	if confirmedBal2, _, _, err := wt.wallet.ConfirmedBalance(); err != nil || !confirmedBal2.Equals(confirmedBal) {
+		t.Fatal("rescan changed the confirmed balance:", confirmedBal, confirmedBal2, err)
+	}
+}
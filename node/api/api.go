@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -156,6 +157,15 @@ func WriteJSON(w http.ResponseWriter, obj interface{}) {
 	}
 }
 
+// WriteCSV writes the records to the ResponseWriter as comma separated values.
+// The first record is expected to be the header row. The Content-Type of the
+// response header is set accordingly.
+func WriteCSV(w http.ResponseWriter, records [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	cw := csv.NewWriter(w)
+	cw.WriteAll(records)
+}
+
 // WriteSuccess writes the HTTP header with status 204 No Content to the
 // ResponseWriter. WriteSuccess should only be used to indicate that the
 // requested action succeeded AND there is no data to return.
@@ -24,6 +24,10 @@ type Client struct {
 	// UserAgent must match the User-Agent required by the siad server. If not
 	// set, it defaults to "Sia-Agent".
 	UserAgent string
+
+	// SpendingPassword is sent with the requests that spend from the wallet.
+	// It is only needed if the wallet has a spending password.
+	SpendingPassword string
 }
 
 // New creates a new Client using the provided address.
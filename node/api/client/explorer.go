@@ -0,0 +1,26 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/NebulousLabs/Sia/node/api"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// ExplorerAddressGet requests the /explorer/addresses/:addr api resource
+func (c *Client) ExplorerAddressGet(addr types.UnlockHash) (eag api.ExplorerAddressGET, err error) {
+	err = c.get("/explorer/addresses/"+addr.String(), &eag)
+	return
+}
+
+// ExplorerAddressesGet requests the /explorer/addresses api resource
+func (c *Client) ExplorerAddressesGet(count int) (eag api.ExplorerAddressesGET, err error) {
+	err = c.get(fmt.Sprintf("/explorer/addresses?count=%v", count), &eag)
+	return
+}
+
+// ExplorerSupplyGet requests the /explorer/supply api resource
+func (c *Client) ExplorerSupplyGet() (esg api.ExplorerSupplyGET, err error) {
+	err = c.get("/explorer/supply", &esg)
+	return
+}
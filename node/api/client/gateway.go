@@ -1,6 +1,12 @@
 package client
 
 import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/node/api"
 	"github.com/NebulousLabs/errors"
@@ -30,8 +36,46 @@ func (c *Client) GatewayDisconnectPost(address modules.NetAddress) (err error) {
 	return
 }
 
+// GatewayRateLimitPost uses the /gateway endpoint to change the global
+// bandwidth limits of the gateway in bytes per second. Zero means unlimited.
+func (c *Client) GatewayRateLimitPost(downloadSpeed, uploadSpeed int64) (err error) {
+	values := url.Values{}
+	values.Set("maxdownloadspeed", strconv.FormatInt(downloadSpeed, 10))
+	values.Set("maxuploadspeed", strconv.FormatInt(uploadSpeed, 10))
+	err = c.post("/gateway", values.Encode(), nil)
+	return
+}
+
 // GatewayGet requests the /gateway api resource
 func (c *Client) GatewayGet() (gwg api.GatewayGET, err error) {
 	err = c.get("/gateway", &gwg)
 	return
 }
+
+// GatewayBlacklistGet requests the /gateway/blacklist api resource
+func (c *Client) GatewayBlacklistGet() (gbg api.GatewayBlacklistGET, err error) {
+	err = c.get("/gateway/blacklist", &gbg)
+	return
+}
+
+// GatewayBlacklistAddPost uses the /gateway/blacklist endpoint to blacklist
+// hosts for the given duration. A duration of zero blacklists the hosts until
+// they are removed.
+func (c *Client) GatewayBlacklistAddPost(hosts []string, duration time.Duration) (err error) {
+	values := url.Values{}
+	values.Set("action", "add")
+	values.Set("addresses", strings.Join(hosts, ","))
+	values.Set("duration", fmt.Sprint(uint64(duration.Seconds())))
+	err = c.post("/gateway/blacklist", values.Encode(), nil)
+	return
+}
+
+// GatewayBlacklistRemovePost uses the /gateway/blacklist endpoint to remove
+// hosts from the blacklist.
+func (c *Client) GatewayBlacklistRemovePost(hosts []string) (err error) {
+	values := url.Values{}
+	values.Set("action", "remove")
+	values.Set("addresses", strings.Join(hosts, ","))
+	err = c.post("/gateway/blacklist", values.Encode(), nil)
+	return
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/node/api"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 // HostParam is a parameter in the host's settings that can be changed via the
@@ -47,6 +48,27 @@ const (
 	HostParamMaxReviseBatchSize = HostParam("maxrevisebatchsize")
 	// HostParamNetAddress is the announced netaddress of the host.
 	HostParamNetAddress = HostParam("netaddress")
+	// HostParamMaxConnections is the maximum number of concurrent connections
+	// of the host.
+	HostParamMaxConnections = HostParam("maxconnections")
+	// HostParamMaxConnectionsPerIP is the maximum number of concurrent
+	// connections of the host per remote IP address.
+	HostParamMaxConnectionsPerIP = HostParam("maxconnectionsperip")
+	// HostParamMaxDownloadSpeed is the maximum combined download speed of the
+	// host's connections in bytes/second.
+	HostParamMaxDownloadSpeed = HostParam("maxdownloadspeed")
+	// HostParamMaxUploadSpeed is the maximum combined upload speed of the
+	// host's connections in bytes/second.
+	HostParamMaxUploadSpeed = HostParam("maxuploadspeed")
+	// HostParamMaxRenterCollateral is the maximum collateral the host locks
+	// in the contracts of a single renter in hastings.
+	HostParamMaxRenterCollateral = HostParam("maxrentercollateral")
+	// HostParamMinRenewFileSize is the minimum amount of data in bytes that a
+	// contract needs to store to be renewed.
+	HostParamMinRenewFileSize = HostParam("minrenewfilesize")
+	// HostParamMaxRenewExtension is the maximum number of blocks by which a
+	// renewal may extend a contract.
+	HostParamMaxRenewExtension = HostParam("maxrenewextension")
 )
 
 // HostAnnouncePost uses the /host/announce endpoint to announce the host to
@@ -82,6 +104,46 @@ func (c *Client) HostGet() (hg api.HostGET, err error) {
 	return
 }
 
+// HostLedgerGet requests the /host/ledger endpoint for the ledger entries
+// between the start and end heights.
+func (c *Client) HostLedgerGet(start, end types.BlockHeight) (hlg api.HostLedgerGET, err error) {
+	err = c.get(fmt.Sprintf("/host/ledger?startheight=%v&endheight=%v", start, end), &hlg)
+	return
+}
+
+// HostLedgerCSVGet requests the /host/ledger endpoint for the ledger entries
+// between the start and end heights, encoded as comma separated values.
+func (c *Client) HostLedgerCSVGet(start, end types.BlockHeight) ([]byte, error) {
+	return c.getRawResponse(fmt.Sprintf("/host/ledger?startheight=%v&endheight=%v&format=csv", start, end))
+}
+
+// HostLedgerSummaryGet requests the /host/ledger/summary endpoint for the
+// ledger totals between the start and end heights in periods of the provided
+// number of blocks.
+func (c *Client) HostLedgerSummaryGet(start, end, period types.BlockHeight) (hlsg api.HostLedgerSummaryGET, err error) {
+	err = c.get(fmt.Sprintf("/host/ledger/summary?startheight=%v&endheight=%v&period=%v", start, end, period), &hlsg)
+	return
+}
+
+// HostMaintenanceGet requests the /host/maintenance endpoint for the
+// maintenance state of the host and the next downtime window of at least
+// minDuration blocks.
+func (c *Client) HostMaintenanceGet(minDuration types.BlockHeight) (hmg api.HostMaintenanceGET, err error) {
+	err = c.get(fmt.Sprintf("/host/maintenance?minduration=%v", minDuration), &hmg)
+	return
+}
+
+// HostMaintenancePost uses the /host/maintenance endpoint to enable or disable
+// maintenance mode. If announce is set when enabling maintenance mode, the
+// host re-announces itself when maintenance mode is disabled.
+func (c *Client) HostMaintenancePost(enabled, announce bool) (err error) {
+	values := url.Values{}
+	values.Set("enabled", strconv.FormatBool(enabled))
+	values.Set("announce", strconv.FormatBool(announce))
+	err = c.post("/host/maintenance", values.Encode(), nil)
+	return
+}
+
 // HostModifySettingPost uses the /host endpoint to change a param of the host
 // settings to a certain value.
 func (c *Client) HostModifySettingPost(param HostParam, value interface{}) (err error) {
@@ -89,6 +151,25 @@ func (c *Client) HostModifySettingPost(param HostParam, value interface{}) (err
 	return
 }
 
+// HostRentersGet requests the /host/renters endpoint.
+func (c *Client) HostRentersGet() (hrg api.HostRentersGET, err error) {
+	err = c.get("/host/renters", &hrg)
+	return
+}
+
+// HostRentersPost uses the /host/renters endpoint to set the quota of the
+// renter with the provided public key.
+func (c *Client) HostRentersPost(spk types.SiaPublicKey, quota modules.HostRenterQuota) (err error) {
+	values := url.Values{}
+	values.Set("publickey", spk.String())
+	values.Set("maxstorage", strconv.FormatUint(quota.MaxStorage, 10))
+	values.Set("maxbandwidth", strconv.FormatUint(quota.MaxBandwidth, 10))
+	values.Set("bandwidthperiod", strconv.FormatUint(uint64(quota.BandwidthPeriod), 10))
+	values.Set("maxconnections", strconv.FormatUint(quota.MaxConnections, 10))
+	err = c.post("/host/renters", values.Encode(), nil)
+	return
+}
+
 // HostStorageFoldersAddPost uses the /host/storage/folders/add api endpoint to
 // add a storage folder to a host
 func (c *Client) HostStorageFoldersAddPost(path string, size uint64) (err error) {
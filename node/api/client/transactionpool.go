@@ -1,6 +1,8 @@
 package client
 
 import (
+	"encoding/base64"
+	"fmt"
 	"net/url"
 
 	"github.com/NebulousLabs/Sia/encoding"
@@ -14,12 +16,21 @@ func (c *Client) TransactionPoolFeeGet() (tfg api.TpoolFeeGET, err error) {
 	return
 }
 
+// TransactionPoolFeeTargetGet uses the /tpool/fee endpoint to get the fee
+// for being confirmed within target blocks.
+func (c *Client) TransactionPoolFeeTargetGet(target types.BlockHeight) (tftg api.TpoolFeeTargetGET, err error) {
+	err = c.get(fmt.Sprintf("/tpool/fee?target=%v", target), &tftg)
+	return
+}
+
 // TransactionPoolRawPost uses the /tpool/raw endpoint to send a raw
 // transaction to the transaction pool.
-func (c *Client) TransactionPoolRawPost(txn types.Transaction, parents types.Transaction) (err error) {
+func (c *Client) TransactionPoolRawPost(txn types.Transaction, parents []types.Transaction) (err error) {
 	values := url.Values{}
-	values.Set("transaction", string(encoding.Marshal(txn)))
-	values.Set("parents", string(encoding.Marshal(parents)))
+	values.Set("transaction", base64.StdEncoding.EncodeToString(encoding.Marshal(txn)))
+	if len(parents) != 0 {
+		values.Set("parents", base64.StdEncoding.EncodeToString(encoding.Marshal(parents)))
+	}
 	err = c.post("/tpool/raw", values.Encode(), nil)
 	return
 }
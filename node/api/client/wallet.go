@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/node/api"
 	"github.com/NebulousLabs/Sia/types"
 )
@@ -23,6 +26,63 @@ func (c *Client) WalletAddressesGet() (wag api.WalletAddressesGET, err error) {
 	return
 }
 
+// WalletAddressesBalanceGet requests the wallets known addresses together
+// with their balances from the /wallet/addresses endpoint.
+func (c *Client) WalletAddressesBalanceGet() (wag api.WalletAddressesGET, err error) {
+	err = c.get("/wallet/addresses?withbalance=true", &wag)
+	return
+}
+
+// WalletAddressBalanceGet requests the /wallet/address/:addr/balance endpoint
+// for the balance of an address.
+func (c *Client) WalletAddressBalanceGet(addr types.UnlockHash) (ab modules.AddressBalance, err error) {
+	err = c.get(fmt.Sprintf("/wallet/address/%v/balance", addr), &ab)
+	return
+}
+
+// WalletBumpFeePost uses the /wallet/bumpfee/:txid api endpoint to raise the
+// fee of an unconfirmed transaction. A zero fee lets the wallet choose the
+// fee.
+func (c *Client) WalletBumpFeePost(txid types.TransactionID, fee types.Currency, method modules.BumpFeeMethod) (wbfp api.WalletBumpFeePOST, err error) {
+	values := url.Values{}
+	values.Set("method", string(method))
+	if !fee.IsZero() {
+		values.Set("fee", fee.String())
+	}
+	c.setSpendingPassword(values)
+	err = c.post("/wallet/bumpfee/"+txid.String(), values.Encode(), &wbfp)
+	return
+}
+
+// WalletLabelsGet requests the /wallet/labels endpoint for the labels of all
+// labeled transactions and addresses.
+func (c *Client) WalletLabelsGet() (wlg api.WalletLabelsGET, err error) {
+	err = c.get("/wallet/labels", &wlg)
+	return
+}
+
+// WalletAddressLabelPost uses the /wallet/labels endpoint to label an
+// address.
+func (c *Client) WalletAddressLabelPost(addr types.UnlockHash, label modules.WalletLabel) (err error) {
+	values := url.Values{}
+	values.Set("address", addr.String())
+	values.Set("label", label.Label)
+	values.Set("note", label.Note)
+	err = c.post("/wallet/labels", values.Encode(), nil)
+	return
+}
+
+// WalletTransactionLabelPost uses the /wallet/labels endpoint to label a
+// transaction.
+func (c *Client) WalletTransactionLabelPost(txid types.TransactionID, label modules.WalletLabel) (err error) {
+	values := url.Values{}
+	values.Set("transactionid", txid.String())
+	values.Set("label", label.Label)
+	values.Set("note", label.Note)
+	err = c.post("/wallet/labels", values.Encode(), nil)
+	return
+}
+
 // WalletChangePasswordPost uses the /wallet/changepassword endpoint to change
 // the wallet's password.
 func (c *Client) WalletChangePasswordPost(currentPassword, newPassword string) (err error) {
@@ -33,6 +93,26 @@ func (c *Client) WalletChangePasswordPost(currentPassword, newPassword string) (
 	return
 }
 
+// WalletSpendingPasswordPost uses the /wallet/spendingpassword endpoint to
+// set the password that is required to spend from the wallet. An empty
+// spending password removes it.
+func (c *Client) WalletSpendingPasswordPost(encryptionPassword, spendingPassword string) (err error) {
+	values := url.Values{}
+	values.Set("encryptionpassword", encryptionPassword)
+	values.Set("spendingpassword", spendingPassword)
+	err = c.post("/wallet/spendingpassword", values.Encode(), nil)
+	return
+}
+
+// setSpendingPassword adds the spending password of the client to the values
+// of a request that signs transactions with the keys of the wallet or exports
+// them.
+func (c *Client) setSpendingPassword(values url.Values) {
+	if c.SpendingPassword != "" {
+		values.Set("spendingpassword", c.SpendingPassword)
+	}
+}
+
 // WalletInitPost uses the /wallet/init endpoint to initialize and encrypt a
 // wallet
 func (c *Client) WalletInitPost(password string, force bool) (wip api.WalletInitPOST, err error) {
@@ -66,6 +146,128 @@ func (c *Client) WalletLockPost() (err error) {
 	return
 }
 
+// WalletOutputsGet requests the /wallet/outputs endpoint and returns the
+// spendable siacoin outputs of the wallet.
+func (c *Client) WalletOutputsGet() (wog api.WalletOutputsGET, err error) {
+	err = c.get("/wallet/outputs", &wog)
+	return
+}
+
+// WalletOutputsLockPost uses the /wallet/outputs/lock endpoint to prevent
+// the wallet from choosing the outputs to fund transactions.
+func (c *Client) WalletOutputsLockPost(ids []types.SiacoinOutputID) (err error) {
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	values := url.Values{}
+	values.Set("ids", string(idsJSON))
+	err = c.post("/wallet/outputs/lock", values.Encode(), nil)
+	return
+}
+
+// WalletOutputsUnlockPost uses the /wallet/outputs/unlock endpoint to unlock
+// outputs that were locked with WalletOutputsLockPost.
+func (c *Client) WalletOutputsUnlockPost(ids []types.SiacoinOutputID) (err error) {
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	values := url.Values{}
+	values.Set("ids", string(idsJSON))
+	err = c.post("/wallet/outputs/unlock", values.Encode(), nil)
+	return
+}
+
+// WalletPreparePost uses the /wallet/prepare endpoint to build an unsigned
+// transaction that sends the outputs. If watchOnly is set, the transaction is
+// funded from the watch-only addresses with the provided unlock conditions.
+func (c *Client) WalletPreparePost(outputs []types.SiacoinOutput, watchOnly bool, ucs []types.UnlockConditions) (wpp api.WalletPreparePOST, err error) {
+	outputsJSON, err := json.Marshal(outputs)
+	if err != nil {
+		return api.WalletPreparePOST{}, err
+	}
+	values := url.Values{}
+	values.Set("outputs", string(outputsJSON))
+	values.Set("watchonly", strconv.FormatBool(watchOnly))
+	if len(ucs) != 0 {
+		ucsJSON, err := json.Marshal(ucs)
+		if err != nil {
+			return api.WalletPreparePOST{}, err
+		}
+		values.Set("unlockconditions", string(ucsJSON))
+	}
+	err = c.post("/wallet/prepare", values.Encode(), &wpp)
+	return
+}
+
+// WalletMultisigGet requests the /wallet/multisig endpoint and returns the
+// multisig addresses of the wallet.
+func (c *Client) WalletMultisigGet() (wmg api.WalletMultisigGET, err error) {
+	err = c.get("/wallet/multisig", &wmg)
+	return
+}
+
+// WalletMultisigPost uses the /wallet/multisig endpoint to create a multisig
+// address that requires the given number of signatures from the public keys.
+// If unused is set, the wallet does not rescan the blockchain for the
+// address.
+func (c *Client) WalletMultisigPost(publicKeys []types.SiaPublicKey, required uint64, unused bool) (wma api.WalletMultisigAddress, err error) {
+	pkStrs := make([]string, len(publicKeys))
+	for i := range publicKeys {
+		pkStrs[i] = publicKeys[i].String()
+	}
+	pkJSON, err := json.Marshal(pkStrs)
+	if err != nil {
+		return api.WalletMultisigAddress{}, err
+	}
+	values := url.Values{}
+	values.Set("publickeys", string(pkJSON))
+	values.Set("required", strconv.FormatUint(required, 10))
+	values.Set("unused", strconv.FormatBool(unused))
+	err = c.post("/wallet/multisig", values.Encode(), &wma)
+	return
+}
+
+// WalletMultisigSpendPost uses the /wallet/multisig/spend endpoint to build a
+// transaction that sends the outputs from a multisig address. The
+// transaction is signed with the keys of the wallet.
+func (c *Client) WalletMultisigSpendPost(addr types.UnlockHash, outputs []types.SiacoinOutput) (wmsp api.WalletMultisigSpendPOST, err error) {
+	outputsJSON, err := json.Marshal(outputs)
+	if err != nil {
+		return api.WalletMultisigSpendPOST{}, err
+	}
+	values := url.Values{}
+	values.Set("address", addr.String())
+	values.Set("outputs", string(outputsJSON))
+	c.setSpendingPassword(values)
+	err = c.post("/wallet/multisig/spend", values.Encode(), &wmsp)
+	return
+}
+
+// WalletPublicKeyGet requests the /wallet/publickey endpoint and returns a
+// new public key of the wallet.
+func (c *Client) WalletPublicKeyGet() (wpkg api.WalletPublicKeyGET, err error) {
+	err = c.get("/wallet/publickey", &wpkg)
+	return
+}
+
+// WalletSignPost uses the /wallet/sign endpoint to add the signatures of the
+// wallet to a transaction. If broadcast is set and the transaction is
+// complete, it is submitted to the transaction pool.
+func (c *Client) WalletSignPost(txn types.Transaction, broadcast bool) (wsp api.WalletSignPOST, err error) {
+	txnJSON, err := json.Marshal(txn)
+	if err != nil {
+		return api.WalletSignPOST{}, err
+	}
+	values := url.Values{}
+	values.Set("transaction", string(txnJSON))
+	values.Set("broadcast", strconv.FormatBool(broadcast))
+	c.setSpendingPassword(values)
+	err = c.post("/wallet/sign", values.Encode(), &wsp)
+	return
+}
+
 // WalletSeedPost uses the /wallet/seed endpoint to add a seed to the wallet's list
 // of seeds.
 func (c *Client) WalletSeedPost(seed, password string) (err error) {
@@ -79,7 +281,34 @@ func (c *Client) WalletSeedPost(seed, password string) (err error) {
 // WalletSeedsGet uses the /wallet/seeds endpoint to return the wallet's
 // current seeds.
 func (c *Client) WalletSeedsGet() (wsg api.WalletSeedsGET, err error) {
-	err = c.get("/wallet/seeds", &wsg)
+	values := url.Values{}
+	c.setSpendingPassword(values)
+	err = c.get("/wallet/seeds?"+values.Encode(), &wsg)
+	return
+}
+
+// WalletSettingsGet requests the /wallet/settings endpoint and returns the
+// settings of the wallet.
+func (c *Client) WalletSettingsGet() (wsg api.WalletSettingsGET, err error) {
+	err = c.get("/wallet/settings", &wsg)
+	return
+}
+
+// WalletCoinSelectionPost uses the /wallet/settings endpoint to change the
+// coin selection strategy of the wallet.
+func (c *Client) WalletCoinSelectionPost(strategy modules.CoinSelectionStrategy) (err error) {
+	values := url.Values{}
+	values.Set("coinselection", string(strategy))
+	err = c.post("/wallet/settings", values.Encode(), nil)
+	return
+}
+
+// WalletAutoLockPost uses the /wallet/settings endpoint to set the time after
+// which the unlocked wallet locks itself. Zero disables the auto-lock.
+func (c *Client) WalletAutoLockPost(timeout time.Duration) (err error) {
+	values := url.Values{}
+	values.Set("autolocktimeout", fmt.Sprint(uint64(timeout.Seconds())))
+	err = c.post("/wallet/settings", values.Encode(), nil)
 	return
 }
 
@@ -92,6 +321,7 @@ func (c *Client) WalletSiacoinsMultiPost(outputs []types.SiacoinOutput) (wsp api
 		return api.WalletSiacoinsPOST{}, err
 	}
 	values.Set("outputs", string(marshaledOutputs))
+	c.setSpendingPassword(values)
 	err = c.post("/wallet/siacoins", values.Encode(), &wsp)
 	return
 }
@@ -102,6 +332,37 @@ func (c *Client) WalletSiacoinsPost(amount types.Currency, destination types.Unl
 	values := url.Values{}
 	values.Set("amount", amount.String())
 	values.Set("destination", destination.String())
+	c.setSpendingPassword(values)
+	err = c.post("/wallet/siacoins", values.Encode(), &wsp)
+	return
+}
+
+// WalletSiacoinsTargetPost uses the /wallet/siacoins api endpoint to send
+// money to a single address, paying the fee for being confirmed within
+// target blocks.
+func (c *Client) WalletSiacoinsTargetPost(amount types.Currency, destination types.UnlockHash, target types.BlockHeight) (wsp api.WalletSiacoinsPOST, err error) {
+	values := url.Values{}
+	values.Set("amount", amount.String())
+	values.Set("destination", destination.String())
+	values.Set("target", fmt.Sprint(target))
+	c.setSpendingPassword(values)
+	err = c.post("/wallet/siacoins", values.Encode(), &wsp)
+	return
+}
+
+// WalletSiacoinsFromOutputsPost uses the /wallet/siacoins api endpoint to
+// send money to a single address, funded from the wallet outputs with the
+// given IDs.
+func (c *Client) WalletSiacoinsFromOutputsPost(amount types.Currency, destination types.UnlockHash, ids []types.SiacoinOutputID) (wsp api.WalletSiacoinsPOST, err error) {
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return api.WalletSiacoinsPOST{}, err
+	}
+	values := url.Values{}
+	values.Set("amount", amount.String())
+	values.Set("destination", destination.String())
+	values.Set("outputids", string(idsJSON))
+	c.setSpendingPassword(values)
 	err = c.post("/wallet/siacoins", values.Encode(), &wsp)
 	return
 }
@@ -112,6 +373,7 @@ func (c *Client) WalletSiafundsPost(amount types.Currency, destination types.Unl
 	values := url.Values{}
 	values.Set("amount", amount.String())
 	values.Set("destination", destination.String())
+	c.setSpendingPassword(values)
 	err = c.post("/wallet/siafunds", values.Encode(), &wsp)
 	return
 }
@@ -131,6 +393,7 @@ func (c *Client) WalletSiagKeyPost(keyfiles, password string) (err error) {
 func (c *Client) WalletSweepPost(seed string) (wsp api.WalletSweepPOST, err error) {
 	values := url.Values{}
 	values.Set("seed", seed)
+	c.setSpendingPassword(values)
 	err = c.post("/wallet/sweep/seed", values.Encode(), &wsp)
 	return
 }
@@ -143,6 +406,29 @@ func (c *Client) WalletTransactionsGet(startHeight types.BlockHeight, endHeight
 	return
 }
 
+// WalletTimelockedGet requests the /wallet/timelocked endpoint for the
+// timelocked addresses of the wallet.
+func (c *Client) WalletTimelockedGet() (wtg api.WalletTimelockedGET, err error) {
+	err = c.get("/wallet/timelocked", &wtg)
+	return
+}
+
+// WalletTimelockedPost uses the /wallet/timelocked endpoint to create an
+// address whose outputs cannot be spent before the unlock height.
+func (c *Client) WalletTimelockedPost(unlockHeight types.BlockHeight) (ta modules.TimelockedAddress, err error) {
+	values := url.Values{}
+	values.Set("unlockheight", fmt.Sprint(unlockHeight))
+	err = c.post("/wallet/timelocked", values.Encode(), &ta)
+	return
+}
+
+// WalletTransactionsCSVGet requests the /wallet/transactions endpoint for the
+// confirmed transactions between the start and end heights, encoded as comma
+// separated values.
+func (c *Client) WalletTransactionsCSVGet(startHeight, endHeight types.BlockHeight) ([]byte, error) {
+	return c.getRawResponse(fmt.Sprintf("/wallet/transactions?startheight=%v&endheight=%v&format=csv", startHeight, endHeight))
+}
+
 // WalletTransactionGet requests the /wallet/transaction/:id api resource for a
 // certain TransactionID.
 func (c *Client) WalletTransactionGet(id types.TransactionID) (wtg api.WalletTransactionGETid, err error) {
@@ -159,6 +445,42 @@ func (c *Client) WalletUnlockPost(password string) (err error) {
 	return
 }
 
+// WalletWatchGet requests the /wallet/watch endpoint for the watch-only
+// addresses of the wallet and their balance.
+func (c *Client) WalletWatchGet() (wwg api.WalletWatchGET, err error) {
+	err = c.get("/wallet/watch", &wwg)
+	return
+}
+
+// WalletWatchAddPost uses the /wallet/watch endpoint to add watch-only
+// addresses to the wallet. If unused is set, the wallet does not rescan the
+// blockchain for the addresses.
+func (c *Client) WalletWatchAddPost(addrs []types.UnlockHash, unused bool) (err error) {
+	addrBytes, err := json.Marshal(addrs)
+	if err != nil {
+		return err
+	}
+	values := url.Values{}
+	values.Set("addresses", string(addrBytes))
+	values.Set("unused", strconv.FormatBool(unused))
+	err = c.post("/wallet/watch", values.Encode(), nil)
+	return
+}
+
+// WalletWatchRemovePost uses the /wallet/watch endpoint to remove watch-only
+// addresses from the wallet.
+func (c *Client) WalletWatchRemovePost(addrs []types.UnlockHash) (err error) {
+	addrBytes, err := json.Marshal(addrs)
+	if err != nil {
+		return err
+	}
+	values := url.Values{}
+	values.Set("addresses", string(addrBytes))
+	values.Set("remove", "true")
+	err = c.post("/wallet/watch", values.Encode(), nil)
+	return
+}
+
 // Wallet033xPost uses the /wallet/033x endpoint to load a v0.3.3.x wallet into
 // the current wallet.
 func (c *Client) Wallet033xPost(path, password string) (err error) {
@@ -168,3 +490,35 @@ func (c *Client) Wallet033xPost(path, password string) (err error) {
 	err = c.post("/wallet/033x", values.Encode(), nil)
 	return
 }
+
+// WalletWebhooksGet requests the /wallet/webhooks endpoint for the webhooks
+// of the wallet.
+func (c *Client) WalletWebhooksGet() (wwg api.WalletWebhooksGET, err error) {
+	err = c.get("/wallet/webhooks", &wwg)
+	return
+}
+
+// WalletWebhookPost uses the /wallet/webhooks endpoint to add a webhook that
+// the wallet posts its events to.
+func (c *Client) WalletWebhookPost(hook modules.WalletWebhook) (err error) {
+	var events []string
+	for _, t := range hook.Events {
+		events = append(events, string(t))
+	}
+	values := url.Values{}
+	values.Set("url", hook.URL)
+	values.Set("secret", hook.Secret)
+	values.Set("events", strings.Join(events, ","))
+	values.Set("confirmations", fmt.Sprint(hook.Confirmations))
+	err = c.post("/wallet/webhooks", values.Encode(), nil)
+	return
+}
+
+// WalletWebhookRemovePost uses the /wallet/webhooks/remove endpoint to remove
+// a webhook.
+func (c *Client) WalletWebhookRemovePost(hookURL string) (err error) {
+	values := url.Values{}
+	values.Set("url", hookURL)
+	err = c.post("/wallet/webhooks/remove", values.Encode(), nil)
+	return
+}
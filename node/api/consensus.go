@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 
 	"github.com/julienschmidt/httprouter"
@@ -195,7 +196,7 @@ func (api *API) consensusBlocksHandler(w http.ResponseWriter, req *http.Request,
 
 	var b types.Block
 	var h types.BlockHeight
-	var exists bool
+	var exists, pruned bool
 
 	// Handle request by id
 	if id != "" {
@@ -205,6 +206,7 @@ func (api *API) consensusBlocksHandler(w http.ResponseWriter, req *http.Request,
 			return
 		}
 		b, h, exists = api.cs.BlockByID(bid)
+		pruned = !exists && api.cs.BlockPruned(bid)
 	}
 	// Handle request by height
 	if height != "" {
@@ -213,8 +215,13 @@ func (api *API) consensusBlocksHandler(w http.ResponseWriter, req *http.Request,
 			return
 		}
 		b, exists = api.cs.BlockAtHeight(types.BlockHeight(h))
+		pruned = !exists && h <= api.cs.Height()
 	}
 	// Check if block was found
+	if pruned {
+		WriteError(w, Error{modules.ErrBlockPruned.Error()}, http.StatusBadRequest)
+		return
+	}
 	if !exists {
 		WriteError(w, Error{"block doesn't exist"}, http.StatusBadRequest)
 		return
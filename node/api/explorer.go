@@ -12,6 +12,16 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
+const (
+	// defaultRichListCount is the number of addresses returned by
+	// /explorer/addresses if no count is provided.
+	defaultRichListCount = 100
+
+	// maxRichListCount is the maximum number of addresses that can be
+	// requested from /explorer/addresses.
+	maxRichListCount = 1000
+)
+
 type (
 	// ExplorerBlock is a block with some extra information such as the id and
 	// height. This information is provided for programs that may not be
@@ -53,6 +63,25 @@ type (
 		modules.BlockFacts
 	}
 
+	// ExplorerAddressGET is the object returned by a GET request to
+	// /explorer/addresses/:addr.
+	ExplorerAddressGET struct {
+		modules.ExplorerAddressBalance
+	}
+
+	// ExplorerAddressesGET is the object returned by a GET request to
+	// /explorer/addresses. The addresses are ordered by siacoin balance, from
+	// largest to smallest.
+	ExplorerAddressesGET struct {
+		Addresses []modules.ExplorerAddressBalance `json:"addresses"`
+	}
+
+	// ExplorerSupplyGET is the object returned by a GET request to
+	// /explorer/supply.
+	ExplorerSupplyGET struct {
+		modules.ExplorerSupply
+	}
+
 	// ExplorerBlockGET is the object returned by a GET request to
 	// /explorer/block.
 	ExplorerBlockGET struct {
@@ -351,6 +380,50 @@ func (api *API) explorerHashHandler(w http.ResponseWriter, req *http.Request, ps
 	WriteError(w, Error{"unrecognized hash used as input to /explorer/hash"}, http.StatusBadRequest)
 }
 
+// explorerAddressHandler handles GET requests to /explorer/addresses/:addr.
+func (api *API) explorerAddressHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	addr, err := scanAddress(ps.ByName("addr"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	balance, exists := api.explorer.AddressBalance(addr)
+	if !exists {
+		WriteError(w, Error{"address does not appear in the blockchain"}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, ExplorerAddressGET{
+		ExplorerAddressBalance: balance,
+	})
+}
+
+// explorerAddressesHandler handles GET requests to /explorer/addresses, which
+// returns the addresses with the largest siacoin balances.
+func (api *API) explorerAddressesHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	count := defaultRichListCount
+	if c := req.FormValue("count"); c != "" {
+		_, err := fmt.Sscan(c, &count)
+		if err != nil {
+			WriteError(w, Error{"unable to parse count: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if count <= 0 || count > maxRichListCount {
+			WriteError(w, Error{fmt.Sprintf("count must be between 1 and %v", maxRichListCount)}, http.StatusBadRequest)
+			return
+		}
+	}
+	WriteJSON(w, ExplorerAddressesGET{
+		Addresses: api.explorer.RichList(count),
+	})
+}
+
+// explorerSupplyHandler handles GET requests to /explorer/supply.
+func (api *API) explorerSupplyHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, ExplorerSupplyGET{
+		ExplorerSupply: api.explorer.Supply(),
+	})
+}
+
 // explorerHandler handles API calls to /explorer
 func (api *API) explorerHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	facts := api.explorer.LatestBlockFacts()
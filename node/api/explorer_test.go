@@ -81,3 +81,49 @@ func TestIntegrationExplorerHashGet(t *testing.T) {
 		t.Error("wrong block type returned")
 	}
 }
+
+// TestIntegrationExplorerAddressAndSupplyGET probes the GET calls to
+// /explorer/addresses, /explorer/addresses/:addr and /explorer/supply.
+func TestIntegrationExplorerAddressAndSupplyGET(t *testing.T) {
+	t.Skip("Explorer has deadlock issues")
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var eas ExplorerAddressesGET
+	err = st.getAPI("/explorer/addresses?count=1", &eas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eas.Addresses) != 1 {
+		t.Fatal("expected 1 address, got", len(eas.Addresses))
+	}
+	var eag ExplorerAddressGET
+	err = st.getAPI("/explorer/addresses/"+eas.Addresses[0].UnlockHash.String(), &eag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !eag.SiacoinBalance.Equals(eas.Addresses[0].SiacoinBalance) {
+		t.Error("address balance does not match the rich list")
+	}
+	if err := st.getAPI("/explorer/addresses?count=0", &eas); err == nil {
+		t.Error("expected an error for an invalid count")
+	}
+
+	var esg ExplorerSupplyGET
+	err = st.getAPI("/explorer/supply", &esg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if esg.Height != st.server.api.cs.Height() {
+		t.Error("height not accurately reported by /explorer/supply")
+	}
+	if esg.Mined.IsZero() || esg.Circulating.IsZero() {
+		t.Error("supply is not reported")
+	}
+}
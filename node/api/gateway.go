@@ -1,7 +1,11 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/NebulousLabs/Sia/modules"
 
@@ -11,7 +15,18 @@ import (
 // GatewayGET contains the fields returned by a GET call to "/gateway".
 type GatewayGET struct {
 	NetAddress modules.NetAddress `json:"netaddress"`
+	NodeID     modules.NodeID     `json:"nodeid"`
 	Peers      []modules.Peer     `json:"peers"`
+
+	Bandwidth        modules.GatewayBandwidth `json:"bandwidth"`
+	MaxDownloadSpeed int64                    `json:"maxdownloadspeed"`
+	MaxUploadSpeed   int64                    `json:"maxuploadspeed"`
+}
+
+// GatewayBlacklistGET contains the fields returned by a GET call to
+// "/gateway/blacklist".
+type GatewayBlacklistGET struct {
+	Blacklist []modules.BlacklistEntry `json:"blacklist"`
 }
 
 // gatewayHandler handles the API call asking for the gatway status.
@@ -23,7 +38,39 @@ func (api *API) gatewayHandler(w http.ResponseWriter, req *http.Request, _ httpr
 	if peers == nil {
 		peers = make([]modules.Peer, 0)
 	}
-	WriteJSON(w, GatewayGET{api.gateway.Address(), peers})
+	downloadSpeed, uploadSpeed := api.gateway.RateLimits()
+	WriteJSON(w, GatewayGET{
+		NetAddress: api.gateway.Address(),
+		NodeID:     api.gateway.ID(),
+		Peers:      peers,
+
+		Bandwidth:        api.gateway.Bandwidth(),
+		MaxDownloadSpeed: downloadSpeed,
+		MaxUploadSpeed:   uploadSpeed,
+	})
+}
+
+// gatewayHandlerPOST handles the API call changing the rate limits of the
+// gateway.
+func (api *API) gatewayHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	downloadSpeed, uploadSpeed := api.gateway.RateLimits()
+	if d := req.FormValue("maxdownloadspeed"); d != "" {
+		if _, err := fmt.Sscan(d, &downloadSpeed); err != nil {
+			WriteError(w, Error{"unable to parse maxdownloadspeed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if u := req.FormValue("maxuploadspeed"); u != "" {
+		if _, err := fmt.Sscan(u, &uploadSpeed); err != nil {
+			WriteError(w, Error{"unable to parse maxuploadspeed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if err := api.gateway.SetRateLimits(downloadSpeed, uploadSpeed); err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
 }
 
 // gatewayConnectHandler handles the API call to add a peer to the gateway.
@@ -49,3 +96,48 @@ func (api *API) gatewayDisconnectHandler(w http.ResponseWriter, req *http.Reques
 
 	WriteSuccess(w)
 }
+
+// gatewayBlacklistHandlerGET handles the API call to list the blacklisted
+// hosts.
+func (api *API) gatewayBlacklistHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, GatewayBlacklistGET{api.gateway.Blacklist()})
+}
+
+// gatewayBlacklistHandlerPOST handles the API call to add hosts to or remove
+// hosts from the blacklist.
+func (api *API) gatewayBlacklistHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var hosts []string
+	for _, host := range strings.Split(req.FormValue("addresses"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		WriteError(w, Error{"no addresses provided"}, http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	switch req.FormValue("action") {
+	case "add":
+		var duration uint64
+		if d := req.FormValue("duration"); d != "" {
+			duration, err = strconv.ParseUint(d, 10, 32)
+			if err != nil {
+				WriteError(w, Error{"could not read 'duration': " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+		err = api.gateway.AddToBlacklist(hosts, time.Duration(duration)*time.Second)
+	case "remove":
+		err = api.gateway.RemoveFromBlacklist(hosts)
+	default:
+		WriteError(w, Error{"action must be 'add' or 'remove'"}, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
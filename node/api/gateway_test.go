@@ -1,7 +1,10 @@
 package api
 
 import (
+	"errors"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules/gateway"
@@ -25,6 +28,9 @@ func TestGatewayStatus(t *testing.T) {
 	if len(info.Peers) != 0 {
 		t.Fatal("/gateway gave bad peer list:", info.Peers)
 	}
+	if info.NodeID != st.server.api.gateway.ID() {
+		t.Fatal("/gateway gave the wrong node id:", info.NodeID)
+	}
 }
 
 // TestGatewayPeerConnect checks that /gateway/connect is adding a peer to the
@@ -63,6 +69,9 @@ func TestGatewayPeerConnect(t *testing.T) {
 	if len(info.Peers) != 1 || info.Peers[0].NetAddress != peer.Address() {
 		t.Fatal("/gateway/connect did not connect to peer", peer.Address())
 	}
+	if !info.Peers[0].Encrypted || info.Peers[0].NodeID != peer.ID() {
+		t.Fatal("connection to peer should be encrypted and authenticated:", info.Peers[0])
+	}
 }
 
 // TestGatewayPeerDisconnect checks that /gateway/disconnect removes the
@@ -111,3 +120,137 @@ func TestGatewayPeerDisconnect(t *testing.T) {
 		t.Fatal("/gateway/disconnect did not disconnect from peer", peer.Address())
 	}
 }
+
+// TestGatewayBlacklist checks that /gateway/blacklist adds hosts to and
+// removes hosts from the blacklist of the gateway.
+func TestGatewayBlacklist(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	peer, err := gateway.New("localhost:0", false, build.TempDir("api", t.Name()+"2", "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := peer.Close()
+		if err != nil {
+			panic(err)
+		}
+	}()
+	if err = st.stdPostAPI("/gateway/connect/"+string(peer.Address()), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	host := peer.Address().Host()
+	values := url.Values{}
+	values.Set("action", "add")
+	values.Set("addresses", host)
+	if err = st.stdPostAPI("/gateway/blacklist", values); err != nil {
+		t.Fatal(err)
+	}
+	var gbg GatewayBlacklistGET
+	if err = st.getAPI("/gateway/blacklist", &gbg); err != nil {
+		t.Fatal(err)
+	}
+	if len(gbg.Blacklist) != 1 || gbg.Blacklist[0].Host != host {
+		t.Fatal("host was not blacklisted:", gbg.Blacklist)
+	}
+	var info GatewayGET
+	if err = st.getAPI("/gateway", &info); err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Peers) != 0 {
+		t.Fatal("blacklisted peer is still connected")
+	}
+	if err = st.stdPostAPI("/gateway/connect/"+string(peer.Address()), nil); err == nil {
+		t.Fatal("expected connecting to a blacklisted peer to fail")
+	}
+
+	values.Set("action", "remove")
+	if err = st.stdPostAPI("/gateway/blacklist", values); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/gateway/blacklist", &gbg); err != nil {
+		t.Fatal(err)
+	}
+	if len(gbg.Blacklist) != 0 {
+		t.Fatal("host was not removed from the blacklist:", gbg.Blacklist)
+	}
+	if err = st.stdPostAPI("/gateway/connect/"+string(peer.Address()), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	values.Set("action", "ban")
+	if err = st.stdPostAPI("/gateway/blacklist", values); err == nil {
+		t.Fatal("expected an unknown action to be rejected")
+	}
+}
+
+// TestGatewayBandwidth checks that /gateway reports the traffic of the
+// gateway, and that its rate limits can be changed.
+func TestGatewayBandwidth(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	peer, err := gateway.New("localhost:0", false, build.TempDir("api", t.Name()+"2", "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := peer.Close()
+		if err != nil {
+			panic(err)
+		}
+	}()
+	if err = st.stdPostAPI("/gateway/connect/"+string(peer.Address()), nil); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		var info GatewayGET
+		if err := st.getAPI("/gateway", &info); err != nil {
+			return err
+		}
+		if info.Bandwidth.Upload == 0 || len(info.Bandwidth.RPCs) == 0 {
+			return errors.New("traffic was not counted")
+		}
+		if len(info.Peers) != 1 || info.Peers[0].Upload == 0 {
+			return errors.New("traffic of the peer was not counted")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := url.Values{}
+	values.Set("maxdownloadspeed", "1000000")
+	values.Set("maxuploadspeed", "2000000")
+	if err = st.stdPostAPI("/gateway", values); err != nil {
+		t.Fatal(err)
+	}
+	var info GatewayGET
+	if err = st.getAPI("/gateway", &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.MaxDownloadSpeed != 1e6 || info.MaxUploadSpeed != 2e6 {
+		t.Fatal("rate limits were not changed:", info.MaxDownloadSpeed, info.MaxUploadSpeed)
+	}
+	values.Set("maxuploadspeed", "-1")
+	if err = st.stdPostAPI("/gateway", values); err == nil {
+		t.Fatal("expected a negative rate limit to be rejected")
+	}
+}
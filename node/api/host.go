@@ -3,6 +3,7 @@ package api
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 
 	"github.com/NebulousLabs/Sia/build"
@@ -12,6 +13,12 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
+const (
+	// defaultLedgerSummaryPeriod is the number of blocks per period used by
+	// /host/ledger/summary when no period is provided, about one month.
+	defaultLedgerSummaryPeriod = types.BlockHeight(4320)
+)
+
 var (
 	// errNoPath is returned when a call fails to provide a nonempty string
 	// for the path parameter.
@@ -41,6 +48,33 @@ type (
 		WorkingStatus        modules.HostWorkingStatus        `json:"workingstatus"`
 	}
 
+	// HostLedgerGET contains the information that is returned after a GET
+	// request to /host/ledger - the revenue and collateral events of the host.
+	HostLedgerGET struct {
+		Entries []modules.HostLedgerEntry `json:"entries"`
+	}
+
+	// HostLedgerSummaryGET contains the information that is returned after a
+	// GET request to /host/ledger/summary - the host's ledger totalled per
+	// period.
+	HostLedgerSummaryGET struct {
+		Summaries []modules.HostLedgerSummary `json:"summaries"`
+	}
+
+	// HostMaintenanceGET contains the information that is returned after a
+	// GET request to /host/maintenance - the maintenance state of the host
+	// and the next window in which it can safely go offline.
+	HostMaintenanceGET struct {
+		modules.HostMaintenanceStatus
+	}
+
+	// HostRentersGET contains the information that is returned after a GET
+	// request to /host/renters - the quota and resource usage of every renter
+	// known to the host.
+	HostRentersGET struct {
+		Renters []modules.HostRenter `json:"renters"`
+	}
+
 	// HostEstimateScoreGET contains the information that is returned from a
 	// /host/estimatescore call.
 	HostEstimateScoreGET struct {
@@ -96,6 +130,142 @@ func (api *API) hostHandlerGET(w http.ResponseWriter, req *http.Request, _ httpr
 	WriteJSON(w, hg)
 }
 
+// parseLedgerRange parses the optional startheight and endheight query
+// strings of a request to /host/ledger. The start defaults to 0 and the end
+// defaults to the end of the ledger, which can also be requested with -1.
+func parseLedgerRange(req *http.Request) (start, end types.BlockHeight, err error) {
+	end = types.BlockHeight(math.MaxUint64)
+	if s := req.FormValue("startheight"); s != "" {
+		if _, err = fmt.Sscan(s, &start); err != nil {
+			return 0, 0, errors.New("parsing integer value for parameter `startheight` failed: " + err.Error())
+		}
+	}
+	if e := req.FormValue("endheight"); e != "" && e != "-1" {
+		if _, err = fmt.Sscan(e, &end); err != nil {
+			return 0, 0, errors.New("parsing integer value for parameter `endheight` failed: " + err.Error())
+		}
+	}
+	return start, end, nil
+}
+
+// hostLedgerHandlerGET handles GET requests to the /host/ledger API endpoint,
+// returning the ledger entries of the host either as JSON or, if the format
+// query string is set to csv, as comma separated values.
+func (api *API) hostLedgerHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	start, end, err := parseLedgerRange(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	entries, err := api.host.Ledger(start, end)
+	if err != nil {
+		WriteError(w, Error{"error when calling /host/ledger: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	switch format := req.FormValue("format"); format {
+	case "", "json":
+		WriteJSON(w, HostLedgerGET{Entries: entries})
+	case "csv":
+		records := [][]string{{"blockheight", "type", "obligationid", "amount"}}
+		for _, e := range entries {
+			records = append(records, []string{
+				fmt.Sprint(e.BlockHeight),
+				string(e.Type),
+				e.ObligationID.String(),
+				e.Amount.String(),
+			})
+		}
+		WriteCSV(w, records)
+	default:
+		WriteError(w, Error{"unrecognized format: " + format}, http.StatusBadRequest)
+	}
+}
+
+// hostLedgerSummaryHandlerGET handles GET requests to the /host/ledger/summary
+// API endpoint, returning the ledger of the host totalled per period.
+func (api *API) hostLedgerSummaryHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	start, end, err := parseLedgerRange(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	period := defaultLedgerSummaryPeriod
+	if p := req.FormValue("period"); p != "" {
+		if _, err = fmt.Sscan(p, &period); err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `period` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	summaries, err := api.host.LedgerSummary(start, end, period)
+	if err != nil {
+		WriteError(w, Error{"error when calling /host/ledger/summary: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, HostLedgerSummaryGET{Summaries: summaries})
+}
+
+// hostRentersHandlerGET handles GET requests to the /host/renters API
+// endpoint, returning the quota and resource usage of every renter known to
+// the host.
+func (api *API) hostRentersHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	renters, err := api.host.Renters()
+	if err != nil {
+		WriteError(w, Error{"error when calling /host/renters: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, HostRentersGET{Renters: renters})
+}
+
+// hostRentersHandlerPOST handles POST requests to the /host/renters API
+// endpoint, which sets the quota of a renter. Quota fields that are not
+// provided keep their current value.
+func (api *API) hostRentersHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var spk types.SiaPublicKey
+	spk.LoadString(req.FormValue("publickey"))
+	if len(spk.Key) == 0 {
+		WriteError(w, Error{"invalid or missing parameter `publickey`"}, http.StatusBadRequest)
+		return
+	}
+	renters, err := api.host.Renters()
+	if err != nil {
+		WriteError(w, Error{"error when calling /host/renters: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var quota modules.HostRenterQuota
+	for _, r := range renters {
+		if r.PublicKey.String() == spk.String() {
+			quota = r.Quota
+			break
+		}
+	}
+
+	fields := []struct {
+		name string
+		dst  interface{}
+	}{
+		{"maxstorage", &quota.MaxStorage},
+		{"maxbandwidth", &quota.MaxBandwidth},
+		{"bandwidthperiod", &quota.BandwidthPeriod},
+		{"maxconnections", &quota.MaxConnections},
+	}
+	for _, f := range fields {
+		if v := req.FormValue(f.name); v != "" {
+			if _, err := fmt.Sscan(v, f.dst); err != nil {
+				WriteError(w, Error{"parsing integer value for parameter `" + f.name + "` failed: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	err = api.host.SetRenterQuota(spk, quota)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // parseHostSettings a request's query strings and returns a
 // modules.HostInternalSettings configured with the request's query string
 // parameters.
@@ -208,6 +378,62 @@ func (api *API) parseHostSettings(req *http.Request) (modules.HostInternalSettin
 		}
 		settings.MinUploadBandwidthPrice = x
 	}
+	if req.FormValue("maxconnections") != "" {
+		var x uint64
+		_, err := fmt.Sscan(req.FormValue("maxconnections"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxConnections = x
+	}
+	if req.FormValue("maxconnectionsperip") != "" {
+		var x uint64
+		_, err := fmt.Sscan(req.FormValue("maxconnectionsperip"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxConnectionsPerIP = x
+	}
+	if req.FormValue("maxdownloadspeed") != "" {
+		var x int64
+		_, err := fmt.Sscan(req.FormValue("maxdownloadspeed"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxDownloadSpeed = x
+	}
+	if req.FormValue("maxuploadspeed") != "" {
+		var x int64
+		_, err := fmt.Sscan(req.FormValue("maxuploadspeed"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxUploadSpeed = x
+	}
+	if req.FormValue("maxrentercollateral") != "" {
+		var x types.Currency
+		_, err := fmt.Sscan(req.FormValue("maxrentercollateral"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxRenterCollateral = x
+	}
+	if req.FormValue("minrenewfilesize") != "" {
+		var x uint64
+		_, err := fmt.Sscan(req.FormValue("minrenewfilesize"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MinRenewFileSize = x
+	}
+	if req.FormValue("maxrenewextension") != "" {
+		var x types.BlockHeight
+		_, err := fmt.Sscan(req.FormValue("maxrenewextension"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxRenewExtension = x
+	}
 
 	return settings, nil
 }
@@ -389,3 +615,46 @@ func (api *API) storageSectorsDeleteHandler(w http.ResponseWriter, req *http.Req
 	}
 	WriteSuccess(w)
 }
+
+// hostMaintenanceHandlerGET handles GET requests to the /host/maintenance API
+// endpoint, returning the maintenance state of the host.
+func (api *API) hostMaintenanceHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	minDuration := types.BlockHeight(1)
+	if d := req.FormValue("minduration"); d != "" {
+		if _, err := fmt.Sscan(d, &minDuration); err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `minduration` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	status, err := api.host.MaintenanceStatus(minDuration)
+	if err != nil {
+		WriteError(w, Error{"error when calling /host/maintenance: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, HostMaintenanceGET{HostMaintenanceStatus: status})
+}
+
+// hostMaintenanceHandlerPOST handles POST requests to the /host/maintenance
+// API endpoint, enabling or disabling maintenance mode.
+func (api *API) hostMaintenanceHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	if req.FormValue("enabled") == "" {
+		WriteError(w, Error{"missing parameter `enabled`"}, http.StatusBadRequest)
+		return
+	}
+	enabled, err := scanBool(req.FormValue("enabled"))
+	if err != nil {
+		WriteError(w, Error{"invalid parameter `enabled`: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	announce, err := scanBool(req.FormValue("announce"))
+	if err != nil {
+		WriteError(w, Error{"invalid parameter `announce`: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = api.host.SetMaintenance(enabled, announce)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -1119,3 +1120,213 @@ func TestDeleteNonexistentSector(t *testing.T) {
 		t.Fatalf("expected error to be %v; got %v", crypto.ErrHashWrongLen, err)
 	}
 }
+
+// TestHostLedger checks that the host ledger can be queried as JSON and as
+// CSV, and that the summary endpoint validates its parameters.
+func TestHostLedger(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var hlg HostLedgerGET
+	if err = st.getAPI("/host/ledger?startheight=0&endheight=-1", &hlg); err != nil {
+		t.Fatal(err)
+	}
+	if len(hlg.Entries) != 0 {
+		t.Fatal("new host should have an empty ledger, got", len(hlg.Entries))
+	}
+
+	resp, err := HttpGET("http://" + st.server.listener.Addr().String() + "/host/ledger?format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "blockheight,type,obligationid,amount\n" {
+		t.Fatalf("unexpected csv ledger: %q", body)
+	}
+
+	if err = st.getAPI("/host/ledger?format=xml", &hlg); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+	var hlsg HostLedgerSummaryGET
+	if err = st.getAPI("/host/ledger/summary?period=0", &hlsg); err == nil {
+		t.Fatal("expected an error for a zero length period")
+	}
+	if err = st.getAPI("/host/ledger/summary?period=2", &hlsg); err != nil {
+		t.Fatal(err)
+	}
+	height := st.cs.Height()
+	if len(hlsg.Summaries) != int(height/2+1) || hlsg.Summaries[len(hlsg.Summaries)-1].EndHeight != height {
+		t.Fatal("summary periods do not cover the blockchain:", len(hlsg.Summaries), height)
+	}
+}
+
+// TestHostRenters checks that renter quotas can be set and viewed through the
+// /host/renters endpoint.
+func TestHostRenters(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var hrg HostRentersGET
+	if err = st.getAPI("/host/renters", &hrg); err != nil {
+		t.Fatal(err)
+	}
+	if len(hrg.Renters) != 0 {
+		t.Fatal("new host should not know any renters, got", len(hrg.Renters))
+	}
+
+	// Set a quota for a renter that the host has not seen yet.
+	_, pk := crypto.GenerateKeyPair()
+	spk := types.Ed25519PublicKey(pk)
+	values := url.Values{}
+	values.Set("publickey", spk.String())
+	values.Set("maxstorage", "1000")
+	values.Set("maxconnections", "2")
+	if err = st.stdPostAPI("/host/renters", values); err != nil {
+		t.Fatal(err)
+	}
+
+	// Update a single field, the other fields should keep their value.
+	values = url.Values{}
+	values.Set("publickey", spk.String())
+	values.Set("maxbandwidth", "500")
+	if err = st.stdPostAPI("/host/renters", values); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/host/renters", &hrg); err != nil {
+		t.Fatal(err)
+	}
+	if len(hrg.Renters) != 1 || hrg.Renters[0].PublicKey.String() != spk.String() {
+		t.Fatal("renter was not added to the registry:", hrg.Renters)
+	}
+	quota := hrg.Renters[0].Quota
+	if quota.MaxStorage != 1000 || quota.MaxConnections != 2 || quota.MaxBandwidth != 500 {
+		t.Fatal("renter quota was not set correctly:", quota)
+	}
+
+	// Invalid parameters should be rejected.
+	values = url.Values{}
+	values.Set("publickey", "notakey")
+	if err = st.stdPostAPI("/host/renters", values); err == nil {
+		t.Fatal("expected an error for an invalid public key")
+	}
+	values.Set("publickey", spk.String())
+	values.Set("maxstorage", "lots")
+	if err = st.stdPostAPI("/host/renters", values); err == nil {
+		t.Fatal("expected an error for an invalid quota")
+	}
+}
+
+// TestHostLimitSettings checks that the connection and speed limits of the
+// host can be changed through the API.
+func TestHostLimitSettings(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	values := url.Values{}
+	values.Set("maxconnections", "100")
+	values.Set("maxconnectionsperip", "5")
+	values.Set("maxdownloadspeed", "1000000")
+	values.Set("maxuploadspeed", "2000000")
+	if err = st.stdPostAPI("/host", values); err != nil {
+		t.Fatal(err)
+	}
+	var hg HostGET
+	if err = st.getAPI("/host", &hg); err != nil {
+		t.Fatal(err)
+	}
+	is := hg.InternalSettings
+	if is.MaxConnections != 100 || is.MaxConnectionsPerIP != 5 || is.MaxDownloadSpeed != 1e6 || is.MaxUploadSpeed != 2e6 {
+		t.Fatal("limits were not updated:", is.MaxConnections, is.MaxConnectionsPerIP, is.MaxDownloadSpeed, is.MaxUploadSpeed)
+	}
+
+	// Negative speeds should be rejected.
+	values = url.Values{}
+	values.Set("maxuploadspeed", "-1")
+	if err = st.stdPostAPI("/host", values); err == nil {
+		t.Fatal("expected an error for a negative speed")
+	}
+}
+
+// TestHostMaintenance checks that maintenance mode can be enabled and
+// disabled through the API.
+func TestHostMaintenance(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var hmg HostMaintenanceGET
+	if err = st.getAPI("/host/maintenance", &hmg); err != nil {
+		t.Fatal(err)
+	}
+	if hmg.Enabled || hmg.ActiveObligations != 0 || hmg.NextDowntime.Start != st.cs.Height() || hmg.NextDowntime.End != 0 {
+		t.Fatal("unexpected maintenance status for a new host:", hmg)
+	}
+
+	values := url.Values{}
+	values.Set("enabled", "true")
+	values.Set("announce", "true")
+	if err = st.stdPostAPI("/host/maintenance", values); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/host/maintenance?minduration=10", &hmg); err != nil {
+		t.Fatal(err)
+	}
+	if !hmg.Enabled || !hmg.AnnounceOnExit {
+		t.Fatal("maintenance mode was not enabled:", hmg)
+	}
+	var hg HostGET
+	if err = st.getAPI("/host", &hg); err != nil {
+		t.Fatal(err)
+	}
+	if hg.ExternalSettings.AcceptingContracts {
+		t.Fatal("host is accepting contracts in maintenance mode")
+	}
+
+	// The enabled parameter is required.
+	if err = st.stdPostAPI("/host/maintenance", url.Values{}); err == nil {
+		t.Fatal("expected an error when enabled is missing")
+	}
+
+	values = url.Values{}
+	values.Set("enabled", "false")
+	if err = st.stdPostAPI("/host/maintenance", values); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/host/maintenance", &hmg); err != nil {
+		t.Fatal(err)
+	}
+	if hmg.Enabled || hmg.AnnounceOnExit {
+		t.Fatal("maintenance mode was not disabled:", hmg)
+	}
+}
@@ -2289,3 +2289,117 @@ func TestRemoteFileRepairMassive(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// TestHostRenterQuotaUpload checks that the host tracks the storage used by a
+// renter and refuses uploads that would exceed the renter's storage quota.
+func TestHostRenterQuotaUpload(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Announce the host and start accepting contracts.
+	err = st.announceHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.acceptContracts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.setHostStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Set an allowance for the renter, allowing a contract to be formed.
+	allowanceValues := url.Values{}
+	allowanceValues.Set("funds", "10000000000000000000000000000") // 10k SC
+	allowanceValues.Set("period", "10")
+	allowanceValues.Set("renewwindow", testRenewWindow)
+	allowanceValues.Set("hosts", fmt.Sprint(recommendedHosts))
+	err = st.stdPostAPI("/renter", allowanceValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Block until the host knows about the renter.
+	var hrg HostRentersGET
+	err = build.Retry(50, time.Millisecond*250, func() error {
+		if err := st.getAPI("/host/renters", &hrg); err != nil {
+			return err
+		}
+		if len(hrg.Renters) != 1 || hrg.Renters[0].ContractCount != 1 {
+			return errors.New("renter has not formed a contract")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Upload a file, which should be counted against the renter.
+	path := filepath.Join(st.dir, "test.dat")
+	err = createRandFile(path, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploadValues := url.Values{}
+	uploadValues.Set("source", path)
+	err = st.stdPostAPI("/renter/upload/test", uploadValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(200, time.Millisecond*100, func() error {
+		if err := st.getAPI("/host/renters", &hrg); err != nil {
+			return err
+		}
+		if hrg.Renters[0].StorageUsed != modules.SectorSize || hrg.Renters[0].UploadBandwidth < modules.SectorSize {
+			return errors.New("upload has not been counted against the renter")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Limit the renter to the data that it already stores, a second upload
+	// should be refused.
+	quotaValues := url.Values{}
+	quotaValues.Set("publickey", hrg.Renters[0].PublicKey.String())
+	quotaValues.Set("maxstorage", fmt.Sprint(modules.SectorSize))
+	err = st.stdPostAPI("/host/renters", quotaValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path2 := filepath.Join(st.dir, "test2.dat")
+	err = createRandFile(path2, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploadValues.Set("source", path2)
+	err = st.stdPostAPI("/renter/upload/test2", uploadValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Second)
+	var rf RenterFiles
+	if err = st.getAPI("/renter/files", &rf); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range rf.Files {
+		if f.SiaPath == "test2" && f.UploadProgress != 0 {
+			t.Fatal("upload exceeding the storage quota was accepted:", f.UploadProgress)
+		}
+	}
+	if err = st.getAPI("/host/renters", &hrg); err != nil {
+		t.Fatal(err)
+	}
+	if hrg.Renters[0].StorageUsed != modules.SectorSize {
+		t.Fatal("renter stored more data than its quota allows:", hrg.Renters[0].StorageUsed)
+	}
+}
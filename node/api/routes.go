@@ -28,15 +28,21 @@ func (api *API) buildHTTPRoutes(requiredUserAgent string, requiredPassword strin
 	// Explorer API Calls
 	if api.explorer != nil {
 		router.GET("/explorer", api.explorerHandler)
+		router.GET("/explorer/addresses", api.explorerAddressesHandler)
+		router.GET("/explorer/addresses/:addr", api.explorerAddressHandler)
 		router.GET("/explorer/blocks/:height", api.explorerBlocksHandler)
 		router.GET("/explorer/hashes/:hash", api.explorerHashHandler)
+		router.GET("/explorer/supply", api.explorerSupplyHandler)
 	}
 
 	// Gateway API Calls
 	if api.gateway != nil {
 		router.GET("/gateway", api.gatewayHandler)
+		router.POST("/gateway", RequirePassword(api.gatewayHandlerPOST, requiredPassword))
 		router.POST("/gateway/connect/:netaddress", RequirePassword(api.gatewayConnectHandler, requiredPassword))
 		router.POST("/gateway/disconnect/:netaddress", RequirePassword(api.gatewayDisconnectHandler, requiredPassword))
+		router.GET("/gateway/blacklist", api.gatewayBlacklistHandlerGET)
+		router.POST("/gateway/blacklist", RequirePassword(api.gatewayBlacklistHandlerPOST, requiredPassword))
 	}
 
 	// Host API Calls
@@ -47,6 +53,12 @@ func (api *API) buildHTTPRoutes(requiredUserAgent string, requiredPassword strin
 		router.POST("/host/announce", RequirePassword(api.hostAnnounceHandler, requiredPassword)) // Announce the host to the network.
 		router.GET("/host/contracts", api.hostContractInfoHandler)                                // Get info about contracts.
 		router.GET("/host/estimatescore", api.hostEstimateScoreGET)
+		router.GET("/host/ledger", api.hostLedgerHandlerGET)                                                // Get the revenue and collateral ledger.
+		router.GET("/host/ledger/summary", api.hostLedgerSummaryHandlerGET)                                 // Get the ledger totals per period.
+		router.GET("/host/maintenance", api.hostMaintenanceHandlerGET)                                      // Get the maintenance state.
+		router.POST("/host/maintenance", RequirePassword(api.hostMaintenanceHandlerPOST, requiredPassword)) // Enable or disable maintenance mode.
+		router.GET("/host/renters", api.hostRentersHandlerGET)                                              // Get renter quotas and usage.
+		router.POST("/host/renters", RequirePassword(api.hostRentersHandlerPOST, requiredPassword))         // Set the quota of a renter.
 
 		// Calls pertaining to the storage manager that the host uses.
 		router.GET("/host/storage", api.storageHandler)
@@ -114,22 +126,46 @@ func (api *API) buildHTTPRoutes(requiredUserAgent string, requiredPassword strin
 		router.POST("/wallet/033x", RequirePassword(api.wallet033xHandler, requiredPassword))
 		router.GET("/wallet/address", RequirePassword(api.walletAddressHandler, requiredPassword))
 		router.GET("/wallet/addresses", api.walletAddressesHandler)
+		router.GET("/wallet/address/:addr/balance", api.walletAddressBalanceHandler)
 		router.GET("/wallet/backup", RequirePassword(api.walletBackupHandler, requiredPassword))
+		router.POST("/wallet/bumpfee/:txid", RequirePassword(api.walletBumpFeeHandler, requiredPassword))
 		router.POST("/wallet/init", RequirePassword(api.walletInitHandler, requiredPassword))
 		router.POST("/wallet/init/seed", RequirePassword(api.walletInitSeedHandler, requiredPassword))
 		router.POST("/wallet/lock", RequirePassword(api.walletLockHandler, requiredPassword))
+		router.GET("/wallet/multisig", api.walletMultisigHandlerGET)
+		router.POST("/wallet/multisig", RequirePassword(api.walletMultisigHandlerPOST, requiredPassword))
+		router.POST("/wallet/multisig/spend", RequirePassword(api.walletMultisigSpendHandler, requiredPassword))
+		router.GET("/wallet/outputs", api.walletOutputsHandler)
+		router.POST("/wallet/outputs/lock", RequirePassword(api.walletOutputsLockHandler, requiredPassword))
+		router.POST("/wallet/outputs/unlock", RequirePassword(api.walletOutputsUnlockHandler, requiredPassword))
+		router.POST("/wallet/prepare", RequirePassword(api.walletPrepareHandler, requiredPassword))
 		router.POST("/wallet/seed", RequirePassword(api.walletSeedHandler, requiredPassword))
+		router.GET("/wallet/publickey", RequirePassword(api.walletPublicKeyHandler, requiredPassword))
 		router.GET("/wallet/seeds", RequirePassword(api.walletSeedsHandler, requiredPassword))
+		router.GET("/wallet/settings", api.walletSettingsHandlerGET)
+		router.POST("/wallet/settings", RequirePassword(api.walletSettingsHandlerPOST, requiredPassword))
 		router.POST("/wallet/siacoins", RequirePassword(api.walletSiacoinsHandler, requiredPassword))
 		router.POST("/wallet/siafunds", RequirePassword(api.walletSiafundsHandler, requiredPassword))
+		router.POST("/wallet/sign", RequirePassword(api.walletSignHandler, requiredPassword))
 		router.POST("/wallet/siagkey", RequirePassword(api.walletSiagkeyHandler, requiredPassword))
+		router.POST("/wallet/spendingpassword", RequirePassword(api.walletSpendingPasswordHandler, requiredPassword))
 		router.POST("/wallet/sweep/seed", RequirePassword(api.walletSweepSeedHandler, requiredPassword))
 		router.GET("/wallet/transaction/:id", api.walletTransactionHandler)
+		router.GET("/wallet/labels", api.walletLabelsHandlerGET)
+		router.POST("/wallet/labels", RequirePassword(api.walletLabelsHandlerPOST, requiredPassword))
+		router.GET("/wallet/timelocked", api.walletTimelockedHandlerGET)
+		router.POST("/wallet/timelocked", RequirePassword(api.walletTimelockedHandlerPOST, requiredPassword))
 		router.GET("/wallet/transactions", api.walletTransactionsHandler)
 		router.GET("/wallet/transactions/:addr", api.walletTransactionsAddrHandler)
 		router.GET("/wallet/verify/address/:addr", api.walletVerifyAddressHandler)
 		router.POST("/wallet/unlock", RequirePassword(api.walletUnlockHandler, requiredPassword))
 		router.POST("/wallet/changepassword", RequirePassword(api.walletChangePasswordHandler, requiredPassword))
+		router.GET("/wallet/events", api.walletEventsHandler)
+		router.GET("/wallet/webhooks", api.walletWebhooksHandlerGET)
+		router.POST("/wallet/webhooks", RequirePassword(api.walletWebhooksHandlerPOST, requiredPassword))
+		router.POST("/wallet/webhooks/remove", RequirePassword(api.walletWebhooksRemoveHandler, requiredPassword))
+		router.GET("/wallet/watch", api.walletWatchHandlerGET)
+		router.POST("/wallet/watch", RequirePassword(api.walletWatchHandlerPOST, requiredPassword))
 	}
 
 	// Apply UserAgent middleware and return the Router
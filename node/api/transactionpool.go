@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/base64"
+	"fmt"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
@@ -19,6 +20,13 @@ type (
 		Maximum types.Currency `json:"maximum"`
 	}
 
+	// TpoolFeeTargetGET contains the estimated fee for being confirmed
+	// within a target number of blocks.
+	TpoolFeeTargetGET struct {
+		Target types.BlockHeight `json:"target"`
+		Fee    types.Currency    `json:"fee"`
+	}
+
 	// TpoolRawGET contains the requested transaction encoded to the raw
 	// format, along with the id of that transaction.
 	TpoolRawGET struct {
@@ -45,8 +53,27 @@ func decodeTransactionID(txidStr string) (types.TransactionID, error) {
 }
 
 // tpoolFeeHandlerGET returns the current estimated fee. Transactions with
-// fees are lower than the estimated fee may take longer to confirm.
+// fees are lower than the estimated fee may take longer to confirm. If a
+// confirmation target is provided, the fee for being confirmed within the
+// target is returned instead.
 func (api *API) tpoolFeeHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	if t := req.FormValue("target"); t != "" {
+		var target types.BlockHeight
+		if _, err := fmt.Sscan(t, &target); err != nil {
+			WriteError(w, Error{"failed to parse target: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		fee, err := api.tpool.FeeEstimationTarget(target)
+		if err != nil {
+			WriteError(w, Error{"failed to estimate fee: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		WriteJSON(w, TpoolFeeTargetGET{
+			Target: target,
+			Fee:    fee,
+		})
+		return
+	}
 	min, max := api.tpool.FeeEstimation()
 	WriteJSON(w, TpoolFeeGET{
 		Minimum: min,
@@ -77,7 +104,8 @@ func (api *API) tpoolRawHandlerGET(w http.ResponseWriter, req *http.Request, ps
 
 // tpoolRawHandlerPOST takes a raw encoded transaction set and posts
 // it to the transaction pool, relaying it to the transaction pool's peers
-// regardless of if the set is accepted.
+// regardless of if the set is accepted. The parents are optional, e.g. for
+// transactions prepared by /wallet/prepare that only spend confirmed outputs.
 func (api *API) tpoolRawHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	// Try accepting the transactions both as base64 and as clean values.
 	rawParents, err := base64.StdEncoding.DecodeString(req.FormValue("parents"))
@@ -93,10 +121,12 @@ func (api *API) tpoolRawHandlerPOST(w http.ResponseWriter, req *http.Request, _
 	// given to the transaction pool.
 	var parents []types.Transaction
 	var txn types.Transaction
-	err = encoding.Unmarshal(rawParents, &parents)
-	if err != nil {
-		WriteError(w, Error{"error decoding parents:" + err.Error()}, http.StatusBadRequest)
-		return
+	if len(rawParents) != 0 {
+		err = encoding.Unmarshal(rawParents, &parents)
+		if err != nil {
+			WriteError(w, Error{"error decoding parents:" + err.Error()}, http.StatusBadRequest)
+			return
+		}
 	}
 	err = encoding.Unmarshal(rawTransaction, &txn)
 	if err != nil {
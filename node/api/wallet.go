@@ -2,12 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
@@ -25,7 +30,10 @@ type (
 		Rescanning bool              `json:"rescanning"`
 		Unlocked   bool              `json:"unlocked"`
 
+		SpendingPasswordRequired bool `json:"spendingpasswordrequired"`
+
 		ConfirmedSiacoinBalance     types.Currency `json:"confirmedsiacoinbalance"`
+		TimelockedSiacoinBalance    types.Currency `json:"timelockedsiacoinbalance"`
 		UnconfirmedOutgoingSiacoins types.Currency `json:"unconfirmedoutgoingsiacoins"`
 		UnconfirmedIncomingSiacoins types.Currency `json:"unconfirmedincomingsiacoins"`
 
@@ -45,6 +53,15 @@ type (
 	// GET call to /wallet/addresses.
 	WalletAddressesGET struct {
 		Addresses []types.UnlockHash `json:"addresses"`
+
+		// Balances is only set if the balances were requested.
+		Balances []modules.AddressBalance `json:"balances,omitempty"`
+	}
+
+	// WalletBumpFeePOST contains the IDs of the transactions created by a
+	// POST call to /wallet/bumpfee/:txid.
+	WalletBumpFeePOST struct {
+		TransactionIDs []types.TransactionID `json:"transactionids"`
 	}
 
 	// WalletInitPOST contains the primary seed that gets generated during a
@@ -53,6 +70,88 @@ type (
 		PrimarySeed string `json:"primaryseed"`
 	}
 
+	// WalletAddressLabel is the label of an address.
+	WalletAddressLabel struct {
+		Address types.UnlockHash `json:"address"`
+		modules.WalletLabel
+	}
+
+	// WalletLabelsGET contains the labels of the transactions and addresses
+	// returned by a GET call to /wallet/labels.
+	WalletLabelsGET struct {
+		Transactions []WalletTransactionLabel `json:"transactions"`
+		Addresses    []WalletAddressLabel     `json:"addresses"`
+	}
+
+	// WalletTransactionLabel is the label of a transaction.
+	WalletTransactionLabel struct {
+		TransactionID types.TransactionID `json:"transactionid"`
+		modules.WalletLabel
+	}
+
+	// WalletMultisigAddress contains a multisig address of the wallet and
+	// its unlock conditions.
+	WalletMultisigAddress struct {
+		Address          types.UnlockHash       `json:"address"`
+		UnlockConditions types.UnlockConditions `json:"unlockconditions"`
+	}
+
+	// WalletMultisigGET contains the multisig addresses of the wallet.
+	WalletMultisigGET struct {
+		Addresses []WalletMultisigAddress `json:"addresses"`
+	}
+
+	// WalletTimelockedGET contains the timelocked addresses of the wallet.
+	WalletTimelockedGET struct {
+		Addresses []modules.TimelockedAddress `json:"addresses"`
+	}
+
+	// WalletWebhooksGET contains the webhooks of the wallet.
+	WalletWebhooksGET struct {
+		Webhooks []modules.WalletWebhook `json:"webhooks"`
+	}
+
+	// WalletMultisigSpendPOST contains the partially signed transaction
+	// returned by a POST call to /wallet/multisig/spend.
+	WalletMultisigSpendPOST struct {
+		Transaction types.Transaction `json:"transaction"`
+		Complete    bool              `json:"complete"`
+	}
+
+	// WalletOutputsGET contains the spendable siacoin outputs of the wallet
+	// returned by a GET call to /wallet/outputs.
+	WalletOutputsGET struct {
+		Outputs []modules.SpendableOutput `json:"outputs"`
+	}
+
+	// WalletPreparePOST contains the unsigned transaction prepared by the
+	// POST call to /wallet/prepare.
+	WalletPreparePOST struct {
+		modules.PreparedTransaction
+	}
+
+	// WalletPublicKeyGET contains a public key of the wallet returned by a
+	// GET call to /wallet/publickey.
+	WalletPublicKeyGET struct {
+		PublicKey string `json:"publickey"`
+	}
+
+	// WalletSettingsGET contains the settings of the wallet returned by a
+	// GET call to /wallet/settings.
+	WalletSettingsGET struct {
+		AutoLockTimeout uint64                        `json:"autolocktimeout"`
+		CoinSelection   modules.CoinSelectionStrategy `json:"coinselection"`
+		NoDefrag        bool                          `json:"nodefrag"`
+	}
+
+	// WalletSignPOST contains the transaction signed in a POST call to
+	// /wallet/sign.
+	WalletSignPOST struct {
+		Transaction types.Transaction `json:"transaction"`
+		Complete    bool              `json:"complete"`
+		Broadcast   bool              `json:"broadcast"`
+	}
+
 	// WalletSiacoinsPOST contains the transaction sent in the POST call to
 	// /wallet/siacoins.
 	WalletSiacoinsPOST struct {
@@ -79,6 +178,15 @@ type (
 		Funds types.Currency `json:"funds"`
 	}
 
+	// WalletTransaction is a processed transaction of the wallet together
+	// with its category and the label that the user attached to it.
+	WalletTransaction struct {
+		modules.ProcessedTransaction
+		Category modules.TransactionCategory `json:"category"`
+		Label    string                      `json:"label,omitempty"`
+		Note     string                      `json:"note,omitempty"`
+	}
+
 	// WalletTransactionGETid contains the transaction returned by a call to
 	// /wallet/transaction/:id
 	WalletTransactionGETid struct {
@@ -88,8 +196,12 @@ type (
 	// WalletTransactionsGET contains the specified set of confirmed and
 	// unconfirmed transactions.
 	WalletTransactionsGET struct {
-		ConfirmedTransactions   []modules.ProcessedTransaction `json:"confirmedtransactions"`
-		UnconfirmedTransactions []modules.ProcessedTransaction `json:"unconfirmedtransactions"`
+		ConfirmedTransactions   []WalletTransaction `json:"confirmedtransactions"`
+		UnconfirmedTransactions []WalletTransaction `json:"unconfirmedtransactions"`
+		// Replacements lists the transactions that were replaced by a
+		// transaction with a higher fee, and the transaction that replaced
+		// them.
+		Replacements []modules.TransactionReplacement `json:"replacements"`
 	}
 
 	// WalletTransactionsGETaddr contains the set of wallet transactions
@@ -105,6 +217,14 @@ type (
 	WalletVerifyAddressGET struct {
 		Valid bool `json:"valid"`
 	}
+
+	// WalletWatchGET contains the watch-only addresses of the wallet and
+	// their confirmed balance.
+	WalletWatchGET struct {
+		Addresses      []types.UnlockHash `json:"addresses"`
+		SiacoinBalance types.Currency     `json:"siacoinbalance"`
+		SiafundBalance types.Currency     `json:"siafundbalance"`
+	}
 )
 
 // encryptionKeys enumerates the possible encryption keys that can be derived
@@ -129,6 +249,11 @@ func (api *API) walletHandler(w http.ResponseWriter, req *http.Request, _ httpro
 		WriteError(w, Error{fmt.Sprintf("Error when calling /wallet: %v", err)}, http.StatusBadRequest)
 		return
 	}
+	timelockedBal, err := api.wallet.TimelockedBalance()
+	if err != nil {
+		WriteError(w, Error{fmt.Sprintf("Error when calling /wallet: %v", err)}, http.StatusBadRequest)
+		return
+	}
 	siacoinsOut, siacoinsIn, err := api.wallet.UnconfirmedBalance()
 	if err != nil {
 		WriteError(w, Error{fmt.Sprintf("Error when calling /wallet: %v", err)}, http.StatusBadRequest)
@@ -159,13 +284,21 @@ func (api *API) walletHandler(w http.ResponseWriter, req *http.Request, _ httpro
 		WriteError(w, Error{fmt.Sprintf("Error when calling /wallet: %v", err)}, http.StatusBadRequest)
 		return
 	}
+	spendingPasswordRequired, err := api.wallet.SpendingKeyRequired()
+	if err != nil {
+		WriteError(w, Error{fmt.Sprintf("Error when calling /wallet: %v", err)}, http.StatusBadRequest)
+		return
+	}
 	WriteJSON(w, WalletGET{
 		Encrypted:  encrypted,
 		Unlocked:   unlocked,
 		Rescanning: rescanning,
 		Height:     height,
 
+		SpendingPasswordRequired: spendingPasswordRequired,
+
 		ConfirmedSiacoinBalance:     siacoinBal,
+		TimelockedSiacoinBalance:    timelockedBal,
 		UnconfirmedOutgoingSiacoins: siacoinsOut,
 		UnconfirmedIncomingSiacoins: siacoinsIn,
 
@@ -213,6 +346,27 @@ func (api *API) walletAddressHandler(w http.ResponseWriter, req *http.Request, _
 
 // walletAddressHandler handles API calls to /wallet/addresses.
 func (api *API) walletAddressesHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	withBalance, err := scanBool(req.FormValue("withbalance"))
+	if err != nil {
+		WriteError(w, Error{"could not read 'withbalance': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if withBalance {
+		balances, err := api.wallet.AddressBalances()
+		if err != nil {
+			WriteError(w, Error{fmt.Sprintf("Error when calling /wallet/addresses: %v", err)}, http.StatusBadRequest)
+			return
+		}
+		addresses := make([]types.UnlockHash, 0, len(balances))
+		for _, b := range balances {
+			addresses = append(addresses, b.Address)
+		}
+		WriteJSON(w, WalletAddressesGET{
+			Addresses: addresses,
+			Balances:  balances,
+		})
+		return
+	}
 	addresses, err := api.wallet.AllAddresses()
 	if err != nil {
 		WriteError(w, Error{fmt.Sprintf("Error when calling /wallet/addresses: %v", err)}, http.StatusBadRequest)
@@ -223,6 +377,22 @@ func (api *API) walletAddressesHandler(w http.ResponseWriter, req *http.Request,
 	})
 }
 
+// walletAddressBalanceHandler handles GET calls to
+// /wallet/address/:addr/balance.
+func (api *API) walletAddressBalanceHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	addr, err := scanAddress(ps.ByName("addr"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse address: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	balance, err := api.wallet.AddressBalance(addr)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/address/:addr/balance: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, balance)
+}
+
 // walletBackupHandler handles API calls to /wallet/backup.
 func (api *API) walletBackupHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	destination := req.FormValue("destination")
@@ -231,7 +401,12 @@ func (api *API) walletBackupHandler(w http.ResponseWriter, req *http.Request, _
 		WriteError(w, Error{"error when calling /wallet/backup: destination must be an absolute path"}, http.StatusBadRequest)
 		return
 	}
-	err := api.wallet.CreateBackup(destination)
+	wallet, err := api.spendingWallet(req)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/backup: " + err.Error()}, http.StatusUnauthorized)
+		return
+	}
+	err = wallet.CreateBackup(destination)
 	if err != nil {
 		WriteError(w, Error{"error when calling /wallet/backup: " + err.Error()}, http.StatusBadRequest)
 		return
@@ -378,8 +553,14 @@ func (api *API) walletSeedsHandler(w http.ResponseWriter, req *http.Request, _ h
 		dictionary = mnemonics.English
 	}
 
+	wallet, err := api.spendingWallet(req)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/seeds: " + err.Error()}, http.StatusUnauthorized)
+		return
+	}
+
 	// Get the primary seed information.
-	primarySeed, addrsRemaining, err := api.wallet.PrimarySeed()
+	primarySeed, addrsRemaining, err := wallet.PrimarySeed()
 	if err != nil {
 		WriteError(w, Error{"error when calling /wallet/seeds: " + err.Error()}, http.StatusBadRequest)
 		return
@@ -391,7 +572,7 @@ func (api *API) walletSeedsHandler(w http.ResponseWriter, req *http.Request, _ h
 	}
 
 	// Get the list of seeds known to the wallet.
-	allSeeds, err := api.wallet.AllSeeds()
+	allSeeds, err := wallet.AllSeeds()
 	if err != nil {
 		WriteError(w, Error{"error when calling /wallet/seeds: " + err.Error()}, http.StatusBadRequest)
 		return
@@ -412,8 +593,333 @@ func (api *API) walletSeedsHandler(w http.ResponseWriter, req *http.Request, _ h
 	})
 }
 
+// scanOutputs reads the outputs of a transaction from either the 'outputs'
+// JSON array or the 'amount' and 'destination' parameters of req.
+func scanOutputs(req *http.Request) ([]types.SiacoinOutput, error) {
+	var outputs []types.SiacoinOutput
+	if req.FormValue("outputs") != "" {
+		// multiple amounts + destinations
+		if req.FormValue("amount") != "" || req.FormValue("destination") != "" {
+			return nil, errors.New("cannot supply both 'outputs' and single amount+destination pair")
+		}
+		if err := json.Unmarshal([]byte(req.FormValue("outputs")), &outputs); err != nil {
+			return nil, errors.New("could not decode outputs: " + err.Error())
+		}
+		return outputs, nil
+	}
+	// single amount + destination
+	amount, ok := scanAmount(req.FormValue("amount"))
+	if !ok {
+		return nil, errors.New("could not read amount")
+	}
+	dest, err := scanAddress(req.FormValue("destination"))
+	if err != nil {
+		return nil, errors.New("could not read address")
+	}
+	return []types.SiacoinOutput{{Value: amount, UnlockHash: dest}}, nil
+}
+
+// transactionComplete returns true if none of the signatures of txn are
+// missing.
+func transactionComplete(txn types.Transaction) bool {
+	for _, sig := range txn.TransactionSignatures {
+		if len(sig.Signature) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// walletMultisigHandlerGET handles GET calls to /wallet/multisig.
+func (api *API) walletMultisigHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	ucs, err := api.wallet.MultisigAddresses()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/multisig: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	addrs := make([]WalletMultisigAddress, 0, len(ucs))
+	for _, uc := range ucs {
+		addrs = append(addrs, WalletMultisigAddress{
+			Address:          uc.UnlockHash(),
+			UnlockConditions: uc,
+		})
+	}
+	WriteJSON(w, WalletMultisigGET{Addresses: addrs})
+}
+
+// walletMultisigHandlerPOST handles POST calls to /wallet/multisig.
+func (api *API) walletMultisigHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var pkStrs []string
+	if err := json.Unmarshal([]byte(req.FormValue("publickeys")), &pkStrs); err != nil {
+		WriteError(w, Error{"could not decode publickeys: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	pks := make([]types.SiaPublicKey, len(pkStrs))
+	for i, str := range pkStrs {
+		pks[i].LoadString(str)
+		if len(pks[i].Key) == 0 {
+			WriteError(w, Error{"could not read public key " + str}, http.StatusBadRequest)
+			return
+		}
+	}
+	required, err := strconv.ParseUint(req.FormValue("required"), 10, 64)
+	if err != nil {
+		WriteError(w, Error{"could not read 'required': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	unused, err := scanBool(req.FormValue("unused"))
+	if err != nil {
+		WriteError(w, Error{"could not read 'unused': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	uc, err := api.wallet.AddMultisigAddress(pks, required, unused)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/multisig: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletMultisigAddress{
+		Address:          uc.UnlockHash(),
+		UnlockConditions: uc,
+	})
+}
+
+// walletTimelockedHandlerGET handles GET calls to /wallet/timelocked.
+func (api *API) walletTimelockedHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	addrs, err := api.wallet.TimelockedAddresses()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/timelocked: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if addrs == nil {
+		addrs = []modules.TimelockedAddress{}
+	}
+	WriteJSON(w, WalletTimelockedGET{Addresses: addrs})
+}
+
+// walletTimelockedHandlerPOST handles POST calls to /wallet/timelocked.
+func (api *API) walletTimelockedHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	unlockHeight, err := strconv.ParseUint(req.FormValue("unlockheight"), 10, 64)
+	if err != nil {
+		WriteError(w, Error{"could not read 'unlockheight': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	uc, err := api.wallet.NewTimelockedAddress(types.BlockHeight(unlockHeight))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/timelocked: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, modules.TimelockedAddress{
+		Address:          uc.UnlockHash(),
+		UnlockConditions: uc,
+		UnlockHeight:     uc.Timelock,
+	})
+}
+
+// walletMultisigSpendHandler handles POST calls to /wallet/multisig/spend.
+func (api *API) walletMultisigSpendHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	addr, err := scanAddress(req.FormValue("address"))
+	if err != nil {
+		WriteError(w, Error{"could not read 'address': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	outputs, err := scanOutputs(req)
+	if err != nil {
+		WriteError(w, Error{err.Error() + " from POST call to /wallet/multisig/spend"}, http.StatusBadRequest)
+		return
+	}
+
+	wallet, err := api.spendingWallet(req)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/multisig/spend: " + err.Error()}, http.StatusUnauthorized)
+		return
+	}
+	txn, err := wallet.PrepareMultisigTransaction(addr, outputs)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/multisig/spend: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, WalletMultisigSpendPOST{
+		Transaction: txn,
+		Complete:    transactionComplete(txn),
+	})
+}
+
+// walletPublicKeyHandler handles GET calls to /wallet/publickey.
+func (api *API) walletPublicKeyHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	uc, err := api.wallet.NextAddress()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/publickey: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletPublicKeyGET{PublicKey: uc.PublicKeys[0].String()})
+}
+
+// walletSignHandler handles POST calls to /wallet/sign.
+func (api *API) walletSignHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	wallet, err := api.spendingWallet(req)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/sign: " + err.Error()}, http.StatusUnauthorized)
+		return
+	}
+	var txn types.Transaction
+	if err := json.Unmarshal([]byte(req.FormValue("transaction")), &txn); err != nil {
+		WriteError(w, Error{"could not decode transaction: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	broadcast, err := scanBool(req.FormValue("broadcast"))
+	if err != nil {
+		WriteError(w, Error{"could not read 'broadcast': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := wallet.SignTransaction(&txn); err != nil {
+		WriteError(w, Error{"error when calling /wallet/sign: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	complete := transactionComplete(txn)
+	if broadcast && complete {
+		if err := api.tpool.AcceptTransactionSet([]types.Transaction{txn}); err != nil {
+			WriteError(w, Error{"error when broadcasting signed transaction: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	WriteJSON(w, WalletSignPOST{
+		Transaction: txn,
+		Complete:    complete,
+		Broadcast:   broadcast && complete,
+	})
+}
+
+// walletOutputsHandler handles GET calls to /wallet/outputs.
+func (api *API) walletOutputsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	outputs, err := api.wallet.SpendableOutputs()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/outputs: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletOutputsGET{Outputs: outputs})
+}
+
+// walletOutputsLockHandler handles POST calls to /wallet/outputs/lock.
+func (api *API) walletOutputsLockHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var ids []types.SiacoinOutputID
+	if err := json.Unmarshal([]byte(req.FormValue("ids")), &ids); err != nil {
+		WriteError(w, Error{"could not decode ids: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if err := api.wallet.LockOutputs(ids); err != nil {
+		WriteError(w, Error{"error when calling /wallet/outputs/lock: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletOutputsUnlockHandler handles POST calls to /wallet/outputs/unlock.
+func (api *API) walletOutputsUnlockHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var ids []types.SiacoinOutputID
+	if err := json.Unmarshal([]byte(req.FormValue("ids")), &ids); err != nil {
+		WriteError(w, Error{"could not decode ids: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if err := api.wallet.UnlockOutputs(ids); err != nil {
+		WriteError(w, Error{"error when calling /wallet/outputs/unlock: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletSettingsHandlerGET handles GET calls to /wallet/settings.
+func (api *API) walletSettingsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	settings, err := api.wallet.Settings()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	timeout, err := api.wallet.AutoLockTimeout()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletSettingsGET{
+		AutoLockTimeout: uint64(timeout.Seconds()),
+		CoinSelection:   settings.CoinSelection,
+		NoDefrag:        settings.NoDefrag,
+	})
+}
+
+// walletSettingsHandlerPOST handles POST calls to /wallet/settings. Settings
+// that are not provided are left unchanged.
+func (api *API) walletSettingsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	settings, err := api.wallet.Settings()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if req.FormValue("coinselection") != "" {
+		settings.CoinSelection = modules.CoinSelectionStrategy(req.FormValue("coinselection"))
+	}
+	if req.FormValue("nodefrag") != "" {
+		settings.NoDefrag, err = scanBool(req.FormValue("nodefrag"))
+		if err != nil {
+			WriteError(w, Error{"could not read 'nodefrag': " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if err := api.wallet.SetSettings(settings); err != nil {
+		WriteError(w, Error{"error when calling /wallet/settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if req.FormValue("autolocktimeout") != "" {
+		seconds, err := strconv.ParseUint(req.FormValue("autolocktimeout"), 10, 32)
+		if err != nil {
+			WriteError(w, Error{"could not read 'autolocktimeout': " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err := api.wallet.SetAutoLockTimeout(time.Duration(seconds) * time.Second); err != nil {
+			WriteError(w, Error{"error when calling /wallet/settings: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	WriteSuccess(w)
+}
+
+// walletPrepareHandler handles API calls to /wallet/prepare.
+func (api *API) walletPrepareHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	outputs, err := scanOutputs(req)
+	if err != nil {
+		WriteError(w, Error{err.Error() + " from POST call to /wallet/prepare"}, http.StatusBadRequest)
+		return
+	}
+	watchOnly, err := scanBool(req.FormValue("watchonly"))
+	if err != nil {
+		WriteError(w, Error{"could not read 'watchonly': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var ucs []types.UnlockConditions
+	if req.FormValue("unlockconditions") != "" {
+		err = json.Unmarshal([]byte(req.FormValue("unlockconditions")), &ucs)
+		if err != nil {
+			WriteError(w, Error{"could not decode unlockconditions: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	pt, err := api.wallet.PrepareTransaction(outputs, watchOnly, ucs)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/prepare: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, WalletPreparePOST{pt})
+}
+
 // walletSiacoinsHandler handles API calls to /wallet/siacoins.
 func (api *API) walletSiacoinsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	wallet, err := api.spendingWallet(req)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/siacoins: " + err.Error()}, http.StatusUnauthorized)
+		return
+	}
 	var txns []types.Transaction
 	if req.FormValue("outputs") != "" {
 		// multiple amounts + destinations
@@ -428,7 +934,7 @@ func (api *API) walletSiacoinsHandler(w http.ResponseWriter, req *http.Request,
 			WriteError(w, Error{"could not decode outputs: " + err.Error()}, http.StatusInternalServerError)
 			return
 		}
-		txns, err = api.wallet.SendSiacoinsMulti(outputs)
+		txns, err = wallet.SendSiacoinsMulti(outputs)
 		if err != nil {
 			WriteError(w, Error{"error when calling /wallet/siacoins: " + err.Error()}, http.StatusInternalServerError)
 			return
@@ -446,7 +952,32 @@ func (api *API) walletSiacoinsHandler(w http.ResponseWriter, req *http.Request,
 			return
 		}
 
-		txns, err = api.wallet.SendSiacoins(amount, dest)
+		var ids []types.SiacoinOutputID
+		if req.FormValue("outputids") != "" {
+			if err = json.Unmarshal([]byte(req.FormValue("outputids")), &ids); err != nil {
+				WriteError(w, Error{"could not decode outputids: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+		var target types.BlockHeight
+		if req.FormValue("target") != "" {
+			if _, err = fmt.Sscan(req.FormValue("target"), &target); err != nil || target == 0 {
+				WriteError(w, Error{"could not read target from POST call to /wallet/siacoins"}, http.StatusBadRequest)
+				return
+			}
+		}
+		if len(ids) != 0 && target != 0 {
+			WriteError(w, Error{"cannot supply both 'outputids' and 'target'"}, http.StatusBadRequest)
+			return
+		}
+
+		if len(ids) != 0 {
+			txns, err = wallet.SendSiacoinsFromOutputs(amount, dest, ids)
+		} else if target != 0 {
+			txns, err = wallet.SendSiacoinsWithTarget(amount, dest, target)
+		} else {
+			txns, err = wallet.SendSiacoins(amount, dest)
+		}
 		if err != nil {
 			WriteError(w, Error{"error when calling /wallet/siacoins: " + err.Error()}, http.StatusInternalServerError)
 			return
@@ -465,6 +996,11 @@ func (api *API) walletSiacoinsHandler(w http.ResponseWriter, req *http.Request,
 
 // walletSiafundsHandler handles API calls to /wallet/siafunds.
 func (api *API) walletSiafundsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	wallet, err := api.spendingWallet(req)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/siafunds: " + err.Error()}, http.StatusUnauthorized)
+		return
+	}
 	amount, ok := scanAmount(req.FormValue("amount"))
 	if !ok {
 		WriteError(w, Error{"could not read 'amount' from POST call to /wallet/siafunds"}, http.StatusBadRequest)
@@ -476,7 +1012,7 @@ func (api *API) walletSiafundsHandler(w http.ResponseWriter, req *http.Request,
 		return
 	}
 
-	txns, err := api.wallet.SendSiafunds(amount, dest)
+	txns, err := wallet.SendSiafunds(amount, dest)
 	if err != nil {
 		WriteError(w, Error{"error when calling /wallet/siafunds: " + err.Error()}, http.StatusInternalServerError)
 		return
@@ -490,6 +1026,47 @@ func (api *API) walletSiafundsHandler(w http.ResponseWriter, req *http.Request,
 	})
 }
 
+// walletBumpFeeHandler handles API calls to /wallet/bumpfee/:txid.
+func (api *API) walletBumpFeeHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	var txid types.TransactionID
+	jsonID := "\"" + ps.ByName("txid") + "\""
+	if err := txid.UnmarshalJSON([]byte(jsonID)); err != nil {
+		WriteError(w, Error{"error when calling /wallet/bumpfee/:txid: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	method := modules.BumpFeeReplace
+	if req.FormValue("method") != "" {
+		method = modules.BumpFeeMethod(req.FormValue("method"))
+	}
+	var fee types.Currency
+	if req.FormValue("fee") != "" {
+		var ok bool
+		fee, ok = scanAmount(req.FormValue("fee"))
+		if !ok {
+			WriteError(w, Error{"could not read 'fee' from POST call to /wallet/bumpfee/:txid"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	wallet, err := api.spendingWallet(req)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/bumpfee/:txid: " + err.Error()}, http.StatusUnauthorized)
+		return
+	}
+	txns, err := wallet.BumpFee(txid, fee, method)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/bumpfee/:txid: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var txids []types.TransactionID
+	for _, txn := range txns {
+		txids = append(txids, txn.ID())
+	}
+	WriteJSON(w, WalletBumpFeePOST{
+		TransactionIDs: txids,
+	})
+}
+
 // walletSweepSeedHandler handles API calls to /wallet/sweep/seed.
 func (api *API) walletSweepSeedHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	// Get the seed using the ditionary + phrase
@@ -503,7 +1080,12 @@ func (api *API) walletSweepSeedHandler(w http.ResponseWriter, req *http.Request,
 		return
 	}
 
-	coins, funds, err := api.wallet.SweepSeed(seed)
+	wallet, err := api.spendingWallet(req)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/sweep/seed: " + err.Error()}, http.StatusUnauthorized)
+		return
+	}
+	coins, funds, err := wallet.SweepSeed(seed)
 	if err != nil {
 		WriteError(w, Error{"error when calling /wallet/sweep/seed: " + err.Error()}, http.StatusBadRequest)
 		return
@@ -542,10 +1124,21 @@ func (api *API) walletTransactionHandler(w http.ResponseWriter, req *http.Reques
 // walletTransactionsHandler handles API calls to /wallet/transactions.
 func (api *API) walletTransactionsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	startheightStr, endheightStr := req.FormValue("startheight"), req.FormValue("endheight")
-	if startheightStr == "" || endheightStr == "" {
+	starttimeStr, endtimeStr := req.FormValue("starttime"), req.FormValue("endtime")
+	format := req.FormValue("format")
+	// The height range may only be omitted if the history is filtered by
+	// time or exported as CSV.
+	byTime := starttimeStr != "" || endtimeStr != ""
+	if (startheightStr == "" || endheightStr == "") && !(byTime || format == "csv") {
 		WriteError(w, Error{"startheight and endheight must be provided to a /wallet/transactions call."}, http.StatusBadRequest)
 		return
 	}
+	if startheightStr == "" {
+		startheightStr = "0"
+	}
+	if endheightStr == "" {
+		endheightStr = "-1"
+	}
 	// Get the start and end blocks.
 	start, err := strconv.ParseUint(startheightStr, 10, 64)
 	if err != nil {
@@ -564,6 +1157,25 @@ func (api *API) walletTransactionsHandler(w http.ResponseWriter, req *http.Reque
 		WriteError(w, Error{"parsing integer value for parameter `endheight` failed: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
+	// Get the time range. Both ends are inclusive.
+	startTime, endTime := types.Timestamp(0), types.Timestamp(math.MaxUint64)
+	if starttimeStr != "" {
+		t, err := strconv.ParseUint(starttimeStr, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `starttime` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		startTime = types.Timestamp(t)
+	}
+	if endtimeStr != "" {
+		t, err := strconv.ParseUint(endtimeStr, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `endtime` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		endTime = types.Timestamp(t)
+	}
+
 	confirmedTxns, err := api.wallet.Transactions(types.BlockHeight(start), types.BlockHeight(end))
 	if err != nil {
 		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
@@ -574,11 +1186,197 @@ func (api *API) walletTransactionsHandler(w http.ResponseWriter, req *http.Reque
 		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
+	replacements, err := api.wallet.TransactionReplacements()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	txnLabels, err := api.wallet.TransactionLabels()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	// Filter the confirmed transactions by time and attach the labels.
+	var confirmed []WalletTransaction
+	for _, pt := range confirmedTxns {
+		if pt.ConfirmationTimestamp < startTime || pt.ConfirmationTimestamp > endTime {
+			continue
+		}
+		confirmed = append(confirmed, labelTransaction(pt, txnLabels))
+	}
+	var unconfirmed []WalletTransaction
+	for _, pt := range unconfirmedTxns {
+		unconfirmed = append(unconfirmed, labelTransaction(pt, txnLabels))
+	}
+
+	switch format {
+	case "", "json":
+		WriteJSON(w, WalletTransactionsGET{
+			ConfirmedTransactions:   confirmed,
+			UnconfirmedTransactions: unconfirmed,
+			Replacements:            replacements,
+		})
+	case "csv":
+		addrLabels, err := api.wallet.AddressLabels()
+		if err != nil {
+			WriteError(w, Error{"error when calling /wallet/transactions: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		records := [][]string{{"timestamp", "height", "transactionid", "category", "siacoinsin", "siacoinsout", "fees", "siafundsin", "siafundsout", "label", "note", "addresslabels"}}
+		for _, wt := range confirmed {
+			scIn, scOut, fees, sfIn, sfOut := transactionFlows(wt.ProcessedTransaction)
+			records = append(records, []string{
+				time.Unix(int64(wt.ConfirmationTimestamp), 0).UTC().Format(time.RFC3339),
+				fmt.Sprint(wt.ConfirmationHeight),
+				wt.TransactionID.String(),
+				string(wt.Category),
+				scIn.String(),
+				scOut.String(),
+				fees.String(),
+				sfIn.String(),
+				sfOut.String(),
+				wt.Label,
+				wt.Note,
+				strings.Join(transactionAddressLabels(wt.ProcessedTransaction, addrLabels), ";"),
+			})
+		}
+		WriteCSV(w, records)
+	default:
+		WriteError(w, Error{"unknown format: " + format}, http.StatusBadRequest)
+	}
+}
+
+// labelTransaction wraps a processed transaction together with its category
+// and label.
+func labelTransaction(pt modules.ProcessedTransaction, labels map[types.TransactionID]modules.WalletLabel) WalletTransaction {
+	l := labels[pt.TransactionID]
+	return WalletTransaction{
+		ProcessedTransaction: pt,
+		Category:             pt.Category(),
+		Label:                l.Label,
+		Note:                 l.Note,
+	}
+}
+
+// transactionFlows returns the siacoins and siafunds that a transaction moved
+// into and out of the wallet, and the miner fees that the wallet paid.
+func transactionFlows(pt modules.ProcessedTransaction) (scIn, scOut, fees, sfIn, sfOut types.Currency) {
+	var paid bool
+	for _, input := range pt.Inputs {
+		if !input.WalletAddress {
+			continue
+		}
+		switch input.FundType {
+		case types.SpecifierSiacoinInput:
+			scOut = scOut.Add(input.Value)
+			paid = true
+		case types.SpecifierSiafundInput:
+			sfOut = sfOut.Add(input.Value)
+		}
+	}
+	for _, output := range pt.Outputs {
+		switch {
+		case output.FundType == types.SpecifierMinerFee:
+			fees = fees.Add(output.Value)
+		case !output.WalletAddress:
+		case output.FundType == types.SpecifierSiafundOutput:
+			sfIn = sfIn.Add(output.Value)
+		default:
+			scIn = scIn.Add(output.Value)
+		}
+	}
+	if !paid {
+		fees = types.ZeroCurrency
+	}
+	return
+}
+
+// transactionAddressLabels returns the labels of the labeled addresses that
+// appear in a transaction.
+func transactionAddressLabels(pt modules.ProcessedTransaction, labels map[types.UnlockHash]modules.WalletLabel) []string {
+	seen := make(map[types.UnlockHash]bool)
+	var ls []string
+	add := func(addr types.UnlockHash) {
+		l, ok := labels[addr]
+		if !ok || seen[addr] {
+			return
+		}
+		seen[addr] = true
+		ls = append(ls, l.Label)
+	}
+	for _, input := range pt.Inputs {
+		add(input.RelatedAddress)
+	}
+	for _, output := range pt.Outputs {
+		add(output.RelatedAddress)
+	}
+	return ls
+}
 
-	WriteJSON(w, WalletTransactionsGET{
-		ConfirmedTransactions:   confirmedTxns,
-		UnconfirmedTransactions: unconfirmedTxns,
+// walletLabelsHandlerGET handles GET calls to /wallet/labels.
+func (api *API) walletLabelsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	txnLabels, err := api.wallet.TransactionLabels()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/labels: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	addrLabels, err := api.wallet.AddressLabels()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/labels: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	wlg := WalletLabelsGET{
+		Transactions: []WalletTransactionLabel{},
+		Addresses:    []WalletAddressLabel{},
+	}
+	for txid, l := range txnLabels {
+		wlg.Transactions = append(wlg.Transactions, WalletTransactionLabel{TransactionID: txid, WalletLabel: l})
+	}
+	for addr, l := range addrLabels {
+		wlg.Addresses = append(wlg.Addresses, WalletAddressLabel{Address: addr, WalletLabel: l})
+	}
+	sort.Slice(wlg.Transactions, func(i, j int) bool {
+		return wlg.Transactions[i].TransactionID.String() < wlg.Transactions[j].TransactionID.String()
 	})
+	sort.Slice(wlg.Addresses, func(i, j int) bool {
+		return wlg.Addresses[i].Address.String() < wlg.Addresses[j].Address.String()
+	})
+	WriteJSON(w, wlg)
+}
+
+// walletLabelsHandlerPOST handles POST calls to /wallet/labels.
+func (api *API) walletLabelsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	label := modules.WalletLabel{
+		Label: req.FormValue("label"),
+		Note:  req.FormValue("note"),
+	}
+	txidStr, addrStr := req.FormValue("transactionid"), req.FormValue("address")
+	if (txidStr == "") == (addrStr == "") {
+		WriteError(w, Error{"exactly one of transactionid and address must be provided to a /wallet/labels call."}, http.StatusBadRequest)
+		return
+	}
+	var err error
+	if txidStr != "" {
+		var txid types.TransactionID
+		if err := txid.UnmarshalJSON([]byte("\"" + txidStr + "\"")); err != nil {
+			WriteError(w, Error{"unable to parse transactionid: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		err = api.wallet.SetTransactionLabel(txid, label)
+	} else {
+		addr, parseErr := scanAddress(addrStr)
+		if parseErr != nil {
+			WriteError(w, Error{"unable to parse address: " + parseErr.Error()}, http.StatusBadRequest)
+			return
+		}
+		err = api.wallet.SetAddressLabel(addr, label)
+	}
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/labels: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
 }
 
 // walletTransactionsAddrHandler handles API calls to
@@ -651,6 +1449,43 @@ func (api *API) walletChangePasswordHandler(w http.ResponseWriter, req *http.Req
 	WriteError(w, Error{"error when calling /wallet/changepassword: " + modules.ErrBadEncryptionKey.Error()}, http.StatusBadRequest)
 }
 
+// spendingWallet returns the view of the wallet that handles a request that
+// signs transactions with the keys of the wallet or exports them. If the
+// request supplies a spending password, the view is authorized by it.
+// Otherwise the wallet itself is returned, which refuses these operations if
+// a spending password was set with /wallet/spendingpassword.
+func (api *API) spendingWallet(req *http.Request) (modules.Wallet, error) {
+	password := req.FormValue("spendingpassword")
+	if password == "" {
+		return api.wallet, nil
+	}
+	return api.wallet.AuthorizeSpending(crypto.TwofishKey(crypto.HashObject(password)))
+}
+
+// walletSpendingPasswordHandler handles API calls to
+// /wallet/spendingpassword.
+func (api *API) walletSpendingPasswordHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// An empty spending password removes the spending password.
+	var spendingKey crypto.TwofishKey
+	if password := req.FormValue("spendingpassword"); password != "" {
+		spendingKey = crypto.TwofishKey(crypto.HashObject(password))
+	}
+
+	keys := encryptionKeys(req.FormValue("encryptionpassword"))
+	for _, key := range keys {
+		err := api.wallet.SetSpendingKey(key, spendingKey)
+		if err == nil {
+			WriteSuccess(w)
+			return
+		}
+		if err != modules.ErrBadEncryptionKey {
+			WriteError(w, Error{"error when calling /wallet/spendingpassword: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	WriteError(w, Error{"error when calling /wallet/spendingpassword: " + modules.ErrBadEncryptionKey.Error()}, http.StatusBadRequest)
+}
+
 // walletVerifyAddressHandler handles API calls to /wallet/verify/address/:addr.
 func (api *API) walletVerifyAddressHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	addrString := ps.ByName("addr")
@@ -658,3 +1493,236 @@ func (api *API) walletVerifyAddressHandler(w http.ResponseWriter, req *http.Requ
 	err := new(types.UnlockHash).LoadString(addrString)
 	WriteJSON(w, WalletVerifyAddressGET{Valid: err == nil})
 }
+
+// walletWatchHandlerGET handles GET calls to /wallet/watch.
+func (api *API) walletWatchHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	addrs, err := api.wallet.WatchAddresses()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/watch: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	siacoinBal, siafundBal, err := api.wallet.WatchBalance()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/watch: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletWatchGET{
+		Addresses:      addrs,
+		SiacoinBalance: siacoinBal,
+		SiafundBalance: siafundBal,
+	})
+}
+
+// walletWatchHandlerPOST handles POST calls to /wallet/watch.
+func (api *API) walletWatchHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var addrs []types.UnlockHash
+	if err := json.Unmarshal([]byte(req.FormValue("addresses")), &addrs); err != nil {
+		WriteError(w, Error{"could not decode addresses: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	remove, err := scanBool(req.FormValue("remove"))
+	if err != nil {
+		WriteError(w, Error{"could not read 'remove': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	unused, err := scanBool(req.FormValue("unused"))
+	if err != nil {
+		WriteError(w, Error{"could not read 'unused': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if remove {
+		err = api.wallet.RemoveWatchAddresses(addrs)
+	} else {
+		err = api.wallet.AddWatchAddresses(addrs, unused)
+	}
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/watch: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletEventStreamBuffer is the number of batches of events that a
+// /wallet/events stream buffers for a client that falls behind.
+const walletEventStreamBuffer = 64
+
+// walletEventStream is a WalletEventSubscriber that forwards the wallet
+// events to a /wallet/events stream. If the client falls behind, the stream
+// is closed instead of blocking the wallet.
+type walletEventStream struct {
+	events      chan []modules.WalletEvent
+	dropped     chan struct{}
+	droppedOnce sync.Once
+}
+
+// ReceiveWalletEvents implements modules.WalletEventSubscriber.
+func (s *walletEventStream) ReceiveWalletEvents(events []modules.WalletEvent) {
+	select {
+	case s.events <- events:
+	default:
+		s.droppedOnce.Do(func() { close(s.dropped) })
+	}
+}
+
+// scanEventTypes parses a comma separated list of wallet event types.
+func scanEventTypes(param string) ([]modules.WalletEventType, error) {
+	if param == "" {
+		return nil, nil
+	}
+	var eventTypes []modules.WalletEventType
+	for _, t := range strings.Split(param, ",") {
+		switch t := modules.WalletEventType(strings.TrimSpace(t)); t {
+		case modules.WalletEventUnconfirmed, modules.WalletEventConfirmed,
+			modules.WalletEventReverted, modules.WalletEventConfirmations:
+			eventTypes = append(eventTypes, t)
+		default:
+			return nil, fmt.Errorf("unknown event type %q", t)
+		}
+	}
+	return eventTypes, nil
+}
+
+// scanConfirmations parses the confirmations parameter of the wallet event
+// endpoints. A number of confirmations is required to receive confirmations
+// events.
+func scanConfirmations(param string, eventTypes []modules.WalletEventType) (uint64, error) {
+	var confirmations uint64
+	if param != "" {
+		var err error
+		confirmations, err = strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if confirmations > modules.WalletEventMaxConfirmations {
+		return 0, fmt.Errorf("confirmations must be at most %v", modules.WalletEventMaxConfirmations)
+	}
+	tooFew := confirmations != 0 && confirmations < modules.WalletEventMinConfirmations
+	for _, t := range eventTypes {
+		tooFew = tooFew || (t == modules.WalletEventConfirmations && confirmations == 0)
+	}
+	if tooFew {
+		return 0, fmt.Errorf("confirmations must be at least %v", modules.WalletEventMinConfirmations)
+	}
+	return confirmations, nil
+}
+
+// writeWalletEvents writes the events that pass the filter to a
+// /wallet/events stream.
+func writeWalletEvents(w io.Writer, events []modules.WalletEvent, filter []modules.WalletEventType, confirmations uint64) error {
+	for _, e := range events {
+		if !e.Matches(filter, confirmations) {
+			continue
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walletEventsHandler handles GET calls to /wallet/events. It streams the
+// wallet events to the client as server-sent events until the client
+// disconnects. If the client falls behind and events have to be dropped, a
+// dropped event is sent and the stream is closed, so that the client knows to
+// resync.
+func (api *API) walletEventsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	filter, err := scanEventTypes(req.FormValue("events"))
+	if err != nil {
+		WriteError(w, Error{"could not read 'events': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	confirmations, err := scanConfirmations(req.FormValue("confirmations"), filter)
+	if err != nil {
+		WriteError(w, Error{"could not read 'confirmations': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, Error{"streaming is not supported by the connection"}, http.StatusInternalServerError)
+		return
+	}
+
+	stream := &walletEventStream{
+		events:  make(chan []modules.WalletEvent, walletEventStreamBuffer),
+		dropped: make(chan struct{}),
+	}
+	api.wallet.SubscribeEvents(stream)
+	defer api.wallet.UnsubscribeEvents(stream)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case events := <-stream.events:
+			if err := writeWalletEvents(w, events, filter, confirmations); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-stream.dropped:
+			// Send the events that were queued before the dropped events,
+			// then tell the client that events were dropped.
+			for len(stream.events) > 0 {
+				if err := writeWalletEvents(w, <-stream.events, filter, confirmations); err != nil {
+					return
+				}
+			}
+			fmt.Fprint(w, "event: dropped\ndata: {}\n\n")
+			flusher.Flush()
+			return
+		}
+	}
+}
+
+// walletWebhooksHandlerGET handles GET calls to /wallet/webhooks.
+func (api *API) walletWebhooksHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	hooks, err := api.wallet.Webhooks()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/webhooks: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletWebhooksGET{Webhooks: hooks})
+}
+
+// walletWebhooksHandlerPOST handles POST calls to /wallet/webhooks.
+func (api *API) walletWebhooksHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	events, err := scanEventTypes(req.FormValue("events"))
+	if err != nil {
+		WriteError(w, Error{"could not read 'events': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	confirmations, err := scanConfirmations(req.FormValue("confirmations"), events)
+	if err != nil {
+		WriteError(w, Error{"could not read 'confirmations': " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = api.wallet.AddWebhook(modules.WalletWebhook{
+		URL:           req.FormValue("url"),
+		Secret:        req.FormValue("secret"),
+		Events:        events,
+		Confirmations: confirmations,
+	})
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/webhooks: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletWebhooksRemoveHandler handles POST calls to /wallet/webhooks/remove.
+func (api *API) walletWebhooksRemoveHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	if err := api.wallet.RemoveWebhook(req.FormValue("url")); err != nil {
+		WriteError(w, Error{"error when calling /wallet/webhooks/remove: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
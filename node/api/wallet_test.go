@@ -1,16 +1,24 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/consensus"
 	"github.com/NebulousLabs/Sia/modules/gateway"
@@ -1762,3 +1770,1067 @@ func TestWalletTransactionsGetAddr(t *testing.T) {
 		t.Errorf("There should be exactly 0 unconfirmed and 1 confirmed related txns")
 	}
 }
+
+// TestWalletWatch probes the GET and POST calls to /wallet/watch.
+func TestWalletWatch(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Send coins to an address and mine a block before watching it, so that
+	// the wallet has to rescan to find the coins.
+	var addr types.UnlockHash
+	fastrand.Read(addr[:])
+	sentValue := types.SiacoinPrecision.Mul64(3)
+	if _, err = st.wallet.SendSiacoins(sentValue, addr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	addrsJSON, err := json.Marshal([]types.UnlockHash{addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := url.Values{}
+	values.Set("addresses", string(addrsJSON))
+	if err = st.stdPostAPI("/wallet/watch", values); err != nil {
+		t.Fatal(err)
+	}
+	var wwg WalletWatchGET
+	if err = st.getAPI("/wallet/watch", &wwg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wwg.Addresses) != 1 || wwg.Addresses[0] != addr {
+		t.Fatal("watched address was not added:", wwg.Addresses)
+	}
+	if !wwg.SiacoinBalance.Equals(sentValue) || !wwg.SiafundBalance.IsZero() {
+		t.Fatalf("expected watched balance of %v, got %v and %v SF", sentValue, wwg.SiacoinBalance, wwg.SiafundBalance)
+	}
+	var wtga WalletTransactionsGETaddr
+	if err = st.getAPI("/wallet/transactions/"+addr.String(), &wtga); err != nil {
+		t.Fatal(err)
+	}
+	if len(wtga.ConfirmedTransactions) != 1 {
+		t.Fatal("expected 1 confirmed transaction for the watched address, got", len(wtga.ConfirmedTransactions))
+	}
+
+	// Remove the address.
+	values.Set("remove", "true")
+	if err = st.stdPostAPI("/wallet/watch", values); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/wallet/watch", &wwg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wwg.Addresses) != 0 || !wwg.SiacoinBalance.IsZero() {
+		t.Fatal("watched address was not removed:", wwg)
+	}
+
+	// Invalid addresses should be rejected.
+	values = url.Values{}
+	values.Set("addresses", "[\"foo\"]")
+	if err = st.stdPostAPI("/wallet/watch", values); err == nil {
+		t.Fatal("expected invalid addresses to be rejected")
+	}
+}
+
+// TestWalletPrepare probes the offline signing workflow: a transaction is
+// prepared with /wallet/prepare, signed without the wallet, and broadcast
+// with /tpool/raw.
+func TestWalletPrepare(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var addr types.UnlockHash
+	fastrand.Read(addr[:])
+	values := url.Values{}
+	values.Set("amount", types.SiacoinPrecision.Mul64(3).String())
+	values.Set("destination", addr.String())
+	var wpp WalletPreparePOST
+	if err = st.postAPI("/wallet/prepare", values, &wpp); err != nil {
+		t.Fatal(err)
+	}
+	txn := wpp.Transaction
+	if len(txn.SiacoinInputs) == 0 || len(wpp.ParentOutputs) != len(txn.SiacoinInputs) || len(wpp.SigHashes) != len(txn.TransactionSignatures) {
+		t.Fatal("unexpected prepared transaction:", wpp)
+	}
+	for _, sig := range txn.TransactionSignatures {
+		if len(sig.Signature) != 0 {
+			t.Fatal("prepared transaction should not be signed")
+		}
+	}
+
+	// Sign the transaction with the primary seed and broadcast it.
+	seed, _, err := st.wallet.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = wallet.SignTransaction(&txn, seed, modules.PublicKeysPerSeed); err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("transaction", base64.StdEncoding.EncodeToString(encoding.Marshal(txn)))
+	if err = st.stdPostAPI("/tpool/raw", values); err != nil {
+		t.Fatal(err)
+	}
+	var trg TpoolRawGET
+	if err = st.getAPI("/tpool/raw/"+txn.ID().String(), &trg); err != nil {
+		t.Fatal(err)
+	}
+	if trg.ID != txn.ID() {
+		t.Fatal("transaction was not added to the transaction pool")
+	}
+
+	// A locked wallet can only prepare watch-only transactions.
+	if err = st.stdPostAPI("/wallet/lock", nil); err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("amount", "1")
+	values.Set("destination", addr.String())
+	if err = st.postAPI("/wallet/prepare", values, &wpp); err == nil {
+		t.Fatal("expected locked wallet to refuse preparing a transaction")
+	}
+}
+
+// TestWalletMultisig checks that two nodes can create a 2-of-2 multisig
+// address, and that a spend prepared by one node can be co-signed and
+// broadcast by the other.
+func TestWalletMultisig(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+	st2, err := blankServerTester(t.Name() + "-st2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st2.server.panicClose()
+	sts := []*serverTester{st, st2}
+	if err = fullyConnectNodes(sts); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both nodes create the address from one public key of each node.
+	var pkStrs []string
+	for _, tester := range sts {
+		var wpkg WalletPublicKeyGET
+		if err = tester.getAPI("/wallet/publickey", &wpkg); err != nil {
+			t.Fatal(err)
+		}
+		pkStrs = append(pkStrs, wpkg.PublicKey)
+	}
+	pkJSON, err := json.Marshal(pkStrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addr types.UnlockHash
+	for _, tester := range sts {
+		values := url.Values{}
+		values.Set("publickeys", string(pkJSON))
+		values.Set("required", "2")
+		values.Set("unused", "true")
+		var wma WalletMultisigAddress
+		if err = tester.postAPI("/wallet/multisig", values, &wma); err != nil {
+			t.Fatal(err)
+		}
+		if addr != (types.UnlockHash{}) && wma.Address != addr {
+			t.Fatal("nodes created different multisig addresses")
+		}
+		addr = wma.Address
+	}
+	var wmg WalletMultisigGET
+	if err = st2.getAPI("/wallet/multisig", &wmg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wmg.Addresses) != 1 || wmg.Addresses[0].Address != addr || wmg.Addresses[0].UnlockConditions.SignaturesRequired != 2 {
+		t.Fatal("unexpected multisig addresses:", wmg.Addresses)
+	}
+
+	// Fund the address.
+	values := url.Values{}
+	values.Set("amount", types.SiacoinPrecision.Mul64(100).String())
+	values.Set("destination", addr.String())
+	if err = st.stdPostAPI("/wallet/siacoins", values); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = synchronizationCheck(sts); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first node prepares and signs the spend.
+	var dest types.UnlockHash
+	fastrand.Read(dest[:])
+	values = url.Values{}
+	values.Set("address", addr.String())
+	values.Set("amount", types.SiacoinPrecision.Mul64(10).String())
+	values.Set("destination", dest.String())
+	var wmsp WalletMultisigSpendPOST
+	if err = st.postAPI("/wallet/multisig/spend", values, &wmsp); err != nil {
+		t.Fatal(err)
+	}
+	if wmsp.Complete {
+		t.Fatal("transaction should need the signature of the second node")
+	}
+
+	// The second node adds its signature and broadcasts the transaction.
+	txnJSON, err := json.Marshal(wmsp.Transaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("transaction", string(txnJSON))
+	values.Set("broadcast", "true")
+	var wsp WalletSignPOST
+	if err = st2.postAPI("/wallet/sign", values, &wsp); err != nil {
+		t.Fatal(err)
+	}
+	if !wsp.Complete || !wsp.Broadcast {
+		t.Fatal("transaction was not completed and broadcast:", wsp.Complete, wsp.Broadcast)
+	}
+	var trg TpoolRawGET
+	if err = st2.getAPI("/tpool/raw/"+wsp.Transaction.ID().String(), &trg); err != nil {
+		t.Fatal(err)
+	}
+
+	// Signing the complete transaction again should fail.
+	txnJSON, err = json.Marshal(wsp.Transaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values.Set("transaction", string(txnJSON))
+	if err = st.postAPI("/wallet/sign", values, &wsp); err == nil {
+		t.Fatal("expected an error when there is nothing to sign")
+	}
+}
+
+// TestWalletOutputs probes the /wallet/outputs and /wallet/settings endpoints
+// and funding a transaction from explicit outputs.
+func TestWalletOutputs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var wog WalletOutputsGET
+	if err = st.getAPI("/wallet/outputs", &wog); err != nil {
+		t.Fatal(err)
+	}
+	if len(wog.Outputs) == 0 {
+		t.Fatal("wallet has no spendable outputs")
+	}
+	id := wog.Outputs[0].ID
+
+	// Lock the output.
+	idsJSON, err := json.Marshal([]types.SiacoinOutputID{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := url.Values{}
+	values.Set("ids", string(idsJSON))
+	if err = st.stdPostAPI("/wallet/outputs/lock", values); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/wallet/outputs", &wog); err != nil {
+		t.Fatal(err)
+	}
+	if wog.Outputs[0].ID != id || !wog.Outputs[0].Locked {
+		t.Fatal("output was not locked:", wog.Outputs[0])
+	}
+
+	// Change the coin selection strategy.
+	values = url.Values{}
+	values.Set("coinselection", "random")
+	if err = st.stdPostAPI("/wallet/settings", values); err == nil {
+		t.Fatal("expected an unknown coin selection strategy to be rejected")
+	}
+	values.Set("coinselection", string(modules.CoinSelectionBranchAndBound))
+	if err = st.stdPostAPI("/wallet/settings", values); err != nil {
+		t.Fatal(err)
+	}
+	var wsg WalletSettingsGET
+	if err = st.getAPI("/wallet/settings", &wsg); err != nil {
+		t.Fatal(err)
+	}
+	if wsg.CoinSelection != modules.CoinSelectionBranchAndBound || wsg.NoDefrag {
+		t.Fatal("unexpected settings:", wsg)
+	}
+
+	// Spend the locked output explicitly.
+	var addr types.UnlockHash
+	fastrand.Read(addr[:])
+	values = url.Values{}
+	values.Set("amount", types.SiacoinPrecision.String())
+	values.Set("destination", addr.String())
+	values.Set("outputids", string(idsJSON))
+	var wsp WalletSiacoinsPOST
+	if err = st.postAPI("/wallet/siacoins", values, &wsp); err != nil {
+		t.Fatal(err)
+	}
+	var trg TpoolRawGET
+	if err = st.getAPI("/tpool/raw/"+wsp.TransactionIDs[0].String(), &trg); err != nil {
+		t.Fatal(err)
+	}
+	var parent types.Transaction
+	if err = encoding.Unmarshal(trg.Transaction, &parent); err != nil {
+		t.Fatal(err)
+	}
+	if len(parent.SiacoinInputs) != 1 || parent.SiacoinInputs[0].ParentID != id {
+		t.Fatal("transaction was not funded from the requested output:", parent.SiacoinInputs)
+	}
+
+	// The spent output is no longer spendable, but it can still be unlocked.
+	if err = st.getAPI("/wallet/outputs", &wog); err != nil {
+		t.Fatal(err)
+	}
+	for _, so := range wog.Outputs {
+		if so.ID == id {
+			t.Fatal("spent output is still listed")
+		}
+	}
+	values = url.Values{}
+	values.Set("ids", string(idsJSON))
+	if err = st.stdPostAPI("/wallet/outputs/unlock", values); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWalletBumpFee checks that /wallet/bumpfee/:txid replaces a stuck
+// transaction and that /wallet/transactions reports the replacement.
+func TestWalletBumpFee(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var addr types.UnlockHash
+	fastrand.Read(addr[:])
+	values := url.Values{}
+	values.Set("amount", types.SiacoinPrecision.Mul64(10).String())
+	values.Set("destination", addr.String())
+	var wsp WalletSiacoinsPOST
+	if err = st.postAPI("/wallet/siacoins", values, &wsp); err != nil {
+		t.Fatal(err)
+	}
+	txid := wsp.TransactionIDs[len(wsp.TransactionIDs)-1]
+
+	values = url.Values{}
+	values.Set("method", "foo")
+	if err = st.stdPostAPI("/wallet/bumpfee/"+txid.String(), values); err == nil {
+		t.Fatal("expected an unknown method to be rejected")
+	}
+	values.Set("method", string(modules.BumpFeeReplace))
+	var wbfp WalletBumpFeePOST
+	if err = st.postAPI("/wallet/bumpfee/"+txid.String(), values, &wbfp); err != nil {
+		t.Fatal(err)
+	}
+	if len(wbfp.TransactionIDs) != 1 {
+		t.Fatal("expected one replacement transaction, got", len(wbfp.TransactionIDs))
+	}
+
+	var wtg WalletTransactionsGET
+	if err = st.getAPI("/wallet/transactions?startheight=0&endheight=10000", &wtg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wtg.Replacements) != len(wsp.TransactionIDs) {
+		t.Fatal("expected the sent transactions to be replaced:", wtg.Replacements)
+	}
+	for _, r := range wtg.Replacements {
+		if r.ReplacementID != wbfp.TransactionIDs[0] {
+			t.Fatal("wrong replacement:", r)
+		}
+	}
+	if len(wtg.UnconfirmedTransactions) != 1 || wtg.UnconfirmedTransactions[0].TransactionID != wbfp.TransactionIDs[0] {
+		t.Fatal("replacement is not the only unconfirmed transaction:", wtg.UnconfirmedTransactions)
+	}
+}
+
+// TestWalletLabels checks that transactions and addresses can be labeled, and
+// that the labels and categories appear in the transaction history and its
+// CSV export.
+func TestWalletLabels(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var addr types.UnlockHash
+	fastrand.Read(addr[:])
+	values := url.Values{}
+	values.Set("amount", types.SiacoinPrecision.Mul64(10).String())
+	values.Set("destination", addr.String())
+	var wsp WalletSiacoinsPOST
+	if err = st.postAPI("/wallet/siacoins", values, &wsp); err != nil {
+		t.Fatal(err)
+	}
+	txid := wsp.TransactionIDs[len(wsp.TransactionIDs)-1]
+	if _, err = st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Exactly one of transactionid and address must be provided.
+	values = url.Values{}
+	values.Set("label", "rent")
+	if err = st.stdPostAPI("/wallet/labels", values); err == nil {
+		t.Fatal("expected a label without an id to be rejected")
+	}
+	values.Set("transactionid", txid.String())
+	values.Set("address", addr.String())
+	if err = st.stdPostAPI("/wallet/labels", values); err == nil {
+		t.Fatal("expected a label with two ids to be rejected")
+	}
+	values.Del("address")
+	values.Set("note", "October")
+	if err = st.stdPostAPI("/wallet/labels", values); err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("address", addr.String())
+	values.Set("label", "landlord")
+	if err = st.stdPostAPI("/wallet/labels", values); err != nil {
+		t.Fatal(err)
+	}
+
+	var wlg WalletLabelsGET
+	if err = st.getAPI("/wallet/labels", &wlg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wlg.Transactions) != 1 || wlg.Transactions[0].TransactionID != txid || wlg.Transactions[0].Label != "rent" {
+		t.Fatal("unexpected transaction labels:", wlg.Transactions)
+	}
+	if len(wlg.Addresses) != 1 || wlg.Addresses[0].Address != addr || wlg.Addresses[0].Label != "landlord" {
+		t.Fatal("unexpected address labels:", wlg.Addresses)
+	}
+
+	// The label and the category appear in the transaction history.
+	var wtg WalletTransactionsGET
+	if err = st.getAPI("/wallet/transactions?startheight=0&endheight=10000", &wtg); err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, txn := range wtg.ConfirmedTransactions {
+		if txn.TransactionID != txid {
+			continue
+		}
+		found = true
+		if txn.Label != "rent" || txn.Note != "October" || txn.Category != modules.CategorySend {
+			t.Fatal("unexpected label or category:", txn.Label, txn.Note, txn.Category)
+		}
+	}
+	if !found {
+		t.Fatal("labeled transaction is not in the history")
+	}
+
+	// A time range that ends before the transaction was confirmed excludes
+	// it.
+	if err = st.getAPI("/wallet/transactions?endtime=1", &wtg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wtg.ConfirmedTransactions) != 0 {
+		t.Fatal("expected no transactions before the time range, got", len(wtg.ConfirmedTransactions))
+	}
+
+	// The CSV export contains the labeled transaction.
+	resp, err := HttpGET("http://" + st.server.listener.Addr().String() + "/wallet/transactions?format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) < 2 || records[0][0] != "timestamp" {
+		t.Fatal("unexpected csv history:", records)
+	}
+	found = false
+	for _, record := range records[1:] {
+		if record[2] != txid.String() {
+			continue
+		}
+		found = true
+		if record[3] != string(modules.CategorySend) || record[9] != "rent" || record[10] != "October" || record[11] != "landlord" {
+			t.Fatal("unexpected csv record:", record)
+		}
+	}
+	if !found {
+		t.Fatal("labeled transaction is not in the csv history")
+	}
+
+	if err = st.getAPI("/wallet/transactions?format=xml", &wtg); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+// TestWalletTransactionsHostPayout checks that a storage proof which pays out
+// to the wallet is exported as a host payout of the value of the payout.
+func TestWalletTransactionsHostPayout(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Create a file contract whose valid proof output pays the wallet.
+	uc, err := st.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := fastrand.Bytes(4e3)
+	payout := types.SiacoinPrecision.Mul64(10)
+	payoutValue := types.PostTax(st.cs.Height(), payout)
+	fc := types.FileContract{
+		FileSize:           uint64(len(file)),
+		FileMerkleRoot:     crypto.MerkleRoot(file),
+		WindowStart:        st.cs.Height() + 2,
+		WindowEnd:          st.cs.Height() + 10,
+		Payout:             payout,
+		ValidProofOutputs:  []types.SiacoinOutput{{Value: payoutValue, UnlockHash: uc.UnlockHash()}},
+		MissedProofOutputs: []types.SiacoinOutput{{Value: payoutValue}},
+	}
+	builder, err := st.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.FundSiacoins(payout); err != nil {
+		t.Fatal(err)
+	}
+	fcIndex := builder.AddFileContract(fc)
+	tSet, err := builder.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.tpool.AcceptTransactionSet(tSet); err != nil {
+		t.Fatal(err)
+	}
+	fcid := tSet[len(tSet)-1].FileContractID(fcIndex)
+	for st.cs.Height() < fc.WindowStart {
+		if _, err := st.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Submit a storage proof, paying a miner fee like a host does.
+	segmentIndex, err := st.cs.StorageProofSegment(fcid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	segment, hashSet := crypto.MerkleProof(file, segmentIndex)
+	sp := types.StorageProof{
+		ParentID: fcid,
+		HashSet:  hashSet,
+	}
+	copy(sp.Segment[:], segment)
+	fee := types.SiacoinPrecision
+	builder, err = st.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.FundSiacoins(fee); err != nil {
+		t.Fatal(err)
+	}
+	builder.AddMinerFee(fee)
+	builder.AddStorageProof(sp)
+	tSet, err = builder.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.tpool.AcceptTransactionSet(tSet); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	txid := tSet[len(tSet)-1].ID()
+
+	// The CSV export reports the payout and the fee of the proof.
+	resp, err := HttpGET("http://" + st.server.listener.Addr().String() + "/wallet/transactions?format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, record := range records[1:] {
+		if record[2] != txid.String() {
+			continue
+		}
+		found = true
+		if record[3] != string(modules.CategoryHostPayout) || record[4] != payoutValue.String() || record[6] != fee.String() {
+			t.Fatal("unexpected csv record:", record)
+		}
+	}
+	if !found {
+		t.Fatal("storage proof is not in the csv history")
+	}
+}
+
+// TestWalletTimelocked checks that the wallet creates timelocked addresses
+// and reports the siacoins sent to them as timelocked.
+func TestWalletTimelocked(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	values := url.Values{}
+	values.Set("unlockheight", "1")
+	if err = st.stdPostAPI("/wallet/timelocked", values); err == nil {
+		t.Fatal("expected an unlock height in the past to be rejected")
+	}
+	unlockHeight := st.cs.Height() + 10
+	values.Set("unlockheight", fmt.Sprint(unlockHeight))
+	var ta modules.TimelockedAddress
+	if err = st.postAPI("/wallet/timelocked", values, &ta); err != nil {
+		t.Fatal(err)
+	}
+	if ta.UnlockHeight != unlockHeight || ta.UnlockConditions.UnlockHash() != ta.Address {
+		t.Fatal("unexpected timelocked address:", ta)
+	}
+
+	amount := types.SiacoinPrecision.Mul64(10)
+	values = url.Values{}
+	values.Set("amount", amount.String())
+	values.Set("destination", ta.Address.String())
+	if err = st.stdPostAPI("/wallet/siacoins", values); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg WalletGET
+	if err = st.getAPI("/wallet", &wg); err != nil {
+		t.Fatal(err)
+	}
+	if !wg.TimelockedSiacoinBalance.Equals(amount) {
+		t.Fatalf("expected %v timelocked siacoins, got %v", amount, wg.TimelockedSiacoinBalance)
+	}
+	var wtg WalletTimelockedGET
+	if err = st.getAPI("/wallet/timelocked", &wtg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wtg.Addresses) != 1 || wtg.Addresses[0].Address != ta.Address || !wtg.Addresses[0].Balance.Equals(amount) {
+		t.Fatal("unexpected timelocked addresses:", wtg.Addresses)
+	}
+}
+
+// TestWalletEventStream checks that /wallet/events streams the events of the
+// wallet to the client.
+func TestWalletEventStream(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	if err = st.getAPI("/wallet/events?events=foo", nil); err == nil {
+		t.Fatal("expected an unknown event type to be rejected")
+	}
+	if err = st.getAPI("/wallet/events?events=confirmations", nil); err == nil {
+		t.Fatal("expected confirmations events without confirmations to be rejected")
+	}
+	resp, err := HttpGET("http://" + st.server.listener.Addr().String() + "/wallet/events?events=unconfirmed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatal("unexpected content type:", resp.Header.Get("Content-Type"))
+	}
+
+	// Read the events of the stream in the background.
+	events := make(chan modules.WalletEvent)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			var e modules.WalletEvent
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+				t.Error(err)
+				return
+			}
+			events <- e
+		}
+	}()
+
+	var wsp WalletSiacoinsPOST
+	values := url.Values{}
+	values.Set("amount", types.SiacoinPrecision.Mul64(10).String())
+	values.Set("destination", types.UnlockHash{}.String())
+	if err = st.postAPI("/wallet/siacoins", values, &wsp); err != nil {
+		t.Fatal(err)
+	}
+	txid := wsp.TransactionIDs[len(wsp.TransactionIDs)-1]
+	for {
+		select {
+		case e := <-events:
+			if e.Type != modules.WalletEventUnconfirmed {
+				t.Fatal("received an event that was filtered:", e.Type)
+			}
+			if e.TransactionID == txid {
+				return
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("the stream did not receive the unconfirmed event")
+		}
+	}
+}
+
+// eventStreamWallet is a wallet that sends a number of batches of events to
+// every event subscriber as soon as it subscribes.
+type eventStreamWallet struct {
+	modules.Wallet
+	batches int
+}
+
+func (w eventStreamWallet) SubscribeEvents(s modules.WalletEventSubscriber) {
+	for i := 0; i < w.batches; i++ {
+		s.ReceiveWalletEvents([]modules.WalletEvent{{Type: modules.WalletEventUnconfirmed, Height: types.BlockHeight(i)}})
+	}
+}
+
+func (w eventStreamWallet) UnsubscribeEvents(modules.WalletEventSubscriber) {}
+
+// TestWalletEventStreamDropped checks that a /wallet/events stream that
+// falls behind sends the buffered events and a dropped event, and is closed.
+func TestWalletEventStreamDropped(t *testing.T) {
+	api := &API{wallet: eventStreamWallet{batches: walletEventStreamBuffer + 1}}
+	rec := httptest.NewRecorder()
+	api.walletEventsHandler(rec, httptest.NewRequest("GET", "/wallet/events", nil), nil)
+
+	body := rec.Body.String()
+	if n := strings.Count(body, "event: unconfirmed\n"); n != walletEventStreamBuffer {
+		t.Fatalf("expected %v buffered events, got %v", walletEventStreamBuffer, n)
+	}
+	if !strings.HasSuffix(body, "event: dropped\ndata: {}\n\n") {
+		t.Fatal("stream did not end with a dropped event")
+	}
+}
+
+// TestWalletWebhooks checks that webhooks can be added, listed and removed
+// through the API.
+func TestWalletWebhooks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	values := url.Values{}
+	values.Set("url", "foo")
+	if err = st.stdPostAPI("/wallet/webhooks", values); err == nil {
+		t.Fatal("expected an invalid URL to be rejected")
+	}
+	values.Set("url", "http://localhost:9980/hook")
+	values.Set("confirmations", fmt.Sprint(modules.WalletEventMaxConfirmations+1))
+	if err = st.stdPostAPI("/wallet/webhooks", values); err == nil {
+		t.Fatal("expected too many confirmations to be rejected")
+	}
+	values.Set("confirmations", "1")
+	if err = st.stdPostAPI("/wallet/webhooks", values); err == nil {
+		t.Fatal("expected too few confirmations to be rejected")
+	}
+	values.Del("confirmations")
+	values.Set("events", "confirmations")
+	if err = st.stdPostAPI("/wallet/webhooks", values); err == nil {
+		t.Fatal("expected confirmations events without confirmations to be rejected")
+	}
+	values.Set("confirmations", "6")
+	values.Set("events", "confirmed,confirmations")
+	values.Set("secret", "foo")
+	if err = st.stdPostAPI("/wallet/webhooks", values); err != nil {
+		t.Fatal(err)
+	}
+
+	var wwg WalletWebhooksGET
+	if err = st.getAPI("/wallet/webhooks", &wwg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wwg.Webhooks) != 1 {
+		t.Fatal("expected one webhook, got", len(wwg.Webhooks))
+	}
+	hook := wwg.Webhooks[0]
+	if hook.URL != "http://localhost:9980/hook" || hook.Secret != "" || hook.Confirmations != 6 || len(hook.Events) != 2 {
+		t.Fatal("unexpected webhook:", hook)
+	}
+
+	values = url.Values{}
+	values.Set("url", hook.URL)
+	if err = st.stdPostAPI("/wallet/webhooks/remove", values); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.stdPostAPI("/wallet/webhooks/remove", values); err == nil {
+		t.Fatal("expected removing an unknown webhook to fail")
+	}
+	if err = st.getAPI("/wallet/webhooks", &wwg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wwg.Webhooks) != 0 {
+		t.Fatal("webhook was not removed:", wwg.Webhooks)
+	}
+}
+
+// TestWalletAddressBalance checks that the API reports the balance of each
+// address of the wallet.
+func TestWalletAddressBalance(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	if err = st.getAPI("/wallet/address/"+types.UnlockHash{}.String()+"/balance", nil); err == nil {
+		t.Fatal("expected the balance of an unknown address to be rejected")
+	}
+	var wag WalletAddressGET
+	if err = st.getAPI("/wallet/address", &wag); err != nil {
+		t.Fatal(err)
+	}
+	amount := types.SiacoinPrecision.Mul64(10)
+	values := url.Values{}
+	values.Set("amount", amount.String())
+	values.Set("destination", wag.Address.String())
+	if err = st.stdPostAPI("/wallet/siacoins", values); err != nil {
+		t.Fatal(err)
+	}
+
+	var ab modules.AddressBalance
+	if err = st.getAPI("/wallet/address/"+wag.Address.String()+"/balance", &ab); err != nil {
+		t.Fatal(err)
+	}
+	if !ab.UnconfirmedIncomingSiacoins.Equals(amount) || ab.UnconfirmedSiacoinOutputs != 1 {
+		t.Fatal("unexpected unconfirmed balance:", ab)
+	}
+	if _, err = st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/wallet/address/"+wag.Address.String()+"/balance", &ab); err != nil {
+		t.Fatal(err)
+	}
+	if !ab.ConfirmedSiacoinBalance.Equals(amount) || ab.ConfirmedSiacoinOutputs != 1 || !ab.UnconfirmedIncomingSiacoins.IsZero() {
+		t.Fatal("unexpected confirmed balance:", ab)
+	}
+
+	var wasg WalletAddressesGET
+	if err = st.getAPI("/wallet/addresses", &wasg); err != nil {
+		t.Fatal(err)
+	}
+	if wasg.Balances != nil {
+		t.Fatal("balances were returned without being requested")
+	}
+	if err = st.getAPI("/wallet/addresses?withbalance=true", &wasg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wasg.Balances) != len(wasg.Addresses) {
+		t.Fatal("expected a balance for every address")
+	}
+	for _, b := range wasg.Balances {
+		if b.Address == wag.Address {
+			if !b.ConfirmedSiacoinBalance.Equals(amount) {
+				t.Fatal("wrong balance for the address:", b)
+			}
+			return
+		}
+	}
+	t.Fatal("address is missing from the balances")
+}
+
+// TestWalletSpendingPassword checks that the wallet refuses to spend without
+// the spending password once it was set, and that the auto-lock timeout can
+// be changed through /wallet/settings.
+func TestWalletSpendingPassword(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Give the wallet a known encryption password.
+	if err = st.wallet.ChangeKey(st.walletKey, crypto.TwofishKey(crypto.HashObject("password"))); err != nil {
+		t.Fatal(err)
+	}
+	values := url.Values{}
+	values.Set("encryptionpassword", "wrong password")
+	values.Set("spendingpassword", "spend")
+	if err = st.stdPostAPI("/wallet/spendingpassword", values); err == nil {
+		t.Fatal("expected the wrong encryption password to be rejected")
+	}
+	values.Set("encryptionpassword", "password")
+	if err = st.stdPostAPI("/wallet/spendingpassword", values); err != nil {
+		t.Fatal(err)
+	}
+	var wg WalletGET
+	if err = st.getAPI("/wallet", &wg); err != nil {
+		t.Fatal(err)
+	}
+	if !wg.SpendingPasswordRequired {
+		t.Fatal("wallet should require a spending password")
+	}
+
+	var wag WalletAddressGET
+	if err = st.getAPI("/wallet/address", &wag); err != nil {
+		t.Fatal(err)
+	}
+	send := url.Values{}
+	send.Set("amount", types.SiacoinPrecision.String())
+	send.Set("destination", wag.Address.String())
+	if err = st.stdPostAPI("/wallet/siacoins", send); err == nil {
+		t.Fatal("expected a send without the spending password to fail")
+	}
+	send.Set("spendingpassword", "wrong")
+	if err = st.stdPostAPI("/wallet/siacoins", send); err == nil {
+		t.Fatal("expected a send with the wrong spending password to fail")
+	}
+	send.Set("spendingpassword", "spend")
+	var wsp WalletSiacoinsPOST
+	if err = st.postAPI("/wallet/siacoins", send, &wsp); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every other call that signs transactions or exports keys is rejected
+	// without the spending password too.
+	var seed modules.Seed
+	fastrand.Read(seed[:])
+	seedStr, err := modules.SeedToString(seed, "english")
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := wsp.TransactionIDs[len(wsp.TransactionIDs)-1]
+	routes := []struct {
+		call    string
+		get     bool
+		values  url.Values
+		succeed bool
+	}{
+		{"/wallet/backup", true, url.Values{"destination": {filepath.Join(st.dir, "spending.backup")}}, true},
+		{"/wallet/bumpfee/" + txid.String(), false, url.Values{}, true},
+		{"/wallet/multisig/spend", false, url.Values{"address": {wag.Address.String()}, "amount": {types.SiacoinPrecision.String()}, "destination": {wag.Address.String()}}, false},
+		{"/wallet/seeds", true, url.Values{"dictionary": {"english"}}, true},
+		{"/wallet/sweep/seed", false, url.Values{"seed": {seedStr}}, false},
+	}
+	for _, r := range routes {
+		call := func(call string, values url.Values) error {
+			if r.get {
+				return st.getAPI(call+"?"+values.Encode(), &struct{}{})
+			}
+			return st.stdPostAPI(call, values)
+		}
+		if err := call(r.call, r.values); err == nil || !strings.Contains(err.Error(), modules.ErrSpendingKeyRequired.Error()) {
+			t.Errorf("%v: expected ErrSpendingKeyRequired without the spending password, got %v", r.call, err)
+		}
+		r.values.Set("spendingpassword", "wrong")
+		if err := call(r.call, r.values); err == nil || !strings.Contains(err.Error(), modules.ErrBadSpendingKey.Error()) {
+			t.Errorf("%v: expected ErrBadSpendingKey with the wrong spending password, got %v", r.call, err)
+		}
+		// Routes that cannot succeed here, because the wallet has nothing to
+		// spend from, must at least get past the spending password.
+		r.values.Set("spendingpassword", "spend")
+		err := call(r.call, r.values)
+		if r.succeed && err != nil {
+			t.Errorf("%v: %v", r.call, err)
+		} else if err != nil && (strings.Contains(err.Error(), modules.ErrSpendingKeyRequired.Error()) || strings.Contains(err.Error(), modules.ErrBadSpendingKey.Error())) {
+			t.Errorf("%v: spending password was rejected: %v", r.call, err)
+		}
+	}
+
+	// Removing the spending password allows sends without it again.
+	values.Set("spendingpassword", "")
+	if err = st.stdPostAPI("/wallet/spendingpassword", values); err != nil {
+		t.Fatal(err)
+	}
+	send.Del("spendingpassword")
+	if err = st.stdPostAPI("/wallet/siacoins", send); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := url.Values{}
+	settings.Set("autolocktimeout", "3600")
+	if err = st.stdPostAPI("/wallet/settings", settings); err != nil {
+		t.Fatal(err)
+	}
+	var wsg WalletSettingsGET
+	if err = st.getAPI("/wallet/settings", &wsg); err != nil {
+		t.Fatal(err)
+	}
+	if wsg.AutoLockTimeout != 3600 {
+		t.Fatal("expected an auto-lock timeout of 3600 seconds, got", wsg.AutoLockTimeout)
+	}
+}
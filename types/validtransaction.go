@@ -292,6 +292,21 @@ func (t Transaction) validUnlockConditions(currentHeight BlockHeight) (err error
 // transaction. StandaloneValid will not check that all outputs being spent are
 // legal outputs, as it has no confirmed or unconfirmed set to look at.
 func (t Transaction) StandaloneValid(currentHeight BlockHeight) (err error) {
+	err = t.StandaloneValidWithoutSignatures(currentHeight)
+	if err != nil {
+		return
+	}
+	err = t.validSignatures(currentHeight)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// StandaloneValidWithoutSignatures performs every check of StandaloneValid
+// except for signature verification. It should only be used for transactions
+// in blocks that are committed to by a trusted checkpoint.
+func (t Transaction) StandaloneValidWithoutSignatures(currentHeight BlockHeight) (err error) {
 	err = t.fitsInABlock(currentHeight)
 	if err != nil {
 		return
@@ -320,9 +335,5 @@ func (t Transaction) StandaloneValid(currentHeight BlockHeight) (err error) {
 	if err != nil {
 		return
 	}
-	err = t.validSignatures(currentHeight)
-	if err != nil {
-		return
-	}
 	return
 }